@@ -0,0 +1,53 @@
+package bank_adapter
+
+import (
+	"context"
+	"math/big"
+)
+
+type MovementKind string
+
+const (
+	MovementMint     MovementKind = "mint"     // native VND issued against the reserve
+	MovementBurn     MovementKind = "burn"     // native VND destroyed, its backing released
+	MovementDeposit  MovementKind = "deposit"  // fiat added to the reserve account
+	MovementWithdraw MovementKind = "withdraw" // fiat taken out of the reserve account
+)
+
+// AccountBalance is the balance of a reserve account in the core banking
+// system of a bank
+type AccountBalance struct {
+	Account string   `json:"account"`
+	Balance *big.Int `json:"balance"`
+}
+
+// Movement is a reserve change recorded on chain, posted to the core banking
+// system so both ledgers move together. Reference is the hash of the
+// transaction that recorded it, core systems use it to ignore duplicates.
+type Movement struct {
+	Reference string       `json:"reference"`
+	Kind      MovementKind `json:"kind"`
+	Amount    *big.Int     `json:"amount"`
+	Height    uint64       `json:"height"`
+	Timestamp uint64       `json:"timestamp"`
+}
+
+// BankAdapter connects a node to the core banking system of its member bank.
+// Every bank runs a different core system, each gets its own implementation.
+type BankAdapter interface {
+	// Balances returns the balances of the reserve accounts of the bank
+	Balances(ctx context.Context) ([]*AccountBalance, error)
+	// PostMovement records an on-chain reserve movement in the core system
+	PostMovement(ctx context.Context, movement *Movement) error
+}
+
+// TotalBalance sums the balances of reserve accounts
+func TotalBalance(balances []*AccountBalance) *big.Int {
+	total := new(big.Int)
+	for _, balance := range balances {
+		if balance.Balance != nil {
+			total.Add(total, balance.Balance)
+		}
+	}
+	return total
+}
@@ -0,0 +1,123 @@
+package bank_adapter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultHTTPTimeout = 10 * time.Second
+
+// HTTPAdapterConfig configures the connection to a core banking JSON API.
+// Both sides authenticate with certificates: the node presents CertFile and
+// only trusts a server certificate signed by CAFile.
+type HTTPAdapterConfig struct {
+	BaseURL  string
+	CertFile string
+	KeyFile  string
+	CAFile   string
+	Timeout  time.Duration // 0 uses the default
+}
+
+// HTTPAdapter talks to a core banking system exposing
+//
+//	GET  {base}/reserve/balances  -> {"accounts": [{"account": "...", "balance": 1000}]}
+//	POST {base}/reserve/movements <- Movement
+//
+// over mutually authenticated TLS
+type HTTPAdapter struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPAdapter(cfg HTTPAdapterConfig) (*HTTPAdapter, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("missing core banking url")
+	}
+	certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load client certificate: %w", err)
+	}
+	caCert, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read core banking CA: %w", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("invalid core banking CA certificate")
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &HTTPAdapter{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					Certificates: []tls.Certificate{certificate},
+					RootCAs:      rootCAs,
+					MinVersion:   tls.VersionTLS12,
+				},
+			},
+		},
+	}, nil
+}
+
+func (a *HTTPAdapter) Balances(ctx context.Context) ([]*AccountBalance, error) {
+	var response struct {
+		Accounts []*AccountBalance `json:"accounts"`
+	}
+	if err := a.do(ctx, http.MethodGet, "/reserve/balances", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Accounts, nil
+}
+
+func (a *HTTPAdapter) PostMovement(ctx context.Context, movement *Movement) error {
+	return a.do(ctx, http.MethodPost, "/reserve/movements", movement, nil)
+}
+
+func (a *HTTPAdapter) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("core banking %s %s failed: %s %s", method, path, resp.Status, message)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,132 @@
+package bank_adapter
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCertificate issues a certificate signed by parent, self signed when
+// parent is nil
+func newTestCertificate(t *testing.T, name string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testCertificate{cert: cert, key: key, der: der}
+}
+
+func (c *testCertificate) writeFiles(t *testing.T, dir, name string) (string, string) {
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestHTTPAdapter(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "core-banking-ca", nil)
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	serverCert := newTestCertificate(t, "core-banking", ca)
+	clientCert := newTestCertificate(t, "node", ca)
+	certFile, keyFile := clientCert.writeFiles(t, dir, "node")
+
+	var posted []*Movement
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reserve/balances", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"accounts": [{"account": "reserve-1", "balance": 700}, {"account": "reserve-2", "balance": 300}]}`))
+	})
+	mux.HandleFunc("/reserve/movements", func(w http.ResponseWriter, r *http.Request) {
+		movement := &Movement{}
+		if err := json.NewDecoder(r.Body).Decode(movement); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		posted = append(posted, movement)
+	})
+	server := httptest.NewUnstartedServer(mux)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.der}, PrivateKey: serverCert.key}},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	adapter, err := NewHTTPAdapter(HTTPAdapterConfig{
+		BaseURL:  server.URL + "/",
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+	})
+	assert.NoError(t, err)
+
+	balances, err := adapter.Balances(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+	assert.Equal(t, big.NewInt(1000), TotalBalance(balances))
+
+	movement := &Movement{Reference: "0x01", Kind: MovementMint, Amount: big.NewInt(20), Height: 1}
+	assert.NoError(t, adapter.PostMovement(context.Background(), movement))
+	assert.Equal(t, []*Movement{movement}, posted)
+
+	t.Run("TestUnknownClient", func(t *testing.T) {
+		otherCA := newTestCertificate(t, "other-ca", nil)
+		otherClient := newTestCertificate(t, "intruder", otherCA)
+		certFile, keyFile := otherClient.writeFiles(t, dir, "intruder")
+		intruder, err := NewHTTPAdapter(HTTPAdapterConfig{
+			BaseURL:  server.URL,
+			CertFile: certFile,
+			KeyFile:  keyFile,
+			CAFile:   caFile,
+		})
+		assert.NoError(t, err)
+		_, err = intruder.Balances(context.Background())
+		assert.Error(t, err)
+	})
+}
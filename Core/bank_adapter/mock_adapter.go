@@ -0,0 +1,103 @@
+package bank_adapter
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+)
+
+// MockAdapter is an in-memory core banking system for tests and local
+// networks. Its balances can be loaded from a JSON file mapping account names
+// to balances.
+type MockAdapter struct {
+	balances  map[string]*big.Int
+	movements []*Movement
+	err       error
+
+	sync.RWMutex
+}
+
+func NewMockAdapter() *MockAdapter {
+	return &MockAdapter{
+		balances: make(map[string]*big.Int),
+	}
+}
+
+// NewMockAdapterFromFile loads the account balances of a mock adapter from a
+// JSON file such as {"reserve-1": 1000}
+func NewMockAdapterFromFile(path string) (*MockAdapter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]*big.Int)
+	if err := json.Unmarshal(data, &balances); err != nil {
+		return nil, err
+	}
+	adapter := NewMockAdapter()
+	for account, balance := range balances {
+		adapter.SetBalance(account, balance)
+	}
+	return adapter, nil
+}
+
+// SetBalance overwrites the balance of a reserve account
+func (a *MockAdapter) SetBalance(account string, balance *big.Int) {
+	a.Lock()
+	defer a.Unlock()
+	a.balances[account] = new(big.Int).Set(balance)
+}
+
+// SetError makes every following call fail with err, nil recovers
+func (a *MockAdapter) SetError(err error) {
+	a.Lock()
+	defer a.Unlock()
+	a.err = err
+}
+
+// Movements returns the movements posted so far
+func (a *MockAdapter) Movements() []*Movement {
+	a.RLock()
+	defer a.RUnlock()
+	return append([]*Movement(nil), a.movements...)
+}
+
+// Balances returns the account balances sorted by account
+func (a *MockAdapter) Balances(ctx context.Context) ([]*AccountBalance, error) {
+	a.RLock()
+	defer a.RUnlock()
+	if a.err != nil {
+		return nil, a.err
+	}
+	balances := make([]*AccountBalance, 0, len(a.balances))
+	for account, balance := range a.balances {
+		balances = append(balances, &AccountBalance{
+			Account: account,
+			Balance: new(big.Int).Set(balance),
+		})
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Account < balances[j].Account
+	})
+	return balances, nil
+}
+
+// PostMovement records the movement, a reference already posted is ignored
+// like a core system would
+func (a *MockAdapter) PostMovement(ctx context.Context, movement *Movement) error {
+	a.Lock()
+	defer a.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	for _, posted := range a.movements {
+		if posted.Reference == movement.Reference {
+			return nil
+		}
+	}
+	a.movements = append(a.movements, movement)
+	return nil
+}
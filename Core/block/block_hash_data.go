@@ -18,7 +18,9 @@ type BlockHashData struct {
 	Timestamp        uint64
 	Prevrandao       common.Hash // <-- New field for PoS randomness
 	Proposer         common.Address
+	GasUsed          uint64
 	ExtraData        []byte // Optional
+	Round            uint64
 }
 
 func (h *BlockHashData) Proto() *pb.BlockHashData {
@@ -33,7 +35,9 @@ func (h *BlockHashData) Proto() *pb.BlockHashData {
 		Timestamp:        h.Timestamp,
 		Prevrandao:       h.Prevrandao.Bytes(),
 		Proposer:         h.Proposer.Bytes(),
+		GasUsed:          h.GasUsed,
 		ExtraData:        h.ExtraData,
+		Round:            h.Round,
 	}
 }
 
@@ -48,5 +52,7 @@ func (h *BlockHashData) FromProto(pbData *pb.BlockHashData) {
 	h.Timestamp = pbData.Timestamp
 	h.Prevrandao = common.BytesToHash(pbData.Prevrandao)
 	h.Proposer = common.BytesToAddress(pbData.Proposer)
+	h.GasUsed = pbData.GasUsed
 	h.ExtraData = pbData.ExtraData
+	h.Round = pbData.Round
 }
@@ -27,6 +27,7 @@ type BlockHeader struct {
 	Signature        []byte
 	ExtraData        []byte
 	GasUsed          uint64 // <-- New field
+	Round            uint64 // proposer round, 0 when produced by the scheduled proposer
 }
 
 // --- BlockHeader conversion functions ---
@@ -47,7 +48,9 @@ func (h *BlockHeader) Proto() *pb.BlockHeader {
 		Prevrandao:       h.Prevrandao.Bytes(), // <-- New
 		Proposer:         h.Proposer.Bytes(),
 		Signature:        h.Signature,
+		GasUsed:          h.GasUsed,
 		ExtraData:        h.ExtraData,
+		Round:            h.Round,
 	}
 }
 
@@ -63,7 +66,9 @@ func (h *BlockHeader) FromProto(pbHeader *pb.BlockHeader) {
 	h.Prevrandao = common.BytesToHash(pbHeader.Prevrandao) // <-- New
 	h.Proposer = common.BytesToAddress(pbHeader.Proposer)
 	h.Signature = pbHeader.Signature
+	h.GasUsed = pbHeader.GasUsed
 	h.ExtraData = pbHeader.ExtraData
+	h.Round = pbHeader.Round
 }
 
 // ToHashData converts BlockHeader to BlockHashData (used for hash calculation)
@@ -85,7 +90,9 @@ func (h *BlockHeader) ToHashData() *BlockHashData {
 		Timestamp:        h.Timestamp,
 		Prevrandao:       h.Prevrandao, // <-- New
 		Proposer:         h.Proposer,
+		GasUsed:          h.GasUsed,
 		ExtraData:        h.ExtraData,
+		Round:            h.Round,
 	}
 }
 
@@ -114,6 +121,7 @@ func (h *BlockHeader) String() string {
   Signature:        %s
   ExtraData:        %s
   GasUsed:          %d
+  Round:            %d
 }`,
 		h.Height,
 		h.ParentHash.String(),
@@ -128,5 +136,6 @@ func (h *BlockHeader) String() string {
 		hex.EncodeToString(h.Signature),
 		hex.EncodeToString(h.ExtraData),
 		h.GasUsed,
+		h.Round,
 	)
 }
@@ -1,15 +1,15 @@
 package block_builder
 
 import (
-	"math/big"
-	"time"
+	"errors"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
 
 	"FichainCore/block"
 	"FichainCore/block_chain"
-	"FichainCore/bloom"
 	"FichainCore/common"
+	"FichainCore/consensus"
+	core_errors "FichainCore/errors"
 	"FichainCore/gas_pool"
 	"FichainCore/log"
 	"FichainCore/params"
@@ -19,7 +19,6 @@ import (
 	"FichainCore/transaction"
 	"FichainCore/transaction_pool"
 	"FichainCore/transaction_validator"
-	"FichainCore/trie"
 )
 
 type BlockBuilder struct {
@@ -28,6 +27,7 @@ type BlockBuilder struct {
 	coinBase             *common.Address
 
 	bc            *block_chain.BlockChain
+	engine        consensus.Engine
 	statedb       *state.StateDB
 	gasPool       *gas_pool.GasPool
 	currentHeader *block.BlockHeader
@@ -41,6 +41,7 @@ func NewBlockBuilder(
 	transactionValidator *transaction_validator.TransactionValidator,
 	coinBase *common.Address,
 	bc *block_chain.BlockChain,
+	engine consensus.Engine,
 	statedb *state.StateDB,
 ) *BlockBuilder {
 	return &BlockBuilder{
@@ -48,6 +49,7 @@ func NewBlockBuilder(
 		transactionValidator: transactionValidator,
 		coinBase:             coinBase,
 		bc:                   bc,
+		engine:               engine,
 		statedb:              statedb,
 	}
 }
@@ -87,69 +89,85 @@ func (bb *BlockBuilder) GenerateBlock(
 ) (*block.Block, error) {
 	bb.statedb.Reset(lastBlockHeader.StateRoot)
 
-	// TODO: initial block header
 	bb.currentHeader = &block.BlockHeader{
 		Height:     lastBlockHeader.Height + 1,
 		ParentHash: lastBlockHeader.Hash(),
-		Proposer:   *bb.coinBase,
-		Timestamp:  uint64(time.Now().Unix()),
-		Prevrandao: common.BigToHash(big.NewInt(time.Now().Unix())),
 	}
-
-	bl := &block.Block{
-		Header: bb.currentHeader,
+	// let the consensus engine fill proposer, timestamp and randomness
+	if err := bb.engine.Prepare(bb.bc, bb.currentHeader); err != nil {
+		return nil, err
 	}
+
 	bb.txs = make([]*transaction.Transaction, 0)
 	bb.receipts = make([]*receipt.Receipt, 0)
 	gp := gas_pool.GasPool(params.TempGasLimit)
 	bb.gasPool = &gp
 
-	// take all transaction from pool by address and nonce
+	bb.commitTransactions(func(tx *transaction.Transaction, txIndex int) error {
+		err, _ := bb.applyTransaction(tx, txIndex)
+		return err
+	})
+	bl, err := bb.engine.Finalize(
+		bb.bc,
+		bb.currentHeader,
+		bb.statedb,
+		bb.txs,
+		nil,
+		bb.receipts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// sign the header as the scheduled proposer
+	return bb.engine.Seal(bb.bc, bl, nil)
+}
+
+// commitTransactions takes the executable transactions from pool by address
+// and nonce. The ones left out stay pooled until a block including them is
+// imported, except the ones that cannot apply on the state of any later block
+// either, which are discarded so the next nonces of their sender are queued
+// until a valid transaction fills the nonce.
+func (bb *BlockBuilder) commitTransactions(
+	apply func(tx *transaction.Transaction, txIndex int) error,
+) {
 	mAddressTxs := bb.transactionPool.GetPendingTransactions()
-	bb.transactionPool.Clear()
 	txIndex := 0
 	for _, txs := range mAddressTxs {
-		// deep verify transaction
 		for _, tx := range txs {
+			// deep verify transaction
 			err := bb.transactionValidator.DeepVerify(tx)
-			// apply transaction
 			if err != nil {
-				//
-				logger.Warn("error when deep verify transaction", tx, "skiped")
-				continue
+				logger.Warn("error when deep verify transaction", tx, "discarded")
+				bb.transactionPool.Discard(tx)
+				break
 			}
 
-			err, _ = bb.applyTransaction(
-				tx,
-				txIndex,
-			)
-			if err != nil {
-				logger.Warn("error when apply transaction", err, tx, "skiped")
+			err = apply(tx, txIndex)
+			if err == nil {
+				txIndex++
 				continue
 			}
-			txIndex++
+			// the later nonces of the account cannot apply either
+			switch {
+			case errors.Is(err, core_errors.ErrNonceTooLow):
+				// the pool is behind the state, none of the account
+				// transactions applied yet so the working state holds the
+				// nonce of the parent block
+				logger.Warn("error when apply transaction", err, tx, "skiped")
+				if from, err := tx.From(params.TempChainId); err == nil {
+					bb.transactionPool.SetNonce(from, bb.statedb.GetNonce(from))
+				}
+			case errors.Is(err, core_errors.ErrGasLimitReached),
+				errors.Is(err, core_errors.ErrNonceTooHigh):
+				// the block is full or the state is behind the pool, the
+				// transaction may apply in a later block
+				logger.Warn("error when apply transaction", err, tx, "skiped")
+			default:
+				logger.Warn("error when apply transaction", err, tx, "discarded")
+				bb.transactionPool.Discard(tx)
+			}
+			break
 		}
 	}
-	bl.Transactions = bb.txs
-	unclesHash := block.CalcUncleHash(bl.Uncles)
-	bl.Header.UncleHash = unclesHash
-	var err error
-	//
-	bl.Header.TransactionsRoot, err = trie.DeriveSha(bl.Transactions)
-	if err != nil {
-		logger.DebugP("Error 1")
-		return nil, err
-	}
-	//
-	bl.Header.ReceiptRoot, err = trie.DeriveSha(bb.receipts)
-	if err != nil {
-		logger.DebugP("Error 2")
-		return nil, err
-	}
-	bl.Header.StateRoot = bb.statedb.IntermediateRoot(true)
-	//
-	rbloom := bloom.CreateBloom(bb.receipts)
-	bl.Header.Bloom = new(big.Int).SetBytes(rbloom.Bytes())
-
-	return bl, nil
 }
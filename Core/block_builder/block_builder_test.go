@@ -0,0 +1,92 @@
+package block_builder
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/common"
+	"FichainCore/crypto"
+	"FichainCore/database"
+	core_errors "FichainCore/errors"
+	"FichainCore/params"
+	"FichainCore/signer"
+	"FichainCore/state"
+	"FichainCore/transaction"
+	"FichainCore/transaction_pool"
+	"FichainCore/transaction_validator"
+	"FichainCore/types"
+)
+
+func newTestTx(t *testing.T, s *signer.Signer, nonce uint64, amount int64) *transaction.Transaction {
+	tx := transaction.NewTransaction(
+		common.HexToAddress("0x1234"),
+		nonce,
+		big.NewInt(amount),
+		nil,
+		21000,
+		big.NewInt(1),
+		"",
+	)
+	hash, err := tx.HashSign(params.TempChainId)
+	assert.NoError(t, err)
+	sign, err := s.SignHash(hash)
+	assert.NoError(t, err)
+	tx.SetSign(sign)
+	return tx
+}
+
+func TestCommitTransactionsDiscardsFailed(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	s := signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key)))
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	memDB, _ := database.NewMemDatabase()
+	st, err := state.New(common.Hash{}, state.NewDatabase(memDB))
+	assert.NoError(t, err)
+	st.AddBalance(from, big.NewInt(100))
+
+	pool := transaction_pool.NewTransactionPool(transaction_pool.DefaultConfig(), st)
+	bb := &BlockBuilder{
+		transactionPool:      pool,
+		transactionValidator: &transaction_validator.TransactionValidator{},
+		statedb:              st,
+	}
+
+	// transfers the amount, like a transaction applied on the state
+	var included []*transaction.Transaction
+	apply := func(tx *transaction.Transaction, txIndex int) error {
+		if tx.Nonce() > st.GetNonce(from) {
+			return core_errors.ErrNonceTooHigh
+		}
+		if st.GetBalance(from).Cmp(tx.Amount()) < 0 {
+			return core_errors.ErrInsufficientFunds
+		}
+		st.SubBalance(from, tx.Amount())
+		st.SetNonce(from, tx.Nonce()+1)
+		included = append(included, tx)
+		return nil
+	}
+
+	unfunded := newTestTx(t, s, 0, 1000)
+	next := newTestTx(t, s, 1, 10)
+	assert.NoError(t, pool.AddTransaction(unfunded))
+	assert.NoError(t, pool.AddTransaction(next))
+
+	bb.commitTransactions(apply)
+	assert.Empty(t, included)
+	assert.Nil(t, pool.Get(unfunded.Hash()), "failed transaction should be discarded")
+	assert.Empty(t, pool.GetPendingTransactions(), "later nonces wait for the nonce to be filled")
+	pending, queued := pool.Stats()
+	assert.Equal(t, 0, pending)
+	assert.Equal(t, 1, queued)
+
+	// a funded transaction fills the nonce and the next one follows it
+	funded := newTestTx(t, s, 0, 50)
+	assert.NoError(t, pool.AddTransaction(funded))
+	bb.commitTransactions(apply)
+	assert.Equal(t, []*transaction.Transaction{funded, next}, included)
+	assert.Equal(t, big.NewInt(40), st.GetBalance(from))
+}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
@@ -44,21 +46,22 @@ func NewClient(
 ) *Client {
 	// init client and connect
 
+	signerInstance := signer.NewSigner(
+		types.PrivateKeyFromBytes(
+			common.FromHex(coreConfig.PrivateKey),
+		),
+	)
+
 	// Setup router with Ping handler
 	router := router.NewRouter()
-	sender := message_sender.NewMessageSender(nil)
+	sender := message_sender.NewMessageSender(nil, signerInstance)
+	router.SetResponseHandler(sender)
 	clientHandler := handlers.NewClientHandler(sender)
 
 	for i, v := range clientHandler.Handlers() {
 		router.RegisterHandler(i, v)
 	}
 
-	signerInstance := signer.NewSigner(
-		types.PrivateKeyFromBytes(
-			common.FromHex(coreConfig.PrivateKey),
-		),
-	)
-
 	if config.GetConfig().BootAddress == "" {
 		logger.Error("missing boot address")
 		panic("err")
@@ -104,14 +107,22 @@ func (c *Client) SendTransaction(
 	if err != nil {
 		return nil, err
 	}
-	c.Sender.SendMessageToPeer(
+	res, err := c.Sender.Request(
+		context.Background(),
 		c.ServerConnectionPeer,
 		message.MessageGetNonce,
 		&message.BytesMessage{
 			Data: fromAddress.Bytes(),
 		},
 	)
-	nonce := <-c.Handler.NonceChan
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	nonceMessage, ok := res.Payload.(*message.BytesMessage)
+	if !ok || len(nonceMessage.Data) != 8 {
+		return nil, errors.New("invalid nonce response")
+	}
+	nonce := binary.BigEndian.Uint64(nonceMessage.Data)
 
 	// Create a new transaction
 	tx := transaction.NewTransaction(toAddress, nonce, amount, data, gas, gasPrice, txMessage)
@@ -125,6 +136,8 @@ func (c *Client) SendTransaction(
 		return nil, err
 	}
 	tx.SetSign(s)
+	receiptChan := c.Handler.WaitReceipt(tx.Hash())
+	defer c.Handler.ForgetReceipt(tx.Hash())
 	err = c.Sender.SendMessageToPeer(
 		c.ServerConnectionPeer,
 		message.MessageSendTransaction,
@@ -138,7 +151,7 @@ func (c *Client) SendTransaction(
 	select {
 	case <-time.After(30 * time.Second):
 		return nil, errors.New("wait for receipt timeout")
-	case r := <-c.Handler.ReceiptChan:
+	case r := <-receiptChan:
 		return r, nil
 	}
 }
@@ -155,20 +168,21 @@ func (c *Client) CallSmartContract(
 	}
 	sign, err := c.Signer.SignHash(hash)
 	callData.SetSign(sign)
-	err = c.Sender.SendMessageToPeer(
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := c.Sender.Request(
+		ctx,
 		c.ServerConnectionPeer,
 		message.MessageCallSmartContract,
 		callData,
 	)
 	if err != nil {
-		logger.Error("error when send message to peer", err)
+		logger.Error("error when call smart contract", err)
 		return nil, err
 	}
-	logger.Info("Call sent")
-	select {
-	case <-time.After(30 * time.Second):
-		return nil, errors.New("wait for receipt timeout")
-	case r := <-c.Handler.CallResponseChan:
-		return r, nil
+	r, ok := res.Payload.(*call_data.CallSmartContractResponse)
+	if !ok {
+		return nil, errors.New("invalid call response")
 	}
+	return r, nil
 }
@@ -1,12 +1,12 @@
 package handlers
 
 import (
-	"encoding/binary"
 	"math/big"
+	"sync"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
 
-	"FichainCore/call_data"
+	"FichainCore/common"
 	"FichainCore/p2p"
 	"FichainCore/p2p/message"
 	"FichainCore/p2p/message_sender"
@@ -14,33 +14,48 @@ import (
 	"FichainCore/transaction"
 )
 
-// simple handler for client
+// simple handler for client, nonces and call results are requested with
+// MessageSender.Request, receipts are pushed once their transaction is mined
+// and handed to the caller waiting for that transaction
 
 type ClientHandler struct {
-	NonceChan        chan uint64
-	ReceiptChan      chan *receipt.Receipt
-	CallResponseChan chan *call_data.CallSmartContractResponse
-	Sender           *message_sender.MessageSender
+	Sender *message_sender.MessageSender
+
+	receipts map[common.Hash]chan *receipt.Receipt
+	mu       sync.Mutex
 }
 
 func NewClientHandler(
 	Sender *message_sender.MessageSender,
 ) *ClientHandler {
 	return &ClientHandler{
-		NonceChan:        make(chan uint64),
-		ReceiptChan:      make(chan *receipt.Receipt),
-		CallResponseChan: make(chan *call_data.CallSmartContractResponse),
-		Sender:           Sender,
+		Sender:   Sender,
+		receipts: make(map[common.Hash]chan *receipt.Receipt),
 	}
 }
 
+// WaitReceipt registers a caller waiting for the receipt of a transaction,
+// it must be called before the transaction is sent and be followed by
+// ForgetReceipt
+func (h *ClientHandler) WaitReceipt(txHash common.Hash) <-chan *receipt.Receipt {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan *receipt.Receipt, 1)
+	h.receipts[txHash] = ch
+	return ch
+}
+
+func (h *ClientHandler) ForgetReceipt(txHash common.Hash) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.receipts, txHash)
+}
+
 func (h *ClientHandler) Handlers() map[string]func(p2p.Peer, *message.Message) error {
 	return map[string]func(p2p.Peer, *message.Message) error{
-		message.MessageBalance:    h.Balance,
-		message.MessageNonce:      h.Nonce,
-		message.MessageTxMined:    h.TxMined,
-		message.MessageReceipt:    h.TxReceipt,
-		message.MessageCallResult: h.CallResult,
+		message.MessageBalance: h.Balance,
+		message.MessageTxMined: h.TxMined,
+		message.MessageReceipt: h.TxReceipt,
 	}
 }
 
@@ -51,18 +66,6 @@ func (h *ClientHandler) Balance(peer p2p.Peer, msg *message.Message) error {
 	return nil
 }
 
-func (h *ClientHandler) Nonce(peer p2p.Peer, msg *message.Message) error {
-	num := binary.BigEndian.Uint64(msg.Payload.(*message.BytesMessage).Data)
-	h.NonceChan <- num
-	return nil
-}
-
-func (h *ClientHandler) CallResult(peer p2p.Peer, msg *message.Message) error {
-	logger.Info("Received call result: ", msg.Payload.(*call_data.CallSmartContractResponse))
-	h.CallResponseChan <- msg.Payload.(*call_data.CallSmartContractResponse)
-	return nil
-}
-
 func (h *ClientHandler) TxMined(peer p2p.Peer, msg *message.Message) error {
 	tx := msg.Payload.(*transaction.Transaction)
 	// send get receipt
@@ -80,7 +83,14 @@ func (h *ClientHandler) TxMined(peer p2p.Peer, msg *message.Message) error {
 }
 
 func (h *ClientHandler) TxReceipt(peer p2p.Peer, msg *message.Message) error {
-	logger.Info("Received receipt: ", msg.Payload.(*receipt.Receipt))
-	h.ReceiptChan <- msg.Payload.(*receipt.Receipt)
+	rc := msg.Payload.(*receipt.Receipt)
+	logger.Info("Received receipt: ", rc)
+	h.mu.Lock()
+	ch, ok := h.receipts[rc.TxHash]
+	delete(h.receipts, rc.TxHash)
+	h.mu.Unlock()
+	if ok {
+		ch <- rc
+	}
 	return nil
 }
@@ -29,10 +29,16 @@ func main() {
 	}
 	config.InitConfig(*configFile)
 
+	signerInstance := signer.NewSigner(
+		types.PrivateKeyFromBytes(
+			common.FromHex(config.GetConfig().PrivateKey),
+		),
+	)
+
 	// Setup router with Ping handler
 	router := router.NewRouter()
-	pingPongHandler := handlers.NewPingPongHandler()
-	sender := message_sender.NewMessageSender(nil)
+	sender := message_sender.NewMessageSender(nil, signerInstance)
+	pingPongHandler := handlers.NewPingPongHandler(sender)
 	clientHandler := client_handlers.NewClientHandler(sender)
 
 	for i, v := range pingPongHandler.Handlers() {
@@ -42,12 +48,6 @@ func main() {
 		router.RegisterHandler(i, v)
 	}
 
-	signerInstance := signer.NewSigner(
-		types.PrivateKeyFromBytes(
-			common.FromHex(config.GetConfig().PrivateKey),
-		),
-	)
-
 	if config.GetConfig().BootAddress == "" {
 		logger.Error("missing boot address")
 		panic("err")
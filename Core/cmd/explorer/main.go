@@ -48,10 +48,16 @@ func main() {
 
 	database.Init(config.GetConfig().Database)
 
+	signerInstance := signer.NewSigner(
+		types.PrivateKeyFromBytes(
+			common.FromHex(config.GetConfig().Core.PrivateKey),
+		),
+	)
+
 	// Setup router with Ping handler
 	router := router.NewRouter()
-	pingPongHandler := handlers.NewPingPongHandler()
-	sender := message_sender.NewMessageSender(nil)
+	sender := message_sender.NewMessageSender(nil, signerInstance)
+	pingPongHandler := handlers.NewPingPongHandler(sender)
 	chainEventHandler := explorer_handlers.NewChainEventHandler(
 		sender,
 	)
@@ -62,12 +68,6 @@ func main() {
 		router.RegisterHandler(i, v)
 	}
 
-	signerInstance := signer.NewSigner(
-		types.PrivateKeyFromBytes(
-			common.FromHex(config.GetConfig().Core.PrivateKey),
-		),
-	)
-
 	if config.GetConfig().Core.BootAddress == "" {
 		logger.Error("missing boot address")
 		panic("err")
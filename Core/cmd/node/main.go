@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -11,6 +12,7 @@ import (
 
 	"FichainCore/config"
 	"FichainCore/node"
+	"FichainCore/p2p/message"
 )
 
 func main() {
@@ -23,8 +25,16 @@ func main() {
 
 	// Parse command-line flags
 	configFile := flag.String("config", "", "Config file path")
+	messageTypes := flag.Bool("message-types", false, "List the registered p2p message types and exit")
 	flag.Parse()
 
+	if *messageTypes {
+		for _, msgType := range message.RegisteredTypes() {
+			fmt.Println(msgType)
+		}
+		return
+	}
+
 	if *configFile == "" {
 		log.Fatal("You must provide a config file --config")
 	}
@@ -42,9 +42,17 @@ func main() {
 	cfg.ListenAddress = config.GetConfig().TCPServerAddress
 	cfg.Debug = true
 
+	signerInstance := signer.NewSigner(
+		types.PrivateKeyFromBytes(
+			common.FromHex(config.GetConfig().PrivateKey),
+		),
+	)
+
 	// Setup router with Ping handler
 	router := router.NewRouter()
-	pingPongHandler := handlers.NewPingPongHandler()
+	pingPongHandler := handlers.NewPingPongHandler(
+		message_sender.NewMessageSender(nil, signerInstance),
+	)
 	clientHandler := &handlers.ClientHandler{}
 	for i, v := range pingPongHandler.Handlers() {
 		router.RegisterHandler(i, v)
@@ -53,12 +61,6 @@ func main() {
 		router.RegisterHandler(i, v)
 	}
 
-	signerInstance := signer.NewSigner(
-		types.PrivateKeyFromBytes(
-			common.FromHex(config.GetConfig().PrivateKey),
-		),
-	)
-
 	// Start server
 	server := server.NewTCPServer(
 		cfg.ListenAddress,
@@ -121,6 +123,7 @@ func main() {
 	// create sender for ez send message
 	sender := message_sender.NewMessageSender(
 		nil,
+		signerInstance,
 	)
 
 	// Test send get balance
@@ -16,6 +16,7 @@ import (
 	"FichainCore/p2p"
 	"FichainCore/p2p/client"
 	"FichainCore/p2p/message"
+	"FichainCore/p2p/message_sender"
 	"FichainCore/p2p/router"
 	"FichainCore/p2p/server"
 	"FichainCore/signer"
@@ -37,19 +38,21 @@ func main() {
 	cfg.ListenAddress = config.GetConfig().TCPServerAddress
 	cfg.Debug = true
 
-	// Setup router with Ping handler
-	router := router.NewRouter()
-	pingPongHandler := handlers.NewPingPongHandler()
-	for i, v := range pingPongHandler.Handlers() {
-		router.RegisterHandler(i, v)
-	}
-
 	signerInstance := signer.NewSigner(
 		types.PrivateKeyFromBytes(
 			common.FromHex(config.GetConfig().PrivateKey),
 		),
 	)
 
+	// Setup router with Ping handler
+	router := router.NewRouter()
+	pingPongHandler := handlers.NewPingPongHandler(
+		message_sender.NewMessageSender(nil, signerInstance),
+	)
+	for i, v := range pingPongHandler.Handlers() {
+		router.RegisterHandler(i, v)
+	}
+
 	// Start server
 	server := server.NewTCPServer(
 		cfg.ListenAddress,
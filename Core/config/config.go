@@ -16,15 +16,50 @@ type Config struct {
 	PrivateKey string
 
 	TCPServerAddress string
+	AdvertiseAddress string // address other nodes dial this node at, empty uses TCPServerAddress
 	BootAddress      string
 	WsServerAddress  string
 	EkycApiUrl       string
 
+	// consensus
+	ProposerTimeout uint64 // seconds before a backup proposer takes over a slot, 0 uses the default
+
+	// core banking, the reserve of this node's bank is reconciled against it
+	BankAdapterURL      string // core banking JSON API, empty disables the HTTP adapter
+	BankAdapterCertFile string // client certificate presented to the core system
+	BankAdapterKeyFile  string // key of the client certificate
+	BankAdapterCAFile   string // CA the core system certificate must be signed by
+	BankAdapterMockFile string // JSON account balances served by a mock adapter, for local networks
+	ReserveSyncInterval uint64 // seconds between reconciliations, 0 uses the default
+
+	// p2p, a zero value uses the default
+	MaxPeers           int                // connected peers of all roles
+	MaxValidatorPeers  int                // connected validators
+	MaxObserverPeers   int                // connected observers
+	MaxClientPeers     int                // connected wallets and explorers
+	DisableRateLimiter bool               // let peers send messages at any rate
+	MessageRate        float64            // messages of each type a peer may send per second
+	MessageRates       map[string]float64 // per message type overrides of MessageRate
+	BanDuration        uint64             // seconds a misbehaving peer stays banned
+
+	MessagePolicies map[string][]string // message type -> roles allowed to send it (validator, observer, explorer, client, anonymous), overriding the defaults
+
+	// transaction pool, a zero value uses the default
+	TxPoolAccountSlots int    // executable transactions held per account
+	TxPoolAccountQueue int    // future transactions held per account
+	TxPoolGlobalSlots  int    // executable transactions held for all accounts
+	TxPoolGlobalQueue  int    // future transactions held for all accounts
+	TxPoolPriceBump    uint64 // percent a replacement must raise the gas price by
+
+	// sync
+	FastSync bool // a node starting from genesis downloads the state of a recent block instead of replaying the chain
+
 	// storage
 	StatesDBPath               string
 	AuthorityValidatorDBPath   string
 	AuthorityObserverDBPath    string
 	AuthorityFiatReserveDBPath string
+	PeerDBPath                 string // known peers, empty keeps them in memory only
 
 	// explorers
 	ExplorerAddresses []string
@@ -21,7 +21,7 @@ const (
 
 type Authority struct {
 	validators map[common.Address]*big.Int         // address -> weight
-	observers  map[common.Address][]common.Address // address -> accessible node list
+	observers  map[common.Address][]common.Address // address -> accounts whose data the observer may pull
 
 	sync.RWMutex
 }
@@ -85,6 +85,20 @@ func (a *Authority) CommitToStorage(
 	a.RLock()
 	defer a.RUnlock()
 
+	// Drop removed entries
+	if err := deleteMissingKeys(validatorDB, func(key []byte) bool {
+		_, ok := a.validators[common.BytesToAddress(key)]
+		return ok
+	}); err != nil {
+		return fmt.Errorf("failed to delete removed validators: %w", err)
+	}
+	if err := deleteMissingKeys(observerDB, func(key []byte) bool {
+		_, ok := a.observers[common.BytesToAddress(key)]
+		return ok
+	}); err != nil {
+		return fmt.Errorf("failed to delete removed observers: %w", err)
+	}
+
 	// Commit validators
 	valBatch := validatorDB.NewBatch()
 	for address, weight := range a.validators {
@@ -110,6 +124,25 @@ func (a *Authority) CommitToStorage(
 	return nil
 }
 
+func deleteMissingKeys(db database.Database, exists func(key []byte) bool) error {
+	var removed [][]byte
+	err := db.IterateKeys(func(key, value []byte) error {
+		if !exists(key) {
+			removed = append(removed, common.CopyBytes(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range removed {
+		if err := db.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // --------------------------- Validator APIs ---------------------------
 
 func (a *Authority) AddValidator(address common.Address, weight *big.Int) error {
@@ -134,6 +167,17 @@ func (a *Authority) RemoveValidator(address common.Address) error {
 	return nil
 }
 
+func (a *Authority) SetValidatorWeight(address common.Address, weight *big.Int) error {
+	a.Lock()
+	defer a.Unlock()
+
+	if _, exists := a.validators[address]; !exists {
+		return fmt.Errorf("validator not found: %s", address.Hex())
+	}
+	a.validators[address] = weight
+	return nil
+}
+
 func (a *Authority) GetValidatorWeight(address common.Address) (*big.Int, bool) {
 	a.RLock()
 	defer a.RUnlock()
@@ -0,0 +1,94 @@
+package poa_consensus
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/state"
+)
+
+var fastSyncPivotKey = []byte("fast-sync-pivot") // height and state root of the block a fast sync downloaded the state of
+
+type fastSyncPivot struct {
+	Height    uint64
+	StateRoot common.Hash
+}
+
+// ChainStates opens the state the processors read a block against. A fast
+// synced node has no state below its pivot, those blocks are read against the
+// pivot state instead. The applied reserve and evidence slots are never
+// cleared so they answer for older blocks too, and the pivot is the last
+// block of an epoch so the stake read at that epoch end is exact. Older epoch
+// ends only shape the snapshots of epochs whose blocks are not verified again.
+type ChainStates struct {
+	db    state.Database
+	kvdb  database.Database
+	pivot *fastSyncPivot
+
+	sync.RWMutex
+}
+
+func NewChainStates(db state.Database, kvdb database.Database) *ChainStates {
+	return &ChainStates{
+		db:   db,
+		kvdb: kvdb,
+	}
+}
+
+// LoadFromDB restores the pivot of a fast sync
+func (s *ChainStates) LoadFromDB() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if ok, err := s.kvdb.Has(fastSyncPivotKey); err != nil || !ok {
+		return err
+	}
+	data, err := s.kvdb.Get(fastSyncPivotKey)
+	if err != nil {
+		return err
+	}
+	pivot := &fastSyncPivot{}
+	if err := json.Unmarshal(data, pivot); err != nil {
+		return fmt.Errorf("error loading fast sync pivot: %w", err)
+	}
+	s.pivot = pivot
+	return nil
+}
+
+// SetPivot records the block a fast sync downloaded the state of, it must be
+// set before the blocks below it are processed
+func (s *ChainStates) SetPivot(header *block.BlockHeader) error {
+	s.Lock()
+	defer s.Unlock()
+
+	pivot := &fastSyncPivot{Height: header.Height, StateRoot: header.StateRoot}
+	data, err := json.Marshal(pivot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fast sync pivot: %w", err)
+	}
+	if err := s.kvdb.Put(fastSyncPivotKey, data); err != nil {
+		return err
+	}
+	s.pivot = pivot
+	return nil
+}
+
+// At opens the state of a block
+func (s *ChainStates) At(header *block.BlockHeader) (*state.StateDB, error) {
+	s.RLock()
+	root := header.StateRoot
+	if s.pivot != nil && header.Height < s.pivot.Height {
+		root = s.pivot.StateRoot
+	}
+	s.RUnlock()
+
+	st, err := state.New(root, s.db)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open state of block %d: %w", header.Height, err)
+	}
+	return st, nil
+}
@@ -0,0 +1,46 @@
+package poa_consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/state"
+)
+
+func TestChainStates(t *testing.T) {
+	memDB, _ := database.NewMemDatabase()
+	stateDatabase := state.NewDatabase(memDB)
+	holder := common.HexToAddress("0xa11ce")
+	roots := make([]common.Hash, 3)
+	for i := range roots {
+		st, err := state.New(common.Hash{}, stateDatabase)
+		assert.NoError(t, err)
+		st.AddBalance(holder, big.NewInt(int64(i+1)))
+		roots[i], err = st.Commit(false)
+		assert.NoError(t, err)
+	}
+	balanceAt := func(states *ChainStates, height uint64) *big.Int {
+		st, err := states.At(&block.BlockHeader{Height: height, StateRoot: roots[height-1]})
+		assert.NoError(t, err)
+		return st.GetBalance(holder)
+	}
+
+	states := NewChainStates(stateDatabase, memDB)
+	assert.NoError(t, states.LoadFromDB())
+	assert.Equal(t, big.NewInt(1), balanceAt(states, 1))
+
+	// blocks below a fast sync pivot are read against the pivot state
+	assert.NoError(t, states.SetPivot(&block.BlockHeader{Height: 2, StateRoot: roots[1]}))
+	assert.Equal(t, big.NewInt(2), balanceAt(states, 1))
+	assert.Equal(t, big.NewInt(2), balanceAt(states, 2))
+	assert.Equal(t, big.NewInt(3), balanceAt(states, 3))
+
+	restarted := NewChainStates(stateDatabase, memDB)
+	assert.NoError(t, restarted.LoadFromDB())
+	assert.Equal(t, big.NewInt(2), balanceAt(restarted, 1))
+}
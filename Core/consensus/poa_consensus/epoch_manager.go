@@ -0,0 +1,514 @@
+package poa_consensus
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/consensus"
+	"FichainCore/consensus/poa_consensus/voting"
+	"FichainCore/database"
+	"FichainCore/event"
+	"FichainCore/params"
+)
+
+var (
+	epochSnapshotPrefix = []byte("epoch-snapshot-") // epochSnapshotPrefix + epoch (uint64 big endian) -> epoch snapshot
+	epochProcessedKey   = []byte("epoch-processed") // height of the last block handled by the epoch manager
+)
+
+// EpochSnapshot is the validator set active during an epoch together with the
+// salt its proposer schedule is derived from.
+type EpochSnapshot struct {
+	Epoch      uint64
+	Salt       common.Hash
+	Validators map[common.Address]*big.Int
+}
+
+func epochSnapshotKey(epoch uint64) []byte {
+	key := make([]byte, len(epochSnapshotPrefix)+8)
+	copy(key, epochSnapshotPrefix)
+	binary.BigEndian.PutUint64(key[len(epochSnapshotPrefix):], epoch)
+	return key
+}
+
+// WriteEpochSnapshot stores the snapshot of an epoch
+func WriteEpochSnapshot(db database.Database, snapshot *EpochSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal epoch snapshot: %w", err)
+	}
+	if err := db.Put(epochSnapshotKey(snapshot.Epoch), data); err != nil {
+		return fmt.Errorf("failed to store epoch snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadEpochSnapshot returns the snapshot of an epoch, nil if it was never taken
+func ReadEpochSnapshot(db database.Database, epoch uint64) (*EpochSnapshot, error) {
+	key := epochSnapshotKey(epoch)
+	if ok, err := db.Has(key); err != nil || !ok {
+		return nil, err
+	}
+	data, err := db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &EpochSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal epoch snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// ChainEventSubscriber is implemented by the blockchain to notify new blocks
+type ChainEventSubscriber interface {
+	SubscribeChainEvent(ch chan<- event.ChainEvent) event.Subscription
+}
+
+// BlockProcessor derives consensus state from the chain. Blocks are handed
+// over in order, and the epoch end is signaled before the next epoch's
+// validator snapshot is taken, so every node derives the same set.
+type BlockProcessor interface {
+	ProcessBlock(bl *block.Block) error
+	EndEpoch(epoch uint64, last *block.Block) error
+}
+
+// ForkProcessor is a BlockProcessor deriving its state per block hash from the
+// state of the parent block. After a reorg the blocks of the new canonical
+// branch are handed over again from the fork point. Epoch ends are not
+// replayed, the snapshot of a started epoch is never rolled back.
+type ForkProcessor interface {
+	BlockProcessor
+	HasProcessed(hash common.Hash) bool
+}
+
+// EpochManager rotates the proposer schedule at every epoch boundary. When the
+// last block of an epoch is imported, the registered processors close the
+// epoch, then the current validator set is snapshotted and the schedule of the
+// next epoch is derived from it, salted with that block's hash. Past epochs are
+// rebuilt from their snapshots so historical blocks are verified against the
+// validators active at the time.
+type EpochManager struct {
+	authority   *Authority
+	db          database.Database
+	chain       consensus.ChainReader
+	epochLength uint64
+
+	processors []BlockProcessor
+	processMu  sync.Mutex // serializes block processing
+
+	current         *ProposerSchedule
+	currentEpoch    uint64
+	historical      *ProposerSchedule // last schedule looked up for a past epoch
+	historicalEpoch uint64
+
+	chainEvent             chan event.ChainEvent
+	chainEventSubscription event.Subscription
+
+	sync.RWMutex
+}
+
+func NewEpochManager(authority *Authority, db database.Database) *EpochManager {
+	return &EpochManager{
+		authority:   authority,
+		db:          db,
+		epochLength: params.TempEpochLength,
+		chainEvent:  make(chan event.ChainEvent),
+	}
+}
+
+// RegisterProcessor adds a processor run on every imported block. It must be
+// registered before Init.
+func (m *EpochManager) RegisterProcessor(p BlockProcessor) {
+	m.processors = append(m.processors, p)
+}
+
+// Init replays the blocks imported since the last run through the
+// processors, then loads the schedule of the epoch containing the chain head.
+// The genesis epoch snapshot is taken if the node never did.
+func (m *EpochManager) Init(chain consensus.ChainReader) error {
+	m.Lock()
+	m.chain = chain
+	m.Unlock()
+
+	if _, err := m.scheduleAt(0); err != nil {
+		return err
+	}
+	head := chain.CurrentHeader().Height
+	if err := m.catchUp(head); err != nil {
+		return err
+	}
+	_, err := m.scheduleAt(head)
+	return err
+}
+
+func (m *EpochManager) SubscribeChanEvent(bc ChainEventSubscriber) {
+	m.chainEventSubscription = bc.SubscribeChainEvent(m.chainEvent)
+	go m.HandleChanEvent()
+}
+
+// HandleChanEvent processes the chain up to every new block
+func (m *EpochManager) HandleChanEvent() {
+	for {
+		event := <-m.chainEvent
+		if err := m.catchUp(event.Block.Header.Height); err != nil {
+			logger.Error("[EpochManager] error when process block", event.Block.Header.Height, err)
+		}
+	}
+}
+
+// CatchUp processes the chain up to its head, for a head moved without a chain
+// event such as a fast synced pivot
+func (m *EpochManager) CatchUp() error {
+	m.RLock()
+	chain := m.chain
+	m.RUnlock()
+
+	head := chain.CurrentHeader().Height
+	if err := m.catchUp(head); err != nil {
+		return err
+	}
+	_, err := m.scheduleAt(head)
+	return err
+}
+
+// catchUp handles every canonical block after the last processed one up to
+// the given height
+func (m *EpochManager) catchUp(height uint64) error {
+	m.processMu.Lock()
+	defer m.processMu.Unlock()
+
+	m.RLock()
+	chain := m.chain
+	m.RUnlock()
+
+	if err := m.replayFork(chain); err != nil {
+		return err
+	}
+	for number := m.processedHeight() + 1; number <= height; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return fmt.Errorf("missing canonical header %d", number)
+		}
+		bl := chain.GetBlock(header.Hash(), number)
+		if bl == nil {
+			return fmt.Errorf("missing canonical block %d", number)
+		}
+		if err := m.handleBlock(bl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayFork hands the canonical blocks above the fork point of a reorg to
+// the fork processors, up to the last processed height. A reorg below the
+// last finalized block is an error.
+func (m *EpochManager) replayFork(chain consensus.ChainReader) error {
+	var processors []ForkProcessor
+	for _, p := range m.processors {
+		if fp, ok := p.(ForkProcessor); ok {
+			processors = append(processors, fp)
+		}
+	}
+	processed := m.processedHeight()
+	if len(processors) == 0 || processed == 0 {
+		return nil
+	}
+	processedByAll := func(hash common.Hash) bool {
+		for _, p := range processors {
+			if !p.HasProcessed(hash) {
+				return false
+			}
+		}
+		return true
+	}
+
+	fork := processed
+	for ; fork > 0 && processed-fork < params.TempMaxReorgDepth; fork-- {
+		header := chain.GetHeaderByNumber(fork)
+		if header == nil {
+			return fmt.Errorf("missing canonical header %d", fork)
+		}
+		if processedByAll(header.Hash()) {
+			break
+		}
+	}
+	if fork == processed {
+		return nil
+	}
+	// finalized blocks are never reverted, the import refuses such branches
+	if finalized := voting.ReadLastFinalizedHeight(m.db); fork < finalized {
+		return fmt.Errorf("%w: reorg from block %d reverts finalized block %d", voting.ErrFinalizedFork, fork+1, finalized)
+	}
+	logger.Warn("[EpochManager] replaying reorged blocks", fork+1, processed)
+	for number := fork + 1; number <= processed; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return fmt.Errorf("missing canonical header %d", number)
+		}
+		bl := chain.GetBlock(header.Hash(), number)
+		if bl == nil {
+			return fmt.Errorf("missing canonical block %d", number)
+		}
+		for _, p := range processors {
+			if err := p.ProcessBlock(bl); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleBlock runs the processors on a block and starts the next epoch when
+// the block is the last one of its epoch
+func (m *EpochManager) handleBlock(bl *block.Block) error {
+	height := bl.Header.Height
+	for _, p := range m.processors {
+		if err := p.ProcessBlock(bl); err != nil {
+			return err
+		}
+	}
+	if (height+1)%m.epochLength == 0 {
+		next := (height + 1) / m.epochLength
+		for _, p := range m.processors {
+			if err := p.EndEpoch(next-1, bl); err != nil {
+				return err
+			}
+		}
+		if err := m.StartEpoch(next, bl.Hash()); err != nil {
+			return err
+		}
+	}
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, height)
+	return m.db.Put(epochProcessedKey, value)
+}
+
+func (m *EpochManager) processedHeight() uint64 {
+	if ok, err := m.db.Has(epochProcessedKey); err != nil || !ok {
+		return 0
+	}
+	value, err := m.db.Get(epochProcessedKey)
+	if err != nil || len(value) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(value)
+}
+
+// StartEpoch snapshots the current validator set for the given epoch and
+// makes its schedule current. An existing snapshot is never overwritten.
+func (m *EpochManager) StartEpoch(epoch uint64, salt common.Hash) error {
+	snapshot, err := ReadEpochSnapshot(m.db, epoch)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		snapshot = &EpochSnapshot{
+			Epoch:      epoch,
+			Salt:       salt,
+			Validators: m.authority.ListValidators(),
+		}
+		if err := WriteEpochSnapshot(m.db, snapshot); err != nil {
+			return err
+		}
+	}
+
+	m.RLock()
+	started := m.current != nil && m.currentEpoch >= epoch
+	m.RUnlock()
+	if started {
+		return nil
+	}
+
+	schedule, err := m.buildSchedule(snapshot)
+	if err != nil {
+		return err
+	}
+	m.setSchedule(epoch, schedule)
+	logger.Info("[EpochManager] started epoch", epoch, "validators", len(snapshot.Validators))
+	return nil
+}
+
+// CurrentEpoch returns the latest epoch whose schedule is loaded
+func (m *EpochManager) CurrentEpoch() uint64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.currentEpoch
+}
+
+// EpochOf returns the epoch a block height belongs to
+func (m *EpochManager) EpochOf(blockHeight uint64) uint64 {
+	return blockHeight / m.epochLength
+}
+
+// LastBlockOfEpoch returns the height of the last block of the latest epoch
+// ending at or below a block height
+func (m *EpochManager) LastBlockOfEpoch(blockHeight uint64) uint64 {
+	if blockHeight+1 < m.epochLength {
+		return 0
+	}
+	return (blockHeight+1)/m.epochLength*m.epochLength - 1
+}
+
+// Snapshot returns the validator snapshot of an epoch
+func (m *EpochManager) Snapshot(epoch uint64) (*EpochSnapshot, error) {
+	return ReadEpochSnapshot(m.db, epoch)
+}
+
+// EpochValidators returns the validator weights snapshotted for an epoch,
+// catching up the chain if the epoch was not started yet
+func (m *EpochManager) EpochValidators(epoch uint64) (map[common.Address]*big.Int, error) {
+	if _, err := m.scheduleAt(epoch * m.epochLength); err != nil {
+		return nil, err
+	}
+	snapshot, err := ReadEpochSnapshot(m.db, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("missing snapshot of epoch %d", epoch)
+	}
+	return snapshot.Validators, nil
+}
+
+// ValidatorsAt returns the validator weights of the epoch containing a block
+// height, it implements voting.HeightValidatorSet
+func (m *EpochManager) ValidatorsAt(blockHeight uint64) (map[common.Address]*big.Int, error) {
+	return m.EpochValidators(m.EpochOf(blockHeight))
+}
+
+// GetProposer returns the scheduled proposer for a given block height
+func (m *EpochManager) GetProposer(blockHeight uint64) (common.Address, error) {
+	schedule, err := m.scheduleAt(blockHeight)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return schedule.GetProposer(blockHeight)
+}
+
+// GetProposerForRound returns the proposer for a given block height and round
+func (m *EpochManager) GetProposerForRound(
+	blockHeight uint64,
+	round uint64,
+) (common.Address, error) {
+	schedule, err := m.scheduleAt(blockHeight)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return schedule.GetProposerForRound(blockHeight, round)
+}
+
+// scheduleAt returns the schedule of the epoch containing blockHeight,
+// building it from the epoch snapshot if it is not loaded.
+func (m *EpochManager) scheduleAt(blockHeight uint64) (*ProposerSchedule, error) {
+	epoch := m.EpochOf(blockHeight)
+
+	m.RLock()
+	if m.current != nil && m.currentEpoch == epoch {
+		defer m.RUnlock()
+		return m.current, nil
+	}
+	if m.historical != nil && m.historicalEpoch == epoch {
+		defer m.RUnlock()
+		return m.historical, nil
+	}
+	m.RUnlock()
+
+	snapshot, err := ReadEpochSnapshot(m.db, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil && epoch > 0 {
+		// the chain may reach a new epoch before its chain event is handled
+		if err := m.catchUp(epoch*m.epochLength - 1); err != nil {
+			return nil, err
+		}
+		return m.loadedSchedule(epoch)
+	}
+	if snapshot == nil {
+		snapshot, err = m.takeGenesisSnapshot()
+		if err != nil {
+			return nil, err
+		}
+	}
+	schedule, err := m.buildSchedule(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	m.setSchedule(epoch, schedule)
+	return schedule, nil
+}
+
+// loadedSchedule returns the schedule of an epoch once it was started
+func (m *EpochManager) loadedSchedule(epoch uint64) (*ProposerSchedule, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if m.current == nil || m.currentEpoch != epoch {
+		return nil, fmt.Errorf("missing validator snapshot for epoch %d", epoch)
+	}
+	return m.current, nil
+}
+
+// takeGenesisSnapshot snapshots the genesis validator set, salted with the
+// genesis block hash
+func (m *EpochManager) takeGenesisSnapshot() (*EpochSnapshot, error) {
+	m.RLock()
+	chain := m.chain
+	m.RUnlock()
+
+	if chain == nil {
+		return nil, fmt.Errorf("no chain to take genesis snapshot")
+	}
+	genesis := chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return nil, fmt.Errorf("missing genesis header")
+	}
+	snapshot := &EpochSnapshot{
+		Epoch:      0,
+		Salt:       genesis.Hash(),
+		Validators: m.authority.ListValidators(),
+	}
+	if err := WriteEpochSnapshot(m.db, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (m *EpochManager) buildSchedule(snapshot *EpochSnapshot) (*ProposerSchedule, error) {
+	schedule := &ProposerSchedule{epochLength: m.epochLength}
+	err := schedule.UpdateSchedule(
+		snapshot.Salt.Bytes(),
+		snapshot.Epoch*m.epochLength,
+		snapshot.Validators,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// setSchedule keeps the schedule as current if it is the newest epoch, or as
+// the cached historical schedule otherwise
+func (m *EpochManager) setSchedule(epoch uint64, schedule *ProposerSchedule) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.current == nil || epoch > m.currentEpoch {
+		m.current = schedule
+		m.currentEpoch = epoch
+		return
+	}
+	if epoch < m.currentEpoch {
+		m.historical = schedule
+		m.historicalEpoch = epoch
+	}
+}
@@ -0,0 +1,210 @@
+package poa_consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/consensus/poa_consensus/voting"
+	"FichainCore/database"
+)
+
+func TestEpochManager(t *testing.T) {
+	validatorA := common.HexToAddress("0xabc123")
+	validatorB := common.HexToAddress("0xdef456")
+
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(validatorA, big.NewInt(1)))
+
+	genesis := &block.BlockHeader{Height: 0}
+	chain := &testChain{
+		headers: map[common.Hash]*block.BlockHeader{genesis.Hash(): genesis},
+		head:    genesis,
+	}
+	db, _ := database.NewMemDatabase()
+
+	manager := NewEpochManager(authority, db)
+	manager.epochLength = 10
+	assert.NoError(t, manager.Init(chain))
+
+	snapshot, err := manager.Snapshot(0)
+	assert.NoError(t, err)
+	assert.NotNil(t, snapshot, "epoch 0 snapshot should be taken on init")
+	assert.Equal(t, genesis.Hash(), snapshot.Salt, "epoch 0 should be salted with genesis")
+
+	proposer, err := manager.GetProposer(5)
+	assert.NoError(t, err)
+	assert.Equal(t, validatorA, proposer)
+
+	// validator set changes during epoch 0 only apply from epoch 1
+	assert.NoError(t, authority.RemoveValidator(validatorA))
+	assert.NoError(t, authority.AddValidator(validatorB, big.NewInt(1)))
+
+	proposer, err = manager.GetProposer(9)
+	assert.NoError(t, err)
+	assert.Equal(t, validatorA, proposer, "epoch 0 should keep its snapshot")
+
+	lastBlock := &block.BlockHeader{Height: 9, Timestamp: 1}
+	assert.NoError(t, manager.StartEpoch(1, lastBlock.Hash()))
+	assert.Equal(t, uint64(1), manager.CurrentEpoch())
+
+	proposer, err = manager.GetProposer(10)
+	assert.NoError(t, err)
+	assert.Equal(t, validatorB, proposer, "epoch 1 should use the new validator set")
+
+	// historical heights are verified against their epoch snapshot
+	proposer, err = manager.GetProposer(3)
+	assert.NoError(t, err)
+	assert.Equal(t, validatorA, proposer)
+
+	// a restarted node rebuilds schedules from stored snapshots
+	restarted := NewEpochManager(NewAuthority(), db)
+	restarted.epochLength = 10
+	assert.NoError(t, restarted.Init(chain))
+
+	proposer, err = restarted.GetProposer(12)
+	assert.NoError(t, err)
+	assert.Equal(t, validatorB, proposer)
+	proposer, err = restarted.GetProposer(1)
+	assert.NoError(t, err)
+	assert.Equal(t, validatorA, proposer)
+
+	// epochs whose salt block is unknown cannot be scheduled
+	_, err = restarted.GetProposer(25)
+	assert.Error(t, err)
+}
+
+func TestEpochManagerCatchUp(t *testing.T) {
+	validatorA := common.HexToAddress("0xabc123")
+	validatorB := common.HexToAddress("0xdef456")
+
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(validatorA, big.NewInt(1)))
+
+	// build a chain crossing the first epoch boundary
+	chain := &testChain{headers: map[common.Hash]*block.BlockHeader{}}
+	var parent common.Hash
+	for height := uint64(0); height <= 12; height++ {
+		header := &block.BlockHeader{Height: height, ParentHash: parent}
+		chain.headers[header.Hash()] = header
+		chain.head = header
+		parent = header.Hash()
+	}
+	db, _ := database.NewMemDatabase()
+
+	processor := &testProcessor{
+		endEpoch: func(epoch uint64) error {
+			// set changes applied at the epoch end land in the next snapshot
+			return authority.AddValidator(validatorB, big.NewInt(1000))
+		},
+	}
+	manager := NewEpochManager(authority, db)
+	manager.epochLength = 10
+	manager.RegisterProcessor(processor)
+	assert.NoError(t, manager.Init(chain))
+
+	assert.Equal(t, uint64(12), processor.processed, "every block should be processed")
+	assert.Equal(t, []uint64{0}, processor.ended)
+	assert.Equal(t, uint64(1), manager.CurrentEpoch())
+
+	snapshot, err := manager.Snapshot(1)
+	assert.NoError(t, err)
+	assert.Equal(t, chain.GetHeaderByNumber(9).Hash(), snapshot.Salt)
+	assert.Len(t, snapshot.Validators, 2)
+
+	// blocks are not processed twice after restart
+	restarted := NewEpochManager(authority, db)
+	restarted.epochLength = 10
+	restarted.RegisterProcessor(processor)
+	assert.NoError(t, restarted.Init(chain))
+	assert.Equal(t, []uint64{0}, processor.ended)
+}
+
+type testProcessor struct {
+	processed uint64
+	ended     []uint64
+	endEpoch  func(epoch uint64) error
+}
+
+func (p *testProcessor) ProcessBlock(bl *block.Block) error {
+	p.processed = bl.Header.Height
+	return nil
+}
+
+func (p *testProcessor) EndEpoch(epoch uint64, last *block.Block) error {
+	p.ended = append(p.ended, epoch)
+	return p.endEpoch(epoch)
+}
+
+type testForkProcessor struct {
+	testProcessor
+	hashes []common.Hash
+}
+
+func (p *testForkProcessor) ProcessBlock(bl *block.Block) error {
+	p.hashes = append(p.hashes, bl.Hash())
+	return p.testProcessor.ProcessBlock(bl)
+}
+
+func (p *testForkProcessor) HasProcessed(hash common.Hash) bool {
+	for _, processed := range p.hashes {
+		if processed == hash {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEpochManagerReplayFork(t *testing.T) {
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(common.HexToAddress("0xabc123"), big.NewInt(1)))
+
+	chain := &testChain{
+		headers:   map[common.Hash]*block.BlockHeader{},
+		canonical: map[uint64]*block.BlockHeader{},
+	}
+	extend := func(parent *block.BlockHeader, round uint64) *block.BlockHeader {
+		header := &block.BlockHeader{Height: parent.Height + 1, ParentHash: parent.Hash(), Round: round}
+		chain.headers[header.Hash()] = header
+		chain.canonical[header.Height] = header
+		chain.head = header
+		return header
+	}
+	genesis := &block.BlockHeader{Height: 0}
+	chain.headers[genesis.Hash()] = genesis
+	chain.canonical[0] = genesis
+	chain.head = genesis
+	first := extend(genesis, 0)
+	second := extend(first, 0)
+	extend(second, 0)
+
+	db, _ := database.NewMemDatabase()
+	plain := &testProcessor{}
+	forkAware := &testForkProcessor{}
+	manager := NewEpochManager(authority, db)
+	manager.epochLength = 10
+	manager.RegisterProcessor(plain)
+	manager.RegisterProcessor(forkAware)
+	assert.NoError(t, manager.Init(chain))
+	assert.Len(t, forkAware.hashes, 3)
+
+	// a backup round block replaces the second block and its child
+	replaced := extend(first, 1)
+	head := extend(replaced, 0)
+	assert.NoError(t, manager.catchUp(head.Height))
+	assert.Equal(t, []common.Hash{replaced.Hash(), head.Hash()}, forkAware.hashes[3:])
+	assert.Equal(t, uint64(3), plain.processed, "plain processors are not handed the branch again")
+
+	// nothing is replayed without a reorg
+	assert.NoError(t, manager.catchUp(head.Height))
+	assert.Len(t, forkAware.hashes, 5)
+
+	// a reorg reverting a finalized block is refused
+	assert.NoError(t, voting.WriteCommitCertificate(db, voting.NewCommitCertificate(2, replaced.Hash(), nil)))
+	extend(extend(first, 2), 0)
+	assert.ErrorIs(t, manager.catchUp(head.Height), voting.ErrFinalizedFork)
+	assert.Len(t, forkAware.hashes, 5)
+}
@@ -0,0 +1,307 @@
+package poa_consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/consensus"
+	"FichainCore/crypto"
+	"FichainCore/database"
+	"FichainCore/errors"
+	"FichainCore/params"
+	pb "FichainCore/proto"
+	"FichainCore/receipt"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+// DoubleSignEvidence holds two different headers signed by the same proposer
+// for the same height and round
+type DoubleSignEvidence struct {
+	First  *block.BlockHeader
+	Second *block.BlockHeader
+}
+
+// NewDoubleSignEvidence orders the conflicting headers by hash so the same
+// pair always encodes to the same evidence
+func NewDoubleSignEvidence(a, b *block.BlockHeader) *DoubleSignEvidence {
+	if bytes.Compare(a.Hash().Bytes(), b.Hash().Bytes()) > 0 {
+		a, b = b, a
+	}
+	return &DoubleSignEvidence{First: a, Second: b}
+}
+
+// Hash identifies the evidence independently of the header order
+func (e *DoubleSignEvidence) Hash() common.Hash {
+	first, second := e.First.Hash(), e.Second.Hash()
+	if bytes.Compare(first.Bytes(), second.Bytes()) > 0 {
+		first, second = second, first
+	}
+	return crypto.Keccak256Hash(first.Bytes(), second.Bytes())
+}
+
+// Height returns the height both headers were signed for
+func (e *DoubleSignEvidence) Height() uint64 {
+	return e.First.Height
+}
+
+// Offender returns the proposer that signed both headers
+func (e *DoubleSignEvidence) Offender() common.Address {
+	return e.First.Proposer
+}
+
+// Verify checks that both headers are signed by the proposer scheduled for
+// their height and round and that they differ
+func (e *DoubleSignEvidence) Verify(selector ProposerSelector) error {
+	if e.First == nil || e.Second == nil {
+		return errors.ErrInvalidEvidence
+	}
+	if e.First.Height != e.Second.Height ||
+		e.First.Round != e.Second.Round ||
+		e.First.Proposer != e.Second.Proposer ||
+		e.First.Hash() == e.Second.Hash() {
+		return errors.ErrInvalidEvidence
+	}
+	for _, header := range []*block.BlockHeader{e.First, e.Second} {
+		signer, err := ecrecover(header)
+		if err != nil {
+			return err
+		}
+		if signer != header.Proposer {
+			return errors.ErrInvalidEvidence
+		}
+	}
+	proposer, err := selector.GetProposerForRound(e.First.Height, e.First.Round)
+	if err != nil {
+		return err
+	}
+	if proposer != e.Offender() {
+		return errors.ErrUnauthorizedProposer
+	}
+	return nil
+}
+
+// Proto converts DoubleSignEvidence to protobuf message
+func (e *DoubleSignEvidence) Proto() proto.Message {
+	return &pb.DoubleSignEvidence{
+		First:  e.First.Proto(),
+		Second: e.Second.Proto(),
+	}
+}
+
+// FromProto populates DoubleSignEvidence from a protobuf message
+func (e *DoubleSignEvidence) FromProto(pbEvidence *pb.DoubleSignEvidence) error {
+	if pbEvidence.First == nil || pbEvidence.Second == nil {
+		return errors.ErrInvalidEvidence
+	}
+	e.First = &block.BlockHeader{}
+	e.First.FromProto(pbEvidence.First)
+	e.Second = &block.BlockHeader{}
+	e.Second.FromProto(pbEvidence.Second)
+	return nil
+}
+
+// Marshal encodes the evidence as transaction data
+func (e *DoubleSignEvidence) Marshal() ([]byte, error) {
+	return proto.Marshal(e.Proto())
+}
+
+// Unmarshal decodes the evidence from transaction data
+func (e *DoubleSignEvidence) Unmarshal(data []byte) error {
+	var pbEvidence pb.DoubleSignEvidence
+	if err := proto.Unmarshal(data, &pbEvidence); err != nil {
+		return err
+	}
+	return e.FromProto(&pbEvidence)
+}
+
+// Slasher punishes proposers that signed conflicting headers. Evidence is
+// included in blocks as the data of transactions sent to
+// params.SlashingAddress. When such a block is finalized a share of the
+// offender's bonded stake is burned and the offender is listed in the staking
+// storage under the epoch of the block. At the end of the epoch the offenders
+// listed in the state of its last block are removed from the validator set so
+// the next schedule excludes them, evidence of a reverted block is never
+// listed in the canonical state.
+type Slasher struct {
+	stakePool   *StakePool
+	selector    ProposerSelector
+	epochs      EpochValidators
+	authority   *Authority
+	validatorDB database.Database
+	observerDB  database.Database
+
+	sync.Mutex
+}
+
+func NewSlasher(
+	stakePool *StakePool,
+	selector ProposerSelector,
+	epochs EpochValidators,
+	authority *Authority,
+	validatorDB, observerDB database.Database,
+) *Slasher {
+	return &Slasher{
+		stakePool:   stakePool,
+		selector:    selector,
+		epochs:      epochs,
+		authority:   authority,
+		validatorDB: validatorDB,
+		observerDB:  observerDB,
+	}
+}
+
+// VerifyEvidence checks evidence included in a block of the given height.
+// Evidence from the future or older than the unbonding period is rejected.
+func (s *Slasher) VerifyEvidence(evidence *DoubleSignEvidence, height uint64) error {
+	if err := evidence.Verify(s.selector); err != nil {
+		return err
+	}
+	if evidence.Height() > height {
+		return errors.ErrInvalidEvidence
+	}
+	if evidence.Height()+params.TempUnbondingPeriod < height {
+		return errors.ErrExpiredEvidence
+	}
+	return nil
+}
+
+// evidenceFromTx decodes and verifies the evidence carried by a transaction
+func (s *Slasher) evidenceFromTx(
+	tx *transaction.Transaction,
+	height uint64,
+) (*DoubleSignEvidence, error) {
+	evidence := &DoubleSignEvidence{}
+	if err := evidence.Unmarshal(tx.Data()); err != nil {
+		return nil, err
+	}
+	if err := s.VerifyEvidence(evidence, height); err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// FinalizeState burns params.TempDoubleSignSlashPercent of the offender's
+// bonded stake for every new piece of evidence in the block and lists the
+// offender for the epoch end. Evidence already applied is recorded in the
+// staking storage and ignored. Any value sent
+// with the evidence is returned.
+func (s *Slasher) FinalizeState(
+	chain consensus.ChainReader,
+	header *block.BlockHeader,
+	st *state.StateDB,
+	txs []*transaction.Transaction,
+	receipts []*receipt.Receipt,
+) error {
+	for i, tx := range txs {
+		if tx.To() != params.SlashingAddress {
+			continue
+		}
+		if i < len(receipts) && receipts[i].Status == 0 {
+			continue
+		}
+		if tx.Amount() != nil && tx.Amount().Sign() > 0 {
+			from, err := tx.From(params.TempChainId)
+			if err != nil {
+				return err
+			}
+			st.SubBalance(params.SlashingAddress, tx.Amount())
+			st.AddBalance(from, tx.Amount())
+		}
+		evidence, err := s.evidenceFromTx(tx, header.Height)
+		if err != nil {
+			logger.Warn("[Slasher] rejected evidence", tx.Hash().Hex(), err)
+			continue
+		}
+		appliedSlot := appliedEvidenceSlot(evidence)
+		if getStakeValue(st, appliedSlot).Sign() > 0 {
+			continue
+		}
+		setStakeValue(st, appliedSlot, new(big.Int).SetUint64(header.Height))
+		s.listOffender(st, s.epochs.EpochOf(header.Height), evidence.Offender())
+		slashed := s.stakePool.slash(st, evidence.Offender(), params.TempDoubleSignSlashPercent)
+		logger.Warn("[Slasher] slashed double signing proposer", evidence.Offender().Hex(), slashed)
+	}
+	return nil
+}
+
+// appliedEvidenceSlot records in the staking storage the height a piece of
+// evidence was applied at
+func appliedEvidenceSlot(evidence *DoubleSignEvidence) common.Hash {
+	return crypto.Keccak256Hash([]byte("applied-evidence"), evidence.Hash().Bytes())
+}
+
+// offenderSlot addresses the offender count of an epoch, or with an index the
+// index-th offender listed in the epoch
+func offenderSlot(epoch uint64, index ...uint64) common.Hash {
+	data := binary.BigEndian.AppendUint64([]byte("slashing-offender"), epoch)
+	for _, i := range index {
+		data = binary.BigEndian.AppendUint64(data, i)
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+func (s *Slasher) listOffender(st *state.StateDB, epoch uint64, offender common.Address) {
+	count := getStakeValue(st, offenderSlot(epoch)).Uint64()
+	setStakeValue(st, offenderSlot(epoch, count), new(big.Int).SetBytes(offender.Bytes()))
+	setStakeValue(st, offenderSlot(epoch), new(big.Int).SetUint64(count+1))
+}
+
+// Offenders returns the validators listed for removal at the end of an epoch,
+// in evidence order
+func (s *Slasher) Offenders(st *state.StateDB, epoch uint64) []common.Address {
+	count := getStakeValue(st, offenderSlot(epoch)).Uint64()
+	offenders := make([]common.Address, 0, count)
+	for i := uint64(0); i < count; i++ {
+		offenders = append(
+			offenders,
+			common.BytesToAddress(getStakeValue(st, offenderSlot(epoch, i)).Bytes()),
+		)
+	}
+	return offenders
+}
+
+// ProcessBlock does nothing, the offenders are read from the state of the
+// last block of the epoch
+func (s *Slasher) ProcessBlock(bl *block.Block) error {
+	return nil
+}
+
+// EndEpoch removes the offenders listed in the state of the last block of the
+// epoch from the validator set. The last validator is never removed so the
+// chain keeps a proposer.
+func (s *Slasher) EndEpoch(epoch uint64, last *block.Block) error {
+	s.Lock()
+	defer s.Unlock()
+
+	st, err := s.stakePool.states.At(last.Header)
+	if err != nil {
+		return err
+	}
+	offenders := s.Offenders(st, epoch)
+	if len(offenders) == 0 {
+		return nil
+	}
+	for _, offender := range offenders {
+		validators := s.authority.ListValidators()
+		if _, ok := validators[offender]; !ok {
+			continue
+		}
+		if len(validators) == 1 {
+			logger.Warn("[Slasher] keep last validator", offender.Hex())
+			continue
+		}
+		if err := s.authority.RemoveValidator(offender); err != nil {
+			return err
+		}
+		logger.Info("[Slasher] removed double signing validator", offender.Hex(), "epoch", epoch)
+	}
+	return s.authority.CommitToStorage(s.validatorDB, s.observerDB)
+}
@@ -0,0 +1,172 @@
+package poa_consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/errors"
+	"FichainCore/params"
+	"FichainCore/receipt"
+	"FichainCore/signer"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+// newDoubleSign returns two different headers signed by s for the same slot
+func newDoubleSign(
+	t *testing.T,
+	s *signer.Signer,
+	proposer common.Address,
+	height uint64,
+) (*block.BlockHeader, *block.BlockHeader) {
+	first := &block.BlockHeader{Height: height, Proposer: proposer, Timestamp: 1}
+	second := &block.BlockHeader{Height: height, Proposer: proposer, Timestamp: 2}
+	signHeader(t, s, first)
+	signHeader(t, s, second)
+	return first, second
+}
+
+func TestEvidenceVerify(t *testing.T) {
+	proposerSigner, proposer := newTestSigner(t)
+	otherSigner, other := newTestSigner(t)
+	schedule := &ProposerSchedule{
+		schedule: map[uint64]common.Address{5: proposer, 6: other},
+	}
+
+	first, second := newDoubleSign(t, proposerSigner, proposer, 5)
+	evidence := NewDoubleSignEvidence(first, second)
+	assert.NoError(t, evidence.Verify(schedule))
+	assert.Equal(t, proposer, evidence.Offender())
+	assert.Equal(t, evidence.Hash(), NewDoubleSignEvidence(second, first).Hash())
+
+	t.Run("TestProtoRoundTrip", func(t *testing.T) {
+		data, err := evidence.Marshal()
+		assert.NoError(t, err)
+		decoded := &DoubleSignEvidence{}
+		assert.NoError(t, decoded.Unmarshal(data))
+		assert.Equal(t, evidence.Hash(), decoded.Hash())
+		assert.NoError(t, decoded.Verify(schedule))
+	})
+
+	t.Run("TestSameHeader", func(t *testing.T) {
+		assert.Equal(t, errors.ErrInvalidEvidence, NewDoubleSignEvidence(first, first).Verify(schedule))
+	})
+
+	t.Run("TestDifferentSlot", func(t *testing.T) {
+		other := &block.BlockHeader{Height: 5, Round: 1, Proposer: proposer}
+		signHeader(t, proposerSigner, other)
+		assert.Equal(t, errors.ErrInvalidEvidence, NewDoubleSignEvidence(first, other).Verify(schedule))
+	})
+
+	t.Run("TestForgedSignature", func(t *testing.T) {
+		forged := &block.BlockHeader{Height: 5, Proposer: proposer, Timestamp: 3}
+		signHeader(t, otherSigner, forged)
+		assert.Equal(t, errors.ErrInvalidEvidence, NewDoubleSignEvidence(first, forged).Verify(schedule))
+	})
+
+	t.Run("TestUnscheduledProposer", func(t *testing.T) {
+		first, second := newDoubleSign(t, proposerSigner, proposer, 6)
+		assert.Equal(
+			t,
+			errors.ErrUnauthorizedProposer,
+			NewDoubleSignEvidence(first, second).Verify(schedule),
+		)
+	})
+}
+
+func TestEvidenceSlashing(t *testing.T) {
+	offenderSigner, offender := newTestSigner(t)
+	reporterSigner, reporter := newTestSigner(t)
+
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(offender, big.NewInt(1)))
+	assert.NoError(t, authority.AddValidator(reporter, big.NewInt(1)))
+	schedule := &ProposerSchedule{
+		schedule: map[uint64]common.Address{5: offender},
+	}
+
+	memDB, _ := database.NewMemDatabase()
+	stateDatabase := state.NewDatabase(memDB)
+	states := NewChainStates(stateDatabase, memDB)
+	st, err := state.New(common.Hash{}, stateDatabase)
+	assert.NoError(t, err)
+	validatorDB, _ := database.NewMemDatabase()
+	observerDB, _ := database.NewMemDatabase()
+	pool := NewStakePool(authority, testEpochValidators(authority.ListValidators()), states, validatorDB, observerDB)
+
+	// the offender bonded 1000
+	st.AddBalance(params.StakingAddress, big.NewInt(1000))
+	assert.NoError(t, pool.delegate(st, offender, offender, big.NewInt(1000)))
+
+	parentRoot, err := st.Commit(true)
+	assert.NoError(t, err)
+	st, err = state.New(parentRoot, stateDatabase)
+	assert.NoError(t, err)
+
+	epochs := testEpochValidators(authority.ListValidators())
+	slasher := NewSlasher(pool, schedule, epochs, authority, validatorDB, observerDB)
+
+	first, second := newDoubleSign(t, offenderSigner, offender, 5)
+	evidence := NewDoubleSignEvidence(first, second)
+	txs := []*transaction.Transaction{
+		newSystemTx(t, reporterSigner, params.SlashingAddress, 0, big.NewInt(0), evidence),
+		// the same evidence is only applied once
+		newSystemTx(
+			t, reporterSigner, params.SlashingAddress, 1, big.NewInt(0),
+			NewDoubleSignEvidence(second, first),
+		),
+	}
+	receipts := []*receipt.Receipt{
+		receipt.NewReceipt(nil, false, 0),
+		receipt.NewReceipt(nil, false, 0),
+	}
+	header := &block.BlockHeader{Height: 10}
+	assert.NoError(t, slasher.FinalizeState(nil, header, st, txs, receipts))
+
+	slashed := int64(1000 * params.TempDoubleSignSlashPercent / 100)
+	assert.Equal(t, big.NewInt(1000-slashed), pool.TotalStake(st, offender))
+	assert.Equal(t, big.NewInt(1000-slashed), st.GetBalance(params.StakingAddress), "slashed stake is burned")
+	assert.Equal(t, []common.Address{offender}, slasher.Offenders(st, 1), "listed once under the epoch of the block")
+
+	t.Run("TestExpiredEvidence", func(t *testing.T) {
+		err := slasher.VerifyEvidence(evidence, 5+params.TempUnbondingPeriod+1)
+		assert.Equal(t, errors.ErrExpiredEvidence, err)
+		assert.Equal(t, errors.ErrInvalidEvidence, slasher.VerifyEvidence(evidence, 4))
+	})
+
+	root, err := st.Commit(true)
+	assert.NoError(t, err)
+	header.StateRoot = root
+
+	t.Run("TestRevertedEvidence", func(t *testing.T) {
+		// the evidence block was reverted, the canonical last block of the
+		// epoch builds on its parent
+		last := &block.Block{Header: &block.BlockHeader{Height: 19, StateRoot: parentRoot}}
+		assert.NoError(t, slasher.EndEpoch(1, last))
+		_, ok := authority.GetValidatorWeight(offender)
+		assert.True(t, ok, "offender of a reverted block should stay")
+	})
+
+	t.Run("TestRemovedAtEpochEnd", func(t *testing.T) {
+		last := &block.Block{Header: &block.BlockHeader{Height: 19, StateRoot: root}}
+		assert.NoError(t, slasher.EndEpoch(0, last))
+		_, ok := authority.GetValidatorWeight(offender)
+		assert.True(t, ok, "offender is listed for the epoch of the evidence block")
+
+		assert.NoError(t, slasher.EndEpoch(1, last))
+		_, ok = authority.GetValidatorWeight(offender)
+		assert.False(t, ok, "offender should be removed")
+		_, ok = authority.GetValidatorWeight(reporter)
+		assert.True(t, ok)
+
+		stored := NewAuthority()
+		assert.NoError(t, stored.LoadFromDB(validatorDB, observerDB))
+		_, ok = stored.GetValidatorWeight(offender)
+		assert.False(t, ok, "removal should be committed")
+	})
+}
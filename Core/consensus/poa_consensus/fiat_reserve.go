@@ -1,10 +1,13 @@
 package poa_consensus
 
 import (
+	"context"
 	"errors"
 	"math/big"
 	"sync"
+	"time"
 
+	"FichainCore/bank_adapter"
 	"FichainCore/common"
 	"FichainCore/database"
 )
@@ -52,6 +55,14 @@ func (r *FiatReserve) Withdraw(address common.Address, amount *big.Int) error {
 	return nil
 }
 
+// SetBalance overwrites the reserve balance of an address
+func (r *FiatReserve) SetBalance(address common.Address, amount *big.Int) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.balances[address] = new(big.Int).Set(amount)
+}
+
 func (r *FiatReserve) GetBalance(address common.Address) (*big.Int, error) {
 	r.RLock()
 	defer r.RUnlock()
@@ -65,10 +76,59 @@ func (r *FiatReserve) GetBalance(address common.Address) (*big.Int, error) {
 	return new(big.Int).Set(balance), nil
 }
 
-func (r *FiatReserve) SyncFromBank() error {
-	// Simulated external sync (stub)
-	// In real implementation, you would query an API or read from file/db
-	return nil
+// ListBalances returns a copy of the reserve balance of every bank
+func (r *FiatReserve) ListBalances() map[common.Address]*big.Int {
+	r.RLock()
+	defer r.RUnlock()
+
+	balances := make(map[common.Address]*big.Int, len(r.balances))
+	for address, balance := range r.balances {
+		balances[address] = new(big.Int).Set(balance)
+	}
+	return balances
+}
+
+// Reconciliation compares the reserve a bank reports from its core banking
+// system with the reserve recorded on chain
+type Reconciliation struct {
+	Bank     common.Address
+	OnChain  *big.Int
+	Reported *big.Int
+	Time     time.Time
+}
+
+// Difference returns the reported minus the on-chain reserve
+func (r *Reconciliation) Difference() *big.Int {
+	return new(big.Int).Sub(r.Reported, r.OnChain)
+}
+
+// Matched reports whether both ledgers agree
+func (r *Reconciliation) Matched() bool {
+	return r.Reported.Cmp(r.OnChain) == 0
+}
+
+// SyncFromBank fetches the reserve accounts of a bank from its core banking
+// system and compares their total with the on-chain balance mirrored here.
+// The mirror is not changed, only transactions move the on-chain reserve.
+func (r *FiatReserve) SyncFromBank(
+	ctx context.Context,
+	bank common.Address,
+	adapter bank_adapter.BankAdapter,
+) (*Reconciliation, error) {
+	balances, err := adapter.Balances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	onChain, err := r.GetBalance(bank)
+	if err != nil {
+		return nil, err
+	}
+	return &Reconciliation{
+		Bank:     bank,
+		OnChain:  onChain,
+		Reported: bank_adapter.TotalBalance(balances),
+		Time:     time.Now(),
+	}, nil
 }
 
 func (r *FiatReserve) LoadFromStorage(db database.Database) error {
@@ -0,0 +1,498 @@
+package poa_consensus
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/consensus/poa_consensus/voting"
+	"FichainCore/database"
+	"FichainCore/params"
+	pb "FichainCore/proto"
+)
+
+var (
+	governanceStateKey         = []byte("governance-state")   // state after the latest processed block
+	governanceBlockStatePrefix = []byte("governance-state-")  // governanceBlockStatePrefix + block hash -> state after the block
+	governanceHeightPrefix     = []byte("governance-height-") // governanceHeightPrefix + height (uint64 big endian) -> hashes of the processed blocks
+)
+
+type GovernanceAction uint32
+
+const (
+	ActionAddValidator GovernanceAction = iota + 1
+	ActionRemoveValidator
+	ActionReweightValidator
+	ActionAddObserver
+	ActionRemoveObserver
+)
+
+// GovernanceProposal is a change of the validator or observer set
+type GovernanceProposal struct {
+	Action     GovernanceAction
+	Target     common.Address
+	Weight     *big.Int
+	AccessList []common.Address
+}
+
+func (p *GovernanceProposal) Validate() error {
+	switch p.Action {
+	case ActionAddValidator, ActionReweightValidator:
+		if p.Weight == nil || p.Weight.Sign() <= 0 {
+			return errors.New("validator weight must be positive")
+		}
+	case ActionRemoveValidator, ActionAddObserver, ActionRemoveObserver:
+	default:
+		return fmt.Errorf("unknown governance action %d", p.Action)
+	}
+	if (p.Target == common.Address{}) {
+		return errors.New("missing proposal target")
+	}
+	return nil
+}
+
+// Apply executes the change on the authority
+func (p *GovernanceProposal) Apply(a *Authority) error {
+	switch p.Action {
+	case ActionAddValidator:
+		return a.AddValidator(p.Target, new(big.Int).Set(p.Weight))
+	case ActionRemoveValidator:
+		return a.RemoveValidator(p.Target)
+	case ActionReweightValidator:
+		return a.SetValidatorWeight(p.Target, new(big.Int).Set(p.Weight))
+	case ActionAddObserver:
+		return a.AddObserver(p.Target, p.AccessList)
+	case ActionRemoveObserver:
+		return a.RemoveObserver(p.Target)
+	}
+	return fmt.Errorf("unknown governance action %d", p.Action)
+}
+
+// Proto converts GovernanceProposal to protobuf message
+func (p *GovernanceProposal) Proto() *pb.GovernanceProposal {
+	accessList := make([][]byte, len(p.AccessList))
+	for i, address := range p.AccessList {
+		accessList[i] = address.Bytes()
+	}
+	var weight []byte
+	if p.Weight != nil {
+		weight = p.Weight.Bytes()
+	}
+	return &pb.GovernanceProposal{
+		Action:     uint32(p.Action),
+		Target:     p.Target.Bytes(),
+		Weight:     weight,
+		AccessList: accessList,
+	}
+}
+
+// FromProto populates GovernanceProposal from a protobuf message
+func (p *GovernanceProposal) FromProto(pbProposal *pb.GovernanceProposal) {
+	p.Action = GovernanceAction(pbProposal.Action)
+	p.Target = common.BytesToAddress(pbProposal.Target)
+	p.Weight = new(big.Int).SetBytes(pbProposal.Weight)
+	p.AccessList = make([]common.Address, len(pbProposal.AccessList))
+	for i, address := range pbProposal.AccessList {
+		p.AccessList[i] = common.BytesToAddress(address)
+	}
+}
+
+// GovernanceCall is the data of a transaction sent to params.GovernanceAddress.
+// It either submits a new proposal, which counts as the proposer's approval,
+// or votes on an open proposal identified by the hash of its transaction.
+type GovernanceCall struct {
+	Proposal   *GovernanceProposal
+	ProposalID common.Hash
+	Approve    bool
+}
+
+// Marshal encodes the call as transaction data
+func (c *GovernanceCall) Marshal() ([]byte, error) {
+	pbCall := &pb.GovernanceCall{
+		ProposalId: c.ProposalID.Bytes(),
+		Approve:    c.Approve,
+	}
+	if c.Proposal != nil {
+		pbCall.Proposal = c.Proposal.Proto()
+	}
+	return proto.Marshal(pbCall)
+}
+
+// Unmarshal decodes the call from transaction data
+func (c *GovernanceCall) Unmarshal(data []byte) error {
+	var pbCall pb.GovernanceCall
+	if err := proto.Unmarshal(data, &pbCall); err != nil {
+		return err
+	}
+	c.Proposal = nil
+	if pbCall.Proposal != nil {
+		c.Proposal = &GovernanceProposal{}
+		c.Proposal.FromProto(pbCall.Proposal)
+	}
+	c.ProposalID = common.BytesToHash(pbCall.ProposalId)
+	c.Approve = pbCall.Approve
+	return nil
+}
+
+// Proposal is an open governance proposal and its votes
+type Proposal struct {
+	ID       common.Hash
+	Change   *GovernanceProposal
+	Proposer common.Address
+	Height   uint64                  // block the proposal was submitted in
+	Votes    map[common.Address]bool // validator -> approve
+	Passed   bool
+}
+
+type governanceState struct {
+	Processed uint64      // height of the last processed block
+	Hash      common.Hash // hash of the last processed block
+	Proposals map[common.Hash]*Proposal
+	Passed    []common.Hash // passed proposals waiting for the epoch end, in passing order
+}
+
+// Governance derives validator and observer set changes from transactions
+// sent to params.GovernanceAddress. Current validators submit and vote on
+// proposals, a proposal approved by more than half of the validator weight is
+// applied to the authority at the end of the epoch so the next epoch snapshot
+// includes it. Proposals and votes are weighed with the validator snapshot of
+// the epoch holding them, never the live authority, so every node tallies the
+// same. The state is kept per block hash and a block is processed on
+// top of the state of its parent, so proposals and votes of a branch abandoned
+// by a reorg are dropped once the new branch is handed over.
+type Governance struct {
+	authority   *Authority
+	validators  voting.HeightValidatorSet
+	db          database.Database
+	validatorDB database.Database
+	observerDB  database.Database
+
+	state governanceState
+
+	sync.RWMutex
+}
+
+func NewGovernance(
+	authority *Authority,
+	validators voting.HeightValidatorSet,
+	db, validatorDB, observerDB database.Database,
+) *Governance {
+	return &Governance{
+		authority:   authority,
+		validators:  validators,
+		db:          db,
+		validatorDB: validatorDB,
+		observerDB:  observerDB,
+		state: governanceState{
+			Proposals: make(map[common.Hash]*Proposal),
+		},
+	}
+}
+
+// LoadFromDB restores the open proposals
+func (g *Governance) LoadFromDB() error {
+	g.Lock()
+	defer g.Unlock()
+
+	if ok, err := g.db.Has(governanceStateKey); err != nil || !ok {
+		return err
+	}
+	data, err := g.db.Get(governanceStateKey)
+	if err != nil {
+		return err
+	}
+	state := governanceState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("error loading governance state: %w", err)
+	}
+	if state.Proposals == nil {
+		state.Proposals = make(map[common.Hash]*Proposal)
+	}
+	g.state = state
+	return nil
+}
+
+func (g *Governance) commit() error {
+	data, err := json.Marshal(g.state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal governance state: %w", err)
+	}
+	return g.db.Put(governanceStateKey, data)
+}
+
+func governanceBlockStateKey(hash common.Hash) []byte {
+	return append(append([]byte{}, governanceBlockStatePrefix...), hash.Bytes()...)
+}
+
+func governanceHeightKey(height uint64) []byte {
+	key := make([]byte, len(governanceHeightPrefix)+8)
+	copy(key, governanceHeightPrefix)
+	binary.BigEndian.PutUint64(key[len(governanceHeightPrefix):], height)
+	return key
+}
+
+// parentState returns a copy of the state the block builds on
+func (g *Governance) parentState(bl *block.Block) (governanceState, error) {
+	parent := bl.Header.ParentHash
+	data, err := g.db.Get(governanceBlockStateKey(parent))
+	if err == nil && len(data) > 0 {
+		state := governanceState{}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return state, fmt.Errorf("error loading governance state of %s: %w", parent.Hex(), err)
+		}
+		if state.Proposals == nil {
+			state.Proposals = make(map[common.Hash]*Proposal)
+		}
+		return state, nil
+	}
+	switch {
+	case bl.Header.Height <= 1:
+		// nothing happened before the first block
+		return governanceState{Proposals: make(map[common.Hash]*Proposal)}, nil
+	case g.state.Hash == (common.Hash{}) && bl.Header.Height == g.state.Processed+1:
+		// state stored before it was kept per block
+		return g.state, nil
+	}
+	return governanceState{}, fmt.Errorf(
+		"missing governance state of block %d parent %s", bl.Header.Height, parent.Hex(),
+	)
+}
+
+// commitBlock stores the state after the block being processed as the state
+// of the block and the latest state, and forgets the states of the blocks
+// too deep for a reorg to reach
+func (g *Governance) commitBlock(height uint64) error {
+	data, err := json.Marshal(g.state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal governance state: %w", err)
+	}
+	hashes, _ := g.db.Get(governanceHeightKey(height))
+	batch := g.db.NewBatch()
+	batch.Put(governanceStateKey, data)
+	batch.Put(governanceBlockStateKey(g.state.Hash), data)
+	batch.Put(governanceHeightKey(height), append(append([]byte{}, hashes...), g.state.Hash.Bytes()...))
+	if err := batch.Write(); err != nil {
+		return err
+	}
+
+	if height <= params.TempMaxReorgDepth {
+		return nil
+	}
+	pruned := governanceHeightKey(height - params.TempMaxReorgDepth)
+	hashes, err = g.db.Get(pruned)
+	if err != nil || len(hashes) == 0 {
+		return nil
+	}
+	for i := 0; i+common.HashLength <= len(hashes); i += common.HashLength {
+		g.db.Delete(governanceBlockStateKey(common.BytesToHash(hashes[i : i+common.HashLength])))
+	}
+	return g.db.Delete(pruned)
+}
+
+// GetProposal returns an open or passed proposal
+func (g *Governance) GetProposal(id common.Hash) (*Proposal, bool) {
+	g.RLock()
+	defer g.RUnlock()
+
+	proposal, ok := g.state.Proposals[id]
+	return proposal, ok
+}
+
+// HasProcessed tells whether the state after a block is known, it implements
+// ForkProcessor
+func (g *Governance) HasProcessed(hash common.Hash) bool {
+	g.RLock()
+	defer g.RUnlock()
+	if g.state.Hash == (common.Hash{}) {
+		// state stored before it was kept per block, its blocks cannot be
+		// told apart
+		return true
+	}
+	ok, err := g.db.Has(governanceBlockStateKey(hash))
+	return err == nil && ok
+}
+
+// ProcessBlock records the proposals and votes of a block on top of the state
+// of its parent
+func (g *Governance) ProcessBlock(bl *block.Block) error {
+	g.Lock()
+	defer g.Unlock()
+
+	hash := bl.Hash()
+	if ok, err := g.db.Has(governanceBlockStateKey(hash)); err != nil || ok {
+		return err
+	}
+	height := bl.Header.Height
+	state, err := g.parentState(bl)
+	if err != nil {
+		return err
+	}
+	g.state = state
+	var validators map[common.Address]*big.Int
+	for _, tx := range bl.Transactions {
+		if tx.To() != params.GovernanceAddress {
+			continue
+		}
+		if validators == nil {
+			if validators, err = g.validators.ValidatorsAt(height); err != nil {
+				return err
+			}
+		}
+		from, err := tx.From(params.TempChainId)
+		if err != nil {
+			logger.Warn("[Governance] skip unsigned transaction", tx.Hash().Hex(), err)
+			continue
+		}
+		call := &GovernanceCall{}
+		if err := call.Unmarshal(tx.Data()); err != nil {
+			logger.Warn("[Governance] skip malformed transaction", tx.Hash().Hex(), err)
+			continue
+		}
+		if call.Proposal != nil {
+			err = g.propose(tx.Hash(), from, call.Proposal, height, validators)
+		} else {
+			err = g.vote(call.ProposalID, from, call.Approve, validators)
+		}
+		if err != nil {
+			logger.Warn("[Governance] rejected transaction", tx.Hash().Hex(), err)
+		}
+	}
+
+	// drop proposals that did not pass in time
+	for id, proposal := range g.state.Proposals {
+		if !proposal.Passed && height >= proposal.Height+params.TempGovernanceProposalTTL {
+			delete(g.state.Proposals, id)
+		}
+	}
+
+	g.state.Processed = height
+	g.state.Hash = hash
+	return g.commitBlock(height)
+}
+
+// EndEpoch applies the passed proposals to the authority
+func (g *Governance) EndEpoch(epoch uint64, last *block.Block) error {
+	g.Lock()
+	defer g.Unlock()
+
+	if len(g.state.Passed) == 0 {
+		return nil
+	}
+	for _, id := range g.state.Passed {
+		proposal, ok := g.state.Proposals[id]
+		if !ok {
+			continue
+		}
+		if err := proposal.Change.Apply(g.authority); err != nil {
+			logger.Warn("[Governance] unable to apply proposal", id.Hex(), err)
+		} else {
+			logger.Info("[Governance] applied proposal", id.Hex(), "epoch", epoch)
+		}
+		delete(g.state.Proposals, id)
+	}
+	g.state.Passed = nil
+
+	if err := g.authority.CommitToStorage(g.validatorDB, g.observerDB); err != nil {
+		return err
+	}
+	if g.state.Hash == (common.Hash{}) {
+		return g.commit()
+	}
+	// blocks built on the last block of the epoch start without the applied
+	// proposals
+	data, err := json.Marshal(g.state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal governance state: %w", err)
+	}
+	batch := g.db.NewBatch()
+	batch.Put(governanceStateKey, data)
+	batch.Put(governanceBlockStateKey(g.state.Hash), data)
+	return batch.Write()
+}
+
+func (g *Governance) propose(
+	id common.Hash,
+	proposer common.Address,
+	change *GovernanceProposal,
+	height uint64,
+	validators map[common.Address]*big.Int,
+) error {
+	if _, ok := validators[proposer]; !ok {
+		return fmt.Errorf("proposer is not a validator: %s", proposer.Hex())
+	}
+	if err := change.Validate(); err != nil {
+		return err
+	}
+	if _, ok := g.state.Proposals[id]; ok {
+		return fmt.Errorf("proposal already exists: %s", id.Hex())
+	}
+	g.state.Proposals[id] = &Proposal{
+		ID:       id,
+		Change:   change,
+		Proposer: proposer,
+		Height:   height,
+		Votes:    map[common.Address]bool{proposer: true},
+	}
+	g.tally(id, validators)
+	return nil
+}
+
+func (g *Governance) vote(
+	id common.Hash,
+	voter common.Address,
+	approve bool,
+	validators map[common.Address]*big.Int,
+) error {
+	if _, ok := validators[voter]; !ok {
+		return fmt.Errorf("voter is not a validator: %s", voter.Hex())
+	}
+	proposal, ok := g.state.Proposals[id]
+	if !ok {
+		return fmt.Errorf("unknown proposal: %s", id.Hex())
+	}
+	if proposal.Passed {
+		return nil
+	}
+	proposal.Votes[voter] = approve
+	g.tally(id, validators)
+	return nil
+}
+
+// tally marks the proposal passed once more than half of the weight of the
+// given validators approved it, or drops it once at least half rejected it
+func (g *Governance) tally(id common.Hash, validators map[common.Address]*big.Int) {
+	proposal := g.state.Proposals[id]
+
+	total := big.NewInt(0)
+	for _, weight := range validators {
+		total.Add(total, weight)
+	}
+	approved, rejected := big.NewInt(0), big.NewInt(0)
+	for voter, approve := range proposal.Votes {
+		weight, ok := validators[voter]
+		if !ok {
+			continue
+		}
+		if approve {
+			approved.Add(approved, weight)
+		} else {
+			rejected.Add(rejected, weight)
+		}
+	}
+
+	switch {
+	case new(big.Int).Mul(approved, big.NewInt(2)).Cmp(total) > 0:
+		proposal.Passed = true
+		g.state.Passed = append(g.state.Passed, id)
+	case new(big.Int).Mul(rejected, big.NewInt(2)).Cmp(total) >= 0:
+		delete(g.state.Proposals, id)
+	}
+}
@@ -0,0 +1,209 @@
+package poa_consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/params"
+	"FichainCore/transaction"
+)
+
+func TestGovernance(t *testing.T) {
+	signerA, validatorA := newTestSigner(t)
+	signerB, validatorB := newTestSigner(t)
+	signerC, validatorC := newTestSigner(t)
+	outsiderSigner, _ := newTestSigner(t)
+	newBank := common.HexToAddress("0x1234")
+
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(validatorA, big.NewInt(1)))
+	assert.NoError(t, authority.AddValidator(validatorB, big.NewInt(1)))
+	assert.NoError(t, authority.AddValidator(validatorC, big.NewInt(1)))
+
+	db, _ := database.NewMemDatabase()
+	validatorDB, _ := database.NewMemDatabase()
+	observerDB, _ := database.NewMemDatabase()
+	assert.NoError(t, authority.CommitToStorage(validatorDB, observerDB))
+	epochValidators := testEpochValidators(authority.ListValidators())
+
+	governance := NewGovernance(authority, epochValidators, db, validatorDB, observerDB)
+
+	addBank := newSystemTx(t, signerA, params.GovernanceAddress, 0, big.NewInt(0), &GovernanceCall{
+		Proposal: &GovernanceProposal{
+			Action: ActionAddValidator,
+			Target: newBank,
+			Weight: big.NewInt(1),
+		},
+	})
+	// outsider proposals are ignored
+	outsiderProposal := newSystemTx(t, outsiderSigner, params.GovernanceAddress, 0, big.NewInt(0), &GovernanceCall{
+		Proposal: &GovernanceProposal{
+			Action: ActionRemoveValidator,
+			Target: validatorA,
+		},
+	})
+	first := &block.Block{
+		Header:       &block.BlockHeader{Height: 1},
+		Transactions: []*transaction.Transaction{addBank, outsiderProposal},
+	}
+	assert.NoError(t, governance.ProcessBlock(first))
+
+	proposal, ok := governance.GetProposal(addBank.Hash())
+	assert.True(t, ok, "proposal should be open")
+	assert.False(t, proposal.Passed, "1/3 of the weight is not a majority")
+	_, ok = governance.GetProposal(outsiderProposal.Hash())
+	assert.False(t, ok, "outsider proposal should be ignored")
+
+	// restored state keeps the open proposal
+	restored := NewGovernance(authority, epochValidators, db, validatorDB, observerDB)
+	assert.NoError(t, restored.LoadFromDB())
+	_, ok = restored.GetProposal(addBank.Hash())
+	assert.True(t, ok, "proposal should be persisted")
+
+	approve := newSystemTx(t, signerB, params.GovernanceAddress, 0, big.NewInt(0), &GovernanceCall{
+		ProposalID: addBank.Hash(),
+		Approve:    true,
+	})
+	second := &block.Block{
+		Header:       &block.BlockHeader{Height: 2, ParentHash: first.Hash()},
+		Transactions: []*transaction.Transaction{approve},
+	}
+	assert.NoError(t, governance.ProcessBlock(second))
+	proposal, _ = governance.GetProposal(addBank.Hash())
+	assert.True(t, proposal.Passed, "2/3 of the weight is a majority")
+
+	// reprocessing a block is a no-op
+	assert.NoError(t, governance.ProcessBlock(second))
+
+	_, ok = authority.GetValidatorWeight(newBank)
+	assert.False(t, ok, "passed proposal should wait for the epoch end")
+
+	assert.NoError(t, governance.EndEpoch(0, nil))
+	weight, ok := authority.GetValidatorWeight(newBank)
+	assert.True(t, ok, "passed proposal should be applied at the epoch end")
+	assert.Equal(t, big.NewInt(1), weight)
+	_, ok = governance.GetProposal(addBank.Hash())
+	assert.False(t, ok, "applied proposal should be removed")
+
+	stored := NewAuthority()
+	assert.NoError(t, stored.LoadFromDB(validatorDB, observerDB))
+	assert.Len(t, stored.ListValidators(), 4, "applied change should be persisted")
+
+	// a proposal rejected by half of the weight is dropped
+	removeC := newSystemTx(t, signerA, params.GovernanceAddress, 1, big.NewInt(0), &GovernanceCall{
+		Proposal: &GovernanceProposal{
+			Action: ActionRemoveValidator,
+			Target: validatorC,
+		},
+	})
+	rejectB := newSystemTx(t, signerB, params.GovernanceAddress, 1, big.NewInt(0),
+		&GovernanceCall{ProposalID: removeC.Hash()})
+	rejectC := newSystemTx(t, signerC, params.GovernanceAddress, 0, big.NewInt(0),
+		&GovernanceCall{ProposalID: removeC.Hash()})
+	assert.NoError(t, governance.ProcessBlock(&block.Block{
+		Header:       &block.BlockHeader{Height: 3, ParentHash: second.Hash()},
+		Transactions: []*transaction.Transaction{removeC, rejectB, rejectC},
+	}))
+	_, ok = governance.GetProposal(removeC.Hash())
+	assert.False(t, ok, "rejected proposal should be dropped")
+}
+
+func TestGovernanceReorg(t *testing.T) {
+	signerA, validatorA := newTestSigner(t)
+	signerB, validatorB := newTestSigner(t)
+	_, validatorC := newTestSigner(t)
+
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(validatorA, big.NewInt(1)))
+	assert.NoError(t, authority.AddValidator(validatorB, big.NewInt(1)))
+	assert.NoError(t, authority.AddValidator(validatorC, big.NewInt(1)))
+	db, _ := database.NewMemDatabase()
+	validatorDB, _ := database.NewMemDatabase()
+	observerDB, _ := database.NewMemDatabase()
+	governance := NewGovernance(authority, testEpochValidators(authority.ListValidators()), db, validatorDB, observerDB)
+
+	propose := newSystemTx(t, signerA, params.GovernanceAddress, 0, big.NewInt(0), &GovernanceCall{
+		Proposal: &GovernanceProposal{Action: ActionRemoveValidator, Target: validatorC},
+	})
+	approve := newSystemTx(t, signerB, params.GovernanceAddress, 0, big.NewInt(0),
+		&GovernanceCall{ProposalID: propose.Hash(), Approve: true})
+	first := &block.Block{
+		Header:       &block.BlockHeader{Height: 1},
+		Transactions: []*transaction.Transaction{propose},
+	}
+	assert.NoError(t, governance.ProcessBlock(first))
+	abandoned := &block.Block{
+		Header:       &block.BlockHeader{Height: 2, ParentHash: first.Hash()},
+		Transactions: []*transaction.Transaction{approve},
+	}
+	assert.NoError(t, governance.ProcessBlock(abandoned))
+	proposal, _ := governance.GetProposal(propose.Hash())
+	assert.True(t, proposal.Passed)
+
+	// the block replacing the abandoned one builds on the state of its parent
+	canonical := &block.Block{Header: &block.BlockHeader{Height: 2, ParentHash: first.Hash(), Round: 1}}
+	assert.False(t, governance.HasProcessed(canonical.Hash()))
+	assert.NoError(t, governance.ProcessBlock(canonical))
+	assert.True(t, governance.HasProcessed(canonical.Hash()))
+	proposal, _ = governance.GetProposal(propose.Hash())
+	assert.False(t, proposal.Passed, "the vote of the abandoned branch is dropped")
+
+	// a block whose parent state is unknown is refused
+	orphan := &block.Block{Header: &block.BlockHeader{Height: 5, ParentHash: common.Hash{0x05}}}
+	assert.Error(t, governance.ProcessBlock(orphan))
+}
+
+func TestGovernanceEpochValidators(t *testing.T) {
+	signerA, validatorA := newTestSigner(t)
+	_, validatorB := newTestSigner(t)
+	_, validatorC := newTestSigner(t)
+
+	authority := NewAuthority()
+	for _, validator := range []common.Address{validatorA, validatorB, validatorC} {
+		assert.NoError(t, authority.AddValidator(validator, big.NewInt(1)))
+	}
+	epochValidators := testEpochValidators(authority.ListValidators())
+	db, _ := database.NewMemDatabase()
+	validatorDB, _ := database.NewMemDatabase()
+	observerDB, _ := database.NewMemDatabase()
+	governance := NewGovernance(authority, epochValidators, db, validatorDB, observerDB)
+
+	// validators leaving the live authority mid-epoch still weigh in the
+	// tally of the epoch
+	assert.NoError(t, authority.RemoveValidator(validatorB))
+	assert.NoError(t, authority.RemoveValidator(validatorC))
+	propose := newSystemTx(t, signerA, params.GovernanceAddress, 0, big.NewInt(0), &GovernanceCall{
+		Proposal: &GovernanceProposal{Action: ActionRemoveValidator, Target: validatorC},
+	})
+	assert.NoError(t, governance.ProcessBlock(&block.Block{
+		Header:       &block.BlockHeader{Height: 1},
+		Transactions: []*transaction.Transaction{propose},
+	}))
+	proposal, ok := governance.GetProposal(propose.Hash())
+	assert.True(t, ok)
+	assert.False(t, proposal.Passed, "1/3 of the epoch weight is not a majority")
+}
+
+func TestAuthorityCommitRemovals(t *testing.T) {
+	validatorDB, _ := database.NewMemDatabase()
+	observerDB, _ := database.NewMemDatabase()
+
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(common.Address{0x01}, big.NewInt(1)))
+	assert.NoError(t, authority.AddObserver(common.Address{0x02}, nil))
+	assert.NoError(t, authority.CommitToStorage(validatorDB, observerDB))
+
+	assert.NoError(t, authority.RemoveValidator(common.Address{0x01}))
+	assert.NoError(t, authority.RemoveObserver(common.Address{0x02}))
+	assert.NoError(t, authority.CommitToStorage(validatorDB, observerDB))
+
+	stored := NewAuthority()
+	assert.NoError(t, stored.LoadFromDB(validatorDB, observerDB))
+	assert.Empty(t, stored.ListValidators(), "removed validator should be deleted")
+	assert.Empty(t, stored.ListObservers(), "removed observer should be deleted")
+}
@@ -0,0 +1,403 @@
+package poa_consensus
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/consensus"
+	"FichainCore/crypto"
+	"FichainCore/database"
+	"FichainCore/params"
+	pb "FichainCore/proto"
+	"FichainCore/receipt"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+type ReserveAction uint32
+
+const (
+	ReserveActionMint     ReserveAction = iota + 1 // issue native VND backed by the bank reserve
+	ReserveActionBurn                              // destroy the transaction amount and release its backing
+	ReserveActionDeposit                           // record fiat added to the bank reserve
+	ReserveActionWithdraw                          // record fiat taken out of the bank reserve
+	ReserveActionAttest                            // attest the fiat balance the bank holds
+)
+
+// ReserveCall is the data of a transaction sent to params.ReserveAddress
+type ReserveCall struct {
+	Action    ReserveAction
+	Recipient common.Address
+	Amount    *big.Int
+	Timestamp uint64
+}
+
+// Marshal encodes the call as transaction data
+func (c *ReserveCall) Marshal() ([]byte, error) {
+	var amount []byte
+	if c.Amount != nil {
+		amount = c.Amount.Bytes()
+	}
+	return proto.Marshal(&pb.ReserveCall{
+		Action:    uint32(c.Action),
+		Recipient: c.Recipient.Bytes(),
+		Amount:    amount,
+		Timestamp: c.Timestamp,
+	})
+}
+
+// Unmarshal decodes the call from transaction data
+func (c *ReserveCall) Unmarshal(data []byte) error {
+	var pbCall pb.ReserveCall
+	if err := proto.Unmarshal(data, &pbCall); err != nil {
+		return err
+	}
+	c.Action = ReserveAction(pbCall.Action)
+	c.Recipient = common.BytesToAddress(pbCall.Recipient)
+	c.Amount = new(big.Int).SetBytes(pbCall.Amount)
+	c.Timestamp = pbCall.Timestamp
+	return nil
+}
+
+// NativeIssuer mints and burns native VND against the fiat reserve of the
+// validator banks. The reserve of every bank and the amount it issued are kept
+// in the storage of params.ReserveAddress, a bank can never issue more than
+// its reserve so native VND stays pegged 1:1 to fiat. Banks also attest the
+// fiat balance they actually hold. After every canonical block the reserve
+// balances are mirrored to the local FiatReserve.
+type NativeIssuer struct {
+	reserve    *FiatReserve
+	validators EpochValidators
+	states     *ChainStates
+	reserveDB  database.Database
+}
+
+func NewNativeIssuer(
+	reserve *FiatReserve,
+	validators EpochValidators,
+	states *ChainStates,
+	reserveDB database.Database,
+) *NativeIssuer {
+	return &NativeIssuer{
+		reserve:    reserve,
+		validators: validators,
+		states:     states,
+		reserveDB:  reserveDB,
+	}
+}
+
+// ReserveAttestation is the latest balance a bank attested. The attestation
+// transaction is signed by the bank, so auditors can verify it from the block
+// that included it.
+type ReserveAttestation struct {
+	Balance   *big.Int
+	Timestamp uint64
+	Height    uint64
+	TxHash    common.Hash
+}
+
+// Proto converts ReserveAttestation to protobuf message
+func (a *ReserveAttestation) Proto() proto.Message {
+	return &pb.ReserveAttestation{
+		Balance:   a.Balance.Bytes(),
+		Timestamp: a.Timestamp,
+		Height:    a.Height,
+		TxHash:    a.TxHash.Bytes(),
+	}
+}
+
+// FromProto populates ReserveAttestation from a protobuf message
+func (a *ReserveAttestation) FromProto(pbAttestation *pb.ReserveAttestation) error {
+	a.Balance = new(big.Int).SetBytes(pbAttestation.Balance)
+	a.Timestamp = pbAttestation.Timestamp
+	a.Height = pbAttestation.Height
+	a.TxHash = common.BytesToHash(pbAttestation.TxHash)
+	return nil
+}
+
+// --------------------------- Storage layout ---------------------------
+
+// reserveSlot uses the stake slot layout in the storage of params.ReserveAddress
+func reserveSlot(kind string, addresses ...common.Address) common.Hash {
+	return stakeSlot(kind, addresses...)
+}
+
+func appliedReserveSlot(txHash common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte("reserve-applied"), txHash.Bytes())
+}
+
+func getReserveValue(st *state.StateDB, slot common.Hash) *big.Int {
+	return st.GetState(params.ReserveAddress, slot).Big()
+}
+
+func setReserveValue(st *state.StateDB, slot common.Hash, value *big.Int) {
+	setSystemState(st, params.ReserveAddress, slot, value)
+}
+
+// WriteGenesisReserves records the genesis fiat reserve of every bank in state
+// together with the native supply allocated at genesis, which the reserves
+// back before any bank issued VND
+func WriteGenesisReserves(
+	st *state.StateDB,
+	balances map[common.Address]*big.Int,
+	supply *big.Int,
+) {
+	total := big.NewInt(0)
+	for bank, balance := range balances {
+		setReserveValue(st, reserveSlot("reserve-balance", bank), balance)
+		total.Add(total, balance)
+	}
+	setReserveValue(st, reserveSlot("reserve-total-balance"), total)
+	setReserveValue(st, reserveSlot("reserve-genesis-supply"), supply)
+}
+
+// --------------------------- Read APIs ---------------------------
+
+// ReserveBalance returns the fiat reserve recorded for a bank
+func (n *NativeIssuer) ReserveBalance(st *state.StateDB, bank common.Address) *big.Int {
+	return getReserveValue(st, reserveSlot("reserve-balance", bank))
+}
+
+// Issued returns the native VND outstanding against a bank reserve
+func (n *NativeIssuer) Issued(st *state.StateDB, bank common.Address) *big.Int {
+	return getReserveValue(st, reserveSlot("reserve-issued", bank))
+}
+
+// TotalIssued returns the native VND outstanding against all reserves
+func (n *NativeIssuer) TotalIssued(st *state.StateDB) *big.Int {
+	return getReserveValue(st, reserveSlot("reserve-total-issued"))
+}
+
+// TotalReserve returns the sum of the fiat reserves of all banks
+func (n *NativeIssuer) TotalReserve(st *state.StateDB) *big.Int {
+	return getReserveValue(st, reserveSlot("reserve-total-balance"))
+}
+
+// GenesisSupply returns the native VND allocated at genesis
+func (n *NativeIssuer) GenesisSupply(st *state.StateDB) *big.Int {
+	return getReserveValue(st, reserveSlot("reserve-genesis-supply"))
+}
+
+// Supply returns the native VND outstanding, the genesis allocation plus the
+// VND issued against the reserves
+func (n *NativeIssuer) Supply(st *state.StateDB) *big.Int {
+	return new(big.Int).Add(n.TotalIssued(st), n.GenesisSupply(st))
+}
+
+// Applied reports whether a reserve transaction was executed, rejected calls
+// stay in the block but never change the reserve
+func (n *NativeIssuer) Applied(st *state.StateDB, txHash common.Hash) bool {
+	return getReserveValue(st, appliedReserveSlot(txHash)).Sign() != 0
+}
+
+// Attestation returns the latest reserve attestation of a bank, nil if the
+// bank never attested
+func (n *NativeIssuer) Attestation(st *state.StateDB, bank common.Address) *ReserveAttestation {
+	height := getReserveValue(st, reserveSlot("attestation-height", bank))
+	if height.Sign() == 0 {
+		return nil
+	}
+	return &ReserveAttestation{
+		Balance:   getReserveValue(st, reserveSlot("attestation-balance", bank)),
+		Timestamp: getReserveValue(st, reserveSlot("attestation-time", bank)).Uint64(),
+		Height:    height.Uint64(),
+		TxHash:    st.GetState(params.ReserveAddress, reserveSlot("attestation-tx", bank)),
+	}
+}
+
+// --------------------------- Operations ---------------------------
+
+func (n *NativeIssuer) mint(
+	st *state.StateDB,
+	bank, recipient common.Address,
+	amount *big.Int,
+) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("mint amount must be positive")
+	}
+	if (recipient == common.Address{}) {
+		return errors.New("missing mint recipient")
+	}
+	issued := new(big.Int).Add(n.Issued(st, bank), amount)
+	if issued.Cmp(n.ReserveBalance(st, bank)) > 0 {
+		return fmt.Errorf("mint exceeds the reserve of %s", bank.Hex())
+	}
+	// the genesis supply is backed by the reserves as a whole
+	total := new(big.Int).Add(n.TotalIssued(st), amount)
+	if new(big.Int).Add(n.Supply(st), amount).Cmp(n.TotalReserve(st)) > 0 {
+		return errors.New("mint exceeds the total reserve")
+	}
+	setReserveValue(st, reserveSlot("reserve-issued", bank), issued)
+	setReserveValue(st, reserveSlot("reserve-total-issued"), total)
+	st.AddBalance(recipient, amount)
+	return nil
+}
+
+func (n *NativeIssuer) burn(st *state.StateDB, bank common.Address, amount *big.Int) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("burn amount must be positive")
+	}
+	issued := n.Issued(st, bank)
+	if issued.Cmp(amount) < 0 {
+		return fmt.Errorf("burn exceeds the amount issued by %s", bank.Hex())
+	}
+	setReserveValue(st, reserveSlot("reserve-issued", bank), issued.Sub(issued, amount))
+	total := n.TotalIssued(st)
+	setReserveValue(st, reserveSlot("reserve-total-issued"), total.Sub(total, amount))
+	st.SubBalance(params.ReserveAddress, amount)
+	return nil
+}
+
+func (n *NativeIssuer) deposit(st *state.StateDB, bank common.Address, amount *big.Int) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("deposit amount must be positive")
+	}
+	balance := n.ReserveBalance(st, bank)
+	setReserveValue(st, reserveSlot("reserve-balance", bank), balance.Add(balance, amount))
+	total := n.TotalReserve(st)
+	setReserveValue(st, reserveSlot("reserve-total-balance"), total.Add(total, amount))
+	return nil
+}
+
+func (n *NativeIssuer) withdraw(st *state.StateDB, bank common.Address, amount *big.Int) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("withdrawal amount must be positive")
+	}
+	balance := new(big.Int).Sub(n.ReserveBalance(st, bank), amount)
+	if balance.Cmp(n.Issued(st, bank)) < 0 {
+		return fmt.Errorf("withdrawal leaves issued VND of %s unbacked", bank.Hex())
+	}
+	total := new(big.Int).Sub(n.TotalReserve(st), amount)
+	if total.Cmp(n.Supply(st)) < 0 {
+		return errors.New("withdrawal leaves the native supply unbacked")
+	}
+	setReserveValue(st, reserveSlot("reserve-balance", bank), balance)
+	setReserveValue(st, reserveSlot("reserve-total-balance"), total)
+	return nil
+}
+
+// attest records the fiat balance a bank attested, it does not change the
+// recorded reserve so auditors can compare both
+func (n *NativeIssuer) attest(
+	st *state.StateDB,
+	bank common.Address,
+	call *ReserveCall,
+	height uint64,
+	txHash common.Hash,
+) error {
+	if call.Amount == nil {
+		return errors.New("missing attested balance")
+	}
+	if call.Timestamp == 0 {
+		return errors.New("missing attestation timestamp")
+	}
+	if previous := n.Attestation(st, bank); previous != nil && call.Timestamp <= previous.Timestamp {
+		return fmt.Errorf("attestation of %s is older than the latest one", bank.Hex())
+	}
+	setReserveValue(st, reserveSlot("attestation-balance", bank), call.Amount)
+	setReserveValue(st, reserveSlot("attestation-time", bank), new(big.Int).SetUint64(call.Timestamp))
+	setReserveValue(st, reserveSlot("attestation-height", bank), new(big.Int).SetUint64(height))
+	setReserveValue(st, reserveSlot("attestation-tx", bank), txHash.Big())
+	return nil
+}
+
+// FinalizeState executes the reserve transactions of a block. Only validator
+// banks of the block's epoch may call the reserve. The value of a burn was
+// already moved to params.ReserveAddress by the transaction, the value of any
+// other or rejected call is sent back.
+func (n *NativeIssuer) FinalizeState(
+	chain consensus.ChainReader,
+	header *block.BlockHeader,
+	st *state.StateDB,
+	txs []*transaction.Transaction,
+	receipts []*receipt.Receipt,
+) error {
+	var banks map[common.Address]*big.Int
+	for i, tx := range txs {
+		if tx.To() != params.ReserveAddress {
+			continue
+		}
+		if i < len(receipts) && receipts[i].Status == 0 {
+			continue
+		}
+		from, err := tx.From(params.TempChainId)
+		if err != nil {
+			return err
+		}
+		if banks == nil {
+			banks, err = n.validators.EpochValidators(n.validators.EpochOf(header.Height))
+			if err != nil {
+				return err
+			}
+		}
+
+		call := &ReserveCall{}
+		if _, ok := banks[from]; !ok {
+			err = fmt.Errorf("sender is not a validator bank: %s", from.Hex())
+		} else if err = call.Unmarshal(tx.Data()); err == nil {
+			switch call.Action {
+			case ReserveActionMint:
+				err = n.mint(st, from, call.Recipient, call.Amount)
+			case ReserveActionBurn:
+				err = n.burn(st, from, tx.Amount())
+			case ReserveActionDeposit:
+				err = n.deposit(st, from, call.Amount)
+			case ReserveActionWithdraw:
+				err = n.withdraw(st, from, call.Amount)
+			case ReserveActionAttest:
+				err = n.attest(st, from, call, header.Height, tx.Hash())
+			default:
+				err = fmt.Errorf("unknown reserve action %d", call.Action)
+			}
+		}
+		if err != nil {
+			logger.Warn("[NativeIssuer] rejected transaction", tx.Hash().Hex(), err)
+		} else {
+			setReserveValue(st, appliedReserveSlot(tx.Hash()), big.NewInt(1))
+		}
+		if (err != nil || call.Action != ReserveActionBurn) &&
+			tx.Amount() != nil && tx.Amount().Sign() > 0 {
+			st.SubBalance(params.ReserveAddress, tx.Amount())
+			st.AddBalance(from, tx.Amount())
+		}
+	}
+	return nil
+}
+
+// ProcessBlock mirrors the reserve balances of the banks that called the
+// reserve in a canonical block to the local FiatReserve
+func (n *NativeIssuer) ProcessBlock(bl *block.Block) error {
+	banks := make(map[common.Address]struct{})
+	for _, tx := range bl.Transactions {
+		if tx.To() != params.ReserveAddress {
+			continue
+		}
+		from, err := tx.From(params.TempChainId)
+		if err != nil {
+			continue
+		}
+		banks[from] = struct{}{}
+	}
+	if len(banks) == 0 {
+		return nil
+	}
+	st, err := n.states.At(bl.Header)
+	if err != nil {
+		return err
+	}
+	for bank := range banks {
+		n.reserve.SetBalance(bank, n.ReserveBalance(st, bank))
+	}
+	return n.reserve.CommitToStorage(n.reserveDB)
+}
+
+// EndEpoch implements BlockProcessor, reserves do not change at epoch ends
+func (n *NativeIssuer) EndEpoch(epoch uint64, last *block.Block) error {
+	return nil
+}
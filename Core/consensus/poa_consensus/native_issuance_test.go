@@ -0,0 +1,139 @@
+package poa_consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/params"
+	"FichainCore/receipt"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+// applyReserveTxs moves the transaction values like the EVM would then runs
+// the issuer finalization
+func applyReserveTxs(
+	t *testing.T,
+	issuer *NativeIssuer,
+	st *state.StateDB,
+	txs ...*transaction.Transaction,
+) {
+	receipts := make([]*receipt.Receipt, len(txs))
+	for i, tx := range txs {
+		from, err := tx.From(params.TempChainId)
+		assert.NoError(t, err)
+		st.SubBalance(from, tx.Amount())
+		st.AddBalance(params.ReserveAddress, tx.Amount())
+		receipts[i] = receipt.NewReceipt(nil, false, 0)
+	}
+	header := &block.BlockHeader{Height: 1}
+	assert.NoError(t, issuer.FinalizeState(nil, header, st, txs, receipts))
+}
+
+func TestNativeIssuer(t *testing.T) {
+	bankSigner, bank := newTestSigner(t)
+	outsiderSigner, _ := newTestSigner(t)
+	customer := common.HexToAddress("0xc0ffee")
+
+	memDB, _ := database.NewMemDatabase()
+	stateDatabase := state.NewDatabase(memDB)
+	states := NewChainStates(stateDatabase, memDB)
+	st, err := state.New(common.Hash{}, stateDatabase)
+	assert.NoError(t, err)
+	WriteGenesisReserves(st, map[common.Address]*big.Int{bank: big.NewInt(1000)}, big.NewInt(0))
+
+	reserveDB, _ := database.NewMemDatabase()
+	reserve := NewFiatReserve()
+	issuer := NewNativeIssuer(
+		reserve,
+		testEpochValidators{bank: big.NewInt(1)},
+		states,
+		reserveDB,
+	)
+	assert.Equal(t, big.NewInt(1000), issuer.ReserveBalance(st, bank))
+
+	applyReserveTxs(t, issuer, st,
+		newSystemTx(t, bankSigner, params.ReserveAddress, 0, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionMint,
+			Recipient: customer,
+			Amount:    big.NewInt(600),
+		}),
+		// exceeds the remaining reserve
+		newSystemTx(t, bankSigner, params.ReserveAddress, 1, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionMint,
+			Recipient: customer,
+			Amount:    big.NewInt(401),
+		}),
+		// only validator banks may mint
+		newSystemTx(t, outsiderSigner, params.ReserveAddress, 0, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionMint,
+			Recipient: customer,
+			Amount:    big.NewInt(1),
+		}),
+	)
+	assert.Equal(t, big.NewInt(600), st.GetBalance(customer))
+	assert.Equal(t, big.NewInt(600), issuer.Issued(st, bank))
+	assert.Equal(t, big.NewInt(600), issuer.TotalIssued(st))
+
+	t.Run("TestBurn", func(t *testing.T) {
+		applyReserveTxs(t, issuer, st,
+			newSystemTx(t, bankSigner, params.ReserveAddress, 2, big.NewInt(0), &ReserveCall{
+				Action:    ReserveActionMint,
+				Recipient: bank,
+				Amount:    big.NewInt(100),
+			}),
+			newSystemTx(t, bankSigner, params.ReserveAddress, 3, big.NewInt(40), &ReserveCall{
+				Action: ReserveActionBurn,
+			}),
+		)
+		assert.Equal(t, big.NewInt(60), st.GetBalance(bank))
+		assert.Equal(t, big.NewInt(660), issuer.Issued(st, bank))
+		assert.Equal(t, 0, st.GetBalance(params.ReserveAddress).Sign(), "burned VND is destroyed")
+	})
+
+	t.Run("TestReserveMovements", func(t *testing.T) {
+		applyReserveTxs(t, issuer, st,
+			// would leave issued VND unbacked, the value is sent back
+			newSystemTx(t, bankSigner, params.ReserveAddress, 4, big.NewInt(5), &ReserveCall{
+				Action: ReserveActionWithdraw,
+				Amount: big.NewInt(341),
+			}),
+			newSystemTx(t, bankSigner, params.ReserveAddress, 5, big.NewInt(0), &ReserveCall{
+				Action: ReserveActionDeposit,
+				Amount: big.NewInt(500),
+			}),
+			newSystemTx(t, bankSigner, params.ReserveAddress, 6, big.NewInt(0), &ReserveCall{
+				Action: ReserveActionWithdraw,
+				Amount: big.NewInt(200),
+			}),
+		)
+		assert.Equal(t, big.NewInt(60), st.GetBalance(bank))
+		assert.Equal(t, big.NewInt(1300), issuer.ReserveBalance(st, bank))
+	})
+
+	t.Run("TestMirrorReserve", func(t *testing.T) {
+		root, err := st.Commit(true)
+		assert.NoError(t, err)
+		bl := &block.Block{
+			Header: &block.BlockHeader{Height: 1, StateRoot: root},
+			Transactions: []*transaction.Transaction{
+				newSystemTx(t, bankSigner, params.ReserveAddress, 7, big.NewInt(0), &ReserveCall{
+					Action: ReserveActionDeposit,
+					Amount: big.NewInt(1),
+				}),
+			},
+		}
+		assert.NoError(t, issuer.ProcessBlock(bl))
+
+		stored := NewFiatReserve()
+		assert.NoError(t, stored.LoadFromStorage(reserveDB))
+		balance, err := stored.GetBalance(bank)
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(1300), balance)
+	})
+}
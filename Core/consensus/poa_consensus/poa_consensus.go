@@ -2,20 +2,130 @@ package poa_consensus
 
 import (
 	"math/big"
+	"sync"
+	"time"
 
 	"FichainCore/block"
 	"FichainCore/common"
 	"FichainCore/consensus"
+	"FichainCore/crypto"
+	"FichainCore/errors"
+	"FichainCore/params"
 	"FichainCore/receipt"
+	"FichainCore/signer"
 	"FichainCore/state"
 	"FichainCore/transaction"
 )
 
-type POAConsensus struct{}
+// allowedFutureBlockTime is the max time from current time allowed for blocks,
+// before they're considered future blocks.
+const allowedFutureBlockTime = 15 * time.Second
 
+// ProposerSelector resolves the validator allowed to seal a block height in
+// a given round, implemented by ProposerSchedule and EpochManager.
+type ProposerSelector interface {
+	GetProposer(blockHeight uint64) (common.Address, error)
+	GetProposerForRound(blockHeight uint64, round uint64) (common.Address, error)
+}
+
+// StateFinalizer applies consensus owned state transitions when a block is
+// finalized, on the proposer as well as on every importing node.
+type StateFinalizer interface {
+	FinalizeState(
+		chain consensus.ChainReader,
+		header *block.BlockHeader,
+		state *state.StateDB,
+		txs []*transaction.Transaction,
+		receipts []*receipt.Receipt,
+	) error
+}
+
+type POAConsensus struct {
+	proposerSchedule ProposerSelector
+	proposerTimeout  uint64 // seconds a proposer round lasts before the next backup may seal
+	finalizers       []StateFinalizer
+
+	signer        *signer.Signer // local proposer key, nil on non-sealing nodes
+	signerAddress common.Address
+
+	sync.RWMutex
+}
+
+func NewPOAConsensus(proposerSchedule ProposerSelector) *POAConsensus {
+	return &POAConsensus{
+		proposerSchedule: proposerSchedule,
+		proposerTimeout:  params.TempProposerTimeout,
+	}
+}
+
+// SetProposerTimeout changes how long, in seconds, a proposer round lasts.
+func (c *POAConsensus) SetProposerTimeout(timeout uint64) {
+	c.Lock()
+	defer c.Unlock()
+	c.proposerTimeout = timeout
+}
+
+// AddStateFinalizer registers a state transition run by Finalize, in
+// registration order.
+func (c *POAConsensus) AddStateFinalizer(f StateFinalizer) {
+	c.Lock()
+	defer c.Unlock()
+	c.finalizers = append(c.finalizers, f)
+}
+
+// round returns the proposer round a block produced at the given time on top
+// of parent belongs to.
+func (c *POAConsensus) round(parent *block.BlockHeader, now uint64) uint64 {
+	c.RLock()
+	timeout := c.proposerTimeout
+	c.RUnlock()
+
+	if timeout == 0 || now <= parent.Timestamp {
+		return 0
+	}
+	return (now - parent.Timestamp) / timeout
+}
+
+// CurrentProposer returns the validator allowed to seal the child of parent
+// at the current time and the round it belongs to. Once the scheduled
+// proposer misses its timeout the slot rotates to a backup proposer.
+func (c *POAConsensus) CurrentProposer(parent *block.BlockHeader) (common.Address, uint64, error) {
+	round := c.round(parent, uint64(time.Now().Unix()))
+	proposer, err := c.proposerSchedule.GetProposerForRound(parent.Height+1, round)
+	return proposer, round, err
+}
+
+// Authorize injects the local signer used by Prepare and Seal to produce blocks.
+func (c *POAConsensus) Authorize(s *signer.Signer) error {
+	address, err := s.WalletAddress()
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.signer = s
+	c.signerAddress = address
+	return nil
+}
+
+// Author retrieves the address of the account that signed the given block,
+// recovered from the header signature.
 func (c *POAConsensus) Author(header *block.BlockHeader) (common.Address, error) {
-	// TODO
-	return header.Proposer, nil
+	return ecrecover(header)
+}
+
+// ecrecover extracts the signer address from a signed header. The signature
+// covers the header hash, which does not include the signature itself.
+func ecrecover(header *block.BlockHeader) (common.Address, error) {
+	if len(header.Signature) == 0 {
+		return common.Address{}, errors.ErrMissingSignature
+	}
+	pub, err := crypto.SigToPub(header.Hash().Bytes(), header.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
 }
 
 // VerifyHeader checks whether a header conforms to the consensus rules of a
@@ -26,7 +136,59 @@ func (c *POAConsensus) VerifyHeader(
 	header *block.BlockHeader,
 	seal bool,
 ) error {
-	// TODO
+	return c.verifyHeader(chain, header, nil, seal)
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules. The
+// caller may optionally pass in a batch of parents (ascending order) to avoid
+// looking those up from the database, which is useful for concurrently
+// verifying a batch of new headers.
+func (c *POAConsensus) verifyHeader(
+	chain consensus.ChainReader,
+	header *block.BlockHeader,
+	parents []*block.BlockHeader,
+	seal bool,
+) error {
+	// The genesis block is committed from the genesis spec, never imported
+	if header.Height == 0 {
+		return errors.ErrInvalidNumber
+	}
+	// Don't waste time checking blocks from the future
+	if header.Timestamp > uint64(time.Now().Add(allowedFutureBlockTime).Unix()) {
+		return errors.ErrFutureBlock
+	}
+	if header.GasUsed > params.TempGasLimit {
+		return errors.ErrInvalidGasUsed
+	}
+
+	var parent *block.BlockHeader
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, header.Height-1)
+	}
+	if parent == nil || parent.Hash() != header.ParentHash {
+		return errors.ErrUnknownAncestor
+	}
+	if parent.Height+1 != header.Height {
+		return errors.ErrInvalidNumber
+	}
+	if header.Timestamp < parent.Timestamp {
+		return errors.ErrInvalidTimestamp
+	}
+	// A backup proposer may only seal once the previous rounds timed out
+	if header.Round > 0 {
+		if c.round(parent, header.Timestamp) < header.Round {
+			return errors.ErrPrematureRound
+		}
+		if header.Timestamp > uint64(time.Now().Unix()) {
+			return errors.ErrFutureBlock
+		}
+	}
+
+	if seal {
+		return c.VerifySeal(chain, header)
+	}
 	return nil
 }
 
@@ -39,18 +201,23 @@ func (c *POAConsensus) VerifyHeaders(
 	headers []*block.BlockHeader,
 	seals []bool,
 ) (chan<- struct{}, <-chan error) {
-	// TODO
 	abort := make(chan struct{})
 	results := make(chan error, len(headers))
 
 	go func() {
 		defer close(results)
-		for range headers {
+		for i, header := range headers {
+			// headers in a batch are chained, so each one is the parent of the next
+			var parents []*block.BlockHeader
+			if i > 0 {
+				parents = headers[:i]
+			}
+			err := c.verifyHeader(chain, header, parents, seals[i])
+
 			select {
 			case <-abort:
 				return
-			default:
-				results <- nil // always succeed
+			case results <- err:
 			}
 		}
 	}()
@@ -61,21 +228,51 @@ func (c *POAConsensus) VerifyHeaders(
 // VerifyUncles verifies that the given block's uncles conform to the consensus
 // rules of a given engine.
 func (c *POAConsensus) VerifyUncles(chain consensus.ChainReader, block *block.Block) error {
-	// TODO
+	if len(block.Uncles) > 0 {
+		return errors.ErrUnclesNotAllowed
+	}
 	return nil
 }
 
 // VerifySeal checks whether the crypto seal on a header is valid according to
 // the consensus rules of the given engine.
 func (c *POAConsensus) VerifySeal(chain consensus.ChainReader, header *block.BlockHeader) error {
-	// TODO
+	signer, err := ecrecover(header)
+	if err != nil {
+		return err
+	}
+	if signer != header.Proposer {
+		return errors.ErrUnauthorizedProposer
+	}
+	proposer, err := c.proposerSchedule.GetProposerForRound(header.Height, header.Round)
+	if err != nil {
+		return err
+	}
+	if signer != proposer {
+		return errors.ErrUnauthorizedProposer
+	}
 	return nil
 }
 
 // Prepare initializes the consensus fields of a block header according to the
 // rules of a particular engine. The changes are executed inline.
 func (c *POAConsensus) Prepare(chain consensus.ChainReader, header *block.BlockHeader) error {
-	// TODO
+	c.RLock()
+	signerAddress := c.signerAddress
+	c.RUnlock()
+
+	parent := chain.GetHeader(header.ParentHash, header.Height-1)
+	if parent == nil {
+		return errors.ErrUnknownAncestor
+	}
+
+	header.Proposer = signerAddress
+	header.Timestamp = uint64(time.Now().Unix())
+	if header.Timestamp < parent.Timestamp {
+		header.Timestamp = parent.Timestamp
+	}
+	header.Round = c.round(parent, header.Timestamp)
+	header.Prevrandao = common.BigToHash(big.NewInt(time.Now().UnixNano()))
 	return nil
 }
 
@@ -91,19 +288,57 @@ func (c *POAConsensus) Finalize(
 	uncles []*block.BlockHeader,
 	receipts []*receipt.Receipt,
 ) (*block.Block, error) {
-	// TODO
-	return block.NewBlock(header, txs, uncles, receipts), nil
+	c.RLock()
+	finalizers := c.finalizers
+	c.RUnlock()
+
+	for _, f := range finalizers {
+		if err := f.FinalizeState(chain, header, state, txs, receipts); err != nil {
+			return nil, err
+		}
+	}
+	header.StateRoot = state.IntermediateRoot(true)
+	return block.NewBlock(header, txs, nil, receipts), nil
 }
 
 // Seal generates a new block for the given input block with the local miner's
 // seal place on top.
 func (c *POAConsensus) Seal(
 	chain consensus.ChainReader,
-	block *block.Block,
+	bl *block.Block,
 	stop <-chan struct{},
 ) (*block.Block, error) {
-	// TODO
-	return nil, nil
+	c.RLock()
+	s, signerAddress := c.signer, c.signerAddress
+	c.RUnlock()
+
+	if s == nil {
+		return nil, errors.ErrNoAuthorizedSigner
+	}
+	header := *bl.Header
+	if header.Height == 0 {
+		return nil, errors.ErrInvalidNumber
+	}
+	// only the proposer of the header round may seal this height
+	proposer, err := c.proposerSchedule.GetProposerForRound(header.Height, header.Round)
+	if err != nil {
+		return nil, err
+	}
+	if header.Proposer != signerAddress || proposer != signerAddress {
+		return nil, errors.ErrUnauthorizedProposer
+	}
+
+	sign, err := s.SignHash(header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	header.Signature = sign.Bytes()
+
+	return &block.Block{
+		Header:       &header,
+		Transactions: bl.Transactions,
+		Uncles:       bl.Uncles,
+	}, nil
 }
 
 // CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
@@ -0,0 +1,324 @@
+package poa_consensus
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/crypto"
+	"FichainCore/errors"
+	"FichainCore/params"
+	"FichainCore/signer"
+	"FichainCore/transaction"
+	"FichainCore/types"
+)
+
+// testChain is a minimal consensus.ChainReader backed by a header map
+type testChain struct {
+	headers   map[common.Hash]*block.BlockHeader
+	blocks    map[common.Hash]*block.Block
+	canonical map[uint64]*block.BlockHeader // canonical header by height when the chain has branches
+	head      *block.BlockHeader
+}
+
+func (c *testChain) Config() *params.ChainConfig       { return params.TestChainConfig }
+func (c *testChain) CurrentHeader() *block.BlockHeader { return c.head }
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *block.Block {
+	if bl, ok := c.blocks[hash]; ok {
+		return bl
+	}
+	if header, ok := c.headers[hash]; ok {
+		return &block.Block{Header: header}
+	}
+	return nil
+}
+func (c *testChain) GetHeaderByNumber(number uint64) *block.BlockHeader {
+	if c.canonical != nil {
+		return c.canonical[number]
+	}
+	for _, header := range c.headers {
+		if header.Height == number {
+			return header
+		}
+	}
+	return nil
+}
+func (c *testChain) GetHeaderByHash(hash common.Hash) *block.BlockHeader {
+	return c.headers[hash]
+}
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *block.BlockHeader {
+	return c.headers[hash]
+}
+
+func newTestSigner(t *testing.T) (*signer.Signer, common.Address) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	s := signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key)))
+	return s, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// newSystemTx signs a transaction calling a system address with the encoded
+// call as data
+func newSystemTx(
+	t *testing.T,
+	s *signer.Signer,
+	to common.Address,
+	nonce uint64,
+	amount *big.Int,
+	call interface{ Marshal() ([]byte, error) },
+) *transaction.Transaction {
+	data, err := call.Marshal()
+	assert.NoError(t, err)
+	tx := transaction.NewTransaction(to, nonce, amount, data, 100000, big.NewInt(1), "")
+	hash, err := tx.HashSign(params.TempChainId)
+	assert.NoError(t, err)
+	sign, err := s.SignHash(hash)
+	assert.NoError(t, err)
+	tx.SetSign(sign)
+	return tx
+}
+
+func signHeader(t *testing.T, s *signer.Signer, header *block.BlockHeader) {
+	sign, err := s.SignHash(header.Hash())
+	assert.NoError(t, err)
+	header.Signature = sign.Bytes()
+}
+
+func TestPOAConsensusVerifyHeader(t *testing.T) {
+	proposerSigner, proposer := newTestSigner(t)
+	otherSigner, other := newTestSigner(t)
+
+	// proposer is scheduled for block 1 only
+	schedule := &ProposerSchedule{
+		schedule: map[uint64]common.Address{1: proposer},
+	}
+	engine := NewPOAConsensus(schedule)
+
+	now := uint64(time.Now().Unix())
+	genesis := &block.BlockHeader{Height: 0, Timestamp: now}
+	chain := &testChain{
+		headers: map[common.Hash]*block.BlockHeader{genesis.Hash(): genesis},
+	}
+	newHeader := func(proposer common.Address) *block.BlockHeader {
+		return &block.BlockHeader{
+			Height:     1,
+			ParentHash: genesis.Hash(),
+			Timestamp:  now,
+			Proposer:   proposer,
+		}
+	}
+
+	t.Run("TestValidHeader", func(t *testing.T) {
+		header := newHeader(proposer)
+		signHeader(t, proposerSigner, header)
+		assert.NoError(t, engine.VerifyHeader(chain, header, true))
+
+		author, err := engine.Author(header)
+		assert.NoError(t, err)
+		assert.Equal(t, proposer, author)
+	})
+
+	t.Run("TestMissingSignature", func(t *testing.T) {
+		header := newHeader(proposer)
+		assert.Equal(t, errors.ErrMissingSignature, engine.VerifyHeader(chain, header, true))
+		// seal verification is optional
+		assert.NoError(t, engine.VerifyHeader(chain, header, false))
+	})
+
+	t.Run("TestUnscheduledProposer", func(t *testing.T) {
+		header := newHeader(other)
+		signHeader(t, otherSigner, header)
+		assert.Equal(t, errors.ErrUnauthorizedProposer, engine.VerifyHeader(chain, header, true))
+	})
+
+	t.Run("TestSignerProposerMismatch", func(t *testing.T) {
+		header := newHeader(proposer)
+		signHeader(t, otherSigner, header)
+		assert.Equal(t, errors.ErrUnauthorizedProposer, engine.VerifyHeader(chain, header, true))
+	})
+
+	t.Run("TestUnknownParent", func(t *testing.T) {
+		header := newHeader(proposer)
+		header.ParentHash = common.Hash{0x01}
+		assert.Equal(t, errors.ErrUnknownAncestor, engine.VerifyHeader(chain, header, false))
+	})
+
+	t.Run("TestInvalidTimestamp", func(t *testing.T) {
+		header := newHeader(proposer)
+		header.Timestamp = now - 1
+		assert.Equal(t, errors.ErrInvalidTimestamp, engine.VerifyHeader(chain, header, false))
+
+		header.Timestamp = now + 3600
+		assert.Equal(t, errors.ErrFutureBlock, engine.VerifyHeader(chain, header, false))
+	})
+
+	t.Run("TestInvalidGasUsed", func(t *testing.T) {
+		header := newHeader(proposer)
+		header.GasUsed = params.TempGasLimit + 1
+		assert.Equal(t, errors.ErrInvalidGasUsed, engine.VerifyHeader(chain, header, false))
+
+		// the seal covers the gas used
+		header.GasUsed = 1
+		signHeader(t, proposerSigner, header)
+		assert.NoError(t, engine.VerifyHeader(chain, header, true))
+		header.GasUsed = 2
+		assert.Equal(t, errors.ErrUnauthorizedProposer, engine.VerifyHeader(chain, header, true))
+	})
+
+	t.Run("TestVerifyHeadersBatch", func(t *testing.T) {
+		first := newHeader(proposer)
+		signHeader(t, proposerSigner, first)
+		second := &block.BlockHeader{
+			Height:     2,
+			ParentHash: first.Hash(),
+			Timestamp:  now,
+			Proposer:   proposer,
+		}
+
+		_, results := engine.VerifyHeaders(
+			chain,
+			[]*block.BlockHeader{first, second},
+			[]bool{true, false},
+		)
+		assert.NoError(t, <-results)
+		assert.NoError(t, <-results)
+	})
+}
+
+func TestPOAConsensusSeal(t *testing.T) {
+	proposerSigner, proposer := newTestSigner(t)
+	otherSigner, _ := newTestSigner(t)
+
+	schedule := &ProposerSchedule{
+		schedule: map[uint64]common.Address{1: proposer},
+	}
+	genesis := &block.BlockHeader{Height: 0, Timestamp: uint64(time.Now().Unix())}
+	chain := &testChain{
+		headers: map[common.Hash]*block.BlockHeader{genesis.Hash(): genesis},
+	}
+
+	// the scheduled proposer seals a block that passes verification
+	engine := NewPOAConsensus(schedule)
+	assert.NoError(t, engine.Authorize(proposerSigner))
+
+	header := &block.BlockHeader{Height: 1, ParentHash: genesis.Hash()}
+	assert.NoError(t, engine.Prepare(chain, header))
+	assert.Equal(t, proposer, header.Proposer)
+
+	sealed, err := engine.Seal(chain, &block.Block{Header: header}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, header.Signature, "Seal should not modify the input header")
+	assert.NoError(t, engine.VerifyHeader(chain, sealed.Header, true))
+
+	// any other key refuses to seal the scheduled height
+	otherEngine := NewPOAConsensus(schedule)
+	assert.NoError(t, otherEngine.Authorize(otherSigner))
+
+	otherHeader := &block.BlockHeader{Height: 1, ParentHash: genesis.Hash()}
+	assert.NoError(t, otherEngine.Prepare(chain, otherHeader))
+	_, err = otherEngine.Seal(chain, &block.Block{Header: otherHeader}, nil)
+	assert.Equal(t, errors.ErrUnauthorizedProposer, err)
+
+	// an engine without signer cannot seal
+	_, err = NewPOAConsensus(schedule).Seal(chain, &block.Block{Header: header}, nil)
+	assert.Equal(t, errors.ErrNoAuthorizedSigner, err)
+}
+
+func TestPOAConsensusBackupProposer(t *testing.T) {
+	signerA, addressA := newTestSigner(t)
+	signerB, addressB := newTestSigner(t)
+
+	// round r of height 1 goes to the validator at (1+r) mod 2 in address
+	// order, so the lower address backs round 1 up
+	primarySigner, primary, backupSigner, backup := signerA, addressA, signerB, addressB
+	if bytes.Compare(addressA.Bytes(), addressB.Bytes()) < 0 {
+		primarySigner, primary, backupSigner, backup = signerB, addressB, signerA, addressA
+	}
+	validators := []common.Address{backup, primary}
+	schedule := &ProposerSchedule{
+		schedule:   map[uint64]common.Address{1: primary},
+		validators: validators,
+	}
+	scheduled, err := schedule.GetProposerForRound(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, primary, scheduled)
+	rotated, err := schedule.GetProposerForRound(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, backup, rotated, "round 1 should hand the slot to the backup")
+	wrapped, err := schedule.GetProposerForRound(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, primary, wrapped, "round 2 should wrap around to the scheduled proposer")
+
+	// a height without scheduled proposer still rotates its backups
+	_, err = schedule.GetProposerForRound(2, 0)
+	assert.Error(t, err)
+	unscheduled, err := schedule.GetProposerForRound(2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, primary, unscheduled)
+
+	engine := NewPOAConsensus(schedule)
+	engine.SetProposerTimeout(10)
+
+	now := uint64(time.Now().Unix())
+	genesis := &block.BlockHeader{Height: 0, Timestamp: now - 15}
+	chain := &testChain{
+		headers: map[common.Hash]*block.BlockHeader{genesis.Hash(): genesis},
+	}
+	newHeader := func(timestamp, round uint64, proposer common.Address) *block.BlockHeader {
+		return &block.BlockHeader{
+			Height:     1,
+			ParentHash: genesis.Hash(),
+			Timestamp:  timestamp,
+			Proposer:   proposer,
+			Round:      round,
+		}
+	}
+
+	t.Run("TestBackupAfterTimeout", func(t *testing.T) {
+		header := newHeader(genesis.Timestamp+10, 1, backup)
+		signHeader(t, backupSigner, header)
+		assert.NoError(t, engine.VerifyHeader(chain, header, true))
+	})
+
+	t.Run("TestBackupBeforeTimeout", func(t *testing.T) {
+		header := newHeader(genesis.Timestamp+9, 1, backup)
+		signHeader(t, backupSigner, header)
+		assert.Equal(t, errors.ErrPrematureRound, engine.VerifyHeader(chain, header, true))
+	})
+
+	t.Run("TestBackupWrongRound", func(t *testing.T) {
+		header := newHeader(genesis.Timestamp+10, 0, backup)
+		signHeader(t, backupSigner, header)
+		assert.Equal(t, errors.ErrUnauthorizedProposer, engine.VerifyHeader(chain, header, true))
+	})
+
+	t.Run("TestPrimaryLateBlock", func(t *testing.T) {
+		header := newHeader(genesis.Timestamp+10, 0, primary)
+		signHeader(t, primarySigner, header)
+		assert.NoError(t, engine.VerifyHeader(chain, header, true))
+	})
+
+	t.Run("TestBackupSeal", func(t *testing.T) {
+		backupEngine := NewPOAConsensus(schedule)
+		backupEngine.SetProposerTimeout(10)
+		assert.NoError(t, backupEngine.Authorize(backupSigner))
+
+		proposer, round, err := backupEngine.CurrentProposer(genesis)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), round)
+		assert.Equal(t, backup, proposer)
+
+		header := &block.BlockHeader{Height: 1, ParentHash: genesis.Hash()}
+		assert.NoError(t, backupEngine.Prepare(chain, header))
+		assert.Equal(t, uint64(1), header.Round)
+		sealed, err := backupEngine.Seal(chain, &block.Block{Header: header}, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, engine.VerifyHeader(chain, sealed.Header, true))
+	})
+}
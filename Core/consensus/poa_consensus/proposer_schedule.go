@@ -1,9 +1,12 @@
 package poa_consensus
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math/big"
 	"sort"
+	"sync"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
 	"golang.org/x/crypto/sha3"
@@ -13,7 +16,11 @@ import (
 )
 
 type ProposerSchedule struct {
-	schedule map[uint64]common.Address
+	schedule    map[uint64]common.Address
+	validators  []common.Address // sorted by address, used for backup proposer rotation
+	epochLength uint64           // blocks covered by one schedule, 0 uses params.TempEpochLength
+
+	sync.RWMutex
 }
 
 func NewProposerSchedule(
@@ -32,6 +39,13 @@ func NewProposerSchedule(
 
 // GetProposer returns the proposer for a given block height.
 func (ps *ProposerSchedule) GetProposer(blockHeight uint64) (common.Address, error) {
+	ps.RLock()
+	defer ps.RUnlock()
+	return ps.proposer(blockHeight)
+}
+
+// proposer looks the scheduled proposer up, the caller holds the lock
+func (ps *ProposerSchedule) proposer(blockHeight uint64) (common.Address, error) {
 	proposer, exists := ps.schedule[blockHeight]
 	if !exists {
 		return common.Address{}, fmt.Errorf("no proposer found for block height %d", blockHeight)
@@ -39,72 +53,131 @@ func (ps *ProposerSchedule) GetProposer(blockHeight uint64) (common.Address, err
 	return proposer, nil
 }
 
+// GetProposerForRound returns the proposer for a given block height and round.
+// Round 0 is the scheduled proposer, each following round hands the slot to
+// the next validator in address order starting from the height, so a backup
+// exists even for a height the schedule left without proposer.
+func (ps *ProposerSchedule) GetProposerForRound(
+	blockHeight uint64,
+	round uint64,
+) (common.Address, error) {
+	ps.RLock()
+	defer ps.RUnlock()
+
+	if round == 0 {
+		return ps.proposer(blockHeight)
+	}
+	if len(ps.validators) == 0 {
+		return common.Address{}, fmt.Errorf("no backup proposer for block height %d", blockHeight)
+	}
+	return ps.validators[(blockHeight+round)%uint64(len(ps.validators))], nil
+}
+
 func (ps *ProposerSchedule) UpdateSchedule(
 	salt []byte,
 	fromBlock uint64,
 	authoritiesWithWeight map[common.Address]*big.Int,
 ) error {
-	// Step 1: Sort authorities by weight (descending order)
+	ps.Lock()
+	defer ps.Unlock()
+
+	epochLength := ps.epochLength
+	if epochLength == 0 {
+		epochLength = params.TempEpochLength
+	}
+
+	// Step 1: Sort authorities by weight (descending order), equal weights by
+	// address so every node builds the same schedule whatever the map order
 	type authority struct {
 		address common.Address
 		weight  *big.Int
+		slots   uint64
+		rest    *big.Int
 	}
 
-	var sortedAuthorities []authority
+	var sortedAuthorities []*authority
 	for address, weight := range authoritiesWithWeight {
-		sortedAuthorities = append(sortedAuthorities, authority{
+		sortedAuthorities = append(sortedAuthorities, &authority{
 			address: address,
 			weight:  weight,
 		})
 	}
 
-	// Sort in descending order of weight
-	sort.SliceStable(sortedAuthorities, func(i, j int) bool {
-		return sortedAuthorities[i].weight.Cmp(sortedAuthorities[j].weight) > 0
+	sort.Slice(sortedAuthorities, func(i, j int) bool {
+		if c := sortedAuthorities[i].weight.Cmp(sortedAuthorities[j].weight); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(sortedAuthorities[i].address.Bytes(), sortedAuthorities[j].address.Bytes()) < 0
 	})
 
-	// Step 2: Calculate the total weight
+	// Step 2: Calculate the total weight, authorities without any weight
+	// share the epoch evenly
 	totalWeight := big.NewInt(0)
 	for _, auth := range sortedAuthorities {
 		totalWeight.Add(totalWeight, auth.weight)
 	}
+	if totalWeight.Sign() == 0 {
+		for _, auth := range sortedAuthorities {
+			auth.weight = big.NewInt(1)
+		}
+		totalWeight.SetInt64(int64(len(sortedAuthorities)))
+	}
 
-	// Step 3: Generate a deterministic proposer schedule based on salt and weight distribution
 	ps.schedule = make(map[uint64]common.Address)
+	ps.validators = make([]common.Address, 0, len(sortedAuthorities))
+	for _, auth := range sortedAuthorities {
+		ps.validators = append(ps.validators, auth.address)
+	}
+	sort.Slice(ps.validators, func(i, j int) bool {
+		return bytes.Compare(ps.validators[i].Bytes(), ps.validators[j].Bytes()) < 0
+	})
+	if len(sortedAuthorities) == 0 {
+		return nil
+	}
 
-	// Initialize variables for block assignments
-	authoritiesAssigned := make(map[common.Address]uint64)
+	// Step 3: Give each authority its share of the epoch rounded down, then
+	// the slots left to the largest remainders, equal ones by address
+	assigned := uint64(0)
+	for _, auth := range sortedAuthorities {
+		share := new(big.Int).Mul(auth.weight, new(big.Int).SetUint64(epochLength))
+		quotient, rest := new(big.Int).QuoRem(share, totalWeight, new(big.Int))
+		auth.slots = quotient.Uint64()
+		auth.rest = rest
+		assigned += auth.slots
+	}
+	byRest := append([]*authority(nil), sortedAuthorities...)
+	sort.Slice(byRest, func(i, j int) bool {
+		if c := byRest[i].rest.Cmp(byRest[j].rest); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(byRest[i].address.Bytes(), byRest[j].address.Bytes()) < 0
+	})
+	for i := 0; assigned < epochLength; i++ {
+		byRest[i%len(byRest)].slots++
+		assigned++
+	}
 
-	// Use the salt to ensure deterministic results
+	// Step 4: Shuffle the slots with the salt so the proposers of an epoch
+	// interleave, deterministic for a given salt and weight distribution
+	slots := make([]common.Address, 0, epochLength)
+	for _, auth := range sortedAuthorities {
+		for i := uint64(0); i < auth.slots; i++ {
+			slots = append(slots, auth.address)
+		}
+	}
 	hash := sha3.NewLegacyKeccak256()
 	hash.Write(salt)
 	hash.Write(totalWeight.Bytes()) // Adding total weight for determinism
-
-	// Step 4: Assign blocks to authorities based on their weight
-	for blockHeight := uint64(fromBlock); blockHeight < fromBlock+params.TempEpochLength; blockHeight++ { // Example for the first 100 blocks
-		// Hash block height with the base hash
-		blockHash := sha3.NewLegacyKeccak256()
-		blockHash.Write(hash.Sum(nil))
-		blockHash.Write([]byte(fmt.Sprintf("%d", blockHeight)))
-
-		// Select an authority based on weight
-		for _, auth := range sortedAuthorities {
-			weight := auth.weight
-			// Calculate how many blocks this authority should control
-			// Proportional assignment: authority weight / total weight * total blocks
-			assignedBlocks := new(
-				big.Int,
-			).Mul(weight, big.NewInt(params.TempEpochLength))
-			// Total of 100 blocks
-			assignedBlocks.Div(assignedBlocks, totalWeight)
-
-			// Check if this authority should be assigned to this block
-			if authoritiesAssigned[auth.address] < assignedBlocks.Uint64() {
-				ps.schedule[blockHeight] = auth.address
-				authoritiesAssigned[auth.address]++
-				break
-			}
-		}
+	seed := hash.Sum(nil)
+	for i := len(slots) - 1; i > 0; i-- {
+		slotHash := sha3.NewLegacyKeccak256()
+		slotHash.Write(seed)
+		slotHash.Write([]byte(fmt.Sprintf("%d", i)))
+		j := binary.BigEndian.Uint64(slotHash.Sum(nil)) % uint64(i+1)
+		slots[i], slots[j] = slots[j], slots[i]
+	}
+	for i, proposer := range slots {
+		ps.schedule[fromBlock+uint64(i)] = proposer
 	}
 
 	return nil
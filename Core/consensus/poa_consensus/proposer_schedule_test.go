@@ -21,7 +21,7 @@ func TestProposerSchedule(t *testing.T) {
 	salt := []byte("test_salt")
 
 	// Create a new ProposerSchedule
-	ps := &ProposerSchedule{}
+	ps := &ProposerSchedule{epochLength: 100}
 
 	// Update the schedule with authorities and salt
 	err := ps.UpdateSchedule(salt, 10, authorities)
@@ -29,7 +29,7 @@ func TestProposerSchedule(t *testing.T) {
 
 	// Test that the same input produces the same result (deterministic)
 	t.Run("TestDeterministicProposerGeneration", func(t *testing.T) {
-		ps2 := &ProposerSchedule{}
+		ps2 := &ProposerSchedule{epochLength: 100}
 		err := ps2.UpdateSchedule(salt, 10, authorities)
 		assert.NoError(t, err, "Error should be nil when updating schedule")
 
@@ -83,3 +83,42 @@ func TestProposerSchedule(t *testing.T) {
 		)
 	})
 }
+
+func TestProposerScheduleCoversEpoch(t *testing.T) {
+	salt := []byte("test_salt")
+	authorities := map[common.Address]*big.Int{
+		common.HexToAddress("0xabc123"): big.NewInt(50),
+		common.HexToAddress("0xdef456"): big.NewInt(100),
+		common.HexToAddress("0x789012"): big.NewInt(200),
+	}
+	ps := &ProposerSchedule{epochLength: 100}
+	assert.NoError(t, ps.UpdateSchedule(salt, 100, authorities))
+
+	// every height of the epoch has a proposer, the slot left by rounding
+	// down goes to the largest remainder
+	counts := make(map[common.Address]int)
+	for height := uint64(100); height < 200; height++ {
+		proposer, err := ps.GetProposer(height)
+		assert.NoError(t, err, "height %d", height)
+		counts[proposer]++
+	}
+	assert.Equal(t, map[common.Address]int{
+		common.HexToAddress("0xabc123"): 14,
+		common.HexToAddress("0xdef456"): 29,
+		common.HexToAddress("0x789012"): 57,
+	}, counts)
+
+	// equal weights are ordered by address, not by map iteration
+	equal := make(map[common.Address]*big.Int)
+	for i := 1; i <= 7; i++ {
+		equal[common.BigToAddress(big.NewInt(int64(i)))] = big.NewInt(1)
+	}
+	first := &ProposerSchedule{epochLength: 100}
+	assert.NoError(t, first.UpdateSchedule(salt, 0, equal))
+	assert.Len(t, first.schedule, 100)
+	for i := 0; i < 20; i++ {
+		other := &ProposerSchedule{epochLength: 100}
+		assert.NoError(t, other.UpdateSchedule(salt, 0, equal))
+		assert.Equal(t, first.schedule, other.schedule)
+	}
+}
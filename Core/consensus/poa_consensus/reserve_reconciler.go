@@ -0,0 +1,264 @@
+package poa_consensus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/bank_adapter"
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/params"
+	"FichainCore/state"
+)
+
+const defaultReconcileInterval = 5 * time.Minute
+
+var reservePendingKey = []byte("reserve-pending-movements") // movements not posted to the core system yet
+
+// ReserveReconciler keeps the reserve ledger of the local bank in its core
+// banking system and on chain together. Reserve movements the bank executed
+// on chain are posted to the core system, and on every interval the balances
+// reported by the core system are compared with the on-chain reserve, a
+// discrepancy is reported for operators to investigate. Movements are stored
+// until the core system accepted them so a restart does not lose any.
+type ReserveReconciler struct {
+	issuer   *NativeIssuer
+	bank     common.Address
+	adapter  bank_adapter.BankAdapter
+	interval time.Duration
+	db       database.Database
+
+	pending []*bank_adapter.Movement
+	last    *Reconciliation
+	notify  chan struct{}
+	quit    chan struct{}
+
+	flushMu sync.Mutex
+	sync.RWMutex
+}
+
+func NewReserveReconciler(
+	issuer *NativeIssuer,
+	bank common.Address,
+	adapter bank_adapter.BankAdapter,
+	interval time.Duration,
+	db database.Database,
+) *ReserveReconciler {
+	if interval == 0 {
+		interval = defaultReconcileInterval
+	}
+	return &ReserveReconciler{
+		issuer:   issuer,
+		bank:     bank,
+		adapter:  adapter,
+		interval: interval,
+		db:       db,
+		notify:   make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+}
+
+// LoadFromDB restores the movements not posted before a restart
+func (r *ReserveReconciler) LoadFromDB() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if ok, err := r.db.Has(reservePendingKey); err != nil || !ok {
+		return err
+	}
+	data, err := r.db.Get(reservePendingKey)
+	if err != nil {
+		return err
+	}
+	var pending []*bank_adapter.Movement
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return fmt.Errorf("error loading pending reserve movements: %w", err)
+	}
+	r.pending = pending
+	return nil
+}
+
+// commit stores the pending movements, the caller holds the lock
+func (r *ReserveReconciler) commit() error {
+	data, err := json.Marshal(r.pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending reserve movements: %w", err)
+	}
+	return r.db.Put(reservePendingKey, data)
+}
+
+// Start reconciles and posts pending movements in the background
+func (r *ReserveReconciler) Start() {
+	go r.loop()
+}
+
+func (r *ReserveReconciler) Stop() {
+	close(r.quit)
+}
+
+func (r *ReserveReconciler) loop() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Flush(context.Background())
+			if _, err := r.Reconcile(context.Background()); err != nil {
+				logger.Warn("[ReserveReconciler] error when reconcile reserve", err)
+			}
+		case <-r.notify:
+			r.Flush(context.Background())
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// Reconcile compares the reserve reported by the core banking system with the
+// on-chain reserve of the bank
+func (r *ReserveReconciler) Reconcile(ctx context.Context) (*Reconciliation, error) {
+	reconciliation, err := r.issuer.reserve.SyncFromBank(ctx, r.bank, r.adapter)
+	if err != nil {
+		return nil, err
+	}
+	if !reconciliation.Matched() {
+		logger.Warn(
+			"[ReserveReconciler] reserve discrepancy", r.bank.Hex(),
+			"on chain", reconciliation.OnChain,
+			"reported", reconciliation.Reported,
+		)
+	}
+	r.Lock()
+	r.last = reconciliation
+	r.Unlock()
+	return reconciliation, nil
+}
+
+// Last returns the latest reconciliation, nil before the first one
+func (r *ReserveReconciler) Last() *Reconciliation {
+	r.RLock()
+	defer r.RUnlock()
+	return r.last
+}
+
+// Pending returns the number of movements not posted yet
+func (r *ReserveReconciler) Pending() int {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.pending)
+}
+
+// Flush posts the pending movements in order, stopping at the first failure
+// so it is retried on the next flush
+func (r *ReserveReconciler) Flush(ctx context.Context) {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+	for {
+		r.RLock()
+		if len(r.pending) == 0 {
+			r.RUnlock()
+			return
+		}
+		movement := r.pending[0]
+		r.RUnlock()
+
+		// posted without holding the lock so block processing is not blocked
+		// by the core system
+		if err := r.adapter.PostMovement(ctx, movement); err != nil {
+			logger.Warn("[ReserveReconciler] error when post movement", movement.Reference, err)
+			return
+		}
+		r.Lock()
+		r.pending = r.pending[1:]
+		err := r.commit()
+		r.Unlock()
+		if err != nil {
+			// posted again after a restart, the core system dedupes by reference
+			logger.Warn("[ReserveReconciler] error when store pending movements", err)
+		}
+	}
+}
+
+// ProcessBlock queues the reserve movements the bank executed in a canonical
+// block for posting
+func (r *ReserveReconciler) ProcessBlock(bl *block.Block) error {
+	var st *state.StateDB
+	var movements []*bank_adapter.Movement
+	for _, tx := range bl.Transactions {
+		if tx.To() != params.ReserveAddress {
+			continue
+		}
+		from, err := tx.From(params.TempChainId)
+		if err != nil || from != r.bank {
+			continue
+		}
+		call := &ReserveCall{}
+		if err := call.Unmarshal(tx.Data()); err != nil {
+			continue
+		}
+		movement := &bank_adapter.Movement{
+			Reference: tx.Hash().Hex(),
+			Amount:    call.Amount,
+			Height:    bl.Header.Height,
+			Timestamp: bl.Header.Timestamp,
+		}
+		switch call.Action {
+		case ReserveActionMint:
+			movement.Kind = bank_adapter.MovementMint
+		case ReserveActionBurn:
+			movement.Kind = bank_adapter.MovementBurn
+			movement.Amount = tx.Amount()
+		case ReserveActionDeposit:
+			movement.Kind = bank_adapter.MovementDeposit
+		case ReserveActionWithdraw:
+			movement.Kind = bank_adapter.MovementWithdraw
+		default:
+			continue
+		}
+		if st == nil {
+			st, err = r.issuer.states.At(bl.Header)
+			if err != nil {
+				return err
+			}
+		}
+		if r.issuer.Applied(st, tx.Hash()) {
+			movements = append(movements, movement)
+		}
+	}
+	if len(movements) == 0 {
+		return nil
+	}
+
+	r.Lock()
+	// a block handed again after a restart is not queued twice
+	queued := make(map[string]struct{}, len(r.pending))
+	for _, movement := range r.pending {
+		queued[movement.Reference] = struct{}{}
+	}
+	for _, movement := range movements {
+		if _, ok := queued[movement.Reference]; !ok {
+			r.pending = append(r.pending, movement)
+		}
+	}
+	err := r.commit()
+	r.Unlock()
+	if err != nil {
+		return err
+	}
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// EndEpoch implements BlockProcessor, movements are posted per block
+func (r *ReserveReconciler) EndEpoch(epoch uint64, last *block.Block) error {
+	return nil
+}
@@ -0,0 +1,109 @@
+package poa_consensus
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/bank_adapter"
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/params"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+func TestReserveReconciler(t *testing.T) {
+	bankSigner, bank := newTestSigner(t)
+	customer := common.HexToAddress("0xc0ffee")
+
+	memDB, _ := database.NewMemDatabase()
+	stateDatabase := state.NewDatabase(memDB)
+	states := NewChainStates(stateDatabase, memDB)
+	st, err := state.New(common.Hash{}, stateDatabase)
+	assert.NoError(t, err)
+	WriteGenesisReserves(st, map[common.Address]*big.Int{bank: big.NewInt(1000)}, big.NewInt(0))
+
+	reserveDB, _ := database.NewMemDatabase()
+	reserve := NewFiatReserve()
+	reserve.SetBalance(bank, big.NewInt(1000))
+	issuer := NewNativeIssuer(reserve, testEpochValidators{bank: big.NewInt(1)}, states, reserveDB)
+
+	adapter := bank_adapter.NewMockAdapter()
+	adapter.SetBalance("reserve-1", big.NewInt(700))
+	adapter.SetBalance("reserve-2", big.NewInt(300))
+	reconcilerDB, _ := database.NewMemDatabase()
+	reconciler := NewReserveReconciler(issuer, bank, adapter, time.Minute, reconcilerDB)
+
+	reconciliation, err := reconciler.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, reconciliation.Matched())
+
+	txs := []*transaction.Transaction{
+		newSystemTx(t, bankSigner, params.ReserveAddress, 0, big.NewInt(0), &ReserveCall{
+			Action: ReserveActionDeposit,
+			Amount: big.NewInt(50),
+		}),
+		// rejected, never posted
+		newSystemTx(t, bankSigner, params.ReserveAddress, 1, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionMint,
+			Recipient: customer,
+			Amount:    big.NewInt(5000),
+		}),
+		newSystemTx(t, bankSigner, params.ReserveAddress, 2, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionMint,
+			Recipient: customer,
+			Amount:    big.NewInt(20),
+		}),
+	}
+	applyReserveTxs(t, issuer, st, txs...)
+	root, err := st.Commit(true)
+	assert.NoError(t, err)
+	bl := &block.Block{
+		Header:       &block.BlockHeader{Height: 1, StateRoot: root, Timestamp: 1700000000},
+		Transactions: txs,
+	}
+	assert.NoError(t, issuer.ProcessBlock(bl))
+	assert.NoError(t, reconciler.ProcessBlock(bl))
+	assert.Equal(t, 2, reconciler.Pending())
+	assert.NoError(t, reconciler.ProcessBlock(bl))
+	assert.Equal(t, 2, reconciler.Pending(), "a block handed again is not queued twice")
+
+	// the core system is down, movements stay queued
+	adapter.SetError(errors.New("unavailable"))
+	reconciler.Flush(context.Background())
+	assert.Equal(t, 2, reconciler.Pending())
+	_, err = reconciler.Reconcile(context.Background())
+	assert.Error(t, err)
+
+	// and survive a restart
+	reconciler = NewReserveReconciler(issuer, bank, adapter, time.Minute, reconcilerDB)
+	assert.NoError(t, reconciler.LoadFromDB())
+	assert.Equal(t, 2, reconciler.Pending())
+
+	adapter.SetError(nil)
+	reconciler.Flush(context.Background())
+	assert.Equal(t, 0, reconciler.Pending())
+	restarted := NewReserveReconciler(issuer, bank, adapter, time.Minute, reconcilerDB)
+	assert.NoError(t, restarted.LoadFromDB())
+	assert.Equal(t, 0, restarted.Pending(), "posted movements are removed from the store")
+	movements := adapter.Movements()
+	if assert.Len(t, movements, 2) {
+		assert.Equal(t, bank_adapter.MovementDeposit, movements[0].Kind)
+		assert.Equal(t, big.NewInt(50), movements[0].Amount)
+		assert.Equal(t, bank_adapter.MovementMint, movements[1].Kind)
+		assert.Equal(t, txs[2].Hash().Hex(), movements[1].Reference)
+	}
+
+	// the core system did not book the deposit yet
+	reconciliation, err = reconciler.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, reconciliation.Matched())
+	assert.Equal(t, big.NewInt(-50), reconciliation.Difference())
+	assert.Equal(t, reconciliation, reconciler.Last())
+}
@@ -0,0 +1,278 @@
+package poa_consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/consensus"
+	"FichainCore/crypto"
+	"FichainCore/params"
+	pb "FichainCore/proto"
+	"FichainCore/receipt"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+// RewardPolicy decides how the transaction fees of a block are shared. Native
+// VND is backed by fiat reserves so no block reward is minted, validators are
+// paid from fees only. The policy is part of the state transition, it is
+// written to the storage of params.SettlementAddress by the genesis block.
+type RewardPolicy struct {
+	Treasury        common.Address // consortium treasury, also receives the rounding dust
+	TreasuryPercent uint64         // share of the fees sent to the treasury
+	ProposerPercent uint64         // share of the fees kept by the block proposer
+}
+
+func DefaultRewardPolicy() RewardPolicy {
+	return RewardPolicy{
+		Treasury:        params.TreasuryAddress,
+		TreasuryPercent: params.TempTreasuryFeePercent,
+		ProposerPercent: params.TempProposerFeePercent,
+	}
+}
+
+func (p RewardPolicy) Validate() error {
+	if p.TreasuryPercent+p.ProposerPercent > 100 {
+		return fmt.Errorf(
+			"treasury and proposer fee shares exceed 100%%: %d + %d",
+			p.TreasuryPercent,
+			p.ProposerPercent,
+		)
+	}
+	return nil
+}
+
+// EpochValidators resolves the validator weights an epoch was started with,
+// implemented by EpochManager
+type EpochValidators interface {
+	EpochOf(blockHeight uint64) uint64
+	EpochValidators(epoch uint64) (map[common.Address]*big.Int, error)
+}
+
+// EpochSettlement is what the treasury and every validator earned from the
+// fees of an epoch
+type EpochSettlement struct {
+	Epoch    uint64
+	Fees     *big.Int
+	Treasury *big.Int
+	Rewards  map[common.Address]*big.Int
+}
+
+// Proto converts EpochSettlement to protobuf message, rewards are sorted by
+// validator address
+func (s *EpochSettlement) Proto() proto.Message {
+	validators := make([]common.Address, 0, len(s.Rewards))
+	for validator := range s.Rewards {
+		validators = append(validators, validator)
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return bytes.Compare(validators[i].Bytes(), validators[j].Bytes()) < 0
+	})
+	rewards := make([]*pb.ValidatorReward, len(validators))
+	for i, validator := range validators {
+		rewards[i] = &pb.ValidatorReward{
+			Validator: validator.Bytes(),
+			Amount:    s.Rewards[validator].Bytes(),
+		}
+	}
+	return &pb.EpochSettlement{
+		Epoch:    s.Epoch,
+		Fees:     s.Fees.Bytes(),
+		Treasury: s.Treasury.Bytes(),
+		Rewards:  rewards,
+	}
+}
+
+// FromProto populates EpochSettlement from a protobuf message
+func (s *EpochSettlement) FromProto(pbSettlement *pb.EpochSettlement) error {
+	s.Epoch = pbSettlement.Epoch
+	s.Fees = new(big.Int).SetBytes(pbSettlement.Fees)
+	s.Treasury = new(big.Int).SetBytes(pbSettlement.Treasury)
+	s.Rewards = make(map[common.Address]*big.Int, len(pbSettlement.Rewards))
+	for _, reward := range pbSettlement.Rewards {
+		s.Rewards[common.BytesToAddress(reward.Validator)] = new(big.Int).SetBytes(reward.Amount)
+	}
+	return nil
+}
+
+// FeeDistributor shares the transaction fees of every block between the
+// treasury, the proposer and all validators of the epoch by weight. The fees
+// are credited to the proposer while transactions execute, Finalize takes
+// them back and distributes them. The amounts are accumulated per epoch in
+// the storage of params.SettlementAddress so members can audit their
+// earnings from state.
+type FeeDistributor struct {
+	validators EpochValidators
+}
+
+func NewFeeDistributor(validators EpochValidators) *FeeDistributor {
+	return &FeeDistributor{
+		validators: validators,
+	}
+}
+
+// --------------------------- Storage layout ---------------------------
+
+func settlementSlot(kind string, epoch uint64, addresses ...common.Address) common.Hash {
+	data := make([]byte, len(kind)+8)
+	copy(data, kind)
+	binary.BigEndian.PutUint64(data[len(kind):], epoch)
+	for _, address := range addresses {
+		data = append(data, address.Bytes()...)
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+func addSettlementValue(st *state.StateDB, slot common.Hash, amount *big.Int) {
+	value := st.GetState(params.SettlementAddress, slot).Big()
+	setSystemState(st, params.SettlementAddress, slot, value.Add(value, amount))
+}
+
+func getSettlementValue(st *state.StateDB, slot common.Hash) *big.Int {
+	return st.GetState(params.SettlementAddress, slot).Big()
+}
+
+func policySlot(kind string) common.Hash {
+	return crypto.Keccak256Hash([]byte(kind))
+}
+
+// WriteRewardPolicy stores the reward policy of a chain in its genesis state
+func WriteRewardPolicy(st *state.StateDB, policy RewardPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	setSystemState(st, params.SettlementAddress, policySlot("policy"), big.NewInt(1))
+	setSystemState(st, params.SettlementAddress, policySlot("policy-treasury"), policy.Treasury.Big())
+	setSystemState(
+		st,
+		params.SettlementAddress,
+		policySlot("policy-treasury-percent"),
+		new(big.Int).SetUint64(policy.TreasuryPercent),
+	)
+	setSystemState(
+		st,
+		params.SettlementAddress,
+		policySlot("policy-proposer-percent"),
+		new(big.Int).SetUint64(policy.ProposerPercent),
+	)
+	return nil
+}
+
+// --------------------------- Read APIs ---------------------------
+
+// Policy returns the reward policy stored in a state, the default policy for
+// chains whose genesis did not set one
+func (d *FeeDistributor) Policy(st *state.StateDB) RewardPolicy {
+	if getSettlementValue(st, policySlot("policy")).Sign() == 0 {
+		return DefaultRewardPolicy()
+	}
+	return RewardPolicy{
+		Treasury:        common.BigToAddress(getSettlementValue(st, policySlot("policy-treasury"))),
+		TreasuryPercent: getSettlementValue(st, policySlot("policy-treasury-percent")).Uint64(),
+		ProposerPercent: getSettlementValue(st, policySlot("policy-proposer-percent")).Uint64(),
+	}
+}
+
+// Settlement returns the fee settlement record of an epoch
+func (d *FeeDistributor) Settlement(st *state.StateDB, epoch uint64) (*EpochSettlement, error) {
+	validators, err := d.validators.EpochValidators(epoch)
+	if err != nil {
+		return nil, err
+	}
+	settlement := &EpochSettlement{
+		Epoch:    epoch,
+		Fees:     getSettlementValue(st, settlementSlot("settlement-fees", epoch)),
+		Treasury: getSettlementValue(st, settlementSlot("settlement-treasury", epoch)),
+		Rewards:  make(map[common.Address]*big.Int, len(validators)),
+	}
+	for validator := range validators {
+		settlement.Rewards[validator] = getSettlementValue(
+			st,
+			settlementSlot("settlement-reward", epoch, validator),
+		)
+	}
+	return settlement, nil
+}
+
+// --------------------------- Distribution ---------------------------
+
+// FinalizeState distributes the fees paid by the transactions of a block
+func (d *FeeDistributor) FinalizeState(
+	chain consensus.ChainReader,
+	header *block.BlockHeader,
+	st *state.StateDB,
+	txs []*transaction.Transaction,
+	receipts []*receipt.Receipt,
+) error {
+	fees := big.NewInt(0)
+	for i, rc := range receipts {
+		if i >= len(txs) {
+			break
+		}
+		fee := new(big.Int).SetUint64(rc.GasUsed)
+		fees.Add(fees, fee.Mul(fee, txs[i].GasPrice()))
+	}
+	if fees.Sign() == 0 {
+		return nil
+	}
+
+	epoch := d.validators.EpochOf(header.Height)
+	validators, err := d.validators.EpochValidators(epoch)
+	if err != nil {
+		return err
+	}
+	policy := d.Policy(st)
+
+	// the proposer was credited as coinbase while transactions executed
+	st.SubBalance(header.Proposer, fees)
+
+	treasury := percentOf(fees, policy.TreasuryPercent)
+	proposerShare := percentOf(fees, policy.ProposerPercent)
+	shared := new(big.Int).Sub(fees, treasury)
+	shared.Sub(shared, proposerShare)
+
+	rewards := make(map[common.Address]*big.Int, len(validators)+1)
+	rewards[header.Proposer] = proposerShare
+	totalWeight := big.NewInt(0)
+	for _, weight := range validators {
+		totalWeight.Add(totalWeight, weight)
+	}
+	distributed := big.NewInt(0)
+	if totalWeight.Sign() > 0 {
+		for validator, weight := range validators {
+			share := new(big.Int).Mul(shared, weight)
+			share.Div(share, totalWeight)
+			distributed.Add(distributed, share)
+			if reward, ok := rewards[validator]; ok {
+				share.Add(share, reward)
+			}
+			rewards[validator] = share
+		}
+	}
+	// rounding dust, or everything when no validator has weight
+	treasury.Add(treasury, shared.Sub(shared, distributed))
+
+	st.AddBalance(policy.Treasury, treasury)
+	for validator, reward := range rewards {
+		if reward.Sign() == 0 {
+			continue
+		}
+		st.AddBalance(validator, reward)
+		addSettlementValue(st, settlementSlot("settlement-reward", epoch, validator), reward)
+	}
+	addSettlementValue(st, settlementSlot("settlement-treasury", epoch), treasury)
+	addSettlementValue(st, settlementSlot("settlement-fees", epoch), fees)
+	return nil
+}
+
+func percentOf(amount *big.Int, percent uint64) *big.Int {
+	share := new(big.Int).Mul(amount, new(big.Int).SetUint64(percent))
+	return share.Div(share, big.NewInt(100))
+}
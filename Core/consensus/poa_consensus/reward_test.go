@@ -0,0 +1,114 @@
+package poa_consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	pb "FichainCore/proto"
+	"FichainCore/receipt"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+// testEpochValidators serves the same validator weights for every epoch of
+// 10 blocks
+type testEpochValidators map[common.Address]*big.Int
+
+func (v testEpochValidators) EpochOf(blockHeight uint64) uint64 { return blockHeight / 10 }
+func (v testEpochValidators) EpochValidators(epoch uint64) (map[common.Address]*big.Int, error) {
+	return v, nil
+}
+func (v testEpochValidators) ValidatorsAt(blockHeight uint64) (map[common.Address]*big.Int, error) {
+	return v, nil
+}
+
+// applyFees credits the fees of a block to its proposer like the state
+// transition does, then runs the fee distribution
+func applyFees(
+	t *testing.T,
+	distributor *FeeDistributor,
+	st *state.StateDB,
+	header *block.BlockHeader,
+	gasUsed ...uint64,
+) {
+	txs := make([]*transaction.Transaction, len(gasUsed))
+	receipts := make([]*receipt.Receipt, len(gasUsed))
+	for i, gas := range gasUsed {
+		txs[i] = transaction.NewTransaction(common.Address{}, 0, big.NewInt(0), nil, gas, big.NewInt(1), "")
+		receipts[i] = receipt.NewReceipt(nil, false, gas)
+		receipts[i].GasUsed = gas
+		st.AddBalance(header.Proposer, new(big.Int).SetUint64(gas))
+	}
+	assert.NoError(t, distributor.FinalizeState(nil, header, st, txs, receipts))
+}
+
+func TestFeeDistributor(t *testing.T) {
+	validatorA := common.HexToAddress("0xa")
+	validatorB := common.HexToAddress("0xb")
+	validatorC := common.HexToAddress("0xc")
+	treasury := common.HexToAddress("0x7")
+	validators := testEpochValidators{
+		validatorA: big.NewInt(1),
+		validatorB: big.NewInt(1),
+		validatorC: big.NewInt(2),
+	}
+
+	distributor := NewFeeDistributor(validators)
+
+	memDB, _ := database.NewMemDatabase()
+	st, err := state.New(common.Hash{}, state.NewDatabase(memDB))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRewardPolicy(), distributor.Policy(st), "chains without a policy use the default")
+
+	err = WriteRewardPolicy(st, RewardPolicy{TreasuryPercent: 60, ProposerPercent: 50})
+	assert.Error(t, err, "shares above 100% should be rejected")
+	policy := RewardPolicy{
+		Treasury:        treasury,
+		TreasuryPercent: 10,
+		ProposerPercent: 20,
+	}
+	assert.NoError(t, WriteRewardPolicy(st, policy))
+	assert.Equal(t, policy, distributor.Policy(st))
+
+	// 1003 fees: 100 to the treasury, 200 to the proposer and 703 shared
+	// 1:1:2, the rounding dust goes to the treasury
+	applyFees(t, distributor, st, &block.BlockHeader{Height: 1, Proposer: validatorA}, 1000, 3)
+	assert.Equal(t, big.NewInt(375), st.GetBalance(validatorA))
+	assert.Equal(t, big.NewInt(175), st.GetBalance(validatorB))
+	assert.Equal(t, big.NewInt(351), st.GetBalance(validatorC))
+	assert.Equal(t, big.NewInt(102), st.GetBalance(treasury))
+
+	// blocks of the same epoch accumulate
+	applyFees(t, distributor, st, &block.BlockHeader{Height: 2, Proposer: validatorB}, 100)
+	// next epoch is settled separately
+	applyFees(t, distributor, st, &block.BlockHeader{Height: 10, Proposer: validatorC}, 100)
+
+	settlement, err := distributor.Settlement(st, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1103), settlement.Fees)
+	assert.Equal(t, big.NewInt(113), settlement.Treasury)
+	assert.Equal(t, big.NewInt(392), settlement.Rewards[validatorA])
+	assert.Equal(t, big.NewInt(212), settlement.Rewards[validatorB])
+	assert.Equal(t, big.NewInt(386), settlement.Rewards[validatorC])
+
+	total := new(big.Int).Set(settlement.Treasury)
+	for _, reward := range settlement.Rewards {
+		total.Add(total, reward)
+	}
+	assert.Equal(t, settlement.Fees, total, "every fee should be settled")
+
+	next, err := distributor.Settlement(st, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), next.Fees)
+
+	t.Run("TestProtoRoundTrip", func(t *testing.T) {
+		decoded := &EpochSettlement{}
+		assert.NoError(t, decoded.FromProto(settlement.Proto().(*pb.EpochSettlement)))
+		assert.Equal(t, settlement, decoded)
+	})
+}
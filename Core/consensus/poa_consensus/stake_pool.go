@@ -0,0 +1,373 @@
+package poa_consensus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/consensus"
+	"FichainCore/consensus/poa_consensus/voting"
+	"FichainCore/crypto"
+	"FichainCore/database"
+	"FichainCore/params"
+	pb "FichainCore/proto"
+	"FichainCore/receipt"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+type StakeAction uint32
+
+const (
+	StakeActionDelegate StakeAction = iota + 1 // bond the transaction amount to a validator
+	StakeActionUnbond                          // start unbonding an amount
+	StakeActionWithdraw                        // release the matured unbonding amount
+)
+
+// StakeCall is the data of a transaction sent to params.StakingAddress
+type StakeCall struct {
+	Action    StakeAction
+	Validator common.Address
+	Amount    *big.Int
+}
+
+// Marshal encodes the call as transaction data
+func (c *StakeCall) Marshal() ([]byte, error) {
+	var amount []byte
+	if c.Amount != nil {
+		amount = c.Amount.Bytes()
+	}
+	return proto.Marshal(&pb.StakeCall{
+		Action:    uint32(c.Action),
+		Validator: c.Validator.Bytes(),
+		Amount:    amount,
+	})
+}
+
+// Unmarshal decodes the call from transaction data
+func (c *StakeCall) Unmarshal(data []byte) error {
+	var pbCall pb.StakeCall
+	if err := proto.Unmarshal(data, &pbCall); err != nil {
+		return err
+	}
+	c.Action = StakeAction(pbCall.Action)
+	c.Validator = common.BytesToAddress(pbCall.Validator)
+	c.Amount = new(big.Int).SetBytes(pbCall.Amount)
+	return nil
+}
+
+// UnbondingEntry is an amount unbonded by one transaction
+type UnbondingEntry struct {
+	Amount  *big.Int
+	Release uint64 // height the amount can be withdrawn from
+}
+
+// StakePool keeps the stake bonded to validators in the storage of
+// params.StakingAddress, which also holds the locked native balance. Stake is
+// accounted in shares so slashing a validator cuts all its delegators pro rata.
+// Stake is only bonded to the validators of the epoch. Once every validator
+// bonded params.TempMinSelfBond to itself, the effective stake at the end of
+// every epoch becomes the validator weight used by the next proposer schedule.
+type StakePool struct {
+	authority   *Authority
+	validators  voting.HeightValidatorSet
+	states      *ChainStates
+	validatorDB database.Database
+	observerDB  database.Database
+}
+
+func NewStakePool(
+	authority *Authority,
+	validators voting.HeightValidatorSet,
+	states *ChainStates,
+	validatorDB, observerDB database.Database,
+) *StakePool {
+	return &StakePool{
+		authority:   authority,
+		validators:  validators,
+		states:      states,
+		validatorDB: validatorDB,
+		observerDB:  observerDB,
+	}
+}
+
+// --------------------------- Storage layout ---------------------------
+
+func stakeSlot(kind string, addresses ...common.Address) common.Hash {
+	data := []byte(kind)
+	for _, address := range addresses {
+		data = append(data, address.Bytes()...)
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+// unbondingSlot addresses a field of the index-th unbonding entry of a
+// delegator. Entries are kept in a queue between the unbonding-head and
+// unbonding-tail indexes, ordered by release height.
+func unbondingSlot(kind string, validator, delegator common.Address, index uint64) common.Hash {
+	data := append([]byte(kind), validator.Bytes()...)
+	data = append(data, delegator.Bytes()...)
+	return crypto.Keccak256Hash(binary.BigEndian.AppendUint64(data, index))
+}
+
+func getStakeValue(st *state.StateDB, slot common.Hash) *big.Int {
+	return st.GetState(params.StakingAddress, slot).Big()
+}
+
+func setStakeValue(st *state.StateDB, slot common.Hash, value *big.Int) {
+	setSystemState(st, params.StakingAddress, slot, value)
+}
+
+// --------------------------- Read APIs ---------------------------
+
+// TotalStake returns the effective stake bonded to a validator
+func (p *StakePool) TotalStake(st *state.StateDB, validator common.Address) *big.Int {
+	return getStakeValue(st, stakeSlot("stake-total", validator))
+}
+
+// Stake returns the effective stake a delegator bonded to a validator
+func (p *StakePool) Stake(st *state.StateDB, validator, delegator common.Address) *big.Int {
+	totalShares := getStakeValue(st, stakeSlot("stake-total-shares", validator))
+	if totalShares.Sign() == 0 {
+		return big.NewInt(0)
+	}
+	shares := getStakeValue(st, stakeSlot("stake-shares", validator, delegator))
+	stake := new(big.Int).Mul(shares, p.TotalStake(st, validator))
+	return stake.Div(stake, totalShares)
+}
+
+// Unbonding returns the amounts a delegator is unbonding from a validator,
+// oldest first
+func (p *StakePool) Unbonding(
+	st *state.StateDB,
+	validator, delegator common.Address,
+) []*UnbondingEntry {
+	head := getStakeValue(st, stakeSlot("unbonding-head", validator, delegator)).Uint64()
+	tail := getStakeValue(st, stakeSlot("unbonding-tail", validator, delegator)).Uint64()
+	entries := make([]*UnbondingEntry, 0, tail-head)
+	for index := head; index < tail; index++ {
+		entries = append(entries, &UnbondingEntry{
+			Amount:  getStakeValue(st, unbondingSlot("unbonding-amount", validator, delegator, index)),
+			Release: getStakeValue(st, unbondingSlot("unbonding-release", validator, delegator, index)).Uint64(),
+		})
+	}
+	return entries
+}
+
+// --------------------------- Operations ---------------------------
+
+func (p *StakePool) delegate(
+	st *state.StateDB,
+	validator, delegator common.Address,
+	amount *big.Int,
+) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("delegation amount must be positive")
+	}
+	if (validator == common.Address{}) {
+		return errors.New("missing validator")
+	}
+	total := p.TotalStake(st, validator)
+	totalShares := getStakeValue(st, stakeSlot("stake-total-shares", validator))
+
+	// new shares keep the current share price
+	shares := new(big.Int).Set(amount)
+	if totalShares.Sign() > 0 && total.Sign() > 0 {
+		shares.Mul(amount, totalShares).Div(shares, total)
+	}
+	sharesSlot := stakeSlot("stake-shares", validator, delegator)
+	setStakeValue(st, sharesSlot, new(big.Int).Add(getStakeValue(st, sharesSlot), shares))
+	setStakeValue(st, stakeSlot("stake-total-shares", validator), totalShares.Add(totalShares, shares))
+	setStakeValue(st, stakeSlot("stake-total", validator), total.Add(total, amount))
+	return nil
+}
+
+func (p *StakePool) unbond(
+	st *state.StateDB,
+	validator, delegator common.Address,
+	amount *big.Int,
+	height uint64,
+) error {
+	if amount == nil || amount.Sign() <= 0 {
+		return errors.New("unbond amount must be positive")
+	}
+	if p.Stake(st, validator, delegator).Cmp(amount) < 0 {
+		return errors.New("insufficient bonded stake")
+	}
+	head := getStakeValue(st, stakeSlot("unbonding-head", validator, delegator)).Uint64()
+	tail := getStakeValue(st, stakeSlot("unbonding-tail", validator, delegator)).Uint64()
+	if tail-head >= params.TempMaxUnbondingEntries {
+		return errors.New("too many pending unbonds")
+	}
+	total := p.TotalStake(st, validator)
+	totalShares := getStakeValue(st, stakeSlot("stake-total-shares", validator))
+	sharesSlot := stakeSlot("stake-shares", validator, delegator)
+	shares := getStakeValue(st, sharesSlot)
+
+	// burned shares are rounded up so the remaining shares never gain value
+	burned := new(big.Int).Mul(amount, totalShares)
+	burned.Add(burned, new(big.Int).Sub(total, big.NewInt(1))).Div(burned, total)
+	if burned.Cmp(shares) > 0 {
+		burned.Set(shares)
+	}
+	setStakeValue(st, sharesSlot, shares.Sub(shares, burned))
+	setStakeValue(st, stakeSlot("stake-total-shares", validator), totalShares.Sub(totalShares, burned))
+	setStakeValue(st, stakeSlot("stake-total", validator), total.Sub(total, amount))
+
+	// each unbond keeps its own release height
+	setStakeValue(st, unbondingSlot("unbonding-amount", validator, delegator, tail), amount)
+	setStakeValue(
+		st,
+		unbondingSlot("unbonding-release", validator, delegator, tail),
+		new(big.Int).SetUint64(height+params.TempUnbondingPeriod),
+	)
+	setStakeValue(st, stakeSlot("unbonding-tail", validator, delegator), new(big.Int).SetUint64(tail+1))
+	return nil
+}
+
+func (p *StakePool) withdraw(
+	st *state.StateDB,
+	validator, delegator common.Address,
+	height uint64,
+) error {
+	entries := p.Unbonding(st, validator, delegator)
+	if len(entries) == 0 {
+		return errors.New("nothing to withdraw")
+	}
+	if height < entries[0].Release {
+		return fmt.Errorf("stake is unbonding until block %d", entries[0].Release)
+	}
+
+	// the entries are ordered by release height, the matured ones are a prefix
+	head := getStakeValue(st, stakeSlot("unbonding-head", validator, delegator)).Uint64()
+	amount := big.NewInt(0)
+	for _, entry := range entries {
+		if height < entry.Release {
+			break
+		}
+		amount.Add(amount, entry.Amount)
+		setStakeValue(st, unbondingSlot("unbonding-amount", validator, delegator, head), big.NewInt(0))
+		setStakeValue(st, unbondingSlot("unbonding-release", validator, delegator, head), big.NewInt(0))
+		head++
+	}
+	setStakeValue(st, stakeSlot("unbonding-head", validator, delegator), new(big.Int).SetUint64(head))
+	st.SubBalance(params.StakingAddress, amount)
+	st.AddBalance(delegator, amount)
+	return nil
+}
+
+// slash burns a percentage of the stake bonded to a validator. Only the total
+// is reduced so every delegator share loses the same fraction.
+func (p *StakePool) slash(st *state.StateDB, validator common.Address, percent uint64) *big.Int {
+	total := p.TotalStake(st, validator)
+	slashed := new(big.Int).Mul(total, new(big.Int).SetUint64(percent))
+	slashed.Div(slashed, big.NewInt(100))
+	if slashed.Sign() == 0 {
+		return slashed
+	}
+	setStakeValue(st, stakeSlot("stake-total", validator), total.Sub(total, slashed))
+	st.SubBalance(params.StakingAddress, slashed)
+	return slashed
+}
+
+// FinalizeState executes the staking transactions of a block. The value of a
+// delegation was already moved to params.StakingAddress by the transaction,
+// the value of any other or rejected call is sent back. Delegations to an
+// address outside the validators of the block epoch are rejected.
+func (p *StakePool) FinalizeState(
+	chain consensus.ChainReader,
+	header *block.BlockHeader,
+	st *state.StateDB,
+	txs []*transaction.Transaction,
+	receipts []*receipt.Receipt,
+) error {
+	var validators map[common.Address]*big.Int
+	for i, tx := range txs {
+		if tx.To() != params.StakingAddress {
+			continue
+		}
+		if i < len(receipts) && receipts[i].Status == 0 {
+			continue
+		}
+		from, err := tx.From(params.TempChainId)
+		if err != nil {
+			return err
+		}
+		call := &StakeCall{}
+		if err = call.Unmarshal(tx.Data()); err == nil {
+			switch call.Action {
+			case StakeActionDelegate:
+				if validators == nil {
+					if validators, err = p.validators.ValidatorsAt(header.Height); err != nil {
+						return err
+					}
+				}
+				if _, ok := validators[call.Validator]; ok {
+					err = p.delegate(st, call.Validator, from, tx.Amount())
+				} else {
+					err = fmt.Errorf("delegation target is not a validator: %s", call.Validator.Hex())
+				}
+			case StakeActionUnbond:
+				err = p.unbond(st, call.Validator, from, call.Amount, header.Height)
+			case StakeActionWithdraw:
+				err = p.withdraw(st, call.Validator, from, header.Height)
+			default:
+				err = fmt.Errorf("unknown stake action %d", call.Action)
+			}
+		}
+		if err != nil {
+			logger.Warn("[StakePool] rejected transaction", tx.Hash().Hex(), err)
+		}
+		if (err != nil || call.Action != StakeActionDelegate) &&
+			tx.Amount() != nil && tx.Amount().Sign() > 0 {
+			st.SubBalance(params.StakingAddress, tx.Amount())
+			st.AddBalance(from, tx.Amount())
+		}
+	}
+	return nil
+}
+
+// ProcessBlock implements BlockProcessor, stake is read from the state at the
+// epoch end
+func (p *StakePool) ProcessBlock(bl *block.Block) error {
+	return nil
+}
+
+// EndEpoch sets each validator weight to its effective stake as of the last
+// block of the epoch. Until every validator bonded params.TempMinSelfBond to
+// itself the weights are left unchanged, so the chain can bootstrap and a
+// delegation to a single validator cannot take the slots of the others.
+func (p *StakePool) EndEpoch(epoch uint64, last *block.Block) error {
+	st, err := p.states.At(last.Header)
+	if err != nil {
+		return err
+	}
+
+	minSelfBond := big.NewInt(params.TempMinSelfBond)
+	stakes := make(map[common.Address]*big.Int)
+	totalStake := big.NewInt(0)
+	for validator := range p.authority.ListValidators() {
+		if p.Stake(st, validator, validator).Cmp(minSelfBond) < 0 {
+			logger.Debug("[StakePool] keeping validator weights, no self bond of", validator.Hex())
+			return nil
+		}
+		stakes[validator] = p.TotalStake(st, validator)
+		totalStake.Add(totalStake, stakes[validator])
+	}
+	if totalStake.Sign() == 0 {
+		return nil
+	}
+	for validator, stake := range stakes {
+		if err := p.authority.SetValidatorWeight(validator, stake); err != nil {
+			return err
+		}
+	}
+	logger.Info("[StakePool] updated validator weights", epoch, "total stake", totalStake)
+	return p.authority.CommitToStorage(p.validatorDB, p.observerDB)
+}
@@ -0,0 +1,203 @@
+package poa_consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/params"
+	"FichainCore/receipt"
+	"FichainCore/state"
+	"FichainCore/transaction"
+)
+
+// applyStakeTxs moves the transaction values like the EVM would then runs the
+// stake pool finalization
+func applyStakeTxs(
+	t *testing.T,
+	pool *StakePool,
+	st *state.StateDB,
+	height uint64,
+	txs ...*transaction.Transaction,
+) {
+	receipts := make([]*receipt.Receipt, len(txs))
+	for i, tx := range txs {
+		from, err := tx.From(params.TempChainId)
+		assert.NoError(t, err)
+		st.SubBalance(from, tx.Amount())
+		st.AddBalance(params.StakingAddress, tx.Amount())
+		receipts[i] = receipt.NewReceipt(nil, false, 0)
+	}
+	header := &block.BlockHeader{Height: height}
+	assert.NoError(t, pool.FinalizeState(nil, header, st, txs, receipts))
+}
+
+func TestStakePool(t *testing.T) {
+	delegatorSigner, delegator := newTestSigner(t)
+	validatorA := common.HexToAddress("0xabc123")
+	validatorB := common.HexToAddress("0xdef456")
+
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(validatorA, big.NewInt(1)))
+	assert.NoError(t, authority.AddValidator(validatorB, big.NewInt(1)))
+
+	memDB, _ := database.NewMemDatabase()
+	stateDatabase := state.NewDatabase(memDB)
+	states := NewChainStates(stateDatabase, memDB)
+	st, err := state.New(common.Hash{}, stateDatabase)
+	assert.NoError(t, err)
+	st.AddBalance(delegator, big.NewInt(1000))
+
+	validatorDB, _ := database.NewMemDatabase()
+	observerDB, _ := database.NewMemDatabase()
+	pool := NewStakePool(authority, testEpochValidators(authority.ListValidators()), states, validatorDB, observerDB)
+
+	applyStakeTxs(t, pool, st, 1,
+		newSystemTx(t, delegatorSigner, params.StakingAddress, 0, big.NewInt(300), &StakeCall{
+			Action:    StakeActionDelegate,
+			Validator: validatorA,
+		}),
+		newSystemTx(t, delegatorSigner, params.StakingAddress, 1, big.NewInt(100), &StakeCall{
+			Action:    StakeActionDelegate,
+			Validator: validatorB,
+		}),
+	)
+	assert.Equal(t, big.NewInt(300), pool.TotalStake(st, validatorA))
+	assert.Equal(t, big.NewInt(300), pool.Stake(st, validatorA, delegator))
+	assert.Equal(t, big.NewInt(400), st.GetBalance(params.StakingAddress), "stake should be locked")
+	assert.Equal(t, big.NewInt(600), st.GetBalance(delegator))
+
+	// stake only goes to validators, the value of another delegation is sent back
+	applyStakeTxs(t, pool, st, 1,
+		newSystemTx(t, delegatorSigner, params.StakingAddress, 2, big.NewInt(50), &StakeCall{
+			Action:    StakeActionDelegate,
+			Validator: delegator,
+		}),
+	)
+	assert.Zero(t, pool.TotalStake(st, delegator).Sign())
+	assert.Equal(t, big.NewInt(600), st.GetBalance(delegator))
+
+	t.Run("TestUnbondAndWithdraw", func(t *testing.T) {
+		applyStakeTxs(t, pool, st, 2,
+			newSystemTx(t, delegatorSigner, params.StakingAddress, 3, big.NewInt(0), &StakeCall{
+				Action:    StakeActionUnbond,
+				Validator: validatorA,
+				Amount:    big.NewInt(100),
+			}),
+			// more than bonded is rejected
+			newSystemTx(t, delegatorSigner, params.StakingAddress, 4, big.NewInt(0), &StakeCall{
+				Action:    StakeActionUnbond,
+				Validator: validatorB,
+				Amount:    big.NewInt(101),
+			}),
+		)
+		assert.Equal(t, big.NewInt(200), pool.TotalStake(st, validatorA))
+		assert.Equal(t, big.NewInt(100), pool.TotalStake(st, validatorB))
+		release := uint64(2 + params.TempUnbondingPeriod)
+		assert.Equal(t, []*UnbondingEntry{{Amount: big.NewInt(100), Release: release}},
+			pool.Unbonding(st, validatorA, delegator))
+
+		// a later unbond does not delay the earlier one
+		applyStakeTxs(t, pool, st, 3,
+			newSystemTx(t, delegatorSigner, params.StakingAddress, 5, big.NewInt(0), &StakeCall{
+				Action:    StakeActionUnbond,
+				Validator: validatorA,
+				Amount:    big.NewInt(50),
+			}),
+		)
+		assert.Equal(t, []*UnbondingEntry{
+			{Amount: big.NewInt(100), Release: release},
+			{Amount: big.NewInt(50), Release: release + 1},
+		}, pool.Unbonding(st, validatorA, delegator))
+
+		// withdrawing before the unbonding period is rejected, the value is sent back
+		applyStakeTxs(t, pool, st, 3,
+			newSystemTx(t, delegatorSigner, params.StakingAddress, 6, big.NewInt(5), &StakeCall{
+				Action:    StakeActionWithdraw,
+				Validator: validatorA,
+			}),
+		)
+		assert.Equal(t, big.NewInt(600), st.GetBalance(delegator))
+		assert.Equal(t, big.NewInt(400), st.GetBalance(params.StakingAddress))
+
+		// only the matured unbond is withdrawn
+		applyStakeTxs(t, pool, st, release,
+			newSystemTx(t, delegatorSigner, params.StakingAddress, 7, big.NewInt(0), &StakeCall{
+				Action:    StakeActionWithdraw,
+				Validator: validatorA,
+			}),
+		)
+		assert.Equal(t, big.NewInt(700), st.GetBalance(delegator))
+		assert.Equal(t, big.NewInt(300), st.GetBalance(params.StakingAddress))
+		assert.Equal(t, []*UnbondingEntry{{Amount: big.NewInt(50), Release: release + 1}},
+			pool.Unbonding(st, validatorA, delegator))
+
+		applyStakeTxs(t, pool, st, release+1,
+			newSystemTx(t, delegatorSigner, params.StakingAddress, 8, big.NewInt(0), &StakeCall{
+				Action:    StakeActionWithdraw,
+				Validator: validatorA,
+			}),
+		)
+		assert.Equal(t, big.NewInt(750), st.GetBalance(delegator))
+		assert.Empty(t, pool.Unbonding(st, validatorA, delegator))
+	})
+
+	t.Run("TestEpochWeights", func(t *testing.T) {
+		root, err := st.Commit(true)
+		assert.NoError(t, err)
+
+		last := &block.Block{Header: &block.BlockHeader{Height: 9, StateRoot: root}}
+		assert.NoError(t, pool.EndEpoch(0, last))
+		weightB, _ := authority.GetValidatorWeight(validatorB)
+		assert.Equal(t, big.NewInt(1), weightB, "weights wait for the self bond of every validator")
+
+		// shares round down, the bond keeps a margin over the minimum
+		selfBond := big.NewInt(2 * params.TempMinSelfBond)
+		st, err := state.New(root, stateDatabase)
+		assert.NoError(t, err)
+		assert.NoError(t, pool.delegate(st, validatorA, validatorA, selfBond))
+		root, err = st.Commit(true)
+		assert.NoError(t, err)
+		last = &block.Block{Header: &block.BlockHeader{Height: 19, StateRoot: root}}
+		assert.NoError(t, pool.EndEpoch(1, last))
+		weightB, _ = authority.GetValidatorWeight(validatorB)
+		assert.Equal(t, big.NewInt(1), weightB, "weights wait for the self bond of every validator")
+
+		st, err = state.New(root, stateDatabase)
+		assert.NoError(t, err)
+		assert.NoError(t, pool.delegate(st, validatorB, validatorB, selfBond))
+		root, err = st.Commit(true)
+		assert.NoError(t, err)
+		last = &block.Block{Header: &block.BlockHeader{Height: 29, StateRoot: root}}
+		assert.NoError(t, pool.EndEpoch(2, last))
+		weightA, _ := authority.GetValidatorWeight(validatorA)
+		weightB, _ = authority.GetValidatorWeight(validatorB)
+		assert.Equal(t, new(big.Int).Add(big.NewInt(150), selfBond), weightA, "weight should be the bonded stake")
+		assert.Equal(t, new(big.Int).Add(big.NewInt(100), selfBond), weightB, "weight should be the bonded stake")
+	})
+}
+
+func TestStakePoolBootstrapWeights(t *testing.T) {
+	validator := common.HexToAddress("0xabc123")
+	authority := NewAuthority()
+	assert.NoError(t, authority.AddValidator(validator, big.NewInt(7)))
+
+	memDB, _ := database.NewMemDatabase()
+	stateDatabase := state.NewDatabase(memDB)
+	states := NewChainStates(stateDatabase, memDB)
+	st, _ := state.New(common.Hash{}, stateDatabase)
+	root, err := st.Commit(true)
+	assert.NoError(t, err)
+
+	validatorDB, _ := database.NewMemDatabase()
+	observerDB, _ := database.NewMemDatabase()
+	pool := NewStakePool(authority, testEpochValidators(authority.ListValidators()), states, validatorDB, observerDB)
+	assert.NoError(t, pool.EndEpoch(0, &block.Block{Header: &block.BlockHeader{StateRoot: root}}))
+
+	weight, _ := authority.GetValidatorWeight(validator)
+	assert.Equal(t, big.NewInt(7), weight, "configured weight is kept until stake is bonded")
+}
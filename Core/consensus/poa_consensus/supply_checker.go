@@ -0,0 +1,244 @@
+package poa_consensus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/errors"
+	pb "FichainCore/proto"
+	"FichainCore/state"
+)
+
+// BankReserve is the reserve position of a bank
+type BankReserve struct {
+	Balance     *big.Int
+	Issued      *big.Int
+	Attestation *ReserveAttestation
+}
+
+// SupplyReport compares the native VND supply with the fiat reserves backing
+// it as of a block
+type SupplyReport struct {
+	Height  uint64
+	Supply  *big.Int
+	Reserve *big.Int
+	Banks   map[common.Address]*BankReserve
+}
+
+// Backed reports whether the supply is fully covered by the reserves
+func (r *SupplyReport) Backed() bool {
+	return r.Supply.Cmp(r.Reserve) <= 0
+}
+
+// Proto converts SupplyReport to protobuf message, banks are sorted by address
+func (r *SupplyReport) Proto() proto.Message {
+	addresses := make([]common.Address, 0, len(r.Banks))
+	for bank := range r.Banks {
+		addresses = append(addresses, bank)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+	})
+	banks := make([]*pb.BankReserve, len(addresses))
+	for i, address := range addresses {
+		bank := r.Banks[address]
+		banks[i] = &pb.BankReserve{
+			Bank:    address.Bytes(),
+			Balance: bank.Balance.Bytes(),
+			Issued:  bank.Issued.Bytes(),
+		}
+		if bank.Attestation != nil {
+			banks[i].Attestation = bank.Attestation.Proto().(*pb.ReserveAttestation)
+		}
+	}
+	return &pb.SupplyReport{
+		Height:  r.Height,
+		Supply:  r.Supply.Bytes(),
+		Reserve: r.Reserve.Bytes(),
+		Banks:   banks,
+	}
+}
+
+// FromProto populates SupplyReport from a protobuf message
+func (r *SupplyReport) FromProto(pbReport *pb.SupplyReport) error {
+	r.Height = pbReport.Height
+	r.Supply = new(big.Int).SetBytes(pbReport.Supply)
+	r.Reserve = new(big.Int).SetBytes(pbReport.Reserve)
+	r.Banks = make(map[common.Address]*BankReserve, len(pbReport.Banks))
+	for _, pbBank := range pbReport.Banks {
+		bank := &BankReserve{
+			Balance: new(big.Int).SetBytes(pbBank.Balance),
+			Issued:  new(big.Int).SetBytes(pbBank.Issued),
+		}
+		if pbBank.Attestation != nil {
+			bank.Attestation = &ReserveAttestation{}
+			if err := bank.Attestation.FromProto(pbBank.Attestation); err != nil {
+				return err
+			}
+		}
+		r.Banks[common.BytesToAddress(pbBank.Bank)] = bank
+	}
+	return nil
+}
+
+var supplyViolationKey = []byte("supply-violation")
+
+// supplyViolation is the first block the supply was not backed at
+type supplyViolation struct {
+	Height  uint64
+	Supply  *big.Int
+	Reserve *big.Int
+}
+
+func (v *supplyViolation) err() error {
+	return fmt.Errorf(
+		"%w at block %d: supply %s, reserve %s",
+		errors.ErrSupplyExceedsReserve,
+		v.Height,
+		v.Supply,
+		v.Reserve,
+	)
+}
+
+// SupplyChecker verifies after every imported block that the native VND
+// outstanding never exceeds the fiat reserves. The supply is read from the
+// issuance counters kept in state so the check costs the same on every
+// block. Once violated, block production is halted until an operator
+// intervenes, the violation is stored so a restart does not resume it.
+type SupplyChecker struct {
+	issuer *NativeIssuer
+	states *ChainStates
+	db     database.Database
+
+	last      *SupplyReport
+	violation *supplyViolation
+
+	sync.RWMutex
+}
+
+func NewSupplyChecker(issuer *NativeIssuer, states *ChainStates, db database.Database) *SupplyChecker {
+	return &SupplyChecker{
+		issuer: issuer,
+		states: states,
+		db:     db,
+	}
+}
+
+// LoadFromDB restores a supply violation found before a restart
+func (c *SupplyChecker) LoadFromDB() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if ok, err := c.db.Has(supplyViolationKey); err != nil || !ok {
+		return err
+	}
+	data, err := c.db.Get(supplyViolationKey)
+	if err != nil {
+		return err
+	}
+	violation := &supplyViolation{}
+	if err := json.Unmarshal(data, violation); err != nil {
+		return fmt.Errorf("error loading supply violation: %w", err)
+	}
+	c.violation = violation
+	return nil
+}
+
+// Report builds the supply report of a state. The banks are the ones known to
+// the local FiatReserve.
+func (c *SupplyChecker) Report(st *state.StateDB, height uint64) *SupplyReport {
+	report := &SupplyReport{
+		Height:  height,
+		Supply:  c.issuer.Supply(st),
+		Reserve: c.issuer.TotalReserve(st),
+		Banks:   make(map[common.Address]*BankReserve),
+	}
+	for bank := range c.issuer.reserve.ListBalances() {
+		report.Banks[bank] = &BankReserve{
+			Balance:     c.issuer.ReserveBalance(st, bank),
+			Issued:      c.issuer.Issued(st, bank),
+			Attestation: c.issuer.Attestation(st, bank),
+		}
+	}
+	return report
+}
+
+// Check builds the supply report of a block and stores the first block the
+// supply was not backed at
+func (c *SupplyChecker) Check(bl *block.Block) (*SupplyReport, error) {
+	st, err := c.states.At(bl.Header)
+	if err != nil {
+		return nil, err
+	}
+	report := c.Report(st, bl.Header.Height)
+
+	c.Lock()
+	defer c.Unlock()
+	c.last = report
+	if report.Backed() {
+		return report, nil
+	}
+	if c.violation != nil {
+		return report, nil
+	}
+	violation := &supplyViolation{
+		Height:  report.Height,
+		Supply:  report.Supply,
+		Reserve: report.Reserve,
+	}
+	data, err := json.Marshal(violation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal supply violation: %w", err)
+	}
+	if err := c.db.Put(supplyViolationKey, data); err != nil {
+		return nil, err
+	}
+	c.violation = violation
+	return report, nil
+}
+
+// Violation returns the first supply violation found, nil if the supply was
+// always backed
+func (c *SupplyChecker) Violation() error {
+	c.RLock()
+	defer c.RUnlock()
+	if c.violation == nil {
+		return nil
+	}
+	return c.violation.err()
+}
+
+// LastReport returns the report of the last checked block
+func (c *SupplyChecker) LastReport() *SupplyReport {
+	c.RLock()
+	defer c.RUnlock()
+	return c.last
+}
+
+// ProcessBlock implements BlockProcessor. A violation is not returned so the
+// epoch processing of the chain carries on while production is halted.
+func (c *SupplyChecker) ProcessBlock(bl *block.Block) error {
+	report, err := c.Check(bl)
+	if err != nil {
+		return err
+	}
+	if !report.Backed() {
+		logger.Error("[SupplyChecker] supply invariant violated", c.Violation())
+	}
+	return nil
+}
+
+// EndEpoch implements BlockProcessor, the supply is checked on every block
+func (c *SupplyChecker) EndEpoch(epoch uint64, last *block.Block) error {
+	return nil
+}
@@ -0,0 +1,112 @@
+package poa_consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/errors"
+	"FichainCore/params"
+	pb "FichainCore/proto"
+	"FichainCore/state"
+)
+
+func TestSupplyChecker(t *testing.T) {
+	bankSigner, bank := newTestSigner(t)
+	holder := common.HexToAddress("0xa11ce")
+	customer := common.HexToAddress("0xc0ffee")
+
+	memDB, _ := database.NewMemDatabase()
+	stateDatabase := state.NewDatabase(memDB)
+	states := NewChainStates(stateDatabase, memDB)
+	st, err := state.New(common.Hash{}, stateDatabase)
+	assert.NoError(t, err)
+	// 400 allocated at genesis, backed by the 1000 reserve
+	st.AddBalance(holder, big.NewInt(400))
+	WriteGenesisReserves(st, map[common.Address]*big.Int{bank: big.NewInt(1000)}, big.NewInt(400))
+
+	reserveDB, _ := database.NewMemDatabase()
+	reserve := NewFiatReserve()
+	reserve.SetBalance(bank, big.NewInt(1000))
+	issuer := NewNativeIssuer(reserve, testEpochValidators{bank: big.NewInt(1)}, states, reserveDB)
+	checkerDB, _ := database.NewMemDatabase()
+	checker := NewSupplyChecker(issuer, states, checkerDB)
+
+	applyReserveTxs(t, issuer, st,
+		// the genesis supply leaves 600 to mint
+		newSystemTx(t, bankSigner, params.ReserveAddress, 0, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionMint,
+			Recipient: customer,
+			Amount:    big.NewInt(601),
+		}),
+		newSystemTx(t, bankSigner, params.ReserveAddress, 1, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionMint,
+			Recipient: customer,
+			Amount:    big.NewInt(600),
+		}),
+		// would leave the genesis supply unbacked
+		newSystemTx(t, bankSigner, params.ReserveAddress, 2, big.NewInt(0), &ReserveCall{
+			Action: ReserveActionWithdraw,
+			Amount: big.NewInt(1),
+		}),
+		newSystemTx(t, bankSigner, params.ReserveAddress, 3, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionAttest,
+			Amount:    big.NewInt(1005),
+			Timestamp: 1700000000,
+		}),
+		// older than the latest attestation
+		newSystemTx(t, bankSigner, params.ReserveAddress, 4, big.NewInt(0), &ReserveCall{
+			Action:    ReserveActionAttest,
+			Amount:    big.NewInt(1),
+			Timestamp: 1600000000,
+		}),
+	)
+	assert.Equal(t, big.NewInt(600), st.GetBalance(customer))
+	assert.Equal(t, big.NewInt(1000), issuer.TotalReserve(st))
+
+	attestation := issuer.Attestation(st, bank)
+	if assert.NotNil(t, attestation) {
+		assert.Equal(t, big.NewInt(1005), attestation.Balance)
+		assert.Equal(t, uint64(1700000000), attestation.Timestamp)
+		assert.Equal(t, uint64(1), attestation.Height)
+	}
+
+	root, err := st.Commit(true)
+	assert.NoError(t, err)
+	report, err := checker.Check(&block.Block{Header: &block.BlockHeader{Height: 1, StateRoot: root}})
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1000), report.Supply)
+	assert.Equal(t, big.NewInt(1000), report.Reserve)
+	assert.Equal(t, big.NewInt(600), report.Banks[bank].Issued)
+	assert.Equal(t, attestation, report.Banks[bank].Attestation)
+	assert.NoError(t, checker.Violation())
+
+	t.Run("TestProtoRoundTrip", func(t *testing.T) {
+		decoded := &SupplyReport{}
+		assert.NoError(t, decoded.FromProto(report.Proto().(*pb.SupplyReport)))
+		assert.Equal(t, report, decoded)
+	})
+
+	t.Run("TestViolation", func(t *testing.T) {
+		next, err := state.New(root, stateDatabase)
+		assert.NoError(t, err)
+		// VND issued past the reserve
+		setReserveValue(next, reserveSlot("reserve-total-issued"), big.NewInt(601))
+		root, err := next.Commit(true)
+		assert.NoError(t, err)
+		assert.NoError(t, checker.ProcessBlock(
+			&block.Block{Header: &block.BlockHeader{Height: 2, StateRoot: root}},
+		))
+		assert.ErrorIs(t, checker.Violation(), errors.ErrSupplyExceedsReserve)
+		assert.Equal(t, uint64(2), checker.LastReport().Height)
+
+		// a restart keeps production halted
+		restarted := NewSupplyChecker(issuer, states, checkerDB)
+		assert.NoError(t, restarted.LoadFromDB())
+		assert.Equal(t, checker.Violation(), restarted.Violation())
+	})
+}
@@ -0,0 +1,18 @@
+package poa_consensus
+
+import (
+	"math/big"
+
+	"FichainCore/common"
+	"FichainCore/state"
+)
+
+// setSystemState writes a storage slot of a system address. The account
+// nonce is set so a system account without balance is not deleted as empty
+// together with its storage.
+func setSystemState(st *state.StateDB, address common.Address, slot common.Hash, value *big.Int) {
+	if st.GetNonce(address) == 0 {
+		st.SetNonce(address, 1)
+	}
+	st.SetState(address, slot, common.BigToHash(value))
+}
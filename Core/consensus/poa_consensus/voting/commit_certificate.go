@@ -0,0 +1,198 @@
+package voting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	pb "FichainCore/proto"
+)
+
+var (
+	commitCertificatePrefix = []byte("commit-cert-") // commitCertificatePrefix + hash -> commit certificate
+	lastFinalizedKey        = []byte("LastFinalized")
+)
+
+// ErrFinalizedFork is returned for a block forking off the local chain below
+// its last finalized block
+var ErrFinalizedFork = errors.New("block forks below the last finalized block")
+
+// HeaderReader reads the headers of the local chain, implemented by
+// block_chain.BlockChain
+type HeaderReader interface {
+	GetHeader(hash common.Hash, number uint64) *block.BlockHeader
+	GetHeaderByNumber(number uint64) *block.BlockHeader
+}
+
+// CommitCertificate proves that more than 2/3 of the validator weight
+// pre-committed to a block, making it final
+type CommitCertificate struct {
+	Height    uint64
+	BlockHash common.Hash
+	Votes     []Vote
+}
+
+// NewCommitCertificate builds a certificate from the collected votes, sorted
+// by voter so the encoding is deterministic
+func NewCommitCertificate(height uint64, blockHash common.Hash, votes []Vote) *CommitCertificate {
+	sorted := make([]Vote, len(votes))
+	copy(sorted, votes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Voter.Bytes(), sorted[j].Voter.Bytes()) < 0
+	})
+	return &CommitCertificate{
+		Height:    height,
+		BlockHash: blockHash,
+		Votes:     sorted,
+	}
+}
+
+// Verify checks every vote targets the certified block and that the votes
+// reach quorum on the given validator set
+func (c *CommitCertificate) Verify(validators ValidatorSet) error {
+	return c.VerifyAt(fixedValidatorSet{validators})
+}
+
+// VerifyAt checks the certificate against the validators active at its
+// height
+func (c *CommitCertificate) VerifyAt(validators HeightValidatorSet) error {
+	manager := NewEpochVotingManager(validators)
+	for _, vote := range c.Votes {
+		if vote.Height != c.Height || vote.BlockHash != c.BlockHash {
+			return fmt.Errorf("vote from %s does not match certificate", vote.Voter.Hex())
+		}
+		if err := manager.SubmitVote(vote); err != nil {
+			return err
+		}
+	}
+	ok, err := manager.HasReachedQuorum(c.BlockHash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("commit certificate has not reached quorum")
+	}
+	return nil
+}
+
+// Proto converts CommitCertificate to protobuf message
+func (c *CommitCertificate) Proto() proto.Message {
+	pbVotes := make([]*pb.Vote, len(c.Votes))
+	for i := range c.Votes {
+		pbVotes[i] = c.Votes[i].Proto().(*pb.Vote)
+	}
+	return &pb.CommitCertificate{
+		Height:    c.Height,
+		BlockHash: c.BlockHash.Bytes(),
+		Votes:     pbVotes,
+	}
+}
+
+// FromProto populates CommitCertificate from a protobuf message
+func (c *CommitCertificate) FromProto(pbCert *pb.CommitCertificate) error {
+	if pbCert == nil {
+		return errors.New("missing commit certificate")
+	}
+	c.Height = pbCert.Height
+	c.BlockHash = common.BytesToHash(pbCert.BlockHash)
+	c.Votes = make([]Vote, len(pbCert.Votes))
+	for i, pbVote := range pbCert.Votes {
+		if err := c.Votes[i].FromProto(pbVote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func commitCertificateKey(hash common.Hash) []byte {
+	return append(append([]byte{}, commitCertificatePrefix...), hash.Bytes()...)
+}
+
+// WriteCommitCertificate stores the certificate next to the block it finalizes
+// and moves the last finalized block pointer forward
+func WriteCommitCertificate(db database.Database, cert *CommitCertificate) error {
+	data, err := proto.Marshal(cert.Proto())
+	if err != nil {
+		return fmt.Errorf("failed to encode commit certificate: %w", err)
+	}
+	batch := db.NewBatch()
+	batch.Put(commitCertificateKey(cert.BlockHash), data)
+	if last := ReadLastFinalizedHeight(db); cert.Height > last {
+		batch.Put(lastFinalizedKey, cert.BlockHash.Bytes())
+	}
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("failed to store commit certificate: %w", err)
+	}
+	return nil
+}
+
+// ReadCommitCertificate returns the certificate for a block, nil if the block
+// is not finalized
+func ReadCommitCertificate(db database.Database, hash common.Hash) *CommitCertificate {
+	data, err := db.Get(commitCertificateKey(hash))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var pbCert pb.CommitCertificate
+	if err := proto.Unmarshal(data, &pbCert); err != nil {
+		return nil
+	}
+	cert := &CommitCertificate{}
+	if err := cert.FromProto(&pbCert); err != nil {
+		return nil
+	}
+	return cert
+}
+
+// ReadLastFinalizedHash returns the hash of the latest finalized block
+func ReadLastFinalizedHash(db database.Database) common.Hash {
+	data, err := db.Get(lastFinalizedKey)
+	if err != nil || len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// ReadLastFinalizedHeight returns the height of the latest finalized block
+func ReadLastFinalizedHeight(db database.Database) uint64 {
+	cert := ReadCommitCertificate(db, ReadLastFinalizedHash(db))
+	if cert == nil {
+		return 0
+	}
+	return cert.Height
+}
+
+// CheckFinality tells whether a block may be imported without reverting a
+// finalized block: its branch must join the canonical chain at or above the
+// last finalized block. A block whose ancestors are unknown passes, the
+// import rejects it for its missing parent.
+func CheckFinality(db database.Database, chain HeaderReader, header *block.BlockHeader) error {
+	finalized := ReadCommitCertificate(db, ReadLastFinalizedHash(db))
+	if finalized == nil {
+		return nil
+	}
+	height := header.Height
+	for header.Height > finalized.Height {
+		if canonical := chain.GetHeaderByNumber(header.Height); canonical != nil && canonical.Hash() == header.Hash() {
+			return nil
+		}
+		header = chain.GetHeader(header.ParentHash, header.Height-1)
+		if header == nil {
+			return nil
+		}
+	}
+	if header.Height == finalized.Height {
+		if header.Hash() == finalized.BlockHash {
+			return nil
+		}
+	} else if canonical := chain.GetHeaderByNumber(header.Height); canonical != nil && canonical.Hash() == header.Hash() {
+		return nil
+	}
+	return fmt.Errorf("%w: block %d does not descend from finalized block %d", ErrFinalizedFork, height, finalized.Height)
+}
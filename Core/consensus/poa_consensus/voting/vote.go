@@ -1,7 +1,121 @@
 package voting
 
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/common"
+	"FichainCore/crypto"
+	"FichainCore/database"
+	pb "FichainCore/proto"
+	"FichainCore/signer"
+)
+
+// Vote is a signed pre-commit from a validator for a block
 type Vote struct {
-	BlockHash string
-	Voter     string
+	Height    uint64
+	BlockHash common.Hash
+	Voter     common.Address
 	Signature []byte
 }
+
+// NewVote creates a pre-commit for the given block signed by s
+func NewVote(height uint64, blockHash common.Hash, s *signer.Signer) (*Vote, error) {
+	voter, err := s.WalletAddress()
+	if err != nil {
+		return nil, err
+	}
+	v := &Vote{
+		Height:    height,
+		BlockHash: blockHash,
+		Voter:     voter,
+	}
+	sign, err := s.SignHash(v.SignHash())
+	if err != nil {
+		return nil, err
+	}
+	v.Signature = sign.Bytes()
+	return v, nil
+}
+
+// SignHash returns the hash signed by the voter
+func (v *Vote) SignHash() common.Hash {
+	b, _ := proto.Marshal(&pb.VoteSignData{
+		Height:    v.Height,
+		BlockHash: v.BlockHash.Bytes(),
+	})
+	return crypto.Keccak256Hash(b)
+}
+
+// RecoverVoter returns the address that signed the vote
+func (v *Vote) RecoverVoter() (common.Address, error) {
+	if len(v.Signature) == 0 {
+		return common.Address{}, errors.New("vote is not signed")
+	}
+	pub, err := crypto.SigToPub(v.SignHash().Bytes(), v.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Proto converts Vote to protobuf message
+func (v *Vote) Proto() proto.Message {
+	return &pb.Vote{
+		Height:    v.Height,
+		BlockHash: v.BlockHash.Bytes(),
+		Voter:     v.Voter.Bytes(),
+		Signature: v.Signature,
+	}
+}
+
+// FromProto populates Vote from a protobuf message
+func (v *Vote) FromProto(pbVote *pb.Vote) error {
+	if pbVote == nil {
+		return errors.New("missing vote")
+	}
+	v.Height = pbVote.Height
+	v.BlockHash = common.BytesToHash(pbVote.BlockHash)
+	v.Voter = common.BytesToAddress(pbVote.Voter)
+	v.Signature = pbVote.Signature
+	return nil
+}
+
+var lastVoteKey = []byte("LastVote")
+
+// LastVote is the latest block this node pre-committed to. It is persisted
+// before the vote is sent, so a validator never signs a second block at a
+// height it voted for, restarts, backup rounds and reorgs included.
+type LastVote struct {
+	Height    uint64
+	Round     uint64
+	BlockHash common.Hash
+}
+
+// WriteLastVote stores the latest vote of this node
+func WriteLastVote(db database.Database, vote *LastVote) error {
+	data := make([]byte, 16+common.HashLength)
+	binary.BigEndian.PutUint64(data, vote.Height)
+	binary.BigEndian.PutUint64(data[8:], vote.Round)
+	copy(data[16:], vote.BlockHash.Bytes())
+	if err := db.Put(lastVoteKey, data); err != nil {
+		return fmt.Errorf("failed to store last vote: %w", err)
+	}
+	return nil
+}
+
+// ReadLastVote returns the latest vote of this node, nil if it never voted
+func ReadLastVote(db database.Database) *LastVote {
+	data, err := db.Get(lastVoteKey)
+	if err != nil || len(data) != 16+common.HashLength {
+		return nil
+	}
+	return &LastVote{
+		Height:    binary.BigEndian.Uint64(data),
+		Round:     binary.BigEndian.Uint64(data[8:]),
+		BlockHash: common.BytesToHash(data[16:]),
+	}
+}
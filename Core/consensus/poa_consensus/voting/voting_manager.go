@@ -1,7 +1,33 @@
 package voting
 
+import (
+	"math/big"
+
+	"FichainCore/common"
+)
+
 type VotingManager interface {
 	SubmitVote(vote Vote) error
-	HasReachedQuorum(blockHash string) (bool, error)
-	GetVotes(blockHash string) ([]Vote, error)
+	HasReachedQuorum(blockHash common.Hash) (bool, error)
+	GetVotes(blockHash common.Hash) ([]Vote, error)
+}
+
+// ValidatorSet provides the voting weight of every validator
+type ValidatorSet interface {
+	ListValidators() map[common.Address]*big.Int
+}
+
+// HeightValidatorSet provides the voting weight of the validators active at a
+// block height, implemented by poa_consensus.EpochManager
+type HeightValidatorSet interface {
+	ValidatorsAt(height uint64) (map[common.Address]*big.Int, error)
+}
+
+// fixedValidatorSet weighs the votes of every height with the same validators
+type fixedValidatorSet struct {
+	ValidatorSet
+}
+
+func (s fixedValidatorSet) ValidatorsAt(height uint64) (map[common.Address]*big.Int, error) {
+	return s.ListValidators(), nil
 }
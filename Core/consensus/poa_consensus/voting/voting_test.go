@@ -0,0 +1,249 @@
+package voting
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/crypto"
+	"FichainCore/database"
+	pb "FichainCore/proto"
+	"FichainCore/signer"
+	"FichainCore/types"
+)
+
+type testValidatorSet map[common.Address]*big.Int
+
+func (s testValidatorSet) ListValidators() map[common.Address]*big.Int { return s }
+
+func newTestSigner(t *testing.T) (*signer.Signer, common.Address) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	s := signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key)))
+	return s, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestVoteSignature(t *testing.T) {
+	s, addr := newTestSigner(t)
+	vote, err := NewVote(1, common.Hash{0x01}, s)
+	assert.NoError(t, err)
+
+	voter, err := vote.RecoverVoter()
+	assert.NoError(t, err)
+	assert.Equal(t, addr, voter, "recovered voter should match signer")
+
+	decoded := &Vote{}
+	assert.NoError(t, decoded.FromProto(vote.Proto().(*pb.Vote)))
+	assert.Equal(t, vote, decoded, "vote should survive proto round trip")
+
+	// tampering the block hash changes the signer
+	decoded.BlockHash = common.Hash{0x02}
+	voter, err = decoded.RecoverVoter()
+	assert.NoError(t, err)
+	assert.NotEqual(t, addr, voter)
+}
+
+func TestWeightedVotingManager(t *testing.T) {
+	signers := make([]*signer.Signer, 4)
+	validators := testValidatorSet{}
+	for i := range signers {
+		var addr common.Address
+		signers[i], addr = newTestSigner(t)
+		validators[addr] = big.NewInt(1)
+	}
+	blockHash := common.Hash{0x01}
+	manager := NewWeightedVotingManager(validators)
+
+	submit := func(s *signer.Signer) error {
+		vote, err := NewVote(1, blockHash, s)
+		assert.NoError(t, err)
+		return manager.SubmitVote(*vote)
+	}
+
+	// half of the weight
+	assert.NoError(t, submit(signers[0]))
+	assert.NoError(t, submit(signers[1]))
+	reached, err := manager.HasReachedQuorum(blockHash)
+	assert.NoError(t, err)
+	assert.False(t, reached, "half of the weight should not reach quorum")
+
+	// duplicated vote is not counted twice
+	assert.NoError(t, submit(signers[1]))
+	votes, _ := manager.GetVotes(blockHash)
+	assert.Len(t, votes, 2)
+
+	assert.NoError(t, submit(signers[2]))
+	reached, err = manager.HasReachedQuorum(blockHash)
+	assert.NoError(t, err)
+	assert.True(t, reached, "3/4 of the weight should reach quorum")
+
+	// non validator vote is rejected
+	outsider, _ := newTestSigner(t)
+	assert.Error(t, submit(outsider))
+
+	// vote claiming another voter is rejected
+	forged, err := NewVote(1, blockHash, outsider)
+	assert.NoError(t, err)
+	for addr := range validators {
+		forged.Voter = addr
+		break
+	}
+	assert.Error(t, manager.SubmitVote(*forged))
+
+	manager.Prune(2)
+	votes, _ = manager.GetVotes(blockHash)
+	assert.Empty(t, votes, "votes below pruned height should be dropped")
+}
+
+func TestCommitCertificate(t *testing.T) {
+	signers := make([]*signer.Signer, 3)
+	validators := testValidatorSet{}
+	for i := range signers {
+		var addr common.Address
+		signers[i], addr = newTestSigner(t)
+		validators[addr] = big.NewInt(1)
+	}
+	blockHash := common.Hash{0x01}
+
+	votes := []Vote{}
+	for _, s := range signers {
+		vote, err := NewVote(5, blockHash, s)
+		assert.NoError(t, err)
+		votes = append(votes, *vote)
+	}
+	cert := NewCommitCertificate(5, blockHash, votes)
+	assert.NoError(t, cert.Verify(validators))
+
+	partial := NewCommitCertificate(5, blockHash, votes[:2])
+	assert.Error(t, partial.Verify(validators), "2/3 of the weight is not a quorum")
+	assert.NoError(t, cert.VerifyAt(testHeightValidatorSet{5: validators}))
+	assert.Error(t, cert.VerifyAt(testHeightValidatorSet{6: validators}), "validators of the height are unknown")
+
+	db, _ := database.NewMemDatabase()
+	assert.Nil(t, ReadCommitCertificate(db, blockHash))
+	assert.NoError(t, WriteCommitCertificate(db, cert))
+
+	stored := ReadCommitCertificate(db, blockHash)
+	assert.Equal(t, cert, stored, "stored certificate should match")
+	assert.Equal(t, blockHash, ReadLastFinalizedHash(db))
+	assert.Equal(t, uint64(5), ReadLastFinalizedHeight(db))
+}
+
+type testHeaderChain struct {
+	headers   map[common.Hash]*block.BlockHeader
+	canonical map[uint64]*block.BlockHeader
+}
+
+func (c *testHeaderChain) GetHeader(hash common.Hash, number uint64) *block.BlockHeader {
+	return c.headers[hash]
+}
+
+func (c *testHeaderChain) GetHeaderByNumber(number uint64) *block.BlockHeader {
+	return c.canonical[number]
+}
+
+// extend adds a child of parent, canonical or on a side branch
+func (c *testHeaderChain) extend(parent *block.BlockHeader, timestamp uint64, canonical bool) *block.BlockHeader {
+	header := &block.BlockHeader{Height: parent.Height + 1, ParentHash: parent.Hash(), Timestamp: timestamp}
+	c.headers[header.Hash()] = header
+	if canonical {
+		c.canonical[header.Height] = header
+	}
+	return header
+}
+
+func TestCheckFinality(t *testing.T) {
+	genesis := &block.BlockHeader{}
+	chain := &testHeaderChain{
+		headers:   map[common.Hash]*block.BlockHeader{genesis.Hash(): genesis},
+		canonical: map[uint64]*block.BlockHeader{0: genesis},
+	}
+	canonical := []*block.BlockHeader{genesis}
+	for i := 1; i <= 4; i++ {
+		canonical = append(canonical, chain.extend(canonical[i-1], 1, true))
+	}
+	side := chain.extend(canonical[1], 2, false)
+	side = chain.extend(side, 2, false)
+	child := func(parent *block.BlockHeader) *block.BlockHeader {
+		return &block.BlockHeader{Height: parent.Height + 1, ParentHash: parent.Hash(), Timestamp: 3}
+	}
+
+	db, _ := database.NewMemDatabase()
+	assert.NoError(t, CheckFinality(db, chain, child(side)), "nothing is finalized yet")
+
+	assert.NoError(t, WriteCommitCertificate(db, NewCommitCertificate(2, canonical[2].Hash(), nil)))
+	assert.NoError(t, CheckFinality(db, chain, child(canonical[4])))
+	assert.NoError(t, CheckFinality(db, chain, child(canonical[2])), "forks above the finalized block are allowed")
+	assert.NoError(t, CheckFinality(db, chain, canonical[1]), "known canonical blocks stay importable")
+	assert.ErrorIs(t, CheckFinality(db, chain, child(side)), ErrFinalizedFork)
+	assert.ErrorIs(t, CheckFinality(db, chain, child(canonical[1])), ErrFinalizedFork)
+	assert.ErrorIs(t, CheckFinality(db, chain, child(canonical[0])), ErrFinalizedFork)
+	assert.NoError(
+		t,
+		CheckFinality(db, chain, &block.BlockHeader{Height: 9, ParentHash: common.Hash{0x09}}),
+		"an unknown parent is left to the import",
+	)
+}
+
+type testHeightValidatorSet map[uint64]testValidatorSet
+
+func (s testHeightValidatorSet) ValidatorsAt(height uint64) (map[common.Address]*big.Int, error) {
+	validators, ok := s[height]
+	if !ok {
+		return nil, errors.New("unknown height")
+	}
+	return validators, nil
+}
+
+func TestEpochVotingManager(t *testing.T) {
+	signers := make([]*signer.Signer, 3)
+	addrs := make([]common.Address, 3)
+	for i := range signers {
+		signers[i], addrs[i] = newTestSigner(t)
+	}
+	// the third validator joins at height 2
+	manager := NewEpochVotingManager(testHeightValidatorSet{
+		1: {addrs[0]: big.NewInt(1), addrs[1]: big.NewInt(1)},
+		2: {addrs[0]: big.NewInt(1), addrs[1]: big.NewInt(1), addrs[2]: big.NewInt(1)},
+	})
+	submit := func(s *signer.Signer, height uint64, blockHash common.Hash) error {
+		vote, err := NewVote(height, blockHash, s)
+		assert.NoError(t, err)
+		return manager.SubmitVote(*vote)
+	}
+
+	first, second := common.Hash{0x01}, common.Hash{0x02}
+	assert.Error(t, submit(signers[2], 1, first), "not a validator at height 1")
+	assert.NoError(t, submit(signers[0], 1, first))
+	assert.NoError(t, submit(signers[1], 1, first))
+	reached, err := manager.HasReachedQuorum(first)
+	assert.NoError(t, err)
+	assert.True(t, reached)
+
+	// a second block at the same height is never counted for the same voter
+	assert.NoError(t, submit(signers[0], 2, second))
+	assert.NoError(t, submit(signers[0], 2, second))
+	assert.ErrorIs(t, submit(signers[0], 2, common.Hash{0x03}), ErrConflictingVote)
+	assert.NoError(t, submit(signers[1], 2, second))
+	reached, err = manager.HasReachedQuorum(second)
+	assert.NoError(t, err)
+	assert.False(t, reached, "2/3 of the weight at height 2 is not a quorum")
+	assert.NoError(t, submit(signers[2], 2, second))
+	reached, err = manager.HasReachedQuorum(second)
+	assert.NoError(t, err)
+	assert.True(t, reached)
+
+	assert.Error(t, submit(signers[0], 3, first), "validators of height 3 are unknown")
+}
+
+func TestLastVote(t *testing.T) {
+	db, _ := database.NewMemDatabase()
+	assert.Nil(t, ReadLastVote(db))
+	vote := &LastVote{Height: 7, Round: 2, BlockHash: common.Hash{0x07}}
+	assert.NoError(t, WriteLastVote(db, vote))
+	assert.Equal(t, vote, ReadLastVote(db))
+}
@@ -0,0 +1,155 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"FichainCore/common"
+)
+
+// ErrConflictingVote is returned for a vote on a block other than the one its
+// voter already voted for at the same height
+var ErrConflictingVote = errors.New("conflicting vote")
+
+// WeightedVotingManager collects pre-commit votes and reports quorum once
+// validators holding more than 2/3 of the weight active at the block height
+// voted for a block.
+type WeightedVotingManager struct {
+	validators HeightValidatorSet
+	votes      map[common.Hash]map[common.Address]Vote // block hash -> voter -> vote
+	heights    map[common.Hash]uint64
+	voted      map[uint64]map[common.Address]common.Hash // height -> voter -> voted block
+
+	sync.RWMutex
+}
+
+// NewWeightedVotingManager weighs the votes of every height with the same
+// validator set
+func NewWeightedVotingManager(validators ValidatorSet) *WeightedVotingManager {
+	return NewEpochVotingManager(fixedValidatorSet{validators})
+}
+
+// NewEpochVotingManager weighs the votes of a height with the validators
+// active at that height
+func NewEpochVotingManager(validators HeightValidatorSet) *WeightedVotingManager {
+	return &WeightedVotingManager{
+		validators: validators,
+		votes:      make(map[common.Hash]map[common.Address]Vote),
+		heights:    make(map[common.Hash]uint64),
+		voted:      make(map[uint64]map[common.Address]common.Hash),
+	}
+}
+
+// SubmitVote verifies the vote signature and voter membership then records it.
+// Duplicated votes from the same voter are ignored, a second vote of a voter
+// for another block at the same height is rejected and never counted.
+func (m *WeightedVotingManager) SubmitVote(vote Vote) error {
+	voter, err := vote.RecoverVoter()
+	if err != nil {
+		return fmt.Errorf("invalid vote signature: %w", err)
+	}
+	if voter != vote.Voter {
+		return fmt.Errorf("vote signer %s mismatch voter %s", voter.Hex(), vote.Voter.Hex())
+	}
+	validators, err := m.validators.ValidatorsAt(vote.Height)
+	if err != nil {
+		return err
+	}
+	if _, ok := validators[voter]; !ok {
+		return fmt.Errorf("voter is not a validator: %s", voter.Hex())
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if height, ok := m.heights[vote.BlockHash]; ok && height != vote.Height {
+		return fmt.Errorf("vote height %d mismatch block height %d", vote.Height, height)
+	}
+	if voted, ok := m.voted[vote.Height][voter]; ok {
+		if voted != vote.BlockHash {
+			return fmt.Errorf(
+				"%w: %s voted %s and %s at height %d",
+				ErrConflictingVote, voter.Hex(), voted.Hex(), vote.BlockHash.Hex(), vote.Height,
+			)
+		}
+		return nil
+	}
+	if _, ok := m.votes[vote.BlockHash]; !ok {
+		m.votes[vote.BlockHash] = make(map[common.Address]Vote)
+		m.heights[vote.BlockHash] = vote.Height
+	}
+	if _, ok := m.voted[vote.Height]; !ok {
+		m.voted[vote.Height] = make(map[common.Address]common.Hash)
+	}
+	m.votes[vote.BlockHash][voter] = vote
+	m.voted[vote.Height][voter] = vote.BlockHash
+	return nil
+}
+
+// HasReachedQuorum returns true if the voted weight is strictly greater than
+// 2/3 of the total weight of the validators active at the block height
+func (m *WeightedVotingManager) HasReachedQuorum(blockHash common.Hash) (bool, error) {
+	m.RLock()
+	height, ok := m.heights[blockHash]
+	m.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	validators, err := m.validators.ValidatorsAt(height)
+	if err != nil {
+		return false, err
+	}
+	total := big.NewInt(0)
+	for _, weight := range validators {
+		total.Add(total, weight)
+	}
+	if total.Sign() == 0 {
+		return false, fmt.Errorf("validator set has no weight")
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	voted := big.NewInt(0)
+	for voter := range m.votes[blockHash] {
+		if weight, ok := validators[voter]; ok {
+			voted.Add(voted, weight)
+		}
+	}
+
+	// voted * 3 > total * 2
+	voted.Mul(voted, big.NewInt(3))
+	total.Mul(total, big.NewInt(2))
+	return voted.Cmp(total) > 0, nil
+}
+
+func (m *WeightedVotingManager) GetVotes(blockHash common.Hash) ([]Vote, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	votes := make([]Vote, 0, len(m.votes[blockHash]))
+	for _, vote := range m.votes[blockHash] {
+		votes = append(votes, vote)
+	}
+	return votes, nil
+}
+
+// Prune drops votes for blocks below the given height
+func (m *WeightedVotingManager) Prune(height uint64) {
+	m.Lock()
+	defer m.Unlock()
+
+	for hash, h := range m.heights {
+		if h < height {
+			delete(m.votes, hash)
+			delete(m.heights, hash)
+		}
+	}
+	for h := range m.voted {
+		if h < height {
+			delete(m.voted, h)
+		}
+	}
+}
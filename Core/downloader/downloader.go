@@ -0,0 +1,323 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	fcErrors "FichainCore/errors"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+)
+
+const (
+	defaultRequestTimeout = 5 * time.Second
+	defaultSyncInterval   = 10 * time.Second
+
+	MaxHeaderFetch = 192 // headers requested in one batch
+	MaxBodyFetch   = 64  // bodies requested in one batch
+	MaxStateFetch  = 384 // state trie nodes requested in one batch
+)
+
+var (
+	errNoPeers        = errors.New("no peer ahead of the local chain")
+	errStopped        = errors.New("downloader stopped")
+	errWrongResponse  = errors.New("unexpected response type")
+	errInvalidHeaders = errors.New("invalid header batch")
+	errInvalidBodies  = errors.New("invalid body batch")
+)
+
+// Chain is the local chain the downloader extends
+type Chain interface {
+	CurrentBlock() *block.Block
+}
+
+// BlockImporter verifies and imports a downloaded block, implemented by
+// handlers.BlockHandler
+type BlockImporter interface {
+	ImportBlock(bl *block.Block) error
+}
+
+// PeerSet lists the connected peers by wallet address
+type PeerSet interface {
+	All() map[common.Address]p2p.Peer
+}
+
+// Sender sends a request to a peer and waits for the response echoing its
+// request ID, implemented by message_sender.MessageSender
+type Sender interface {
+	Request(ctx context.Context, peer p2p.Peer, msgType string, payload message.HaveProto) (*message.Message, error)
+}
+
+type peerHead struct {
+	peer   p2p.Peer
+	header *block.BlockHeader
+}
+
+// Downloader brings a new or lagging node up to the chain of its peers. It
+// asks every peer for its head, then fetches headers and bodies in batches
+// from the highest peer and imports them through the same verification as
+// gossiped blocks. A peer that times out or serves an invalid batch is
+// dropped for the round and the next highest peer takes over. Once caught up
+// the node follows the chain through block gossip, the downloader keeps
+// checking the peer heads in case it falls behind again.
+type Downloader struct {
+	chain    Chain
+	importer BlockImporter
+	peers    PeerSet
+	sender   Sender
+	timeout  time.Duration
+
+	// fast sync, only enabled through EnableFastSync
+	fastChain FastSyncChain
+	stateDB   database.Database
+
+	syncing int32
+	quit    chan struct{}
+}
+
+func NewDownloader(
+	chain Chain,
+	importer BlockImporter,
+	peers PeerSet,
+	sender Sender,
+	timeout time.Duration,
+) *Downloader {
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+	return &Downloader{
+		chain:    chain,
+		importer: importer,
+		peers:    peers,
+		sender:   sender,
+		timeout:  timeout,
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start synchronises with the peers periodically. A fresh node fast syncs
+// first when enabled and falls back to a full sync if that fails.
+func (d *Downloader) Start() {
+	go func() {
+		if d.stateDB != nil && d.chain.CurrentBlock().Header.Height == 0 {
+			if err := d.FastSync(); err != nil {
+				logger.Warn("[Downloader] fast sync failed, replaying the chain instead", err)
+			}
+		}
+		ticker := time.NewTicker(defaultSyncInterval)
+		defer ticker.Stop()
+		for {
+			if err := d.Synchronise(); err != nil && err != errNoPeers {
+				logger.Warn("[Downloader] error when synchronise", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-d.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (d *Downloader) Stop() {
+	close(d.quit)
+}
+
+// Synchronising reports whether a sync is in progress, blocks should not be
+// produced on a stale head meanwhile
+func (d *Downloader) Synchronising() bool {
+	return atomic.LoadInt32(&d.syncing) == 1
+}
+
+// Synchronise downloads and imports the blocks the highest peer has beyond
+// the local head
+func (d *Downloader) Synchronise() error {
+	if !atomic.CompareAndSwapInt32(&d.syncing, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&d.syncing, 0)
+
+	heads := d.fetchHeads()
+	local := d.chain.CurrentBlock().Header.Height
+	candidates := heads[:0]
+	for _, head := range heads {
+		if head.header.Height > local {
+			candidates = append(candidates, head)
+		}
+	}
+	if len(candidates) == 0 {
+		return errNoPeers
+	}
+	target := candidates[0].header.Height
+	logger.Info("[Downloader] synchronising", local, "to", target)
+
+	// the target drops to the next peer's head when the highest one fails
+	caughtUp := false
+	for len(candidates) > 0 {
+		head := d.chain.CurrentBlock().Header
+		if candidates[0].header.Height <= head.Height {
+			caughtUp = true
+			candidates = candidates[1:]
+			continue
+		}
+		if err := d.fetchBatch(candidates[0], head); err != nil {
+			logger.Warn("[Downloader] dropping peer", candidates[0].peer.WalletAddress().Hex(), err)
+			candidates = candidates[1:]
+		}
+	}
+	head := d.chain.CurrentBlock().Header.Height
+	if !caughtUp {
+		return fmt.Errorf("synchronised to %d of %d, no peer left", head, target)
+	}
+	logger.Info("[Downloader] synchronised", head)
+	return nil
+}
+
+// fetchHeads asks every peer for its head, sorted from the highest
+func (d *Downloader) fetchHeads() []*peerHead {
+	peers := d.peers.All()
+	results := make(chan *peerHead, len(peers))
+	var wg sync.WaitGroup
+	for _, peer := range peers {
+		wg.Add(1)
+		go func(peer p2p.Peer) {
+			defer wg.Done()
+			msg, err := d.request(peer, message.MessageGetHeadBlock, &message.BytesMessage{}, message.MessageHeadBlock)
+			if err != nil {
+				logger.Debug("[Downloader] head request failed", peer.WalletAddress().Hex(), err)
+				return
+			}
+			results <- &peerHead{peer: peer, header: msg.Payload.(*message.HeaderMessage).Header}
+		}(peer)
+	}
+	wg.Wait()
+	close(results)
+
+	heads := make([]*peerHead, 0, len(peers))
+	for head := range results {
+		heads = append(heads, head)
+	}
+	sort.Slice(heads, func(i, j int) bool {
+		return heads[i].header.Height > heads[j].header.Height
+	})
+	return heads
+}
+
+// fetchBatch downloads and imports the next batch of blocks after head
+func (d *Downloader) fetchBatch(source *peerHead, head *block.BlockHeader) error {
+	count := source.header.Height - head.Height
+	if count > MaxHeaderFetch {
+		count = MaxHeaderFetch
+	}
+	msg, err := d.request(
+		source.peer,
+		message.MessageGetHeaders,
+		&message.HeadersRequest{From: head.Height + 1, Count: count},
+		message.MessageHeaders,
+	)
+	if err != nil {
+		return err
+	}
+	headers := msg.Payload.(*message.HeadersMessage).Headers
+	if err := verifyChain(head, headers); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(headers); start += MaxBodyFetch {
+		end := start + MaxBodyFetch
+		if end > len(headers) {
+			end = len(headers)
+		}
+		batch := headers[start:end]
+		hashes := make([]common.Hash, len(batch))
+		for i, header := range batch {
+			hashes[i] = header.Hash()
+		}
+		msg, err := d.request(
+			source.peer,
+			message.MessageGetBodies,
+			&message.BodiesRequest{Hashes: hashes},
+			message.MessageBodies,
+		)
+		if err != nil {
+			return err
+		}
+		bodies := msg.Payload.(*message.BodiesMessage).Bodies
+		if len(bodies) == 0 || len(bodies) > len(batch) {
+			return errInvalidBodies
+		}
+		for i, body := range bodies {
+			bl := &block.Block{
+				Header:       batch[i],
+				Transactions: body.Transactions,
+				Uncles:       body.Uncles,
+			}
+			if err := d.importer.ImportBlock(bl); err != nil && err != fcErrors.ErrKnownBlock {
+				return fmt.Errorf("block %d: %w", batch[i].Height, err)
+			}
+		}
+		if len(bodies) < len(batch) {
+			// continue from the new head on the next round
+			return nil
+		}
+	}
+	return nil
+}
+
+// verifyChain checks that headers extend head one height at a time
+func verifyChain(head *block.BlockHeader, headers []*block.BlockHeader) error {
+	if len(headers) == 0 {
+		return errInvalidHeaders
+	}
+	parent := head
+	for _, header := range headers {
+		if header.Height != parent.Height+1 || header.ParentHash != parent.Hash() {
+			return fmt.Errorf("%w: header %d does not extend %d", errInvalidHeaders, header.Height, parent.Height)
+		}
+		parent = header
+	}
+	return nil
+}
+
+// request sends a request to a peer and waits for its response, until the
+// timeout elapsed or the downloader stops
+func (d *Downloader) request(
+	peer p2p.Peer,
+	requestType string,
+	payload message.HaveProto,
+	responseType string,
+) (*message.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-d.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	msg, err := d.sender.Request(ctx, peer, requestType, payload)
+	if err != nil {
+		select {
+		case <-d.quit:
+			return nil, errStopped
+		default:
+			return nil, err
+		}
+	}
+	if msg.Header.MessageType != responseType {
+		return nil, fmt.Errorf("%w: %s to %s", errWrongResponse, msg.Header.MessageType, requestType)
+	}
+	return msg, nil
+}
@@ -0,0 +1,160 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+	"FichainCore/state"
+)
+
+func newTestChain(length int) []*block.Block {
+	blocks := []*block.Block{{Header: &block.BlockHeader{}}}
+	for i := 1; i < length; i++ {
+		blocks = append(blocks, &block.Block{Header: &block.BlockHeader{
+			Height:     uint64(i),
+			ParentHash: blocks[i-1].Hash(),
+			Timestamp:  uint64(i),
+		}})
+	}
+	return blocks
+}
+
+// testLocalChain imports blocks extending its head
+type testLocalChain struct {
+	blocks []*block.Block
+	sync.Mutex
+}
+
+func (c *testLocalChain) CurrentBlock() *block.Block {
+	c.Lock()
+	defer c.Unlock()
+	return c.blocks[len(c.blocks)-1]
+}
+
+func (c *testLocalChain) ImportBlock(bl *block.Block) error {
+	c.Lock()
+	defer c.Unlock()
+	if bl.Header.ParentHash != c.blocks[len(c.blocks)-1].Hash() {
+		return fmt.Errorf("block %d does not extend the head", bl.Header.Height)
+	}
+	c.blocks = append(c.blocks, bl)
+	return nil
+}
+
+type testPeer struct {
+	p2p.Peer
+	address common.Address
+	blocks  []*block.Block
+	silent  bool // answers head requests only
+	forged  bool // serves headers that do not link
+	state   state.Database
+	garbage bool // serves state entries nobody asked for
+}
+
+func (p *testPeer) WalletAddress() common.Address { return p.address }
+
+// testNetwork serves requests from the chains of its peers
+type testNetwork struct {
+	peers map[common.Address]p2p.Peer
+}
+
+func (n *testNetwork) All() map[common.Address]p2p.Peer { return n.peers }
+
+func (n *testNetwork) Request(
+	ctx context.Context,
+	peer p2p.Peer,
+	msgType string,
+	payload message.HaveProto,
+) (*message.Message, error) {
+	remote := peer.(*testPeer)
+	var responseType string
+	var response message.HaveProto
+	switch msgType {
+	case message.MessageGetHeadBlock:
+		responseType = message.MessageHeadBlock
+		response = &message.HeaderMessage{Header: remote.blocks[len(remote.blocks)-1].Header}
+	case message.MessageGetHeaders:
+		if remote.silent {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		request := payload.(*message.HeadersRequest)
+		headers := []*block.BlockHeader{}
+		for number := request.From; number < request.From+request.Count && number < uint64(len(remote.blocks)); number++ {
+			header := *remote.blocks[number].Header
+			if remote.forged {
+				header.ParentHash = common.Hash{}
+			}
+			headers = append(headers, &header)
+		}
+		responseType = message.MessageHeaders
+		response = &message.HeadersMessage{Headers: headers}
+	case message.MessageGetBodies:
+		request := payload.(*message.BodiesRequest)
+		bodies := []*block.Body{}
+		for range request.Hashes {
+			bodies = append(bodies, &block.Body{})
+		}
+		responseType = message.MessageBodies
+		response = &message.BodiesMessage{Bodies: bodies}
+	case message.MessageGetNodeData:
+		request := payload.(*message.NodeDataRequest)
+		data := [][]byte{}
+		for _, hash := range request.Hashes {
+			if remote.garbage {
+				data = append(data, hash.Bytes())
+				continue
+			}
+			if entry, err := remote.state.TrieDB().Node(hash); err == nil {
+				data = append(data, entry)
+			}
+		}
+		responseType = message.MessageNodeData
+		response = &message.NodeDataMessage{Data: data}
+	}
+	return &message.Message{
+		Header:  &message.Header{MessageType: responseType},
+		Payload: response,
+	}, nil
+}
+
+func TestDownloader(t *testing.T) {
+	remote := newTestChain(301)
+	local := &testLocalChain{blocks: remote[:11]}
+
+	network := &testNetwork{peers: map[common.Address]p2p.Peer{}}
+	for i, peer := range []*testPeer{
+		// the forged peer gets address 1
+		{blocks: newTestChain(400), forged: true},
+		{blocks: remote, silent: true},
+		{blocks: remote[:120]},
+		{blocks: remote},
+	} {
+		peer.address = common.BigToAddress(big.NewInt(int64(i + 1)))
+		network.peers[peer.address] = peer
+	}
+	downloader := NewDownloader(local, local, network, network, 100*time.Millisecond)
+
+	assert.NoError(t, downloader.Synchronise())
+	assert.Equal(t, uint64(300), local.CurrentBlock().Header.Height)
+	assert.Equal(t, remote[300].Hash(), local.CurrentBlock().Hash())
+	assert.False(t, downloader.Synchronising())
+
+	// the forged chain is still ahead but never served
+	assert.Error(t, downloader.Synchronise())
+	assert.Equal(t, uint64(300), local.CurrentBlock().Header.Height)
+
+	// nothing to do once caught up with every peer
+	delete(network.peers, common.BigToAddress(common.Big1))
+	assert.Equal(t, errNoPeers, downloader.Synchronise())
+}
@@ -0,0 +1,357 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/crypto"
+	"FichainCore/database"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+	"FichainCore/state"
+	"FichainCore/trie"
+)
+
+// FastSyncPivotDistance is how far below the highest peer head the fast sync
+// pivot is taken, recent blocks are imported normally in case the head
+// changes while the state downloads
+const FastSyncPivotDistance = 64
+
+var (
+	errNoPivot       = errors.New("no peer is far enough ahead to fast sync")
+	errInvalidPivot  = errors.New("invalid pivot block")
+	errStalledState  = errors.New("state download stalled, no peer left")
+	errInvalidStates = errors.New("unrequested state entry")
+)
+
+// FastSyncChain stores the blocks a fast sync downloads, implemented by the
+// node over the chain database
+type FastSyncChain interface {
+	// PivotHeight returns the height to fast sync to at or below height, the
+	// pivot must close an epoch so the consensus state derived from its state
+	// is exact
+	PivotHeight(height uint64) uint64
+	// WriteBlocks stores verified blocks below the pivot as canonical, their
+	// state is never downloaded
+	WriteBlocks(blocks []*block.Block) error
+	// CommitPivot makes the pivot the chain head once its state is synced
+	CommitPivot(pivot *block.Block) error
+}
+
+// EnableFastSync makes a node starting from genesis download the state of a
+// recent block instead of replaying every block since genesis. The state is
+// written to db, which must back the state database of the chain.
+func (d *Downloader) EnableFastSync(chain FastSyncChain, db database.Database) {
+	d.fastChain = chain
+	d.stateDB = db
+}
+
+// FastSync picks a pivot block about FastSyncPivotDistance below the highest
+// peer head, downloads the blocks from the local head to the pivot, checking
+// that they link up, then the account and storage tries and the contract code
+// at the pivot StateRoot and commits the pivot as the chain head. Blocks after
+// the pivot are then imported and executed by Synchronise as usual.
+//
+// Every state entry is verified by its hash starting from the pivot StateRoot,
+// so a peer can only stall the download, not corrupt the state.
+func (d *Downloader) FastSync() error {
+	if d.stateDB == nil {
+		return errors.New("fast sync is not enabled")
+	}
+	if !atomic.CompareAndSwapInt32(&d.syncing, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&d.syncing, 0)
+
+	local := d.chain.CurrentBlock().Header
+	heads := d.fetchHeads()
+	if len(heads) == 0 || heads[0].header.Height < local.Height+FastSyncPivotDistance {
+		return errNoPivot
+	}
+	height := d.fastChain.PivotHeight(heads[0].header.Height - FastSyncPivotDistance)
+	if height <= local.Height {
+		return errNoPivot
+	}
+	sources := heads[:0]
+	for _, head := range heads {
+		if head.header.Height >= height {
+			sources = append(sources, head)
+		}
+	}
+	logger.Info("[Downloader] fast syncing to pivot", height)
+
+	var pivot *block.Block
+	for pivot == nil && len(sources) > 0 {
+		var err error
+		pivot, err = d.fetchChain(sources[0], local, height)
+		if err != nil {
+			logger.Warn("[Downloader] dropping peer", sources[0].peer.WalletAddress().Hex(), err)
+			sources = sources[1:]
+		}
+	}
+	if pivot == nil {
+		return fmt.Errorf("%w: no peer served the pivot block", errInvalidPivot)
+	}
+
+	peers := make([]p2p.Peer, len(sources))
+	for i, source := range sources {
+		peers[i] = source.peer
+	}
+	if err := d.syncState(pivot.Header.StateRoot, peers); err != nil {
+		return err
+	}
+	if err := d.fastChain.CommitPivot(pivot); err != nil {
+		return err
+	}
+	logger.Info("[Downloader] fast synced to pivot", height, pivot.Hash().Hex())
+	return nil
+}
+
+// fetchChain downloads the blocks from the local head to the pivot height,
+// checking that the headers link up and the bodies match them. The blocks
+// below the pivot are written as they arrive, the pivot is returned.
+func (d *Downloader) fetchChain(
+	source *peerHead,
+	local *block.BlockHeader,
+	height uint64,
+) (*block.Block, error) {
+	parent := local
+	var pivot *block.Block
+	for parent.Height < height {
+		count := height - parent.Height
+		if count > MaxHeaderFetch {
+			count = MaxHeaderFetch
+		}
+		msg, err := d.request(
+			source.peer,
+			message.MessageGetHeaders,
+			&message.HeadersRequest{From: parent.Height + 1, Count: count},
+			message.MessageHeaders,
+		)
+		if err != nil {
+			return nil, err
+		}
+		headers := msg.Payload.(*message.HeadersMessage).Headers
+		if err := verifyChain(parent, headers); err != nil {
+			return nil, err
+		}
+		if uint64(len(headers)) > count {
+			return nil, errInvalidHeaders
+		}
+		blocks, err := d.fetchBodies(source.peer, headers)
+		if err != nil {
+			return nil, err
+		}
+		parent = headers[len(headers)-1]
+		if parent.Height == height {
+			pivot = blocks[len(blocks)-1]
+			blocks = blocks[:len(blocks)-1]
+		}
+		if err := d.fastChain.WriteBlocks(blocks); err != nil {
+			return nil, err
+		}
+	}
+	return pivot, nil
+}
+
+// fetchBodies downloads the bodies of headers, a peer serving part of a batch
+// is asked again for the rest
+func (d *Downloader) fetchBodies(peer p2p.Peer, headers []*block.BlockHeader) ([]*block.Block, error) {
+	blocks := make([]*block.Block, 0, len(headers))
+	for len(blocks) < len(headers) {
+		batch := headers[len(blocks):]
+		if len(batch) > MaxBodyFetch {
+			batch = batch[:MaxBodyFetch]
+		}
+		hashes := make([]common.Hash, len(batch))
+		for i, header := range batch {
+			hashes[i] = header.Hash()
+		}
+		msg, err := d.request(
+			peer,
+			message.MessageGetBodies,
+			&message.BodiesRequest{Hashes: hashes},
+			message.MessageBodies,
+		)
+		if err != nil {
+			return nil, err
+		}
+		bodies := msg.Payload.(*message.BodiesMessage).Bodies
+		if len(bodies) == 0 || len(bodies) > len(batch) {
+			return nil, errInvalidBodies
+		}
+		for i, body := range bodies {
+			bl := &block.Block{
+				Header:       batch[i],
+				Transactions: body.Transactions,
+				Uncles:       body.Uncles,
+			}
+			if err := verifyBody(bl); err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, bl)
+		}
+	}
+	return blocks, nil
+}
+
+// verifyBody checks that a body is the one committed to by its header
+func verifyBody(bl *block.Block) error {
+	if hash := block.CalcUncleHash(bl.Uncles); hash != bl.Header.UncleHash {
+		return fmt.Errorf("%w: block %d uncle root hash mismatch", errInvalidBodies, bl.Header.Height)
+	}
+	hash, err := trie.DeriveSha(bl.Transactions)
+	if err != nil {
+		return err
+	}
+	if hash != bl.Header.TransactionsRoot {
+		return fmt.Errorf("%w: block %d transaction root hash mismatch", errInvalidBodies, bl.Header.Height)
+	}
+	return nil
+}
+
+// stateFetch is a batch of state entries requested from a peer
+type stateFetch struct {
+	peer   p2p.Peer
+	hashes []common.Hash
+	data   [][]byte
+	err    error
+}
+
+// syncState downloads the state at root from the peers. Each round every
+// peer is asked for a batch of the missing entries in parallel. A peer that
+// fails or serves nothing it was asked for is dropped, the entries it did not
+// serve are asked again from the others.
+func (d *Downloader) syncState(root common.Hash, peers []p2p.Peer) error {
+	sched := state.NewStateSync(root, d.stateDB)
+	var retry []common.Hash
+	written := 0
+	for sched.Pending() > 0 {
+		if len(peers) == 0 {
+			return errStalledState
+		}
+		fetches := make([]*stateFetch, 0, len(peers))
+		for _, peer := range peers {
+			count := len(retry)
+			if count > MaxStateFetch {
+				count = MaxStateFetch
+			}
+			hashes := append([]common.Hash{}, retry[:count]...)
+			retry = retry[count:]
+			if count < MaxStateFetch {
+				hashes = append(hashes, sched.Missing(MaxStateFetch-count)...)
+			}
+			if len(hashes) == 0 {
+				break
+			}
+			fetches = append(fetches, &stateFetch{peer: peer, hashes: hashes})
+		}
+		if len(fetches) == 0 {
+			return fmt.Errorf("state sync has %d pending entries but none to fetch", sched.Pending())
+		}
+
+		var wg sync.WaitGroup
+		for _, fetch := range fetches {
+			wg.Add(1)
+			go func(fetch *stateFetch) {
+				defer wg.Done()
+				msg, err := d.request(
+					fetch.peer,
+					message.MessageGetNodeData,
+					&message.NodeDataRequest{Hashes: fetch.hashes},
+					message.MessageNodeData,
+				)
+				if err != nil {
+					fetch.err = err
+					return
+				}
+				fetch.data = msg.Payload.(*message.NodeDataMessage).Data
+			}(fetch)
+		}
+		wg.Wait()
+
+		for _, fetch := range fetches {
+			missing, err, processErr := processState(sched, fetch)
+			if processErr != nil {
+				return processErr
+			}
+			if err == nil && len(missing) == len(fetch.hashes) {
+				err = errors.New("no requested state entry served")
+			}
+			if err != nil {
+				logger.Warn("[Downloader] dropping state peer", fetch.peer.WalletAddress().Hex(), err)
+				peers = removePeer(peers, fetch.peer)
+			}
+			retry = append(retry, missing...)
+		}
+
+		batch := d.stateDB.NewBatch()
+		count, err := sched.Commit(batch)
+		if err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		written += count
+		logger.Debug("[Downloader] state entries written", written, "pending", sched.Pending())
+	}
+
+	// the whole trie must resolve from the pivot root now
+	if _, err := state.New(root, state.NewDatabase(d.stateDB)); err != nil {
+		return fmt.Errorf("unable to open synced state: %w", err)
+	}
+	logger.Info("[Downloader] state synced", root.Hex(), "entries", written)
+	return nil
+}
+
+// processState hands the entries of a fetch to the scheduler, each entry is
+// matched to its request by its hash. It returns the requested hashes that
+// were not served, the error of the peer if it misbehaved and the error of the
+// scheduler if the state itself cannot be synced.
+func processState(sched *trie.TrieSync, fetch *stateFetch) ([]common.Hash, error, error) {
+	requested := make(map[common.Hash]struct{}, len(fetch.hashes))
+	for _, hash := range fetch.hashes {
+		requested[hash] = struct{}{}
+	}
+	if fetch.err != nil {
+		return fetch.hashes, fetch.err, nil
+	}
+	results := make([]trie.SyncResult, 0, len(fetch.data))
+	var err error
+	for _, data := range fetch.data {
+		hash := crypto.Keccak256Hash(data)
+		if _, ok := requested[hash]; !ok {
+			err = errInvalidStates
+			continue
+		}
+		delete(requested, hash)
+		results = append(results, trie.SyncResult{Hash: hash, Data: data})
+	}
+	if _, index, processErr := sched.Process(results); processErr != nil {
+		// the entry hashed right so it is the requested one, it is unusable
+		return nil, nil, fmt.Errorf("state entry %x: %w", results[index].Hash, processErr)
+	}
+	missing := make([]common.Hash, 0, len(requested))
+	for _, hash := range fetch.hashes {
+		if _, ok := requested[hash]; ok {
+			missing = append(missing, hash)
+		}
+	}
+	return missing, err, nil
+}
+
+func removePeer(peers []p2p.Peer, peer p2p.Peer) []p2p.Peer {
+	for i, p := range peers {
+		if p == peer {
+			return append(peers[:i:i], peers[i+1:]...)
+		}
+	}
+	return peers
+}
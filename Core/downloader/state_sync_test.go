@@ -0,0 +1,131 @@
+package downloader
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/p2p"
+	"FichainCore/state"
+	"FichainCore/transaction"
+	"FichainCore/trie"
+)
+
+// testFastChain records the blocks written by the fast sync, pivots close
+// epochs of 32 blocks
+type testFastChain struct {
+	*testLocalChain
+	written   []*block.Block
+	committed *block.Block
+}
+
+func (c *testFastChain) PivotHeight(height uint64) uint64 {
+	return (height+1)/32*32 - 1
+}
+
+func (c *testFastChain) WriteBlocks(blocks []*block.Block) error {
+	c.written = append(c.written, blocks...)
+	return nil
+}
+
+func (c *testFastChain) CommitPivot(pivot *block.Block) error {
+	c.committed = pivot
+	return nil
+}
+
+func newTestState(t *testing.T) (state.Database, common.Hash) {
+	diskdb, _ := database.NewMemDatabase()
+	db := state.NewDatabase(diskdb)
+	st, err := state.New(common.Hash{}, db)
+	assert.NoError(t, err)
+	for i := int64(1); i <= 200; i++ {
+		address := common.BigToAddress(big.NewInt(i))
+		st.SetBalance(address, big.NewInt(i*1000))
+		if i%10 == 0 {
+			st.SetCode(address, []byte{byte(i), 0x60, 0x00})
+			for j := int64(0); j < 20; j++ {
+				st.SetState(address, common.BigToHash(big.NewInt(j)), common.BigToHash(big.NewInt(i*j+1)))
+			}
+		}
+	}
+	root, err := st.Commit(false)
+	assert.NoError(t, err)
+	return db, root
+}
+
+func newStateChain(length int, root common.Hash) []*block.Block {
+	txRoot, _ := trie.DeriveSha([]*transaction.Transaction(nil))
+	blocks := []*block.Block{{Header: &block.BlockHeader{}}}
+	for i := 1; i < length; i++ {
+		blocks = append(blocks, &block.Block{Header: &block.BlockHeader{
+			Height:           uint64(i),
+			ParentHash:       blocks[i-1].Hash(),
+			StateRoot:        root,
+			TransactionsRoot: txRoot,
+			UncleHash:        block.CalcUncleHash(nil),
+			Timestamp:        uint64(i),
+		}})
+	}
+	return blocks
+}
+
+func TestFastSync(t *testing.T) {
+	source, root := newTestState(t)
+	remote := newStateChain(200, root)
+	local := &testFastChain{testLocalChain: &testLocalChain{blocks: remote[:1]}}
+
+	network := &testNetwork{peers: map[common.Address]p2p.Peer{}}
+	for i, peer := range []*testPeer{
+		{blocks: remote, state: source, garbage: true},
+		{blocks: remote, state: source},
+		{blocks: remote[:100], state: source},
+	} {
+		peer.address = common.BigToAddress(big.NewInt(int64(i + 1)))
+		network.peers[peer.address] = peer
+	}
+	downloader := NewDownloader(local, local, network, network, 100*time.Millisecond)
+
+	// fast sync is opt in
+	assert.Error(t, downloader.FastSync())
+
+	db, _ := database.NewMemDatabase()
+	downloader.EnableFastSync(local, db)
+	assert.NoError(t, downloader.FastSync())
+	// the pivot is the last block of the epoch below the highest head less
+	// the pivot distance, every block below it is stored
+	assert.Equal(t, remote[127].Hash(), local.committed.Hash())
+	assert.Equal(t, remote[1:127], local.written)
+
+	st, err := state.New(root, state.NewDatabase(db))
+	assert.NoError(t, err)
+	for i := int64(1); i <= 200; i++ {
+		address := common.BigToAddress(big.NewInt(i))
+		assert.Equal(t, big.NewInt(i*1000), st.GetBalance(address))
+		if i%10 == 0 {
+			assert.Equal(t, []byte{byte(i), 0x60, 0x00}, st.GetCode(address))
+			assert.Equal(
+				t,
+				common.BigToHash(big.NewInt(i*19+1)),
+				st.GetState(address, common.BigToHash(big.NewInt(19))),
+			)
+		}
+	}
+}
+
+func TestFastSyncNoPivot(t *testing.T) {
+	remote := newStateChain(50, common.Hash{})
+	local := &testFastChain{testLocalChain: &testLocalChain{blocks: remote[:1]}}
+	network := &testNetwork{peers: map[common.Address]p2p.Peer{}}
+	peer := &testPeer{address: common.BigToAddress(common.Big1), blocks: remote}
+	network.peers[peer.address] = peer
+	downloader := NewDownloader(local, local, network, network, 100*time.Millisecond)
+
+	db, _ := database.NewMemDatabase()
+	downloader.EnableFastSync(local, db)
+	assert.Equal(t, errNoPivot, downloader.FastSync())
+}
@@ -78,4 +78,43 @@ var (
 	// ErrInvalidNumber is returned if a block's number doesn't equal it's parent's
 	// plus one.
 	ErrInvalidNumber = errors.New("invalid block number")
+
+	// ErrInvalidTimestamp is returned if a block's timestamp is lower than
+	// its parent's.
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+	// ErrInvalidGasUsed is returned if a block uses more gas than the block
+	// gas limit allows.
+	ErrInvalidGasUsed = errors.New("invalid gas used")
+
+	// ErrUnclesNotAllowed is returned if a block carries uncles, which have
+	// no meaning in proof-of-authority.
+	ErrUnclesNotAllowed = errors.New("uncles not allowed")
+
+	// ErrMissingSignature is returned if a block's header is not signed.
+	ErrMissingSignature = errors.New("missing block signature")
+
+	// ErrUnauthorizedProposer is returned if a header is signed by an address
+	// other than the proposer scheduled for its height.
+	ErrUnauthorizedProposer = errors.New("unauthorized proposer")
+
+	// ErrNoAuthorizedSigner is returned when sealing is attempted on an engine
+	// that has no local signer.
+	ErrNoAuthorizedSigner = errors.New("no authorized signer")
+
+	// ErrPrematureRound is returned if a block is sealed by a backup proposer
+	// before the proposer timeout of its round has passed.
+	ErrPrematureRound = errors.New("proposer round timeout not passed")
+
+	// ErrInvalidEvidence is returned if double sign evidence does not hold two
+	// different headers of the same height and round.
+	ErrInvalidEvidence = errors.New("invalid double sign evidence")
+
+	// ErrExpiredEvidence is returned if double sign evidence is older than the
+	// unbonding period, the offender's stake may already be withdrawn.
+	ErrExpiredEvidence = errors.New("expired double sign evidence")
+
+	// ErrSupplyExceedsReserve is returned if the native supply in state is not
+	// fully backed by the fiat reserves of the banks.
+	ErrSupplyExceedsReserve = errors.New("native supply exceeds fiat reserves")
 )
@@ -2,6 +2,7 @@ package genesis
 
 import (
 	"fmt"
+	"math/big"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
 
@@ -25,6 +26,22 @@ type GenesisFiatReserve struct {
 	Balances map[common.Address]*math.HexOrDecimal256 `json:"balances"`
 }
 
+// GenesisReward is the fee sharing policy of the chain, nil keeps the
+// protocol defaults
+type GenesisReward struct {
+	Treasury        common.Address `json:"treasury"`         // consortium treasury, also receives the rounding dust
+	TreasuryPercent uint64         `json:"treasury_percent"` // share of the fees sent to the treasury
+	ProposerPercent uint64         `json:"proposer_percent"` // share of the fees kept by the block proposer
+}
+
+func (r *GenesisReward) policy() poa_consensus.RewardPolicy {
+	return poa_consensus.RewardPolicy{
+		Treasury:        r.Treasury,
+		TreasuryPercent: r.TreasuryPercent,
+		ProposerPercent: r.ProposerPercent,
+	}
+}
+
 type GenesisAccount struct {
 	Code       hexutil.Bytes               `json:"code,omitempty"`
 	Storage    map[common.Hash]common.Hash `json:"storage,omitempty"`
@@ -49,6 +66,13 @@ type Genesis struct {
 
 	FiatReserve GenesisFiatReserve `json:"fiat_reserve"`
 	Authority   GenesisAuthority   `json:"authority"`
+	Reward      *GenesisReward     `json:"reward,omitempty"`
+
+	// ReserveBacked records the fiat reserves and the allocated supply in the
+	// genesis state so the allocation counts as backed. It changes the genesis
+	// root, networks created before reserve-backed issuance leave it unset to
+	// keep their genesis hash and back only the VND issued afterwards.
+	ReserveBacked bool `json:"reserve_backed,omitempty"`
 }
 
 func (g *Genesis) ToBlock(db database.Database) *block.Block {
@@ -56,15 +80,28 @@ func (g *Genesis) ToBlock(db database.Database) *block.Block {
 		db, _ = database.NewMemDatabase()
 	}
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	supply := new(big.Int)
 	for addr, account := range g.Alloc {
 		address := common.Address(addr)
 		statedb.AddBalance(address, account.Balance.ToBig())
+		supply.Add(supply, account.Balance.ToBig())
 		statedb.SetCode(address, account.Code)
 		statedb.SetNonce(address, uint64(account.Nonce))
 		for key, value := range account.Storage {
 			statedb.SetState(address, key, value)
 		}
 	}
+	if g.ReserveBacked {
+		reserves := make(map[common.Address]*big.Int, len(g.FiatReserve.Balances))
+		for bank, balance := range g.FiatReserve.Balances {
+			reserves[bank] = balance.ToBig()
+		}
+		poa_consensus.WriteGenesisReserves(statedb, reserves, supply)
+	}
+	if g.Reward != nil {
+		// validated by Commit
+		_ = poa_consensus.WriteRewardPolicy(statedb, g.Reward.policy())
+	}
 	root := statedb.IntermediateRoot(true)
 	head := &block.BlockHeader{
 		Height:     uint64(g.Number),
@@ -97,6 +134,11 @@ func (g *Genesis) MustCommit(db database.Database) *block.Block {
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db database.Database) (*block.Block, error) {
+	if g.Reward != nil {
+		if err := g.Reward.policy().Validate(); err != nil {
+			return nil, err
+		}
+	}
 	block := g.ToBlock(db)
 	logger.DebugP("Commiting block", block.Header.Hash().String())
 	if block.Header.Height != 0 {
@@ -1,12 +1,347 @@
 package handlers
 
 import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/block"
+	"FichainCore/block_validator"
+	"FichainCore/common"
+	"FichainCore/consensus"
+	"FichainCore/consensus/poa_consensus/voting"
+	"FichainCore/database"
+	"FichainCore/downloader"
+	"FichainCore/errors"
 	"FichainCore/p2p"
+	"FichainCore/p2p/lookup_table"
 	"FichainCore/p2p/message"
+	"FichainCore/p2p/message_sender"
+	"FichainCore/state"
 )
 
-type BlockHandler struct{}
+// seenBlocksLimit is the number of block hashes remembered to stop a gossiped
+// block from being imported or relayed twice
+const seenBlocksLimit = 1024
+
+// BlockHandler propagates blocks between nodes. The proposer broadcasts every
+// block it seals to the connected nodes and explorers, clients never get raw
+// blocks. A received block is verified by the consensus engine and inserted
+// into the chain, which re-executes it on its parent state and only imports
+// it when the resulting state and receipt roots match its header, then
+// relayed to the peers that may not have it yet.
+type BlockHandler struct {
+	bc            BlockImporter
+	engine        consensus.Engine
+	validator     *block_validator.BlockValidator
+	stateDatabase state.Database
+	database      database.Database
+	evidence      *EvidenceHandler
+	node          Node
+	lookupTable   *lookup_table.LookupTable
+	sender        *message_sender.MessageSender
+	access        Access
+
+	seen      map[common.Hash]struct{}
+	seenOrder []common.Hash
+	seenMu    sync.Mutex
+	importMu  sync.Mutex
+}
+
+func NewBlockHandler(
+	blockchain BlockImporter,
+	engine consensus.Engine,
+	stateDatabase state.Database,
+	database database.Database,
+	evidence *EvidenceHandler,
+	node Node,
+	lookupTable *lookup_table.LookupTable,
+	sender *message_sender.MessageSender,
+	access Access,
+) *BlockHandler {
+	return &BlockHandler{
+		bc:            blockchain,
+		engine:        engine,
+		validator:     block_validator.NewBlockValidator(blockchain.Config(), blockchain, engine),
+		stateDatabase: stateDatabase,
+		database:      database,
+		evidence:      evidence,
+		node:          node,
+		lookupTable:   lookupTable,
+		sender:        sender,
+		access:        access,
+		seen:          make(map[common.Hash]struct{}),
+	}
+}
 
 func (h *BlockHandler) Handlers() map[string]func(p2p.Peer, *message.Message) error {
-	return map[string]func(p2p.Peer, *message.Message) error{}
+	return map[string]func(p2p.Peer, *message.Message) error{
+		message.MessageBlock:        h.Block,
+		message.MessageGetBlock:     h.GetBlock,
+		message.MessageGetHeadBlock: h.GetHeadBlock,
+		message.MessageGetHeaders:   h.GetHeaders,
+		message.MessageGetBodies:    h.GetBodies,
+		message.MessageGetNodeData:  h.GetNodeData,
+	}
+}
+
+// BroadcastBlock sends a block sealed by this node to the connected peers
+// that may see every account
+func (h *BlockHandler) BroadcastBlock(bl *block.Block) {
+	h.markSeen(bl.Hash())
+	go h.sender.BroadcastMessage(
+		h.peersExcept(common.Address{}),
+		message.MessageBlock,
+		&message.BlockMessage{Block: bl},
+	)
+}
+
+// Block imports a block received from a peer and relays it
+func (h *BlockHandler) Block(peer p2p.Peer, msg *message.Message) error {
+	payload, ok := msg.Payload.(*message.BlockMessage)
+	if !ok {
+		return fmt.Errorf("%w: not a block", p2p.ErrInvalidPayload)
+	}
+	bl := payload.Block
+	if !h.markSeen(bl.Hash()) {
+		return nil
+	}
+	logger.Debug("[BlockHandler] receive block", bl.Header.Height, bl.Hash().Hex())
+
+	if err := h.ImportBlock(bl); err != nil {
+		if err == errors.ErrKnownBlock {
+			return nil
+		}
+		if err == errors.ErrUnknownAncestor {
+			// accepted again once the chain caught up, the peer is only ahead
+			h.forgetSeen(bl.Hash())
+			logger.Debug("[BlockHandler] block with unknown parent", bl.Header.Height, bl.Hash().Hex())
+			return nil
+		}
+		logger.Warn("[BlockHandler] rejected block", bl.Header.Height, bl.Hash().Hex(), err)
+		return err
+	}
+	go h.sender.BroadcastMessage(
+		h.peersExcept(peer.WalletAddress()),
+		message.MessageBlock,
+		payload,
+	)
+	return nil
+}
+
+// ImportBlock verifies a block against the local chain and inserts it. Blocks
+// not descending from the last finalized block are refused. The header and
+// body are checked first so a bad block is rejected before it is executed,
+// InsertChain executes it once and checks the resulting roots.
+func (h *BlockHandler) ImportBlock(bl *block.Block) error {
+	h.importMu.Lock()
+	defer h.importMu.Unlock()
+
+	header := bl.Header
+	if header.Height == 0 {
+		return errors.ErrInvalidNumber
+	}
+	// a block stored without state by an interrupted fast sync is imported
+	// again
+	if h.bc.HasBlockAndState(bl.Hash(), header.Height) {
+		return errors.ErrKnownBlock
+	}
+	parent := h.bc.GetBlock(header.ParentHash, header.Height-1)
+	if parent == nil {
+		return errors.ErrUnknownAncestor
+	}
+	// a branch leaving the chain below the last finalized block would revert
+	// it once heavier
+	if err := voting.CheckFinality(h.database, h.bc, header); err != nil {
+		return err
+	}
+	if err := h.engine.VerifyHeader(h.bc, header, true); err != nil {
+		return err
+	}
+	// a proposer signing two blocks for the same slot is reported even if
+	// this one loses
+	if h.evidence != nil {
+		if err := h.evidence.CheckHeader(header); err != nil {
+			logger.Warn("[BlockHandler] error when check header", err)
+		}
+	}
+	if err := h.validator.ValidateBody(bl); err != nil {
+		return err
+	}
+	if _, err := h.bc.InsertChain([]*block.Block{bl}); err != nil {
+		return err
+	}
+	logger.Info("[BlockHandler] imported block", header.Height, bl.Hash().Hex())
+	return nil
+}
+
+// GetBlock returns a canonical block by its big endian uint64 height or by
+// its hash
+func (h *BlockHandler) GetBlock(peer p2p.Peer, msg *message.Message) error {
+	data := msg.Payload.(*message.BytesMessage).Data
+	var bl *block.Block
+	switch len(data) {
+	case 8:
+		header := h.bc.GetHeaderByNumber(binary.BigEndian.Uint64(data))
+		if header != nil {
+			bl = h.bc.GetBlock(header.Hash(), header.Height)
+		}
+	case common.HashLength:
+		header := h.bc.GetHeaderByHash(common.BytesToHash(data))
+		if header != nil {
+			bl = h.bc.GetBlock(header.Hash(), header.Height)
+		}
+	default:
+		return fmt.Errorf("invalid block request")
+	}
+	if bl == nil {
+		return fmt.Errorf("block not found")
+	}
+	err := h.sender.ReplyToPeer(
+		peer,
+		msg,
+		message.MessageBlock,
+		&message.BlockMessage{Block: bl},
+	)
+	if err != nil {
+		logger.Warn("error when send block to peer", err)
+	}
+	return err
+}
+
+// GetHeadBlock returns the header of the current head
+func (h *BlockHandler) GetHeadBlock(peer p2p.Peer, msg *message.Message) error {
+	return h.sender.ReplyToPeer(
+		peer,
+		msg,
+		message.MessageHeadBlock,
+		&message.HeaderMessage{Header: h.bc.CurrentBlock().Header},
+	)
+}
+
+// GetHeaders returns up to downloader.MaxHeaderFetch consecutive canonical
+// headers, stopping at the head
+func (h *BlockHandler) GetHeaders(peer p2p.Peer, msg *message.Message) error {
+	request, ok := msg.Payload.(*message.HeadersRequest)
+	if !ok {
+		return fmt.Errorf("invalid headers request")
+	}
+	count := request.Count
+	if count > downloader.MaxHeaderFetch {
+		count = downloader.MaxHeaderFetch
+	}
+	headers := make([]*block.BlockHeader, 0, count)
+	for number := request.From; number < request.From+count; number++ {
+		header := h.bc.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		headers = append(headers, header)
+	}
+	return h.sender.ReplyToPeer(
+		peer,
+		msg,
+		message.MessageHeaders,
+		&message.HeadersMessage{Headers: headers},
+	)
+}
+
+// GetBodies returns the bodies of the requested blocks in order, stopping at
+// the first unknown block
+func (h *BlockHandler) GetBodies(peer p2p.Peer, msg *message.Message) error {
+	request, ok := msg.Payload.(*message.BodiesRequest)
+	if !ok {
+		return fmt.Errorf("invalid bodies request")
+	}
+	hashes := request.Hashes
+	if len(hashes) > downloader.MaxBodyFetch {
+		hashes = hashes[:downloader.MaxBodyFetch]
+	}
+	bodies := make([]*block.Body, 0, len(hashes))
+	for _, hash := range hashes {
+		header := h.bc.GetHeaderByHash(hash)
+		if header == nil {
+			break
+		}
+		bl := h.bc.GetBlock(hash, header.Height)
+		if bl == nil {
+			break
+		}
+		bodies = append(bodies, bl.Body())
+	}
+	return h.sender.ReplyToPeer(
+		peer,
+		msg,
+		message.MessageBodies,
+		&message.BodiesMessage{Bodies: bodies},
+	)
+}
+
+// GetNodeData returns up to downloader.MaxStateFetch state trie nodes or
+// contract codes by hash, unknown hashes are skipped
+func (h *BlockHandler) GetNodeData(peer p2p.Peer, msg *message.Message) error {
+	request, ok := msg.Payload.(*message.NodeDataRequest)
+	if !ok {
+		return fmt.Errorf("invalid node data request")
+	}
+	hashes := request.Hashes
+	if len(hashes) > downloader.MaxStateFetch {
+		hashes = hashes[:downloader.MaxStateFetch]
+	}
+	data := make([][]byte, 0, len(hashes))
+	for _, hash := range hashes {
+		entry, err := h.stateDatabase.TrieDB().Node(hash)
+		if err != nil || len(entry) == 0 {
+			continue
+		}
+		data = append(data, entry)
+	}
+	return h.sender.ReplyToPeer(
+		peer,
+		msg,
+		message.MessageNodeData,
+		&message.NodeDataMessage{Data: data},
+	)
+}
+
+// markSeen remembers a block hash, it returns false if it was already seen
+func (h *BlockHandler) markSeen(hash common.Hash) bool {
+	h.seenMu.Lock()
+	defer h.seenMu.Unlock()
+	if _, ok := h.seen[hash]; ok {
+		return false
+	}
+	h.seen[hash] = struct{}{}
+	h.seenOrder = append(h.seenOrder, hash)
+	if len(h.seenOrder) > seenBlocksLimit {
+		delete(h.seen, h.seenOrder[0])
+		h.seenOrder = h.seenOrder[1:]
+	}
+	return true
+}
+
+func (h *BlockHandler) forgetSeen(hash common.Hash) {
+	h.seenMu.Lock()
+	defer h.seenMu.Unlock()
+	delete(h.seen, hash)
+}
+
+// peersExcept lists the connected peers that may see every account, other
+// than this node and the given address
+func (h *BlockHandler) peersExcept(except common.Address) []common.Address {
+	self := h.node.Address()
+	peers := h.lookupTable.All()
+	addresses := make([]common.Address, 0, len(peers))
+	for address, peer := range peers {
+		if address == self || address == except {
+			continue
+		}
+		if !h.access.CanAccess(peer) {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
 }
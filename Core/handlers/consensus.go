@@ -1,3 +1,361 @@
 package handlers
 
-type ConsensusHandler struct{}
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/block"
+	"FichainCore/block_chain"
+	"FichainCore/common"
+	"FichainCore/consensus/poa_consensus"
+	"FichainCore/consensus/poa_consensus/voting"
+	"FichainCore/database"
+	"FichainCore/event"
+	"FichainCore/p2p"
+	"FichainCore/p2p/lookup_table"
+	"FichainCore/p2p/message"
+	"FichainCore/p2p/message_sender"
+	"FichainCore/signer"
+)
+
+const (
+	maxFutureVotes         = 1024 // votes kept for blocks above the chain head
+	maxFutureVotesPerVoter = 16   // of them from a single validator
+
+	certificateFetchInterval  = 16 // blocks a node may lag behind finality before it asks for a certificate
+	certificateRequestTimeout = 5 * time.Second
+)
+
+// ConsensusHandler runs the finality gadget on top of POA block production.
+// Every validator signs a pre-commit vote for each imported block and gossips
+// it to the other validators, once more than 2/3 of the weight snapshotted for
+// the epoch voted for a block its commit certificate is persisted and the
+// block is final. A validator signs at most one block per height, its last
+// vote is persisted before it is sent. Nodes missing the votes, like
+// non-validators and syncing nodes, fetch the certificates from the validators
+// instead. The block handler refuses blocks forking below the last
+// finalized one.
+type ConsensusHandler struct {
+	epochs        *poa_consensus.EpochManager
+	votingManager *voting.WeightedVotingManager
+	signer        *signer.Signer
+	database      database.Database
+	node          Node
+	lookupTable   *lookup_table.LookupTable
+	sender        *message_sender.MessageSender
+	chain         voting.HeaderReader
+
+	head     uint64        // height of the latest imported block
+	future   []voting.Vote // votes for blocks above the head, submitted once it is imported
+	mu       sync.Mutex
+	fetching int32 // a certificate request is running
+
+	chainEvent             chan event.ChainEvent
+	chainEventSubscription event.Subscription
+}
+
+func NewConsensusHandler(
+	epochs *poa_consensus.EpochManager,
+	signer *signer.Signer,
+	database database.Database,
+	node Node,
+	lookupTable *lookup_table.LookupTable,
+	sender *message_sender.MessageSender,
+) *ConsensusHandler {
+	return &ConsensusHandler{
+		epochs:        epochs,
+		votingManager: voting.NewEpochVotingManager(epochs),
+		signer:        signer,
+		database:      database,
+		node:          node,
+		lookupTable:   lookupTable,
+		sender:        sender,
+		chainEvent:    make(chan event.ChainEvent),
+	}
+}
+
+func (h *ConsensusHandler) Handlers() map[string]func(p2p.Peer, *message.Message) error {
+	return map[string]func(p2p.Peer, *message.Message) error{
+		message.MessageVote:                 h.Vote,
+		message.MessageGetCommitCertificate: h.GetCommitCertificate,
+	}
+}
+
+func (h *ConsensusHandler) SubscribeChanEvent(bc *block_chain.BlockChain) {
+	h.chain = bc
+	if current := bc.CurrentBlock(); current != nil {
+		h.mu.Lock()
+		h.head = current.Header.Height
+		h.mu.Unlock()
+	}
+	h.chainEventSubscription = bc.SubscribeChainEvent(h.chainEvent)
+	go h.HandleChanEvent()
+}
+
+// HandleChanEvent votes for every new block if this node is a validator of
+// its epoch, then submits the votes that were waiting for the block. A node
+// lagging behind finality asks the validators for their last certificate.
+func (h *ConsensusHandler) HandleChanEvent() {
+	for {
+		event := <-h.chainEvent
+		height := event.Block.Header.Height
+		h.vote(event.Block)
+		h.submitFutureVotes(height)
+		if height%certificateFetchInterval == 0 &&
+			height >= voting.ReadLastFinalizedHeight(h.database)+certificateFetchInterval {
+			go h.fetchCommitCertificate(height)
+		}
+	}
+}
+
+func (h *ConsensusHandler) vote(bl *block.Block) {
+	header := bl.Header
+	validators, err := h.epochs.ValidatorsAt(header.Height)
+	if err != nil {
+		logger.Error("[ConsensusHandler] error when get validators", header.Height, err)
+		return
+	}
+	if _, ok := validators[h.node.Address()]; !ok {
+		return
+	}
+	if last := voting.ReadLastVote(h.database); last != nil && header.Height <= last.Height {
+		logger.Warn(
+			"[ConsensusHandler] already voted", last.Height, last.BlockHash.Hex(),
+			"not voting", header.Height, bl.Hash().Hex(),
+		)
+		return
+	}
+	vote, err := voting.NewVote(header.Height, bl.Hash(), h.signer)
+	if err != nil {
+		logger.Error("[ConsensusHandler] error when sign vote", err)
+		return
+	}
+	err = voting.WriteLastVote(h.database, &voting.LastVote{
+		Height:    header.Height,
+		Round:     header.Round,
+		BlockHash: vote.BlockHash,
+	})
+	if err != nil {
+		logger.Error("[ConsensusHandler] error when persist vote", err)
+		return
+	}
+	if err := h.submitVote(*vote); err != nil {
+		logger.Error("[ConsensusHandler] error when submit own vote", err)
+		return
+	}
+	go h.sender.BroadcastMessage(
+		h.otherValidators(validators),
+		message.MessageVote,
+		vote,
+	)
+}
+
+func (h *ConsensusHandler) Vote(peer p2p.Peer, msg *message.Message) error {
+	vote, ok := msg.Payload.(*voting.Vote)
+	if !ok {
+		return fmt.Errorf("%w: not a vote", p2p.ErrInvalidPayload)
+	}
+	logger.Debug("[ConsensusHandler] receive vote", vote.Height, vote.Voter.Hex())
+	h.mu.Lock()
+	head := h.head
+	h.mu.Unlock()
+	if vote.Height <= head {
+		return h.submitVote(*vote)
+	}
+
+	// the validators of its epoch may not be known yet, the voter must be a
+	// validator at the head and its votes only take a share of the buffer
+	voter, err := vote.RecoverVoter()
+	if err != nil || voter != vote.Voter {
+		return fmt.Errorf("%w: vote not signed by %s", p2p.ErrInvalidPayload, vote.Voter.Hex())
+	}
+	validators, err := h.epochs.ValidatorsAt(vote.Height)
+	if err != nil {
+		if validators, err = h.epochs.ValidatorsAt(head); err != nil {
+			return err
+		}
+	}
+	if _, ok := validators[voter]; !ok {
+		return fmt.Errorf("voter is not a validator: %s", voter.Hex())
+	}
+
+	h.mu.Lock()
+	if vote.Height <= h.head {
+		// imported meanwhile
+		h.mu.Unlock()
+		return h.submitVote(*vote)
+	}
+	defer h.mu.Unlock()
+	fromVoter := 0
+	for _, buffered := range h.future {
+		if buffered.Voter == voter {
+			fromVoter++
+		}
+	}
+	if len(h.future) >= maxFutureVotes || fromVoter >= maxFutureVotesPerVoter {
+		logger.Debug("[ConsensusHandler] dropping future vote", vote.Height, voter.Hex())
+		return nil
+	}
+	h.future = append(h.future, *vote)
+	return nil
+}
+
+// GetCommitCertificate returns the commit certificate of the block whose hash
+// is given, or the last one when no hash is given
+func (h *ConsensusHandler) GetCommitCertificate(peer p2p.Peer, msg *message.Message) error {
+	payload, ok := msg.Payload.(*message.BytesMessage)
+	if !ok {
+		return fmt.Errorf("%w: not a block hash", p2p.ErrInvalidPayload)
+	}
+	hash := voting.ReadLastFinalizedHash(h.database)
+	if len(payload.Data) != 0 {
+		if len(payload.Data) != common.HashLength {
+			return fmt.Errorf("%w: invalid block hash", p2p.ErrInvalidPayload)
+		}
+		hash = common.BytesToHash(payload.Data)
+	}
+	cert := voting.ReadCommitCertificate(h.database, hash)
+	if cert == nil {
+		return fmt.Errorf("no commit certificate for %s", hash.Hex())
+	}
+	err := h.sender.ReplyToPeer(peer, msg, message.MessageCommitCertificate, cert)
+	if err != nil {
+		logger.Warn("error when send commit certificate to peer", err)
+	}
+	return err
+}
+
+// fetchCommitCertificate asks the connected validators of the given height
+// for their last commit certificate, until one finalizes a block of the local
+// chain above the last finalized one
+func (h *ConsensusHandler) fetchCommitCertificate(height uint64) {
+	if !atomic.CompareAndSwapInt32(&h.fetching, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&h.fetching, 0)
+
+	validators, err := h.epochs.ValidatorsAt(height)
+	if err != nil {
+		logger.Error("[ConsensusHandler] error when get validators", height, err)
+		return
+	}
+	peers := h.lookupTable.All()
+	for addr := range validators {
+		peer, ok := peers[addr]
+		if !ok || addr == h.node.Address() {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), certificateRequestTimeout)
+		msg, err := h.sender.Request(ctx, peer, message.MessageGetCommitCertificate, &message.BytesMessage{})
+		cancel()
+		if err != nil {
+			logger.Debug("[ConsensusHandler] commit certificate request failed", addr.Hex(), err)
+			continue
+		}
+		cert, ok := msg.Payload.(*voting.CommitCertificate)
+		if !ok {
+			continue
+		}
+		if err := h.storeCommitCertificate(cert); err != nil {
+			logger.Warn("[ConsensusHandler] rejected commit certificate from", addr.Hex(), err)
+			continue
+		}
+		return
+	}
+}
+
+// storeCommitCertificate stores a certificate received from a peer once
+// verified against the validators of its height, if it finalizes a block of
+// the local chain above the last finalized one
+func (h *ConsensusHandler) storeCommitCertificate(cert *voting.CommitCertificate) error {
+	if cert.Height <= voting.ReadLastFinalizedHeight(h.database) {
+		return fmt.Errorf("certificate of block %d is not above the last finalized block", cert.Height)
+	}
+	header := h.chain.GetHeaderByNumber(cert.Height)
+	if header == nil || header.Hash() != cert.BlockHash {
+		return fmt.Errorf("certified block %d %s is not on the local chain", cert.Height, cert.BlockHash.Hex())
+	}
+	if err := cert.VerifyAt(h.epochs); err != nil {
+		return err
+	}
+	if err := voting.WriteCommitCertificate(h.database, cert); err != nil {
+		return err
+	}
+	h.votingManager.Prune(cert.Height)
+	logger.Info("[ConsensusHandler] block finalized by fetched certificate", cert.Height, cert.BlockHash.Hex())
+	return nil
+}
+
+// submitFutureVotes moves the head and submits the votes for blocks up to it
+func (h *ConsensusHandler) submitFutureVotes(height uint64) {
+	h.mu.Lock()
+	if height > h.head {
+		h.head = height
+	}
+	var ready []voting.Vote
+	waiting := h.future[:0]
+	for _, vote := range h.future {
+		if vote.Height <= h.head {
+			ready = append(ready, vote)
+		} else {
+			waiting = append(waiting, vote)
+		}
+	}
+	h.future = waiting
+	h.mu.Unlock()
+
+	for _, vote := range ready {
+		if err := h.submitVote(vote); err != nil {
+			logger.Warn("[ConsensusHandler] error when submit vote", vote.Height, vote.Voter.Hex(), err)
+		}
+	}
+}
+
+// submitVote records the vote and writes the commit certificate when the
+// voted block reaches quorum for the first time
+func (h *ConsensusHandler) submitVote(vote voting.Vote) error {
+	if vote.Height < voting.ReadLastFinalizedHeight(h.database) ||
+		voting.ReadCommitCertificate(h.database, vote.BlockHash) != nil {
+		// stale or already finalized
+		return nil
+	}
+	if err := h.votingManager.SubmitVote(vote); err != nil {
+		return err
+	}
+	reached, err := h.votingManager.HasReachedQuorum(vote.BlockHash)
+	if err != nil || !reached {
+		return err
+	}
+	if voting.ReadCommitCertificate(h.database, vote.BlockHash) != nil {
+		return nil
+	}
+
+	votes, err := h.votingManager.GetVotes(vote.BlockHash)
+	if err != nil {
+		return err
+	}
+	cert := voting.NewCommitCertificate(vote.Height, vote.BlockHash, votes)
+	if err := voting.WriteCommitCertificate(h.database, cert); err != nil {
+		return err
+	}
+	h.votingManager.Prune(vote.Height)
+	logger.Info("[ConsensusHandler] block finalized", vote.Height, vote.BlockHash.Hex())
+	return nil
+}
+
+func (h *ConsensusHandler) otherValidators(validators map[common.Address]*big.Int) []common.Address {
+	self := h.node.Address()
+	addrs := make([]common.Address, 0, len(validators))
+	for addr := range validators {
+		if addr != self {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/block"
+	"FichainCore/block_chain"
+	"FichainCore/common"
+	"FichainCore/consensus"
+	"FichainCore/consensus/poa_consensus"
+	"FichainCore/event"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+	"FichainCore/p2p/message_sender"
+	"FichainCore/params"
+	"FichainCore/signer"
+	"FichainCore/state"
+	"FichainCore/state_processor"
+	"FichainCore/transaction"
+)
+
+// evidenceHeaderWindow is the number of heights signed headers are kept to
+// detect a proposer signing a conflicting one
+const evidenceHeaderWindow = 1024
+
+type signedSlot struct {
+	height uint64
+	round  uint64
+}
+
+// EvidenceHandler detects proposers signing two different headers for the
+// same slot and gossips the evidence to the validators. Every validator that
+// learns new evidence reports it in a transaction to params.SlashingAddress so
+// it is included in a block and slashed.
+type EvidenceHandler struct {
+	slasher       *poa_consensus.Slasher
+	authority     *poa_consensus.Authority
+	engine        consensus.Engine
+	signer        *signer.Signer
+	node          Node
+	bc            Blockchain
+	stateDatabase state.Database
+	sender        *message_sender.MessageSender
+	txHandler     *TransactionHandler
+
+	headers map[signedSlot]*block.BlockHeader
+	seen    map[common.Hash]struct{}
+	mu      sync.Mutex
+
+	chainEvent             chan event.ChainEvent
+	chainEventSubscription event.Subscription
+}
+
+func NewEvidenceHandler(
+	slasher *poa_consensus.Slasher,
+	authority *poa_consensus.Authority,
+	engine consensus.Engine,
+	signer *signer.Signer,
+	node Node,
+	blockchain Blockchain,
+	stateDatabase state.Database,
+	sender *message_sender.MessageSender,
+	txHandler *TransactionHandler,
+) *EvidenceHandler {
+	return &EvidenceHandler{
+		slasher:       slasher,
+		authority:     authority,
+		engine:        engine,
+		signer:        signer,
+		node:          node,
+		bc:            blockchain,
+		stateDatabase: stateDatabase,
+		sender:        sender,
+		txHandler:     txHandler,
+		headers:       make(map[signedSlot]*block.BlockHeader),
+		seen:          make(map[common.Hash]struct{}),
+		chainEvent:    make(chan event.ChainEvent),
+	}
+}
+
+func (h *EvidenceHandler) Handlers() map[string]func(p2p.Peer, *message.Message) error {
+	return map[string]func(p2p.Peer, *message.Message) error{
+		message.MessageEvidence: h.Evidence,
+	}
+}
+
+func (h *EvidenceHandler) SubscribeChanEvent(bc *block_chain.BlockChain) {
+	h.chainEventSubscription = bc.SubscribeChainEvent(h.chainEvent)
+	go h.HandleChanEvent()
+}
+
+// HandleChanEvent records the headers of imported blocks so a conflicting
+// header received later is detected
+func (h *EvidenceHandler) HandleChanEvent() {
+	for {
+		event := <-h.chainEvent
+		if err := h.CheckHeader(event.Block.Header); err != nil {
+			logger.Warn("[EvidenceHandler] error when check header", err)
+		}
+	}
+}
+
+func (h *EvidenceHandler) Evidence(peer p2p.Peer, msg *message.Message) error {
+	evidence, ok := msg.Payload.(*poa_consensus.DoubleSignEvidence)
+	if !ok {
+		return errors.New("invalid evidence payload")
+	}
+	logger.Debug("[EvidenceHandler] receive evidence", evidence.Height(), evidence.Offender().Hex())
+	return h.submitEvidence(evidence)
+}
+
+// CheckHeader records a signed header and raises evidence when its proposer
+// already signed a different header for the same height and round
+func (h *EvidenceHandler) CheckHeader(header *block.BlockHeader) error {
+	signer, err := h.engine.Author(header)
+	if err != nil || signer != header.Proposer {
+		// only headers signed by their proposer can prove anything
+		return err
+	}
+
+	h.mu.Lock()
+	slot := signedSlot{height: header.Height, round: header.Round}
+	known, ok := h.headers[slot]
+	if !ok {
+		h.headers[slot] = header
+		h.prune(header.Height)
+	}
+	h.mu.Unlock()
+
+	if !ok || known.Hash() == header.Hash() || known.Proposer != header.Proposer {
+		return nil
+	}
+	logger.Warn("[EvidenceHandler] detected double sign", header.Height, header.Proposer.Hex())
+	return h.submitEvidence(poa_consensus.NewDoubleSignEvidence(known, header))
+}
+
+// prune drops headers that are too old to be useful, the caller holds mu
+func (h *EvidenceHandler) prune(height uint64) {
+	if height < evidenceHeaderWindow {
+		return
+	}
+	for slot := range h.headers {
+		if slot.height < height-evidenceHeaderWindow {
+			delete(h.headers, slot)
+		}
+	}
+}
+
+// submitEvidence verifies new evidence, gossips it to the other validators
+// and reports it when this node is a validator
+func (h *EvidenceHandler) submitEvidence(evidence *poa_consensus.DoubleSignEvidence) error {
+	hash := evidence.Hash()
+	h.mu.Lock()
+	_, seen := h.seen[hash]
+	h.mu.Unlock()
+	if seen {
+		return nil
+	}
+	if err := h.slasher.VerifyEvidence(evidence, h.bc.CurrentBlock().Header.Height); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.seen[hash] = struct{}{}
+	h.mu.Unlock()
+
+	go h.sender.BroadcastMessage(
+		h.otherValidators(),
+		message.MessageEvidence,
+		evidence,
+	)
+	if _, ok := h.authority.GetValidatorWeight(h.node.Address()); !ok {
+		return nil
+	}
+	return h.report(evidence)
+}
+
+// report sends a transaction carrying the evidence to params.SlashingAddress
+func (h *EvidenceHandler) report(evidence *poa_consensus.DoubleSignEvidence) error {
+	data, err := evidence.Marshal()
+	if err != nil {
+		return err
+	}
+	gas, err := state_processor.IntrinsicGas(data, false)
+	if err != nil {
+		return err
+	}
+	st, err := state.New(h.bc.CurrentBlock().Header.StateRoot, h.stateDatabase)
+	if err != nil {
+		return err
+	}
+	tx := transaction.NewTransaction(
+		params.SlashingAddress,
+		st.GetNonce(h.node.Address()),
+		big.NewInt(0),
+		data,
+		gas,
+		params.TempGasPrice,
+		"double sign evidence",
+	)
+	hashSign, err := tx.HashSign(params.TempChainId)
+	if err != nil {
+		return err
+	}
+	sign, err := h.signer.SignHash(hashSign)
+	if err != nil {
+		return err
+	}
+	tx.SetSign(sign)
+	logger.Info("[EvidenceHandler] report double sign", evidence.Offender().Hex(), tx.Hash().Hex())
+	return h.txHandler.SubmitTransaction(tx)
+}
+
+func (h *EvidenceHandler) otherValidators() []common.Address {
+	self := h.node.Address()
+	validators := h.authority.ListValidators()
+	addrs := make([]common.Address, 0, len(validators))
+	for addr := range validators {
+		if addr != self {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
@@ -1,14 +1,40 @@
 package handlers
 
 import (
+	"math/big"
+
 	"FichainCore/block"
 	"FichainCore/common"
+	"FichainCore/consensus"
+	"FichainCore/p2p"
 )
 
 type Blockchain interface {
 	CurrentBlock() *block.Block
 }
 
+// BlockImporter is the chain received blocks are verified against and
+// imported into
+type BlockImporter interface {
+	consensus.ChainReader
+	CurrentBlock() *block.Block
+	HasBlock(hash common.Hash, number uint64) bool
+	HasBlockAndState(hash common.Hash, number uint64) bool
+	InsertChain(chain []*block.Block) (int, error)
+}
+
 type Node interface {
 	Address() common.Address
 }
+
+// Validators lists the current validator set, implemented by
+// poa_consensus.Authority
+type Validators interface {
+	ListValidators() map[common.Address]*big.Int
+}
+
+// Access tells which accounts a peer may see the data of, implemented by
+// peer_manager.PeerManager
+type Access interface {
+	CanAccess(peer p2p.Peer, accounts ...common.Address) bool
+}
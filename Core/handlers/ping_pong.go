@@ -8,12 +8,17 @@ import (
 	"FichainCore/config"
 	"FichainCore/p2p"
 	"FichainCore/p2p/message"
+	"FichainCore/p2p/message_sender"
 )
 
-type PingPongHandler struct{}
+type PingPongHandler struct {
+	sender *message_sender.MessageSender
+}
 
-func NewPingPongHandler() *PingPongHandler {
-	return &PingPongHandler{}
+func NewPingPongHandler(sender *message_sender.MessageSender) *PingPongHandler {
+	return &PingPongHandler{
+		sender: sender,
+	}
 }
 
 func (h *PingPongHandler) Handlers() map[string]func(p2p.Peer, *message.Message) error {
@@ -31,18 +36,7 @@ func (h *PingPongHandler) Ping(peer p2p.Peer, msg *message.Message) error {
 		NodeID:    config.GetConfig().NodeID,
 		Timestamp: time.Now().Unix(),
 	}
-	fmtMsg := &message.Message{
-		Header: &message.Header{
-			Version:     1,
-			SenderID:    config.GetConfig().NodeID,
-			MessageType: "pong",
-			Timestamp:   time.Now().Unix(),
-			Signature:   []byte{},
-		},
-		Payload: pongMsg,
-	}
-
-	err := peer.Send(fmtMsg)
+	err := h.sender.ReplyToPeer(peer, msg, message.MessagePong, pongMsg)
 	if err != nil {
 		return err
 	} else {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
 
@@ -22,6 +23,7 @@ type ReceiptHandler struct {
 
 	sender *message_sender.MessageSender
 	bc     *block_chain.BlockChain
+	access Access
 }
 
 func NewReceiptHandler(
@@ -29,12 +31,14 @@ func NewReceiptHandler(
 	database database.Database,
 	sender *message_sender.MessageSender,
 	bc *block_chain.BlockChain,
+	access Access,
 ) *ReceiptHandler {
 	return &ReceiptHandler{
 		stateDB:  stateDB,
 		database: database,
 		sender:   sender,
 		bc:       bc,
+		access:   access,
 	}
 }
 
@@ -45,14 +49,30 @@ func (h *ReceiptHandler) Handlers() map[string]func(p2p.Peer, *message.Message)
 	}
 }
 
+// GetReceipts returns the receipts of a block, a peer that does not see
+// every account only gets the ones of the accounts it may access
 func (h *ReceiptHandler) GetReceipts(peer p2p.Peer, msg *message.Message) error {
-	// todo chekc only explorer is allow to get all receipt
 	blockHash := common.BytesToHash(msg.Payload.(*message.BytesMessage).Data)
 	blockNumber := block_chain.GetBlockNumber(h.database, blockHash)
 	blockReceipts := block_chain.GetBlockReceipts(h.database, blockHash, blockNumber)
+	if !h.access.CanAccess(peer) {
+		body := block_chain.GetBody(h.database, blockHash, blockNumber)
+		if body == nil || len(body.Transactions) != len(blockReceipts) {
+			return errors.New("block body not found")
+		}
+		allowed := make([]*receipt.Receipt, 0, len(blockReceipts))
+		for i, tx := range body.Transactions {
+			from, _ := tx.From(params.TempChainId)
+			if h.access.CanAccess(peer, from, tx.To()) {
+				allowed = append(allowed, blockReceipts[i])
+			}
+		}
+		blockReceipts = allowed
+	}
 	receipts := receipt.NewReceipts(blockReceipts)
-	err := h.sender.SendMessageToPeer(
+	err := h.sender.ReplyToPeer(
 		peer,
+		msg,
 		message.MessageReceipts,
 		receipts,
 	)
@@ -79,14 +99,19 @@ func (h *ReceiptHandler) GetReceipt(peer p2p.Peer, msg *message.Message) error {
 	}
 	rcpt := rcpts[txIndex]
 	from, _ := tx.From(params.TempChainId)
+	// customer receipts are only served to the peers with access to them
+	if !h.access.CanAccess(peer, from, tx.To()) {
+		return fmt.Errorf("%w: receipt of %s", p2p.ErrAccessDenied, txHash.Hex())
+	}
 	rcpt.BlockHash = blockHash
 	rcpt.BlockNumber = blockNumber
 	rcpt.TxIndex = uint32(txIndex)
 	rcpt.From = from
 	rcpt.To = tx.To()
 	rcpt.Amount = tx.Amount()
-	err := h.sender.SendMessageToPeer(
+	err := h.sender.ReplyToPeer(
 		peer,
+		msg,
 		message.MessageReceipt,
 		rcpt,
 	)
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/consensus/poa_consensus"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+	"FichainCore/p2p/message_sender"
+	"FichainCore/state"
+)
+
+// ReserveHandler serves the native supply, the bank reserves and their latest
+// attestations so the central bank auditor can verify the peg independently
+type ReserveHandler struct {
+	checker       *poa_consensus.SupplyChecker
+	bc            Blockchain
+	stateDatabase state.Database
+	sender        *message_sender.MessageSender
+}
+
+func NewReserveHandler(
+	checker *poa_consensus.SupplyChecker,
+	blockchain Blockchain,
+	stateDatabase state.Database,
+	sender *message_sender.MessageSender,
+) *ReserveHandler {
+	return &ReserveHandler{
+		checker:       checker,
+		bc:            blockchain,
+		stateDatabase: stateDatabase,
+		sender:        sender,
+	}
+}
+
+func (h *ReserveHandler) Handlers() map[string]func(p2p.Peer, *message.Message) error {
+	return map[string]func(p2p.Peer, *message.Message) error{
+		message.MessageGetSupplyReport: h.GetSupplyReport,
+	}
+}
+
+// GetSupplyReport returns the supply report of the current head, the report
+// of the last checked block is reused when it is the head
+func (h *ReserveHandler) GetSupplyReport(peer p2p.Peer, msg *message.Message) error {
+	head := h.bc.CurrentBlock().Header
+	report := h.checker.LastReport()
+	if report == nil || report.Height != head.Height {
+		st, err := state.New(head.StateRoot, h.stateDatabase)
+		if err != nil {
+			return err
+		}
+		report = h.checker.Report(st, head.Height)
+	}
+	err := h.sender.ReplyToPeer(
+		peer,
+		msg,
+		message.MessageSupplyReport,
+		report,
+	)
+	if err != nil {
+		logger.Warn("error when send supply report to peer", err)
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"errors"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/consensus/poa_consensus"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+	"FichainCore/p2p/message_sender"
+	"FichainCore/state"
+)
+
+// RewardHandler serves the per-epoch fee settlement records so member banks
+// can audit what their validators earned
+type RewardHandler struct {
+	distributor   *poa_consensus.FeeDistributor
+	bc            Blockchain
+	stateDatabase state.Database
+	sender        *message_sender.MessageSender
+}
+
+func NewRewardHandler(
+	distributor *poa_consensus.FeeDistributor,
+	blockchain Blockchain,
+	stateDatabase state.Database,
+	sender *message_sender.MessageSender,
+) *RewardHandler {
+	return &RewardHandler{
+		distributor:   distributor,
+		bc:            blockchain,
+		stateDatabase: stateDatabase,
+		sender:        sender,
+	}
+}
+
+func (h *RewardHandler) Handlers() map[string]func(p2p.Peer, *message.Message) error {
+	return map[string]func(p2p.Peer, *message.Message) error{
+		message.MessageGetSettlement: h.GetSettlement,
+	}
+}
+
+// GetSettlement returns the settlement of the epoch given as a big endian
+// uint64, as of the current head
+func (h *RewardHandler) GetSettlement(peer p2p.Peer, msg *message.Message) error {
+	data := msg.Payload.(*message.BytesMessage).Data
+	if len(data) != 8 {
+		return errors.New("invalid settlement epoch")
+	}
+	epoch := binary.BigEndian.Uint64(data)
+
+	st, err := state.New(h.bc.CurrentBlock().Header.StateRoot, h.stateDatabase)
+	if err != nil {
+		return err
+	}
+	settlement, err := h.distributor.Settlement(st, epoch)
+	if err != nil {
+		return err
+	}
+	err = h.sender.ReplyToPeer(
+		peer,
+		msg,
+		message.MessageSettlement,
+		settlement,
+	)
+	if err != nil {
+		logger.Warn("error when send settlement to peer", err)
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math/big"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
@@ -22,17 +23,20 @@ type StateHandler struct {
 
 	sender *message_sender.MessageSender
 	bc     *block_chain.BlockChain
+	access Access
 }
 
 func NewStateHandler(
 	stateDB *state.StateDB,
 	sender *message_sender.MessageSender,
 	bc *block_chain.BlockChain,
+	access Access,
 ) *StateHandler {
 	return &StateHandler{
 		stateDB: stateDB,
 		sender:  sender,
 		bc:      bc,
+		access:  access,
 	}
 }
 
@@ -48,8 +52,9 @@ func (h *StateHandler) GetBalance(peer p2p.Peer, msg *message.Message) error {
 	address := peer.WalletAddress()
 	balance := h.stateDB.GetBalance(address)
 
-	err := h.sender.SendMessageToPeer(
+	err := h.sender.ReplyToPeer(
 		peer,
+		msg,
 		message.MessageBalance,
 		&message.BytesMessage{
 			Data: balance.Bytes(),
@@ -64,14 +69,18 @@ func (h *StateHandler) GetBalance(peer p2p.Peer, msg *message.Message) error {
 func (h *StateHandler) GetNonce(peer p2p.Peer, msg *message.Message) error {
 	//
 	address := common.BytesToAddress(msg.Payload.(*message.BytesMessage).Data)
+	if !h.access.CanAccess(peer, address) {
+		return fmt.Errorf("%w: nonce of %s", p2p.ErrAccessDenied, address.Hex())
+	}
 	nonce := h.stateDB.GetNonce(address)
 	logger.Debug("Nonce for ", address.Hex(), "is", nonce)
 
 	bytes := make([]byte, 8) // uint64 takes 8 bytes
 	binary.BigEndian.PutUint64(bytes, nonce)
 
-	err := h.sender.SendMessageToPeer(
+	err := h.sender.ReplyToPeer(
 		peer,
+		msg,
 		message.MessageNonce,
 		&message.BytesMessage{
 			Data: bytes,
@@ -114,8 +123,9 @@ func (h *StateHandler) CallSmartContract(peer p2p.Peer, msg *message.Message) er
 	}
 	logger.DebugP("call response", res)
 
-	err = h.sender.SendMessageToPeer(
+	err = h.sender.ReplyToPeer(
 		peer,
+		msg,
 		message.MessageCallResult,
 		res,
 	)
@@ -1,82 +1,357 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
 	logger "github.com/HendrickPhan/golang-simple-logger"
 
-	"FichainCore/consensus/poa_consensus"
+	"FichainCore/block_chain"
+	"FichainCore/common"
+	"FichainCore/common/lru"
+	"FichainCore/event"
 	"FichainCore/p2p"
+	"FichainCore/p2p/lookup_table"
 	"FichainCore/p2p/message"
 	"FichainCore/p2p/message_sender"
+	"FichainCore/params"
 	"FichainCore/transaction"
 	"FichainCore/transaction_pool"
 	"FichainCore/transaction_validator"
 )
 
+const (
+	// seenTransactionsLimit is the number of transaction hashes remembered so
+	// a gossiped transaction is pooled and announced once
+	seenTransactionsLimit = 32768
+
+	// MaxTransactionFetch caps the transactions announced, asked for or
+	// served in one message
+	MaxTransactionFetch = 256
+
+	// transactionAnnounceInterval is how long new hashes are batched before
+	// they are announced
+	transactionAnnounceInterval = 100 * time.Millisecond
+
+	// transactionFetchTimeout is how long an announced transaction is waited
+	// for before it is asked from the next validator announcing it
+	transactionFetchTimeout = 5 * time.Second
+)
+
+var errNoValidatorConnected = errors.New("no validator connected")
+
+// TransactionHandler gossips transactions between validators, so the pool of
+// every validator holds the pending transactions whoever proposes next. A
+// validator pools every valid transaction it receives and announces its hash
+// to the other connected validators, which fetch the transactions they have
+// not seen yet. Other nodes push the transactions they receive to the
+// validators they are connected to. Transactions leave the pool once a block
+// including them is imported.
 type TransactionHandler struct {
 	transactionValidator *transaction_validator.TransactionValidator
-	proposerSchedule     *poa_consensus.ProposerSchedule
+	validators           Validators
 	txPool               *transaction_pool.TransactionPool
 	node                 Node
-	bc                   Blockchain
+	lookupTable          *lookup_table.LookupTable
 	sender               *message_sender.MessageSender
+
+	seen      lru.BasicLRU[common.Hash, struct{}]
+	fetching  map[common.Hash]time.Time
+	announces []common.Hash
+	quit      chan struct{}
+	mu        sync.Mutex
+
+	chainEvent             chan event.ChainEvent
+	chainEventSubscription event.Subscription
 }
 
 func NewTransactionHandler(
 	validator *transaction_validator.TransactionValidator,
-	schedule *poa_consensus.ProposerSchedule,
+	validators Validators,
 	pool *transaction_pool.TransactionPool,
 	node Node,
-	blockchain Blockchain,
+	lookupTable *lookup_table.LookupTable,
 	sender *message_sender.MessageSender,
 ) *TransactionHandler {
 	return &TransactionHandler{
 		transactionValidator: validator,
-		proposerSchedule:     schedule,
+		validators:           validators,
 		txPool:               pool,
 		node:                 node,
-		bc:                   blockchain,
+		lookupTable:          lookupTable,
 		sender:               sender,
+		seen:                 lru.NewBasicLRU[common.Hash, struct{}](seenTransactionsLimit),
+		fetching:             make(map[common.Hash]time.Time),
+		quit:                 make(chan struct{}),
+		chainEvent:           make(chan event.ChainEvent),
 	}
 }
 
 func (h *TransactionHandler) Handlers() map[string]func(p2p.Peer, *message.Message) error {
 	return map[string]func(p2p.Peer, *message.Message) error{
-		message.MessageSendTransaction: h.Transaction,
+		message.MessageSendTransaction:      h.Transaction,
+		message.MessageNewTransactionHashes: h.NewTransactionHashes,
+		message.MessageGetTransactions:      h.GetTransactions,
+		message.MessageTransactions:         h.Transactions,
+	}
+}
+
+// Start announces the pooled transactions to the validators in batches
+func (h *TransactionHandler) Start() {
+	go func() {
+		ticker := time.NewTicker(transactionAnnounceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.flushAnnounces()
+			case <-h.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (h *TransactionHandler) Stop() {
+	close(h.quit)
+}
+
+// SubscribeChanEvent removes the transactions of imported blocks from the pool
+func (h *TransactionHandler) SubscribeChanEvent(bc *block_chain.BlockChain) {
+	h.chainEventSubscription = bc.SubscribeChainEvent(h.chainEvent)
+	go h.HandleChanEvent()
+}
+
+func (h *TransactionHandler) HandleChanEvent() {
+	for {
+		event := <-h.chainEvent
+		if len(event.Block.Transactions) > 0 {
+			h.txPool.RemoveTransactions(event.Block.Transactions)
+		}
 	}
 }
 
+// Transaction accepts a transaction sent by a client or pushed by a node
 func (h *TransactionHandler) Transaction(peer p2p.Peer, msg *message.Message) error {
-	tx := msg.Payload.(*transaction.Transaction)
+	tx, ok := msg.Payload.(*transaction.Transaction)
+	if !ok {
+		return fmt.Errorf("%w: not a transaction", p2p.ErrInvalidPayload)
+	}
 	logger.Info("[TransactionHandler] receive transaction", tx)
-	err := h.transactionValidator.QuickVerify(*tx)
-	if err != nil {
+	if err := h.verify(tx); err != nil {
 		logger.Error("Error when quick verify transaction", tx, err)
 		return err
 	}
-	currentBlockNumber := h.bc.CurrentBlock().Header.Height
+	return h.SubmitTransaction(tx)
+}
 
-	// forward to next proposer or if is next proposer then import to pool
-	nextProposer, err := h.proposerSchedule.GetProposer(
-		currentBlockNumber + 2,
-	) // + 1 is node producing, so it will be +2
-	if err != nil {
-		logger.Error("error when get next proposer", err)
-		return err
+// SubmitTransaction pools a transaction and announces it to the other
+// validators, a node that is not a validator pushes it to the validators. The
+// hash is only marked seen once the transaction is pooled or pushed, so a
+// rejected transaction is accepted again if it becomes valid.
+func (h *TransactionHandler) SubmitTransaction(tx *transaction.Transaction) error {
+	hash := tx.Hash()
+	if h.hasSeen(hash) {
+		return nil
+	}
+	if !h.isValidator() {
+		validators := h.connectedValidators()
+		if len(validators) == 0 {
+			return errNoValidatorConnected
+		}
+		if err := h.sender.BroadcastMessage(validators, message.MessageSendTransaction, tx); err != nil {
+			return err
+		}
+		h.markSeen(hash)
+		return nil
+	}
+
+	if err := h.txPool.AddTransaction(tx); err != nil {
+		return fmt.Errorf("transaction %s not pooled: %w", hash.Hex(), err)
+	}
+	if !h.markSeen(hash) {
+		// pooled and announced by a concurrent submission
+		return nil
+	}
+	h.mu.Lock()
+	h.announces = append(h.announces, hash)
+	h.mu.Unlock()
+	return nil
+}
+
+// NewTransactionHashes asks the announcing peer for the transactions not seen
+// yet and not already asked from another peer
+func (h *TransactionHandler) NewTransactionHashes(peer p2p.Peer, msg *message.Message) error {
+	announce, ok := msg.Payload.(*message.TransactionHashesMessage)
+	if !ok {
+		return fmt.Errorf("%w: invalid transaction hashes payload", p2p.ErrInvalidPayload)
+	}
+	if len(announce.Hashes) > MaxTransactionFetch {
+		return fmt.Errorf("%w: %d hashes announced", p2p.ErrInvalidPayload, len(announce.Hashes))
+	}
+	now := time.Now()
+	unknown := make([]common.Hash, 0, len(announce.Hashes))
+	h.mu.Lock()
+	for _, hash := range announce.Hashes {
+		if h.seen.Contains(hash) {
+			continue
+		}
+		if asked, ok := h.fetching[hash]; ok && now.Sub(asked) < transactionFetchTimeout {
+			continue
+		}
+		h.fetching[hash] = now
+		unknown = append(unknown, hash)
+	}
+	h.mu.Unlock()
+	if len(unknown) == 0 {
+		return nil
+	}
+	return h.sender.SendMessageToPeer(
+		peer,
+		message.MessageGetTransactions,
+		&message.TransactionHashesMessage{Hashes: unknown},
+	)
+}
+
+// GetTransactions serves the requested transactions found in the pool
+func (h *TransactionHandler) GetTransactions(peer p2p.Peer, msg *message.Message) error {
+	request, ok := msg.Payload.(*message.TransactionHashesMessage)
+	if !ok {
+		return fmt.Errorf("%w: invalid transactions request", p2p.ErrInvalidPayload)
+	}
+	hashes := request.Hashes
+	if len(hashes) > MaxTransactionFetch {
+		hashes = hashes[:MaxTransactionFetch]
+	}
+	txs := make([]*transaction.Transaction, 0, len(hashes))
+	for _, hash := range hashes {
+		if tx := h.txPool.Get(hash); tx != nil {
+			txs = append(txs, tx)
+		}
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+	return h.sender.ReplyToPeer(
+		peer,
+		msg,
+		message.MessageTransactions,
+		&message.TransactionsMessage{Transactions: txs},
+	)
+}
+
+// Transactions pools the transactions fetched from a validator
+func (h *TransactionHandler) Transactions(peer p2p.Peer, msg *message.Message) error {
+	payload, ok := msg.Payload.(*message.TransactionsMessage)
+	if !ok {
+		return fmt.Errorf("%w: invalid transactions payload", p2p.ErrInvalidPayload)
+	}
+	if len(payload.Transactions) > MaxTransactionFetch {
+		return fmt.Errorf("%w: %d transactions served", p2p.ErrInvalidPayload, len(payload.Transactions))
+	}
+	var invalid error
+	for _, tx := range payload.Transactions {
+		if err := h.verify(tx); err != nil {
+			invalid = err
+			continue
+		}
+		h.mu.Lock()
+		delete(h.fetching, tx.Hash())
+		h.mu.Unlock()
+		if err := h.SubmitTransaction(tx); err != nil {
+			logger.Warn("[TransactionHandler] error when submit gossiped transaction", err)
+		}
+	}
+	return invalid
+}
+
+// verify rejects transactions whose hash or signature does not match their
+// content
+func (h *TransactionHandler) verify(tx *transaction.Transaction) error {
+	if err := tx.ValidateHash(); err != nil {
+		return fmt.Errorf("%w: %v", p2p.ErrInvalidPayload, err)
+	}
+	if _, err := tx.From(params.TempChainId); err != nil {
+		return fmt.Errorf("%w: %v", p2p.ErrInvalidPayload, err)
+	}
+	if err := h.transactionValidator.QuickVerify(*tx); err != nil {
+		return fmt.Errorf("%w: %v", p2p.ErrInvalidPayload, err)
+	}
+	return nil
+}
+
+// flushAnnounces sends the hashes pooled since the last flush to the
+// connected validators and forgets the fetches that timed out
+func (h *TransactionHandler) flushAnnounces() {
+	now := time.Now()
+	h.mu.Lock()
+	hashes := h.announces
+	h.announces = nil
+	for hash, asked := range h.fetching {
+		if now.Sub(asked) >= transactionFetchTimeout {
+			delete(h.fetching, hash)
+		}
+	}
+	h.mu.Unlock()
+	if len(hashes) == 0 {
+		return
 	}
 
-	if nextProposer == h.node.Address() {
-		h.txPool.AddTransaction(tx)
-	} else {
-		err := h.sender.SendMessageToAddress(
-			nextProposer,
-			message.MessageSendTransaction,
-			tx,
+	validators := h.connectedValidators()
+	if len(validators) == 0 {
+		return
+	}
+	for len(hashes) > 0 {
+		count := len(hashes)
+		if count > MaxTransactionFetch {
+			count = MaxTransactionFetch
+		}
+		err := h.sender.BroadcastMessage(
+			validators,
+			message.MessageNewTransactionHashes,
+			&message.TransactionHashesMessage{Hashes: hashes[:count]},
 		)
 		if err != nil {
-			logger.Warn("error when send tx to proposer", err, "adding transaction to pool")
-			h.txPool.AddTransaction(tx)
+			logger.Warn("[TransactionHandler] error when announce transactions", err)
 		}
+		hashes = hashes[count:]
 	}
+}
 
-	return nil
+func (h *TransactionHandler) isValidator() bool {
+	_, ok := h.validators.ListValidators()[h.node.Address()]
+	return ok
+}
+
+// connectedValidators lists the validators other than this node with a live
+// connection
+func (h *TransactionHandler) connectedValidators() []common.Address {
+	self := h.node.Address()
+	validators := h.validators.ListValidators()
+	addresses := make([]common.Address, 0, len(validators))
+	for address := range validators {
+		if address != self && h.lookupTable.Has(address) {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// markSeen records a transaction hash, returning false if it was seen before
+func (h *TransactionHandler) markSeen(hash common.Hash) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.seen.Contains(hash) {
+		return false
+	}
+	h.seen.Add(hash, struct{}{})
+	return true
+}
+
+func (h *TransactionHandler) hasSeen(hash common.Hash) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seen.Contains(hash)
 }
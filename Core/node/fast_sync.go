@@ -0,0 +1,78 @@
+package node
+
+import (
+	"fmt"
+
+	"FichainCore/block"
+	"FichainCore/block_chain"
+	"FichainCore/consensus/poa_consensus"
+	"FichainCore/database"
+)
+
+// fastSyncChain stores the blocks downloaded by a fast sync the way the
+// genesis block is committed, implements downloader.FastSyncChain
+type fastSyncChain struct {
+	bc           *block_chain.BlockChain
+	db           database.Database
+	epochManager *poa_consensus.EpochManager
+	chainStates  *poa_consensus.ChainStates
+}
+
+// PivotHeight implements downloader.FastSyncChain, the pivot closes an epoch
+// so the stake read from its state sets the next schedule exactly
+func (c *fastSyncChain) PivotHeight(height uint64) uint64 {
+	return c.epochManager.LastBlockOfEpoch(height)
+}
+
+// WriteBlocks implements downloader.FastSyncChain. The blocks are canonical
+// but have no state or receipts, the epoch manager reads them against the
+// pivot state once it is committed.
+func (c *fastSyncChain) WriteBlocks(blocks []*block.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	first := blocks[0].Header
+	// POA blocks carry no difficulty, so the total difficulty never changes
+	td := c.bc.GetTd(first.ParentHash, first.Height-1)
+	if td == nil {
+		return fmt.Errorf("missing total difficulty of block %d", first.Height-1)
+	}
+	batch := c.db.NewBatch()
+	for _, bl := range blocks {
+		hash := bl.Hash()
+		height := bl.Header.Height
+		if err := block_chain.WriteTd(batch, hash, height, td); err != nil {
+			return err
+		}
+		if err := block_chain.WriteBlock(batch, bl); err != nil {
+			return err
+		}
+		if err := block_chain.WriteCanonicalHash(batch, hash, height); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// CommitPivot implements downloader.FastSyncChain. The pivot becomes the
+// chain head and the epoch manager processes the chain up to it, so the
+// blocks after it are verified against the right schedule.
+func (c *fastSyncChain) CommitPivot(pivot *block.Block) error {
+	if err := c.chainStates.SetPivot(pivot.Header); err != nil {
+		return err
+	}
+	if err := c.WriteBlocks([]*block.Block{pivot}); err != nil {
+		return err
+	}
+	hash := pivot.Hash()
+	if err := block_chain.WriteHeadBlockHash(c.db, hash); err != nil {
+		return err
+	}
+	if err := block_chain.WriteHeadHeaderHash(c.db, hash); err != nil {
+		return err
+	}
+	if err := c.bc.FastSyncCommitHead(hash); err != nil {
+		return err
+	}
+	return c.epochManager.CatchUp()
+}
@@ -0,0 +1,27 @@
+package node
+
+import (
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/block_chain"
+	"FichainCore/common"
+	"FichainCore/state"
+)
+
+// headState reads nonces from the state of the chain head, implements
+// transaction_pool.StateReader. The block builder resets and writes its own
+// StateDB while building, so the pool never reads from it.
+type headState struct {
+	bc *block_chain.BlockChain
+	db state.Database
+}
+
+// GetNonce implements transaction_pool.StateReader
+func (s *headState) GetNonce(address common.Address) uint64 {
+	st, err := state.New(s.bc.CurrentBlock().Header.StateRoot, s.db)
+	if err != nil {
+		logger.Error("error when open head state ", err)
+		return 0
+	}
+	return st.GetNonce(address)
+}
@@ -6,6 +6,7 @@ import (
 
 	logger "github.com/hieuphanuit/golang-simple-logger"
 
+	"FichainCore/bank_adapter"
 	"FichainCore/block"
 	"FichainCore/block_builder"
 	"FichainCore/block_chain"
@@ -13,12 +14,17 @@ import (
 	"FichainCore/config"
 	"FichainCore/consensus/poa_consensus"
 	"FichainCore/database"
+	"FichainCore/downloader"
 	"FichainCore/evm"
 	"FichainCore/handlers"
 	"FichainCore/notifier"
 	"FichainCore/p2p"
+	"FichainCore/p2p/client"
+	"FichainCore/p2p/discovery"
 	"FichainCore/p2p/lookup_table"
+	"FichainCore/p2p/message"
 	"FichainCore/p2p/message_sender"
+	"FichainCore/p2p/peer_manager"
 	"FichainCore/p2p/router"
 	"FichainCore/p2p/server"
 	ws_server "FichainCore/p2p/ws/server"
@@ -40,6 +46,9 @@ type Node struct {
 	lookupTable   *lookup_table.LookupTable
 	messageSender *message_sender.MessageSender
 	router        *router.Router
+	mesh          *discovery.Mesh
+	peerManager   *peer_manager.PeerManager
+	status        *message.Status // chain and protocol announced in handshakes
 
 	// blockchain
 	// ---- genesis
@@ -47,15 +56,27 @@ type Node struct {
 	// ---- statedb
 	// ---- evm
 	bc                   *block_chain.BlockChain
+	engine               *poa_consensus.POAConsensus
 	authority            *poa_consensus.Authority
 	transactionValidator *transaction_validator.TransactionValidator
-	proposerSchedule     *poa_consensus.ProposerSchedule
+	epochManager         *poa_consensus.EpochManager
+	chainStates          *poa_consensus.ChainStates
+	governance           *poa_consensus.Governance
+	stakePool            *poa_consensus.StakePool
+	slasher              *poa_consensus.Slasher
+	feeDistributor       *poa_consensus.FeeDistributor
+	fiatReserve          *poa_consensus.FiatReserve
+	nativeIssuer         *poa_consensus.NativeIssuer
+	supplyChecker        *poa_consensus.SupplyChecker
+	reserveReconciler    *poa_consensus.ReserveReconciler
 	transactionPool      *transaction_pool.TransactionPool
 	signer               *signer.Signer
 	database             database.Database
 	stateDB              *state.StateDB
+	stateDatabase        state.Database
 
 	blockBuilder *block_builder.BlockBuilder
+	downloader   *downloader.Downloader
 
 	clientNotifier   *notifier.ClientNotifier
 	explorerNotifier *notifier.ExplorerNotifier
@@ -69,6 +90,10 @@ type Node struct {
 	receiptHandler     *handlers.ReceiptHandler
 
 	// ---- consensus
+	consensusHandler *handlers.ConsensusHandler
+	evidenceHandler  *handlers.EvidenceHandler
+	rewardHandler    *handlers.RewardHandler
+	reserveHandler   *handlers.ReserveHandler
 }
 
 func New() *Node {
@@ -86,12 +111,20 @@ func (n *Node) initNetwork() {
 	cfg := p2p.DefaultConfig()
 	cfg.ListenAddress = config.GetConfig().TCPServerAddress
 	cfg.Debug = true
+	applyPeerLimits(cfg, config.GetConfig())
+	cfg.Explorers = config.GetConfig().GetExplorerAddresses()
 	//
+	n.peerManager = peer_manager.NewPeerManager(cfg, n.authority)
+	applyMessagePolicies(n.peerManager, config.GetConfig())
 	n.router = router.NewRouter()
+	n.router.SetRateLimiter(n.peerManager)
+	n.router.SetScorer(n.peerManager)
+	n.router.SetAccessController(n.peerManager)
 	//
 	n.lookupTable = lookup_table.NewLookupTable()
 	//
-	n.messageSender = message_sender.NewMessageSender(n.lookupTable)
+	n.messageSender = message_sender.NewMessageSender(n.lookupTable, n.signer)
+	n.router.SetResponseHandler(n.messageSender)
 	// Start server
 	n.server = server.NewTCPServer(
 		cfg.ListenAddress,
@@ -106,31 +139,199 @@ func (n *Node) initNetwork() {
 		n.lookupTable,
 		n.signer,
 	)
+	n.server.SetPeerManager(n.peerManager)
+	n.wsServer.SetPeerManager(n.peerManager)
+	n.status = n.localStatus()
+	n.peerManager.SetStatus(n.status)
+	n.server.SetStatus(n.status)
+	n.wsServer.SetStatus(n.status)
+	n.initMesh(cfg)
 	logger.Info("Inited network")
 }
 
+// localStatus is the status announced in handshakes, peers of another chain
+// are refused. The role is the one of this node at startup.
+func (n *Node) localStatus() *message.Status {
+	var genesisHash common.Hash
+	if genesis := n.bc.GetHeaderByNumber(0); genesis != nil {
+		genesisHash = genesis.Hash()
+	}
+	return message.NewStatus(
+		params.TempChainId.Uint64(),
+		genesisHash,
+		n.peerManager.RoleOf(n.Address()).String(),
+		message.CapabilityTxGossip,
+		message.CapabilityFastSync,
+		message.CapabilityRequestID,
+	)
+}
+
+// applyPeerLimits overrides the default peer caps, rate limits and ban
+// duration with the ones set in the node config
+func applyPeerLimits(cfg *p2p.Config, nodeConfig *config.Config) {
+	if nodeConfig.MaxPeers > 0 {
+		cfg.MaxPeers = nodeConfig.MaxPeers
+	}
+	if nodeConfig.MaxValidatorPeers > 0 {
+		cfg.MaxValidatorPeers = nodeConfig.MaxValidatorPeers
+	}
+	if nodeConfig.MaxObserverPeers > 0 {
+		cfg.MaxObserverPeers = nodeConfig.MaxObserverPeers
+	}
+	if nodeConfig.MaxClientPeers > 0 {
+		cfg.MaxClientPeers = nodeConfig.MaxClientPeers
+	}
+	cfg.EnableRateLimiter = !nodeConfig.DisableRateLimiter
+	if nodeConfig.MessageRate > 0 {
+		cfg.MessageRate = rateLimit(nodeConfig.MessageRate)
+	}
+	for msgType, rate := range nodeConfig.MessageRates {
+		cfg.MessageRates[msgType] = rateLimit(rate)
+	}
+	if nodeConfig.BanDuration > 0 {
+		cfg.BanDuration = time.Duration(nodeConfig.BanDuration) * time.Second
+	}
+}
+
+// applyMessagePolicies overrides the default roles allowed to send a message
+// type with the ones set in the node config
+func applyMessagePolicies(manager *peer_manager.PeerManager, nodeConfig *config.Config) {
+	for msgType, names := range nodeConfig.MessagePolicies {
+		roles := make([]peer_manager.Role, 0, len(names))
+		for _, name := range names {
+			role, err := peer_manager.ParseRole(name)
+			if err != nil {
+				logger.Error("[Node] invalid message policy", msgType, err)
+				continue
+			}
+			roles = append(roles, role)
+		}
+		manager.SetPolicy(msgType, roles...)
+	}
+}
+
+// rateLimit allows bursts of two seconds worth of messages
+func rateLimit(rate float64) p2p.RateLimit {
+	return p2p.RateLimit{Rate: rate, Burst: int(math.Ceil(2 * rate))}
+}
+
+// dialer connects to other nodes announcing the status of this node
+func (n *Node) dialer(cfg *p2p.Config) *client.TCPClient {
+	dialer := client.NewTCPClient(cfg.HandshakeTimeout, n.signer)
+	dialer.SetStatus(n.status)
+	return dialer
+}
+
+// initMesh keeps the node connected to the validators, peers are remembered
+// across restarts when PeerDBPath is set
+func (n *Node) initMesh(cfg *p2p.Config) {
+	var peerDB database.Database
+	var err error
+	if path := config.GetConfig().PeerDBPath; path != "" {
+		peerDB, err = database.NewBadgerDB(path)
+	} else {
+		peerDB, err = database.NewMemDatabase()
+	}
+	if err != nil {
+		panic(err)
+	}
+	advertise := config.GetConfig().AdvertiseAddress
+	if advertise == "" {
+		advertise = cfg.ListenAddress
+	}
+	n.mesh = discovery.NewMesh(
+		n.Address(),
+		advertise,
+		discovery.NewPeerBook(peerDB),
+		n.dialer(cfg),
+		n.server,
+		n.lookupTable,
+		n.messageSender,
+		n.authority,
+		0,
+	)
+	n.server.OnPeerConnected(n.mesh.PeerConnected)
+}
+
 func (n *Node) initHandlers() {
-	n.pingPongHandler = handlers.NewPingPongHandler()
+	n.pingPongHandler = handlers.NewPingPongHandler(n.messageSender)
 	n.transactionHandler = handlers.NewTransactionHandler(
 		n.transactionValidator,
-		n.proposerSchedule,
+		n.authority,
 		n.transactionPool,
-		// n, // todo
 		n,
-		n.bc,
+		n.lookupTable,
 		n.messageSender,
 	)
-	n.blockHandler = &handlers.BlockHandler{}
 	n.stateHandler = handlers.NewStateHandler(
 		n.stateDB,
 		n.messageSender,
 		n.bc,
+		n.peerManager,
 	)
 	n.receiptHandler = handlers.NewReceiptHandler(
 		n.stateDB,
 		n.database,
 		n.messageSender,
 		n.bc,
+		n.peerManager,
+	)
+	n.consensusHandler = handlers.NewConsensusHandler(
+		n.epochManager,
+		n.signer,
+		n.database,
+		n,
+		n.lookupTable,
+		n.messageSender,
+	)
+	n.evidenceHandler = handlers.NewEvidenceHandler(
+		n.slasher,
+		n.authority,
+		n.engine,
+		n.signer,
+		n,
+		n.bc,
+		n.stateDatabase,
+		n.messageSender,
+		n.transactionHandler,
+	)
+	n.blockHandler = handlers.NewBlockHandler(
+		n.bc,
+		n.engine,
+		n.stateDatabase,
+		n.database,
+		n.evidenceHandler,
+		n,
+		n.lookupTable,
+		n.messageSender,
+		n.peerManager,
+	)
+	n.downloader = downloader.NewDownloader(
+		n.bc,
+		n.blockHandler,
+		n.lookupTable,
+		n.messageSender,
+		0,
+	)
+	if config.GetConfig().FastSync {
+		n.downloader.EnableFastSync(&fastSyncChain{
+			bc:           n.bc,
+			db:           n.database,
+			epochManager: n.epochManager,
+			chainStates:  n.chainStates,
+		}, n.database)
+	}
+	n.rewardHandler = handlers.NewRewardHandler(
+		n.feeDistributor,
+		n.bc,
+		n.stateDatabase,
+		n.messageSender,
+	)
+	n.reserveHandler = handlers.NewReserveHandler(
+		n.supplyChecker,
+		n.bc,
+		n.stateDatabase,
+		n.messageSender,
 	)
 
 	// register to router
@@ -139,7 +340,14 @@ func (n *Node) initHandlers() {
 	n.router.RegisterHanlders(n.stateHandler.Handlers())
 	n.router.RegisterHanlders(n.transactionHandler.Handlers())
 	n.router.RegisterHanlders(n.receiptHandler.Handlers())
+	n.router.RegisterHanlders(n.consensusHandler.Handlers())
+	n.router.RegisterHanlders(n.evidenceHandler.Handlers())
+	n.router.RegisterHanlders(n.blockHandler.Handlers())
+	n.router.RegisterHanlders(n.mesh.Handlers())
+	n.router.RegisterHanlders(n.rewardHandler.Handlers())
+	n.router.RegisterHanlders(n.reserveHandler.Handlers())
 
+	logger.Debug("Routed message types", n.router.Routes())
 	logger.Info("Inited handlers")
 }
 
@@ -161,6 +369,7 @@ func (n *Node) initBlockchain() {
 	header := block_chain.GetHeader(bdb, headHeaderHash, blockNumber)
 	logger.Info("Head block header", header)
 	db := state.NewDatabase(bdb)
+	n.stateDatabase = db
 	n.stateDB, err = state.New(header.StateRoot, db)
 
 	n.transactionValidator = &transaction_validator.TransactionValidator{
@@ -168,45 +377,112 @@ func (n *Node) initBlockchain() {
 		ChainConfig: params.TestChainConfig,
 	}
 
+	n.authority = poa_consensus.NewAuthority()
+	validatorDB, err := database.NewBadgerDB(config.GetConfig().AuthorityValidatorDBPath)
+	observerDB, err := database.NewBadgerDB(config.GetConfig().AuthorityObserverDBPath)
+	n.authority.LoadFromDB(validatorDB, observerDB)
+
+	n.epochManager = poa_consensus.NewEpochManager(n.authority, bdb)
+	n.governance = poa_consensus.NewGovernance(n.authority, n.epochManager, bdb, validatorDB, observerDB)
+	if err := n.governance.LoadFromDB(); err != nil {
+		panic(err)
+	}
+	n.chainStates = poa_consensus.NewChainStates(db, bdb)
+	if err := n.chainStates.LoadFromDB(); err != nil {
+		panic(err)
+	}
+	n.stakePool = poa_consensus.NewStakePool(n.authority, n.epochManager, n.chainStates, validatorDB, observerDB)
+	n.slasher = poa_consensus.NewSlasher(
+		n.stakePool,
+		n.epochManager,
+		n.epochManager,
+		n.authority,
+		validatorDB,
+		observerDB,
+	)
+	// governance changes the validator set before stake sets the weights,
+	// double signing validators are removed last
+	n.epochManager.RegisterProcessor(n.governance)
+	n.epochManager.RegisterProcessor(n.stakePool)
+	n.epochManager.RegisterProcessor(n.slasher)
+
+	n.fiatReserve = poa_consensus.NewFiatReserve()
+	fiatReserveDB, err := database.NewBadgerDB(config.GetConfig().AuthorityFiatReserveDBPath)
+	if err != nil {
+		panic(err)
+	}
+	if err := n.fiatReserve.LoadFromStorage(fiatReserveDB); err != nil {
+		panic(err)
+	}
+	n.nativeIssuer = poa_consensus.NewNativeIssuer(n.fiatReserve, n.epochManager, n.chainStates, fiatReserveDB)
+	n.epochManager.RegisterProcessor(n.nativeIssuer)
+	// checked after the reserve mirror is updated
+	n.supplyChecker = poa_consensus.NewSupplyChecker(n.nativeIssuer, n.chainStates, bdb)
+	if err := n.supplyChecker.LoadFromDB(); err != nil {
+		panic(err)
+	}
+	n.epochManager.RegisterProcessor(n.supplyChecker)
+	adapter, err := bankAdapter()
+	if err != nil {
+		panic(err)
+	}
+	if adapter != nil {
+		n.reserveReconciler = poa_consensus.NewReserveReconciler(
+			n.nativeIssuer,
+			n.Address(),
+			adapter,
+			time.Duration(config.GetConfig().ReserveSyncInterval)*time.Second,
+			bdb,
+		)
+		if err := n.reserveReconciler.LoadFromDB(); err != nil {
+			panic(err)
+		}
+		n.epochManager.RegisterProcessor(n.reserveReconciler)
+	}
+
+	n.engine = poa_consensus.NewPOAConsensus(n.epochManager)
+	if timeout := config.GetConfig().ProposerTimeout; timeout > 0 {
+		n.engine.SetProposerTimeout(timeout)
+	}
+	n.engine.AddStateFinalizer(n.stakePool)
+	n.engine.AddStateFinalizer(n.slasher)
+	n.engine.AddStateFinalizer(n.nativeIssuer)
+	n.feeDistributor = poa_consensus.NewFeeDistributor(n.epochManager)
+	n.engine.AddStateFinalizer(n.feeDistributor)
+	if err := n.engine.Authorize(n.signer); err != nil {
+		panic(err)
+	}
+
 	n.bc, err = block_chain.NewBlockChain(
 		bdb,
 		&block_chain.CacheConfig{
 			Disabled: true, // TODO: check if this needed
 		},
-		params.TestChainConfig,        // TODO: check if this needed
-		&poa_consensus.POAConsensus{}, // TODO: check if this needed
-		evm.Config{},                  // TODO: check if this needed
+		params.TestChainConfig, // TODO: check if this needed
+		n.engine,
+		evm.Config{}, // TODO: check if this needed
 	)
 	if err != nil {
 		logger.Error("error when init block chain ", err)
 		panic(err.Error())
 	}
+	if err := n.epochManager.Init(n.bc); err != nil {
+		logger.Error("error when init epoch schedule ", err)
+		panic(err.Error())
+	}
 
-	n.authority = poa_consensus.NewAuthority()
-	validatorDB, err := database.NewBadgerDB(config.GetConfig().AuthorityValidatorDBPath)
-	observerDB, err := database.NewBadgerDB(config.GetConfig().AuthorityObserverDBPath)
-	n.authority.LoadFromDB(validatorDB, observerDB)
-
-	scheduleBlockNum := uint64(params.TempEpochLength * math.Floor(
-		float64(blockNumber)/float64(params.TempEpochLength),
-	))
-	logger.DebugP("[Node] scheduleBlockNum", scheduleBlockNum)
-	salt := block_chain.GetCanonicalHash(bdb, scheduleBlockNum)
-
-	n.proposerSchedule = poa_consensus.NewProposerSchedule(
-		salt.Bytes(),
-		scheduleBlockNum,
-		n.authority.ListValidators(), // todo: need to pass block number to it so it con get correct list of validator at that time
+	n.transactionPool = transaction_pool.NewTransactionPool(
+		txPoolConfig(),
+		&headState{bc: n.bc, db: n.stateDatabase},
 	)
 
-	n.transactionPool = transaction_pool.NewTransactionPool()
-
 	address := n.Address()
 	n.blockBuilder = block_builder.NewBlockBuilder(
 		n.transactionPool,
 		n.transactionValidator,
 		&address,
 		n.bc,
+		n.engine,
 		n.stateDB,
 	)
 
@@ -224,6 +500,51 @@ func (n *Node) initOthers() {
 		n.lookupTable,
 	)
 	n.explorerNotifier.SubscribeChanEvent(n.bc)
+
+	n.consensusHandler.SubscribeChanEvent(n.bc)
+	n.evidenceHandler.SubscribeChanEvent(n.bc)
+	n.transactionHandler.SubscribeChanEvent(n.bc)
+	n.epochManager.SubscribeChanEvent(n.bc)
+}
+
+// txPoolConfig applies the configured caps to the default pool config
+func txPoolConfig() transaction_pool.Config {
+	poolConfig := transaction_pool.DefaultConfig()
+	cfg := config.GetConfig()
+	if cfg.TxPoolAccountSlots > 0 {
+		poolConfig.AccountSlots = cfg.TxPoolAccountSlots
+	}
+	if cfg.TxPoolAccountQueue > 0 {
+		poolConfig.AccountQueue = cfg.TxPoolAccountQueue
+	}
+	if cfg.TxPoolGlobalSlots > 0 {
+		poolConfig.GlobalSlots = cfg.TxPoolGlobalSlots
+	}
+	if cfg.TxPoolGlobalQueue > 0 {
+		poolConfig.GlobalQueue = cfg.TxPoolGlobalQueue
+	}
+	if cfg.TxPoolPriceBump > 0 {
+		poolConfig.PriceBump = cfg.TxPoolPriceBump
+	}
+	return poolConfig
+}
+
+// bankAdapter connects the core banking system configured for this node's
+// bank, nil when none is configured
+func bankAdapter() (bank_adapter.BankAdapter, error) {
+	cfg := config.GetConfig()
+	if cfg.BankAdapterMockFile != "" {
+		return bank_adapter.NewMockAdapterFromFile(cfg.BankAdapterMockFile)
+	}
+	if cfg.BankAdapterURL == "" {
+		return nil, nil
+	}
+	return bank_adapter.NewHTTPAdapter(bank_adapter.HTTPAdapterConfig{
+		BaseURL:  cfg.BankAdapterURL,
+		CertFile: cfg.BankAdapterCertFile,
+		KeyFile:  cfg.BankAdapterKeyFile,
+		CAFile:   cfg.BankAdapterCAFile,
+	})
 }
 
 // run
@@ -243,19 +564,45 @@ func (n *Node) Start() {
 		}
 	}()
 
-	// let test generate block
+	if n.reserveReconciler != nil {
+		n.reserveReconciler.Start()
+	}
+	n.mesh.Start(config.GetConfig().BootAddress)
+	n.downloader.Start()
+	n.transactionHandler.Start()
+
+	// produce blocks on the heights this node is scheduled for, or backs up
+	// once the scheduled proposer timed out
 	go func() {
 		for {
-			bl, err := n.blockBuilder.GenerateBlock(
-				n.bc.CurrentHeader(),
-			)
+			time.Sleep(1500 * time.Millisecond)
+			if err := n.supplyChecker.Violation(); err != nil {
+				logger.Error("[Node] block production halted", err)
+				continue
+			}
+			// never build on a head that is still catching up
+			if n.downloader.Synchronising() {
+				continue
+			}
+			currentHeader := n.bc.CurrentHeader()
+			proposer, round, err := n.engine.CurrentProposer(currentHeader)
+			if err != nil || proposer != n.Address() {
+				continue
+			}
+			if round > 0 {
+				logger.Warn("[Node] proposer timed out, producing backup block", currentHeader.Height+1, round)
+			}
+			bl, err := n.blockBuilder.GenerateBlock(currentHeader)
 			if err != nil {
 				logger.Error("error when generate block", err)
 				continue
 			}
 			// add block to chain
-			_, err = n.bc.InsertChain([]*block.Block{bl})
-			time.Sleep(1500 * time.Millisecond)
+			if _, err = n.bc.InsertChain([]*block.Block{bl}); err != nil {
+				logger.Error("error when insert generated block", err)
+				continue
+			}
+			n.blockHandler.BroadcastBlock(bl)
 		}
 	}()
 	//
@@ -264,6 +611,12 @@ func (n *Node) Start() {
 func (n *Node) Stop() {
 	n.server.Close()
 	n.wsServer.Close()
+	n.mesh.Stop()
+	n.downloader.Stop()
+	n.transactionHandler.Stop()
+	if n.reserveReconciler != nil {
+		n.reserveReconciler.Stop()
+	}
 	// TODO: more clean up
 }
 
@@ -23,6 +23,7 @@ import (
 type TCPClient struct {
 	timeout time.Duration // Timeout for dialing connections
 	signer  *signer.Signer
+	status  *message.Status // Chain and protocol announced in the handshake
 }
 
 // NewTCPClient creates a new instance of a TCPClient with a given timeout.
@@ -33,9 +34,16 @@ func NewTCPClient(
 	return &TCPClient{
 		timeout: timeout,
 		signer:  signer,
+		status:  message.ClientStatus(),
 	}
 }
 
+// SetStatus sets the status announced to the dialed peers, a client that
+// does not set it connects to any chain
+func (c *TCPClient) SetStatus(status *message.Status) {
+	c.status = status
+}
+
 // Dial establishes an outbound TCP connection to a peer using the given address.
 // It also performs a handshake to ensure the peer is ready.
 func (c *TCPClient) Dial(address string) (p2p.Peer, error) {
@@ -60,12 +68,17 @@ func (c *TCPClient) Dial(address string) (p2p.Peer, error) {
 func (c *TCPClient) handshake(conn net.Conn) (p2p.Peer, error) {
 	peerID := conn.RemoteAddr().
 		String()
-	p := peer.NewTCPPeer(conn, peerID)
+	p := peer.NewTCPPeer(conn, peerID).(*peer.TcpPeer)
 	// 1. Send initial handshake with address form signer
 	walletAddress, err := c.signer.WalletAddress()
 	if err != nil {
 		return nil, err
 	}
+	ephemeral, err := peer.GenerateEphemeralKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+	ephemeralKey := peer.EphemeralPublicKey(ephemeral)
 	payload := map[string]any{
 		"time_stamp": time.Now().Unix(),
 		"uuid":       uuid.New().String(),
@@ -75,10 +88,12 @@ func (c *TCPClient) handshake(conn net.Conn) (p2p.Peer, error) {
 	initMsg := &message.HandshakeInit{
 		WalletAddress: walletAddress.Bytes(),
 		Payload:       bPayload,
+		EphemeralKey:  ephemeralKey,
+		Status:        c.status,
 	}
 	fmtInitMsg := &message.Message{
 		Header: &message.Header{
-			Version:     config.GetConfig().Version,
+			Version:     message.ProtocolVersion,
 			SenderID:    config.GetConfig().NodeID,
 			MessageType: message.MessageHandshakeInit,
 			Timestamp:   time.Now().Unix(),
@@ -112,7 +127,9 @@ func (c *TCPClient) handshake(conn net.Conn) (p2p.Peer, error) {
 				continue // malformed payload, ignore
 			}
 
-			pub, err := crypto.SigToPub(crypto.Keccak256(bPayload), ack.Signature)
+			// the ack signs our challenge, both session keys and its status
+			binding := peer.SessionBinding(bPayload, ephemeralKey, ack.EphemeralKey, ack.Status.Bytes())
+			pub, err := crypto.SigToPub(crypto.Keccak256(binding), ack.Signature)
 			if err != nil {
 				return nil, fmt.Errorf("failed to extrack pub from sign: %w", err)
 			}
@@ -121,9 +138,19 @@ func (c *TCPClient) handshake(conn net.Conn) (p2p.Peer, error) {
 				slog.Warn("Invalid sign in HandshakeConfirm message")
 				continue // malformed sign, ignore
 			}
+			version, err := c.status.Negotiate(ack.Status)
+			if err != nil {
+				return nil, fmt.Errorf("incompatible peer %s: %w", peerID, err)
+			}
+			session, err := peer.NewSession(ephemeral, ack.EphemeralKey, true)
+			if err != nil {
+				return nil, fmt.Errorf("failed to establish session: %w", err)
+			}
 			// 3. Send final handshake confirmation
 			// sign message and send confirmation
-			confirmSign, err := c.signer.SignBytes(ack.Payload)
+			confirmSign, err := c.signer.SignBytes(
+				peer.SessionBinding(ack.Payload, ephemeralKey, ack.EphemeralKey, c.status.Bytes()),
+			)
 			if err != nil {
 				return nil, fmt.Errorf("failed sign confirm: %w", err)
 			}
@@ -132,7 +159,7 @@ func (c *TCPClient) handshake(conn net.Conn) (p2p.Peer, error) {
 			}
 			fmtConfirmMsg := &message.Message{
 				Header: &message.Header{
-					Version:     config.GetConfig().Version,
+					Version:     message.ProtocolVersion,
 					SenderID:    config.GetConfig().NodeID,
 					MessageType: message.MessageHandshakeConfirm,
 					Timestamp:   time.Now().Unix(),
@@ -140,7 +167,13 @@ func (c *TCPClient) handshake(conn net.Conn) (p2p.Peer, error) {
 				},
 				Payload: confirmMsg,
 			}
-			p.Send(fmtConfirmMsg)
+			if err := p.Send(fmtConfirmMsg); err != nil {
+				return nil, fmt.Errorf("failed to send handshake confirm: %w", err)
+			}
+			// every frame after the confirmation is encrypted
+			p.EnableEncryption(session)
+			p.SetWalletAddress(addr)
+			p.SetStatus(ack.Status, version)
 			logger.Info("Inited connection with ", peerID)
 			return p, nil
 		}
@@ -2,28 +2,56 @@ package p2p
 
 import (
 	"time"
+
+	"FichainCore/common"
 )
 
+// RateLimit is how many messages of a type a peer may send per second, with
+// bursts of up to Burst messages
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
 // Config holds configuration parameters for the P2P network
 type Config struct {
-	ListenAddress       string        // Address the server listens on
-	MaxPeers            int           // Maximum number of connected peers
-	HandshakeTimeout    time.Duration // Timeout for completing a handshake
-	MessageReadTimeout  time.Duration // Timeout for reading a message
-	MessageWriteTimeout time.Duration // Timeout for sending a message
-	EnableRateLimiter   bool          // Enable rate limiting
-	Debug               bool          // Enable debug logging
+	ListenAddress       string               // Address the server listens on
+	MaxPeers            int                  // Maximum number of connected peers of all roles
+	MaxValidatorPeers   int                  // Maximum number of connected validators, 0 bounds them by MaxPeers only
+	MaxObserverPeers    int                  // Maximum number of connected observers, 0 bounds them by MaxPeers only
+	MaxClientPeers      int                  // Maximum number of connected clients, 0 bounds them by MaxPeers only
+	MaxWsMessageSize    int64                // Largest message a WebSocket peer may send
+	HandshakeTimeout    time.Duration        // Timeout for completing a handshake
+	MessageReadTimeout  time.Duration        // Timeout for reading a message
+	MessageWriteTimeout time.Duration        // Timeout for sending a message
+	EnableRateLimiter   bool                 // Enable rate limiting
+	MessageRate         RateLimit            // Rate of each message type per peer
+	MessageRates        map[string]RateLimit // Rates of specific message types, overriding MessageRate
+	BanThreshold        int                  // Misbehaviour score at which a peer is banned
+	BanDuration         time.Duration        // How long a banned peer may not reconnect
+	ScoreDecay          int                  // Misbehaviour points forgiven every second
+	Explorers           []common.Address     // Wallets of the explorers, they may pull the data of every account
+	Debug               bool                 // Enable debug logging
 }
 
 // DefaultConfig returns a Config with sane defaults
 func DefaultConfig() *Config {
 	return &Config{
 		ListenAddress:       "0.0.0.0:8080",
-		MaxPeers:            100,
+		MaxPeers:            200,
+		MaxValidatorPeers:   0,
+		MaxObserverPeers:    50,
+		MaxClientPeers:      100,
+		MaxWsMessageSize:    4 * 1024 * 1024,
 		HandshakeTimeout:    5 * time.Second,
 		MessageReadTimeout:  10 * time.Second,
 		MessageWriteTimeout: 10 * time.Second,
-		EnableRateLimiter:   false,
+		EnableRateLimiter:   true,
+		MessageRate:         RateLimit{Rate: 200, Burst: 400},
+		MessageRates:        map[string]RateLimit{},
+		BanThreshold:        100,
+		BanDuration:         time.Hour,
+		ScoreDecay:          1,
 		Debug:               false,
 	}
 }
@@ -0,0 +1,291 @@
+package discovery
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/common"
+	"FichainCore/p2p"
+	"FichainCore/p2p/lookup_table"
+	"FichainCore/p2p/message"
+)
+
+const (
+	defaultMaintainInterval = 5 * time.Second
+	minRedialDelay          = time.Second
+	maxRedialDelay          = 5 * time.Minute
+	maxPeerListLength       = 64 // peers and addresses read from or sent in a peer list
+)
+
+// Validators lists the current validator set, implemented by
+// poa_consensus.Authority
+type Validators interface {
+	ListValidators() map[common.Address]*big.Int
+}
+
+// Registrar starts serving a dialed peer, implemented by server.TCPServer
+type Registrar interface {
+	RegisterPeer(p p2p.Peer)
+}
+
+// Sender sends a message to a peer
+type Sender interface {
+	SendMessageToPeer(peer p2p.Peer, msgType string, payload message.HaveProto) error
+}
+
+type redial struct {
+	attempts int
+	next     time.Time
+}
+
+// Mesh keeps this node connected to every validator. Peers exchange the peer
+// lists of their PeerBook after each handshake, so the listening address of
+// every validator spreads through the network. Validators without a live
+// connection are dialed, a failed dial is retried with an exponential backoff
+// and a dropped connection is redialed right away. Of two validators only the
+// one with the lower address dials, so a pair keeps a single connection.
+type Mesh struct {
+	self        common.Address
+	advertise   string // address this node listens on for others
+	book        *PeerBook
+	client      p2p.Client
+	registrar   Registrar
+	lookupTable *lookup_table.LookupTable
+	sender      Sender
+	validators  Validators
+	interval    time.Duration
+
+	redials map[common.Address]*redial
+	dialing map[common.Address]bool
+	wake    chan struct{}
+	quit    chan struct{}
+
+	sync.Mutex
+}
+
+func NewMesh(
+	self common.Address,
+	advertise string,
+	book *PeerBook,
+	client p2p.Client,
+	registrar Registrar,
+	lookupTable *lookup_table.LookupTable,
+	sender Sender,
+	validators Validators,
+	interval time.Duration,
+) *Mesh {
+	if interval == 0 {
+		interval = defaultMaintainInterval
+	}
+	return &Mesh{
+		self:        self,
+		advertise:   advertise,
+		book:        book,
+		client:      client,
+		registrar:   registrar,
+		lookupTable: lookupTable,
+		sender:      sender,
+		validators:  validators,
+		interval:    interval,
+		redials:     make(map[common.Address]*redial),
+		dialing:     make(map[common.Address]bool),
+		wake:        make(chan struct{}, 1),
+		quit:        make(chan struct{}),
+	}
+}
+
+func (m *Mesh) Handlers() map[string]func(p2p.Peer, *message.Message) error {
+	return map[string]func(p2p.Peer, *message.Message) error{
+		message.MessagePeerList: m.PeerList,
+	}
+}
+
+// Start dials the boot node and the known peers once, then keeps the
+// validator connections alive
+func (m *Mesh) Start(bootAddress string) {
+	if bootAddress != "" {
+		m.book.AddKnownPeer(bootAddress)
+	}
+	go func() {
+		for _, address := range m.book.FindPeers() {
+			if _, err := m.dial(address); err != nil {
+				logger.Debug("[Mesh] unable to dial known peer", address, err)
+			}
+		}
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.Maintain()
+			select {
+			case <-ticker.C:
+			case <-m.wake:
+			case <-m.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (m *Mesh) Stop() {
+	close(m.quit)
+}
+
+// PeerConnected shares the peer list with a peer that completed its
+// handshake and redials it once the connection drops
+func (m *Mesh) PeerConnected(peer p2p.Peer) {
+	wallet := peer.WalletAddress()
+	m.resetRedial(wallet)
+	err := m.sender.SendMessageToPeer(peer, message.MessagePeerList, m.peerList())
+	if err != nil {
+		logger.Warn("[Mesh] error when send peer list", err)
+	}
+	go func() {
+		<-peer.Done()
+		m.lookupTable.RemovePeer(wallet, peer)
+		m.resetRedial(wallet)
+		m.trigger()
+	}()
+}
+
+// PeerList stores the peers a peer knows as hints and dials the validators
+// among them, a wallet address is only trusted once dialed
+func (m *Mesh) PeerList(peer p2p.Peer, msg *message.Message) error {
+	list, ok := msg.Payload.(*message.PeerList)
+	if !ok {
+		return fmt.Errorf("invalid peer list payload")
+	}
+	peers, addresses := list.Peers, list.Addresses
+	if len(peers) > maxPeerListLength {
+		peers = peers[:maxPeerListLength]
+	}
+	if len(addresses) > maxPeerListLength {
+		addresses = addresses[:maxPeerListLength]
+	}
+	for _, info := range peers {
+		if info.WalletAddress == m.self {
+			continue
+		}
+		m.book.AddHint(info.WalletAddress, info.Address)
+	}
+	for _, address := range addresses {
+		m.book.AddKnownPeer(address)
+	}
+	m.trigger()
+	return nil
+}
+
+// Maintain dials every validator that is not connected and whose backoff
+// elapsed
+func (m *Mesh) Maintain() {
+	validators := m.validators.ListValidators()
+	_, selfValidator := validators[m.self]
+	now := time.Now()
+	for wallet := range validators {
+		if wallet == m.self || m.lookupTable.Has(wallet) {
+			continue
+		}
+		// the other validator dials this node
+		if selfValidator && bytes.Compare(wallet.Bytes(), m.self.Bytes()) < 0 {
+			continue
+		}
+		address, ok := m.book.Lookup(wallet)
+		if !ok {
+			continue
+		}
+		m.Lock()
+		state := m.redials[wallet]
+		if m.dialing[wallet] || (state != nil && now.Before(state.next)) {
+			m.Unlock()
+			continue
+		}
+		m.dialing[wallet] = true
+		m.Unlock()
+
+		go func(wallet common.Address, address string) {
+			defer func() {
+				m.Lock()
+				delete(m.dialing, wallet)
+				m.Unlock()
+			}()
+			dialed, err := m.dial(address)
+			if err == nil && dialed != wallet {
+				err = fmt.Errorf("%s is now served by %s", address, dialed.Hex())
+			}
+			if err != nil {
+				delay := m.failRedial(wallet)
+				logger.Warn("[Mesh] unable to dial validator", wallet.Hex(), address, err, "retry in", delay)
+			}
+		}(wallet, address)
+	}
+}
+
+// dial connects to an address and registers the peer, it returns the wallet
+// proved in the handshake
+func (m *Mesh) dial(address string) (common.Address, error) {
+	peer, err := m.client.Dial(address)
+	if err != nil {
+		return common.Address{}, err
+	}
+	wallet := peer.WalletAddress()
+	if wallet == m.self || m.lookupTable.Has(wallet) {
+		peer.Close()
+		return wallet, nil
+	}
+	m.book.AddPeer(wallet, address)
+	m.lookupTable.Add(wallet, peer)
+	m.registrar.RegisterPeer(peer)
+	logger.Info("[Mesh] connected to", wallet.Hex(), address)
+	return wallet, nil
+}
+
+// peerList is this node followed by the peers it knows
+func (m *Mesh) peerList() *message.PeerList {
+	peers := []*message.PeerInfo{}
+	if validAddress(m.advertise) {
+		peers = append(peers, &message.PeerInfo{WalletAddress: m.self, Address: m.advertise})
+	}
+	peers = append(peers, m.book.Peers()...)
+	if len(peers) > maxPeerListLength {
+		peers = peers[:maxPeerListLength]
+	}
+	return &message.PeerList{Peers: peers}
+}
+
+func (m *Mesh) resetRedial(wallet common.Address) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.redials, wallet)
+}
+
+// failRedial doubles the delay before the next dial of a wallet
+func (m *Mesh) failRedial(wallet common.Address) time.Duration {
+	m.Lock()
+	defer m.Unlock()
+	state, ok := m.redials[wallet]
+	if !ok {
+		state = &redial{}
+		m.redials[wallet] = state
+	}
+	delay := maxRedialDelay
+	if state.attempts < 16 {
+		delay = minRedialDelay << state.attempts
+		if delay > maxRedialDelay {
+			delay = maxRedialDelay
+		}
+	}
+	state.attempts++
+	state.next = time.Now().Add(delay)
+	return delay
+}
+
+func (m *Mesh) trigger() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,231 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/p2p"
+	"FichainCore/p2p/lookup_table"
+	"FichainCore/p2p/message"
+)
+
+type testPeer struct {
+	p2p.Peer
+	wallet common.Address
+	done   chan struct{}
+}
+
+func (p *testPeer) WalletAddress() common.Address { return p.wallet }
+func (p *testPeer) Done() <-chan struct{}         { return p.done }
+func (p *testPeer) Close() error                  { close(p.done); return nil }
+
+// testNetwork dials the wallets listening at an address, failing the first
+// dials of an address when asked to
+type testNetwork struct {
+	listening  map[string]common.Address
+	failures   map[string]int
+	dials      map[string]int
+	registered []p2p.Peer
+	sent       []*message.PeerList
+	sync.Mutex
+}
+
+func (n *testNetwork) Dial(address string) (p2p.Peer, error) {
+	n.Lock()
+	defer n.Unlock()
+	n.dials[address]++
+	if n.failures[address] > 0 {
+		n.failures[address]--
+		return nil, errors.New("connection refused")
+	}
+	wallet, ok := n.listening[address]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return &testPeer{wallet: wallet, done: make(chan struct{})}, nil
+}
+
+func (n *testNetwork) RegisterPeer(p p2p.Peer) {
+	n.Lock()
+	n.registered = append(n.registered, p)
+	n.Unlock()
+}
+
+func (n *testNetwork) SendMessageToPeer(peer p2p.Peer, msgType string, payload message.HaveProto) error {
+	n.Lock()
+	n.sent = append(n.sent, payload.(*message.PeerList))
+	n.Unlock()
+	return nil
+}
+
+func (n *testNetwork) dialCount(address string) int {
+	n.Lock()
+	defer n.Unlock()
+	return n.dials[address]
+}
+
+type testValidators map[common.Address]*big.Int
+
+func (v testValidators) ListValidators() map[common.Address]*big.Int { return v }
+
+func TestPeerBook(t *testing.T) {
+	db, _ := database.NewMemDatabase()
+	book := NewPeerBook(db)
+	wallet := common.BigToAddress(big.NewInt(7))
+
+	book.AddKnownPeer("10.0.0.1:3000")
+	book.AddKnownPeer("0.0.0.0:3000")
+	book.AddKnownPeer("no-port")
+	book.AddPeer(wallet, "10.0.0.2:3000")
+	assert.Equal(t, []string{"10.0.0.1:3000", "10.0.0.2:3000"}, book.FindPeers())
+
+	// a wallet moving to another address replaces its entry
+	book.AddPeer(wallet, "10.0.0.3:3000")
+	address, ok := book.Lookup(wallet)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.3:3000", address)
+	assert.Len(t, book.Peers(), 1)
+
+	// a gossiped address never replaces the one proved in a handshake
+	book.AddHint(wallet, "10.0.0.66:3000")
+	address, _ = book.Lookup(wallet)
+	assert.Equal(t, "10.0.0.3:3000", address)
+	other := common.BigToAddress(big.NewInt(8))
+	book.AddHint(other, "10.0.0.4:3000")
+	address, ok = book.Lookup(other)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.4:3000", address)
+	assert.Len(t, book.Peers(), 1, "hints are not shared")
+
+	// known peers survive a restart
+	reloaded := NewPeerBook(db)
+	assert.Equal(t, []string{"10.0.0.1:3000", "10.0.0.3:3000"}, reloaded.FindPeers())
+	address, ok = reloaded.Lookup(wallet)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.3:3000", address)
+	_, ok = reloaded.Lookup(other)
+	assert.False(t, ok)
+}
+
+func TestPeerBookCaps(t *testing.T) {
+	db, _ := database.NewMemDatabase()
+	book := NewPeerBook(db)
+	for i := 0; i < maxKnownPeers+10; i++ {
+		book.AddKnownPeer(fmt.Sprintf("10.1.%d.%d:3000", i/256, i%256))
+	}
+	for i := 0; i < maxPeerHints+10; i++ {
+		book.AddHint(common.BigToAddress(big.NewInt(int64(i+1))), "10.0.0.1:3000")
+	}
+	assert.Len(t, book.FindPeers(), maxKnownPeers)
+	assert.Len(t, book.hints, maxPeerHints)
+
+	// a proved wallet makes room in a full book and drops its hint
+	wallet := common.BigToAddress(big.NewInt(1))
+	book.AddPeer(wallet, "10.0.0.2:3000")
+	assert.Len(t, book.FindPeers(), maxKnownPeers)
+	address, ok := book.Lookup(wallet)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.2:3000", address)
+	assert.Len(t, book.hints, maxPeerHints-1)
+
+	// a peer list is read up to its cap
+	meshDB, _ := database.NewMemDatabase()
+	mesh := NewMesh(
+		common.Address{},
+		"",
+		NewPeerBook(meshDB),
+		nil,
+		nil,
+		lookup_table.NewLookupTable(),
+		nil,
+		testValidators{},
+		time.Hour,
+	)
+	list := &message.PeerList{}
+	for i := 0; i < maxPeerListLength+10; i++ {
+		list.Peers = append(list.Peers, &message.PeerInfo{
+			WalletAddress: common.BigToAddress(big.NewInt(int64(i + 1))),
+			Address:       "10.0.0.1:3000",
+		})
+	}
+	assert.NoError(t, mesh.PeerList(nil, &message.Message{Payload: list}))
+	assert.Len(t, mesh.book.hints, maxPeerListLength)
+}
+
+func TestMesh(t *testing.T) {
+	self := common.BigToAddress(big.NewInt(5))
+	lower := common.BigToAddress(big.NewInt(2))
+	higher := common.BigToAddress(big.NewInt(9))
+	validators := testValidators{self: common.Big1, lower: common.Big1, higher: common.Big1}
+
+	network := &testNetwork{
+		listening: map[string]common.Address{"10.0.0.2:3000": lower, "10.0.0.9:3000": higher},
+		failures:  map[string]int{"10.0.0.9:3000": 2},
+		dials:     map[string]int{},
+	}
+	db, _ := database.NewMemDatabase()
+	lookupTable := lookup_table.NewLookupTable()
+	mesh := NewMesh(
+		self,
+		"10.0.0.5:3000",
+		NewPeerBook(db),
+		network,
+		network,
+		lookupTable,
+		network,
+		validators,
+		time.Hour,
+	)
+
+	// validators are learned from the peer list of a connected peer
+	err := mesh.PeerList(nil, &message.Message{Payload: &message.PeerList{Peers: []*message.PeerInfo{
+		{WalletAddress: lower, Address: "10.0.0.2:3000"},
+		{WalletAddress: higher, Address: "10.0.0.9:3000"},
+		{WalletAddress: self, Address: "10.0.0.99:3000"},
+	}}})
+	assert.NoError(t, err)
+	_, ok := mesh.book.Lookup(self)
+	assert.False(t, ok)
+
+	// the lower validator dials this node, the failed dial backs off
+	mesh.Maintain()
+	assert.Eventually(t, func() bool { return network.dialCount("10.0.0.9:3000") == 1 }, time.Second, time.Millisecond)
+	mesh.Maintain()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, network.dialCount("10.0.0.9:3000"))
+	assert.Equal(t, 0, network.dialCount("10.0.0.2:3000"))
+
+	mesh.Lock()
+	mesh.redials[higher].next = time.Now()
+	mesh.Unlock()
+	mesh.Maintain()
+	assert.Eventually(t, func() bool { return network.dialCount("10.0.0.9:3000") == 2 }, time.Second, time.Millisecond)
+	mesh.Lock()
+	assert.Equal(t, 2, mesh.redials[higher].attempts)
+	assert.True(t, mesh.redials[higher].next.After(time.Now().Add(time.Second)))
+	mesh.redials[higher].next = time.Now()
+	mesh.Unlock()
+
+	mesh.Maintain()
+	assert.Eventually(t, func() bool { return lookupTable.Has(higher) }, time.Second, time.Millisecond)
+	peer, _ := lookupTable.Get(higher)
+	mesh.PeerConnected(peer)
+	assert.Len(t, network.registered, 1)
+	assert.Equal(t, self, network.sent[0].Peers[0].WalletAddress)
+	assert.Equal(t, "10.0.0.5:3000", network.sent[0].Peers[0].Address)
+
+	// a dropped connection is redialed without waiting for the backoff
+	peer.Close()
+	assert.Eventually(t, func() bool { return !lookupTable.Has(higher) }, time.Second, time.Millisecond)
+	mesh.Maintain()
+	assert.Eventually(t, func() bool { return lookupTable.Has(higher) }, time.Second, time.Millisecond)
+	assert.Equal(t, 4, network.dialCount("10.0.0.9:3000"))
+}
@@ -0,0 +1,185 @@
+package discovery
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/common"
+	"FichainCore/database"
+	"FichainCore/p2p/message"
+)
+
+const (
+	maxKnownPeers = 1024 // listening addresses remembered
+	maxPeerHints  = 256  // gossiped wallet addresses remembered until dialed
+)
+
+// PeerBook remembers the listening address of every peer heard of, with the
+// wallet it proved in a handshake when known. Entries are persisted so a
+// restarted node can reconnect without a boot node. The wallet addresses
+// gossiped by peers are kept apart as hints until a dial proves them, so a
+// peer cannot redirect the wallet of another one.
+type PeerBook struct {
+	db    database.Database
+	peers map[string]common.Address // listening address -> wallet, zero if unknown
+	hints map[common.Address]string // wallet -> listening address gossiped by a peer
+
+	sync.RWMutex
+}
+
+// NewPeerBook loads the known peers from db
+func NewPeerBook(db database.Database) *PeerBook {
+	b := &PeerBook{
+		db:    db,
+		peers: make(map[string]common.Address),
+		hints: make(map[common.Address]string),
+	}
+	err := db.IterateKeys(func(key, value []byte) error {
+		b.peers[string(key)] = common.BytesToAddress(value)
+		return nil
+	})
+	if err != nil {
+		logger.Warn("[PeerBook] unable to load known peers", err)
+	}
+	return b
+}
+
+// FindPeers returns every known listening address
+func (b *PeerBook) FindPeers() []string {
+	b.RLock()
+	defer b.RUnlock()
+	addresses := make([]string, 0, len(b.peers))
+	for address := range b.peers {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// AddKnownPeer remembers an address whose wallet is not known yet, such as a
+// boot node
+func (b *PeerBook) AddKnownPeer(address string) {
+	if !validAddress(address) {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	if _, ok := b.peers[address]; ok || len(b.peers) >= maxKnownPeers {
+		return
+	}
+	b.put(address, common.Address{})
+}
+
+// AddHint remembers the listening address a peer gossiped for a wallet. It is
+// only used to dial the wallet while no handshake proved its address.
+func (b *PeerBook) AddHint(wallet common.Address, address string) {
+	if wallet == (common.Address{}) || !validAddress(address) {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	if _, ok := b.lookup(wallet); ok {
+		return
+	}
+	if _, ok := b.hints[wallet]; !ok && len(b.hints) >= maxPeerHints {
+		return
+	}
+	b.hints[wallet] = address
+}
+
+// AddPeer remembers the listening address a wallet proved in a handshake,
+// replacing the address it was previously known at
+func (b *PeerBook) AddPeer(wallet common.Address, address string) {
+	if wallet == (common.Address{}) || !validAddress(address) {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	delete(b.hints, wallet)
+	if b.peers[address] == wallet {
+		return
+	}
+	for known, knownWallet := range b.peers {
+		if knownWallet == wallet {
+			b.delete(known)
+		}
+	}
+	if _, ok := b.peers[address]; !ok && len(b.peers) >= maxKnownPeers {
+		// an address whose wallet is unknown makes room
+		for known, knownWallet := range b.peers {
+			if knownWallet == (common.Address{}) {
+				b.delete(known)
+				break
+			}
+		}
+		if len(b.peers) >= maxKnownPeers {
+			return
+		}
+	}
+	b.put(address, wallet)
+}
+
+// Lookup returns the listening address of a wallet, the one proved in a
+// handshake first
+func (b *PeerBook) Lookup(wallet common.Address) (string, bool) {
+	b.RLock()
+	defer b.RUnlock()
+	if address, ok := b.lookup(wallet); ok {
+		return address, true
+	}
+	address, ok := b.hints[wallet]
+	return address, ok
+}
+
+func (b *PeerBook) lookup(wallet common.Address) (string, bool) {
+	for address, known := range b.peers {
+		if known == wallet {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// Peers lists the peers whose wallet was proved, to be shared with others
+func (b *PeerBook) Peers() []*message.PeerInfo {
+	b.RLock()
+	defer b.RUnlock()
+	peers := make([]*message.PeerInfo, 0, len(b.peers))
+	for address, wallet := range b.peers {
+		if wallet == (common.Address{}) {
+			continue
+		}
+		peers = append(peers, &message.PeerInfo{WalletAddress: wallet, Address: address})
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Address < peers[j].Address
+	})
+	return peers
+}
+
+func (b *PeerBook) put(address string, wallet common.Address) {
+	b.peers[address] = wallet
+	if err := b.db.Put([]byte(address), wallet.Bytes()); err != nil {
+		logger.Warn("[PeerBook] unable to persist peer", address, err)
+	}
+}
+
+func (b *PeerBook) delete(address string) {
+	delete(b.peers, address)
+	if err := b.db.Delete([]byte(address)); err != nil {
+		logger.Warn("[PeerBook] unable to delete peer", address, err)
+	}
+}
+
+// validAddress rejects addresses that cannot be dialed from another host
+func validAddress(address string) bool {
+	index := strings.LastIndex(address, ":")
+	if index <= 0 || index == len(address)-1 {
+		return false
+	}
+	host := address[:index]
+	return host != "0.0.0.0" && host != "[::]"
+}
@@ -34,6 +34,16 @@ func (lt *LookupTable) Remove(address common.Address) {
 	delete(lt.table, address)
 }
 
+// RemovePeer deletes an address only while it still maps to the given peer,
+// so a closing connection does not evict the one that replaced it
+func (lt *LookupTable) RemovePeer(address common.Address, p p2p.Peer) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	if lt.table[address] == p {
+		delete(lt.table, address)
+	}
+}
+
 // Get returns the TcpPeer pointer for a given address and a boolean indicating if it was found
 func (lt *LookupTable) Get(address common.Address) (p2p.Peer, bool) {
 	lt.mu.RLock()
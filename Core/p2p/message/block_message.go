@@ -0,0 +1,30 @@
+package message
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	pb "FichainCore/proto"
+)
+
+// BlockMessage carries a full block between nodes
+type BlockMessage struct {
+	Block *block.Block
+}
+
+// Proto converts BlockMessage to protobuf format
+func (m *BlockMessage) Proto() proto.Message {
+	return m.Block.Proto()
+}
+
+// FromProto populates BlockMessage from a protobuf message
+func (m *BlockMessage) FromProto(pbBlock *pb.Block) error {
+	if pbBlock == nil || pbBlock.Header == nil {
+		return errors.New("missing block header")
+	}
+	m.Block = &block.Block{}
+	m.Block.FromProto(pbBlock)
+	return nil
+}
@@ -0,0 +1,180 @@
+package message
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/block"
+	"FichainCore/common"
+	pb "FichainCore/proto"
+)
+
+// HeaderMessage carries a single block header, used to announce the head of
+// a node's chain
+type HeaderMessage struct {
+	Header *block.BlockHeader
+}
+
+// Proto converts HeaderMessage to protobuf format
+func (m *HeaderMessage) Proto() proto.Message {
+	return m.Header.Proto()
+}
+
+// FromProto populates HeaderMessage from a protobuf message
+func (m *HeaderMessage) FromProto(pbHeader *pb.BlockHeader) error {
+	if pbHeader == nil {
+		return errors.New("missing header")
+	}
+	m.Header = &block.BlockHeader{}
+	m.Header.FromProto(pbHeader)
+	return nil
+}
+
+// HeadersRequest asks for Count consecutive canonical headers from height From
+type HeadersRequest struct {
+	From  uint64
+	Count uint64
+}
+
+// Proto converts HeadersRequest to protobuf format
+func (m *HeadersRequest) Proto() proto.Message {
+	return &pb.HeadersRequest{
+		From:  m.From,
+		Count: m.Count,
+	}
+}
+
+// FromProto populates HeadersRequest from a protobuf message
+func (m *HeadersRequest) FromProto(pbRequest *pb.HeadersRequest) error {
+	m.From = pbRequest.From
+	m.Count = pbRequest.Count
+	return nil
+}
+
+// HeadersMessage is a batch of consecutive canonical headers
+type HeadersMessage struct {
+	Headers []*block.BlockHeader
+}
+
+// Proto converts HeadersMessage to protobuf format
+func (m *HeadersMessage) Proto() proto.Message {
+	headers := make([]*pb.BlockHeader, len(m.Headers))
+	for i, header := range m.Headers {
+		headers[i] = header.Proto()
+	}
+	return &pb.BlockHeaders{
+		Headers: headers,
+	}
+}
+
+// FromProto populates HeadersMessage from a protobuf message
+func (m *HeadersMessage) FromProto(pbHeaders *pb.BlockHeaders) error {
+	m.Headers = make([]*block.BlockHeader, len(pbHeaders.Headers))
+	for i, pbHeader := range pbHeaders.Headers {
+		if pbHeader == nil {
+			return errors.New("missing header")
+		}
+		m.Headers[i] = &block.BlockHeader{}
+		m.Headers[i].FromProto(pbHeader)
+	}
+	return nil
+}
+
+// BodiesRequest asks for the bodies of blocks by hash
+type BodiesRequest struct {
+	Hashes []common.Hash
+}
+
+// Proto converts BodiesRequest to protobuf format
+func (m *BodiesRequest) Proto() proto.Message {
+	hashes := make([][]byte, len(m.Hashes))
+	for i, hash := range m.Hashes {
+		hashes[i] = hash.Bytes()
+	}
+	return &pb.BodiesRequest{
+		Hashes: hashes,
+	}
+}
+
+// FromProto populates BodiesRequest from a protobuf message
+func (m *BodiesRequest) FromProto(pbRequest *pb.BodiesRequest) error {
+	m.Hashes = make([]common.Hash, len(pbRequest.Hashes))
+	for i, hash := range pbRequest.Hashes {
+		m.Hashes[i] = common.BytesToHash(hash)
+	}
+	return nil
+}
+
+// BodiesMessage holds block bodies in the order they were requested
+type BodiesMessage struct {
+	Bodies []*block.Body
+}
+
+// Proto converts BodiesMessage to protobuf format
+func (m *BodiesMessage) Proto() proto.Message {
+	bodies := make([]*pb.Body, len(m.Bodies))
+	for i, body := range m.Bodies {
+		bodies[i] = body.Proto()
+	}
+	return &pb.BlockBodies{
+		Bodies: bodies,
+	}
+}
+
+// FromProto populates BodiesMessage from a protobuf message
+func (m *BodiesMessage) FromProto(pbBodies *pb.BlockBodies) error {
+	m.Bodies = make([]*block.Body, len(pbBodies.Bodies))
+	for i, pbBody := range pbBodies.Bodies {
+		if pbBody == nil {
+			return errors.New("missing body")
+		}
+		m.Bodies[i] = &block.Body{}
+		m.Bodies[i].FromProto(pbBody)
+	}
+	return nil
+}
+
+// NodeDataRequest asks for state trie nodes and contract code by hash
+type NodeDataRequest struct {
+	Hashes []common.Hash
+}
+
+// Proto converts NodeDataRequest to protobuf format
+func (m *NodeDataRequest) Proto() proto.Message {
+	hashes := make([][]byte, len(m.Hashes))
+	for i, hash := range m.Hashes {
+		hashes[i] = hash.Bytes()
+	}
+	return &pb.NodeDataRequest{
+		Hashes: hashes,
+	}
+}
+
+// FromProto populates NodeDataRequest from a protobuf message
+func (m *NodeDataRequest) FromProto(pbRequest *pb.NodeDataRequest) error {
+	m.Hashes = make([]common.Hash, len(pbRequest.Hashes))
+	for i, hash := range pbRequest.Hashes {
+		m.Hashes[i] = common.BytesToHash(hash)
+	}
+	return nil
+}
+
+// NodeDataMessage holds the requested entries the serving node has, the
+// receiver matches them to its request by hashing them
+type NodeDataMessage struct {
+	Data [][]byte
+}
+
+// Proto converts NodeDataMessage to protobuf format
+func (m *NodeDataMessage) Proto() proto.Message {
+	return &pb.NodeData{
+		Data: m.Data,
+	}
+}
+
+// FromProto populates NodeDataMessage from a protobuf message
+func (m *NodeDataMessage) FromProto(pbData *pb.NodeData) error {
+	m.Data = pbData.Data
+	return nil
+}
@@ -10,6 +10,8 @@ import (
 type HandshakeInit struct {
 	WalletAddress []byte
 	Payload       []byte
+	EphemeralKey  []byte
+	Status        *Status
 }
 
 // Proto converts HandshakeInit to protobuf format
@@ -17,6 +19,8 @@ func (h *HandshakeInit) Proto() proto.Message {
 	return &pb.HandshakeInit{
 		WalletAddress: h.WalletAddress,
 		Payload:       h.Payload,
+		EphemeralKey:  h.EphemeralKey,
+		Status:        h.Status.Proto(),
 	}
 }
 
@@ -27,6 +31,8 @@ func (h *HandshakeInit) FromProto(pbMsg *pb.HandshakeInit) error {
 	}
 	h.WalletAddress = pbMsg.WalletAddress
 	h.Payload = pbMsg.Payload
+	h.EphemeralKey = pbMsg.EphemeralKey
+	h.Status = statusFromProto(pbMsg.Status)
 	return nil
 }
 
@@ -35,6 +41,8 @@ type HandshakeAck struct {
 	WalletAddress []byte
 	Payload       []byte
 	Signature     []byte
+	EphemeralKey  []byte
+	Status        *Status
 }
 
 // Proto converts HandshakeAck to protobuf format
@@ -43,6 +51,8 @@ func (h *HandshakeAck) Proto() proto.Message {
 		WalletAddress: h.WalletAddress,
 		Payload:       h.Payload,
 		Signature:     h.Signature,
+		EphemeralKey:  h.EphemeralKey,
+		Status:        h.Status.Proto(),
 	}
 }
 
@@ -54,6 +64,8 @@ func (h *HandshakeAck) FromProto(pbMsg *pb.HandshakeAck) error {
 	h.WalletAddress = pbMsg.WalletAddress
 	h.Payload = pbMsg.Payload
 	h.Signature = pbMsg.Signature
+	h.EphemeralKey = pbMsg.EphemeralKey
+	h.Status = statusFromProto(pbMsg.Status)
 	return nil
 }
 
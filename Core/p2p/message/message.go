@@ -8,6 +8,8 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"FichainCore/call_data"
+	"FichainCore/consensus/poa_consensus"
+	"FichainCore/consensus/poa_consensus/voting"
 	pb "FichainCore/proto"
 	"FichainCore/receipt"
 	"FichainCore/transaction"
@@ -50,12 +52,106 @@ const (
 	MessageGetBlock = "get_block"
 	MessageBlock    = "block"
 
+	MessageGetHeaders = "get_headers"
+	MessageHeaders    = "headers"
+
+	MessageGetBodies = "get_bodies"
+	MessageBodies    = "bodies"
+
+	MessageGetNodeData = "get_node_data"
+	MessageNodeData    = "node_data"
+
+	MessageGetSettlement = "get_settlement"
+	MessageSettlement    = "settlement"
+
+	MessageGetSupplyReport = "get_supply_report"
+	MessageSupplyReport    = "supply_report"
+
 	MessageTxMined = "tx_mined"
 
+	MessageNewTransactionHashes = "new_transaction_hashes"
+	MessageGetTransactions      = "get_transactions"
+	MessageTransactions         = "transactions"
+
+	MessageVote     = "vote"
+	MessageEvidence = "evidence"
+
+	MessageGetCommitCertificate = "get_commit_certificate"
+	MessageCommitCertificate    = "commit_certificate"
+
 	//
 	MessageChainEvent = "chain_event"
 )
 
+// the payloads of the core message types, other packages register theirs
+// with Register or RegisterPayload
+func init() {
+	RegisterPayload[pb.Ping, Ping](MessagePing)
+	RegisterPayload[pb.Pong, Pong](MessagePong)
+	RegisterPayload[pb.PeerList, PeerList](MessagePeerList)
+
+	RegisterPayload[pb.HandshakeInit, HandshakeInit](MessageHandshakeInit)
+	RegisterPayload[pb.HandshakeAck, HandshakeAck](MessageHandshakeAck)
+	RegisterPayload[pb.HandshakeConfirm, HandshakeConfirm](MessageHandshakeConfirm)
+
+	RegisterPayload[pb.Transaction, transaction.Transaction](MessageSendTransaction)
+	RegisterPayload[pb.Transaction, transaction.Transaction](MessageTxMined)
+	RegisterPayload[pb.CallSmartContractData, call_data.CallSmartContractData](MessageCallSmartContract)
+	RegisterPayload[pb.CallSmartContractResponse, call_data.CallSmartContractResponse](MessageCallResult)
+
+	RegisterEmpty(MessageGetBalance)
+	RegisterPayload[pb.BytesMessage, BytesMessage](MessageBalance)
+	RegisterPayload[pb.BytesMessage, BytesMessage](MessageGetNonce)
+	RegisterPayload[pb.BytesMessage, BytesMessage](MessageNonce)
+	RegisterPayload[pb.BytesMessage, BytesMessage](MessageGetReceipt)
+	RegisterPayload[pb.Receipt, receipt.Receipt](MessageReceipt)
+	RegisterPayload[pb.BytesMessage, BytesMessage](MessageGetReceipts)
+	Register(MessageReceipts, func(data []byte) (HaveProto, error) {
+		var p pb.Receipts
+		if err := proto.Unmarshal(data, &p); err != nil {
+			return nil, err
+		}
+		receipts := &receipt.Receipts{}
+		if err := receipts.FromProto(&p); err != nil {
+			return nil, err
+		}
+		return receipts, nil
+	})
+
+	RegisterEmpty(MessageGetHeadBlock)
+	RegisterPayload[pb.BlockHeader, HeaderMessage](MessageHeadBlock)
+	RegisterPayload[pb.BytesMessage, BytesMessage](MessageGetBlock)
+	RegisterPayload[pb.Block, BlockMessage](MessageBlock)
+	RegisterPayload[pb.HeadersRequest, HeadersRequest](MessageGetHeaders)
+	RegisterPayload[pb.BlockHeaders, HeadersMessage](MessageHeaders)
+	RegisterPayload[pb.BodiesRequest, BodiesRequest](MessageGetBodies)
+	RegisterPayload[pb.BlockBodies, BodiesMessage](MessageBodies)
+	RegisterPayload[pb.NodeDataRequest, NodeDataRequest](MessageGetNodeData)
+	RegisterPayload[pb.NodeData, NodeDataMessage](MessageNodeData)
+
+	RegisterPayload[pb.BytesMessage, BytesMessage](MessageGetSettlement)
+	RegisterPayload[pb.EpochSettlement, poa_consensus.EpochSettlement](MessageSettlement)
+	RegisterEmpty(MessageGetSupplyReport)
+	RegisterPayload[pb.SupplyReport, poa_consensus.SupplyReport](MessageSupplyReport)
+
+	RegisterPayload[pb.TransactionHashes, TransactionHashesMessage](MessageNewTransactionHashes)
+	RegisterPayload[pb.TransactionHashes, TransactionHashesMessage](MessageGetTransactions)
+	RegisterPayload[pb.Transactions, TransactionsMessage](MessageTransactions)
+
+	RegisterPayload[pb.Vote, voting.Vote](MessageVote)
+	RegisterPayload[pb.DoubleSignEvidence, poa_consensus.DoubleSignEvidence](MessageEvidence)
+	RegisterPayload[pb.BytesMessage, BytesMessage](MessageGetCommitCertificate)
+	RegisterPayload[pb.CommitCertificate, voting.CommitCertificate](MessageCommitCertificate)
+
+	Register(MessageChainEvent, func(data []byte) (HaveProto, error) {
+		var p pb.ChainEvent
+		if err := proto.Unmarshal(data, &p); err != nil {
+			return nil, err
+		}
+		return &types.PbChainEventWrap{PbEvent: &p}, nil
+	})
+}
+
 type HaveProto interface {
 	Proto() proto.Message
 }
@@ -67,12 +163,16 @@ type Header struct {
 	MessageType string
 	Timestamp   int64
 	Signature   []byte
+	RequestID   uint64 // set on requests expecting a response, 0 otherwise
+	Response    bool   // the message answers the request with RequestID
 }
 
 // Message is the high-level wrapper for P2P messages
 type Message struct {
 	Header  *Header
 	Payload HaveProto
+
+	payload []byte // encoded payload, kept so the signed bytes are verified
 }
 
 // Marshal serializes the Message to protobuf bytes
@@ -106,15 +206,13 @@ func (m *Message) Proto() (*pb.Message, error) {
 		MessageType: m.Header.MessageType,
 		Timestamp:   m.Header.Timestamp,
 		Signature:   m.Header.Signature,
+		RequestId:   m.Header.RequestID,
+		Response:    m.Header.Response,
 	}
 
-	var payloadBytes []byte
-	var err error
-
-	payloadBytes, err = proto.Marshal(m.Payload.Proto())
-
+	payloadBytes, err := m.PayloadBytes()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		return nil, err
 	}
 
 	return &pb.Message{
@@ -135,173 +233,17 @@ func (m *Message) FromProto(pbMsg *pb.Message) error {
 		MessageType: pbMsg.Header.MessageType,
 		Timestamp:   pbMsg.Header.Timestamp,
 		Signature:   pbMsg.Header.Signature,
+		RequestID:   pbMsg.Header.RequestId,
+		Response:    pbMsg.Header.Response,
 	}
+	m.payload = pbMsg.Payload
 
-	switch pbMsg.Header.MessageType {
-	case MessagePing:
-		var p pb.Ping
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		ping := &Ping{}
-		if err := ping.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = ping
-	case MessagePong:
-		var p pb.Pong
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		pong := &Pong{}
-		if err := pong.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = pong
-	case MessagePeerList:
-		var p pb.PeerList
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		peerList := &PeerList{}
-		if err := peerList.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = peerList
-		// --------------------- Handshake ---------------------
-	case MessageHandshakeInit:
-		var p pb.HandshakeInit
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		init := &HandshakeInit{}
-		if err := init.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = init
-	case MessageHandshakeAck:
-		var p pb.HandshakeAck
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		ack := &HandshakeAck{}
-		if err := ack.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = ack
-	case MessageHandshakeConfirm:
-		var p pb.HandshakeConfirm
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		confirm := &HandshakeConfirm{}
-		if err := confirm.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = confirm
-		//
-	case MessageTxMined:
-		fallthrough
-	case MessageSendTransaction:
-		var p pb.Transaction
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		tx := &transaction.Transaction{}
-		if err := tx.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = tx
-	case MessageCallSmartContract:
-		var p pb.CallSmartContractData
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		tx := &call_data.CallSmartContractData{}
-		if err := tx.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = tx
-	case MessageCallResult:
-		var p pb.CallSmartContractResponse
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		tx := &call_data.CallSmartContractResponse{}
-		if err := tx.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = tx
-	case MessageReceipt:
-		var p pb.Receipt
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		rc := &receipt.Receipt{}
-		if err := rc.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = rc
-	case MessageChainEvent:
-		var p pb.ChainEvent
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		rc := &types.PbChainEventWrap{
-			PbEvent: &p,
-		}
-		m.Payload = rc
-	case MessageGetReceipt:
-		fallthrough
-	case MessageGetReceipts:
-		fallthrough
-	case MessageGetNonce:
-		fallthrough
-	case MessageNonce:
-		fallthrough
-	case MessageBalance:
-		var p pb.BytesMessage
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		bMessage := &BytesMessage{}
-		if err := bMessage.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = bMessage
-	case MessageReceipts:
-		var p pb.Receipts
-		if err := proto.Unmarshal(pbMsg.Payload, &p); err != nil {
-			return err
-		}
-		receipts := &receipt.Receipts{}
-		if err := receipts.FromProto(&p); err != nil {
-			return err
-		}
-		m.Payload = receipts
-	case MessageGetBalance:
-		return nil
-	// case "block":
-	// 	var b pb.Block
-	// 	if err := proto.Unmarshal(pbMsg.Payload, &b); err != nil {
-	// 		return err
-	// 	}
-	// 	blk := &block.Block{}
-	// 	blk.FromProto(&b)
-	// 	m.Payload = blk
-	// case "transaction":
-	// 	var tx pb.Transaction
-	// 	if err := proto.Unmarshal(pbMsg.Payload, &tx); err != nil {
-	// 		return err
-	// 	}
-	// 	t := &transaction.Transaction{}
-	// 	t.FromProto(&tx)
-	// 	m.Payload = t
-	default:
-		logger.Warn("Receive unsupport message", pbMsg.Header.MessageType)
-		return fmt.Errorf("unknown message type: %s", pbMsg.Header.MessageType)
+	payload, err := DecodePayload(pbMsg.Header.MessageType, pbMsg.Payload)
+	if err != nil {
+		logger.Warn("Receive unsupport message", pbMsg.Header.MessageType, err)
+		return err
 	}
-
+	m.Payload = payload
 	return nil
 }
 
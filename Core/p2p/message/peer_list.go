@@ -3,18 +3,35 @@ package message
 import (
 	"google.golang.org/protobuf/proto"
 
+	"FichainCore/common"
 	pb "FichainCore/proto"
 )
 
+// PeerInfo is the listening address of a peer and the wallet it proves in
+// the handshake
+type PeerInfo struct {
+	WalletAddress common.Address
+	Address       string
+}
+
 // PeerList represents a list of peer addresses
 type PeerList struct {
 	Addresses []string
+	Peers     []*PeerInfo
 }
 
 // Proto converts PeerList to protobuf PeerList
 func (p *PeerList) Proto() proto.Message {
+	peers := make([]*pb.PeerInfo, len(p.Peers))
+	for i, info := range p.Peers {
+		peers[i] = &pb.PeerInfo{
+			WalletAddress: info.WalletAddress.Bytes(),
+			Address:       info.Address,
+		}
+	}
 	return &pb.PeerList{
 		Addresses: p.Addresses,
+		Peers:     peers,
 	}
 }
 
@@ -24,5 +41,15 @@ func (p *PeerList) FromProto(pbPeerList *pb.PeerList) error {
 		return nil
 	}
 	p.Addresses = pbPeerList.Addresses
+	p.Peers = make([]*PeerInfo, 0, len(pbPeerList.Peers))
+	for _, info := range pbPeerList.Peers {
+		if info == nil {
+			continue
+		}
+		p.Peers = append(p.Peers, &PeerInfo{
+			WalletAddress: common.BytesToAddress(info.WalletAddress),
+			Address:       info.Address,
+		})
+	}
 	return nil
 }
@@ -0,0 +1,106 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrUnknownMessageType is returned when decoding a message of a type no
+// package registered
+var ErrUnknownMessageType = errors.New("unknown message type")
+
+// Decoder decodes the encoded payload of a message type
+type Decoder func(data []byte) (HaveProto, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Decoder)
+)
+
+// Register adds a message type with the decoder of its payload, messages of
+// registered types are decoded by peers and routed to the handler of their
+// type. Packages defining their own messages call it from init. Registering
+// a type twice panics, two packages would decode it differently.
+func Register(msgType string, decode Decoder) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if decode == nil {
+		panic("message: nil decoder for " + msgType)
+	}
+	if _, ok := registry[msgType]; ok {
+		panic("message: type registered twice: " + msgType)
+	}
+	registry[msgType] = decode
+}
+
+// RegisterPayload registers a message type whose payload T is decoded from
+// the protobuf message PB by its FromProto method, as in
+// RegisterPayload[pb.Ping, Ping](MessagePing)
+func RegisterPayload[
+	PB any,
+	T any,
+	PBPtr interface {
+		*PB
+		proto.Message
+	},
+	TPtr interface {
+		*T
+		HaveProto
+		FromProto(PBPtr) error
+	},
+](msgType string) {
+	Register(msgType, func(data []byte) (HaveProto, error) {
+		pbPayload := PBPtr(new(PB))
+		if err := proto.Unmarshal(data, pbPayload); err != nil {
+			return nil, err
+		}
+		payload := TPtr(new(T))
+		if err := payload.FromProto(pbPayload); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	})
+}
+
+// RegisterEmpty registers a message type without payload, like requests
+// without parameters
+func RegisterEmpty(msgType string) {
+	Register(msgType, func([]byte) (HaveProto, error) {
+		return nil, nil
+	})
+}
+
+// IsRegistered tells whether a message type can be decoded
+func IsRegistered(msgType string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[msgType]
+	return ok
+}
+
+// RegisteredTypes lists the registered message types in order, for debugging
+func RegisteredTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	types := make([]string, 0, len(registry))
+	for msgType := range registry {
+		types = append(types, msgType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// DecodePayload decodes the payload of a message with the decoder of its type
+func DecodePayload(msgType string, data []byte) (HaveProto, error) {
+	registryMu.RLock()
+	decode, ok := registry[msgType]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMessageType, msgType)
+	}
+	return decode(data)
+}
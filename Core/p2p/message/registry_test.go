@@ -0,0 +1,36 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "FichainCore/proto"
+)
+
+func TestRegistry(t *testing.T) {
+	const msgType = "test_bank_transfer"
+	assert.False(t, IsRegistered(msgType))
+
+	// a message of an unknown type cannot be decoded
+	msg := &Message{
+		Header:  &Header{Version: ProtocolVersion, MessageType: msgType},
+		Payload: &BytesMessage{Data: []byte("transfer")},
+	}
+	data, err := msg.Marshal()
+	assert.NoError(t, err)
+	assert.ErrorIs(t, (&Message{}).Unmarshal(data), ErrUnknownMessageType)
+
+	RegisterPayload[pb.BytesMessage, BytesMessage](msgType)
+	assert.True(t, IsRegistered(msgType))
+	assert.Contains(t, RegisteredTypes(), msgType)
+	assert.Contains(t, RegisteredTypes(), MessagePing)
+
+	received := &Message{}
+	assert.NoError(t, received.Unmarshal(data))
+	assert.Equal(t, []byte("transfer"), received.Payload.(*BytesMessage).Data)
+
+	// a type has a single decoder
+	assert.Panics(t, func() { RegisterEmpty(msgType) })
+	assert.Panics(t, func() { RegisterEmpty(MessagePing) })
+}
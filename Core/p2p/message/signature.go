@@ -0,0 +1,107 @@
+package message
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/common"
+	"FichainCore/crypto"
+)
+
+var (
+	// ErrInvalidSignature is returned when a message is not signed by the
+	// wallet its peer proved in the handshake
+	ErrInvalidSignature = errors.New("invalid message signature")
+
+	// ErrStaleMessage is returned when a message timestamp is too far from
+	// the local clock
+	ErrStaleMessage = errors.New("stale message")
+)
+
+// PayloadBytes returns the encoded payload, as received for a decoded message.
+// A message without payload, such as get_head_block, encodes to no bytes.
+func (m *Message) PayloadBytes() ([]byte, error) {
+	if m.payload != nil {
+		return m.payload, nil
+	}
+	if m.Payload == nil {
+		m.payload = []byte{}
+		return m.payload, nil
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(m.Payload.Proto())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	m.payload = data
+	return data, nil
+}
+
+// SigningHash is the hash signed by the sender, it covers the header fields
+// and the hash of the encoded payload, so a response cannot be replayed as
+// the answer of another request
+func (m *Message) SigningHash() (common.Hash, error) {
+	if m.Header == nil {
+		return common.Hash{}, fmt.Errorf("header is nil")
+	}
+	payload, err := m.PayloadBytes()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	data := binary.BigEndian.AppendUint32(nil, m.Header.Version)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(m.Header.SenderID)))
+	data = append(data, m.Header.SenderID...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(m.Header.MessageType)))
+	data = append(data, m.Header.MessageType...)
+	data = binary.BigEndian.AppendUint64(data, uint64(m.Header.Timestamp))
+	data = binary.BigEndian.AppendUint64(data, m.Header.RequestID)
+	if m.Header.Response {
+		data = append(data, 1)
+	} else {
+		data = append(data, 0)
+	}
+	data = append(data, crypto.Keccak256(payload)...)
+	return crypto.Keccak256Hash(data), nil
+}
+
+// Signer recovers the wallet that signed the message
+func (m *Message) Signer() (common.Address, error) {
+	hash, err := m.SigningHash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(m.Header.Signature) != 65 {
+		return common.Address{}, ErrInvalidSignature
+	}
+	pub, err := crypto.SigToPub(hash.Bytes(), m.Header.Signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Verify checks that the message was signed by the given wallet within
+// maxAge of now
+func (m *Message) Verify(wallet common.Address, maxAge time.Duration, now time.Time) error {
+	if wallet == (common.Address{}) {
+		return fmt.Errorf("%w: peer has no wallet", ErrInvalidSignature)
+	}
+	signer, err := m.Signer()
+	if err != nil {
+		return err
+	}
+	if signer != wallet {
+		return fmt.Errorf("%w: signed by %s, peer is %s", ErrInvalidSignature, signer.Hex(), wallet.Hex())
+	}
+	age := now.Sub(time.Unix(m.Header.Timestamp, 0))
+	if age > maxAge || age < -maxAge {
+		return fmt.Errorf("%w: sent %s ago", ErrStaleMessage, age)
+	}
+	return nil
+}
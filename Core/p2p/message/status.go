@@ -0,0 +1,175 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/common"
+	pb "FichainCore/proto"
+)
+
+const (
+	// ProtocolVersion is the newest protocol version this release speaks,
+	// version 2 signs the request ID of message headers
+	ProtocolVersion uint32 = 2
+
+	// MinProtocolVersion is the oldest protocol version this release speaks
+	MinProtocolVersion uint32 = 2
+)
+
+// Capabilities a node announces in the handshake
+const (
+	CapabilityTxGossip  = "tx_gossip"  // announces and serves pooled transactions
+	CapabilityFastSync  = "fast_sync"  // serves state trie nodes
+	CapabilityRequestID = "request_id" // echoes request IDs in responses
+)
+
+// Roles a node announces in the handshake. The announced role is
+// informative, the peer manager decides the role of a peer from the
+// authority.
+const (
+	RoleNameValidator = "validator"
+	RoleNameObserver  = "observer"
+	RoleNameClient    = "client"
+)
+
+var (
+	ErrNoStatus            = errors.New("peer sent no status")
+	ErrChainMismatch       = errors.New("chain id mismatch")
+	ErrGenesisMismatch     = errors.New("genesis hash mismatch")
+	ErrIncompatibleVersion = errors.New("no common protocol version")
+)
+
+// Status is what a node tells about itself in the handshake. A client that
+// does not know the chain leaves its chain ID and genesis hash empty.
+type Status struct {
+	ChainID      uint64
+	GenesisHash  common.Hash
+	MinVersion   uint32
+	MaxVersion   uint32
+	Role         string
+	Capabilities []string
+}
+
+// NewStatus returns the status of a node speaking every protocol version of
+// this release
+func NewStatus(chainID uint64, genesisHash common.Hash, role string, capabilities ...string) *Status {
+	return &Status{
+		ChainID:      chainID,
+		GenesisHash:  genesisHash,
+		MinVersion:   MinProtocolVersion,
+		MaxVersion:   ProtocolVersion,
+		Role:         role,
+		Capabilities: capabilities,
+	}
+}
+
+// ClientStatus is the status of a client that does not know the chain it
+// connects to, only peers with the client role may connect with it
+func ClientStatus() *Status {
+	return NewStatus(0, common.Hash{}, RoleNameClient, CapabilityRequestID)
+}
+
+// Has tells whether the node announced a capability
+func (s *Status) Has(capability string) bool {
+	return s != nil && slices.Contains(s.Capabilities, capability)
+}
+
+// Negotiate checks that a remote node is on the same chain and returns the
+// newest protocol version both speak. The chain ID and the genesis hash are
+// only compared when both nodes announced them, SameChain requires them once
+// the wallet of the remote node is known to be a validator or an observer.
+func (s *Status) Negotiate(remote *Status) (uint32, error) {
+	if remote == nil {
+		return 0, ErrNoStatus
+	}
+	if s.ChainID != 0 && remote.ChainID != 0 && s.ChainID != remote.ChainID {
+		return 0, fmt.Errorf("%w: ours %d, theirs %d", ErrChainMismatch, s.ChainID, remote.ChainID)
+	}
+	empty := common.Hash{}
+	if s.GenesisHash != empty && remote.GenesisHash != empty && s.GenesisHash != remote.GenesisHash {
+		return 0, fmt.Errorf(
+			"%w: ours %s, theirs %s",
+			ErrGenesisMismatch,
+			s.GenesisHash.Hex(),
+			remote.GenesisHash.Hex(),
+		)
+	}
+	version := min(s.MaxVersion, remote.MaxVersion)
+	if version < max(s.MinVersion, remote.MinVersion) {
+		return 0, fmt.Errorf(
+			"%w: ours %d-%d, theirs %d-%d",
+			ErrIncompatibleVersion,
+			s.MinVersion,
+			s.MaxVersion,
+			remote.MinVersion,
+			remote.MaxVersion,
+		)
+	}
+	return version, nil
+}
+
+// SameChain checks that a remote node announced the chain ID and the genesis
+// hash of this node, the empty status of a client does not match
+func (s *Status) SameChain(remote *Status) error {
+	if remote == nil {
+		return ErrNoStatus
+	}
+	if s.ChainID != remote.ChainID {
+		return fmt.Errorf("%w: ours %d, theirs %d", ErrChainMismatch, s.ChainID, remote.ChainID)
+	}
+	if s.GenesisHash != remote.GenesisHash {
+		return fmt.Errorf(
+			"%w: ours %s, theirs %s",
+			ErrGenesisMismatch,
+			s.GenesisHash.Hex(),
+			remote.GenesisHash.Hex(),
+		)
+	}
+	return nil
+}
+
+// Proto converts Status to protobuf format
+func (s *Status) Proto() *pb.PeerStatus {
+	if s == nil {
+		return nil
+	}
+	pbStatus := &pb.PeerStatus{
+		ChainId:      s.ChainID,
+		MinVersion:   s.MinVersion,
+		MaxVersion:   s.MaxVersion,
+		Role:         s.Role,
+		Capabilities: s.Capabilities,
+	}
+	if s.GenesisHash != (common.Hash{}) {
+		pbStatus.GenesisHash = s.GenesisHash.Bytes()
+	}
+	return pbStatus
+}
+
+// statusFromProto returns nil for a handshake without status
+func statusFromProto(pbStatus *pb.PeerStatus) *Status {
+	if pbStatus == nil {
+		return nil
+	}
+	return &Status{
+		ChainID:      pbStatus.ChainId,
+		GenesisHash:  common.BytesToHash(pbStatus.GenesisHash),
+		MinVersion:   pbStatus.MinVersion,
+		MaxVersion:   pbStatus.MaxVersion,
+		Role:         pbStatus.Role,
+		Capabilities: pbStatus.Capabilities,
+	}
+}
+
+// Bytes is the deterministic encoding of a status, signed in the handshake
+func (s *Status) Bytes() []byte {
+	if s == nil {
+		return nil
+	}
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(s.Proto())
+	return data
+}
@@ -0,0 +1,67 @@
+package message
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+
+	"FichainCore/common"
+	pb "FichainCore/proto"
+	"FichainCore/transaction"
+)
+
+// TransactionHashesMessage announces transactions by hash, or asks for them
+type TransactionHashesMessage struct {
+	Hashes []common.Hash
+}
+
+// Proto converts TransactionHashesMessage to protobuf format
+func (m *TransactionHashesMessage) Proto() proto.Message {
+	hashes := make([][]byte, len(m.Hashes))
+	for i, hash := range m.Hashes {
+		hashes[i] = hash.Bytes()
+	}
+	return &pb.TransactionHashes{
+		Hashes: hashes,
+	}
+}
+
+// FromProto populates TransactionHashesMessage from a protobuf message
+func (m *TransactionHashesMessage) FromProto(pbHashes *pb.TransactionHashes) error {
+	m.Hashes = make([]common.Hash, len(pbHashes.Hashes))
+	for i, hash := range pbHashes.Hashes {
+		m.Hashes[i] = common.BytesToHash(hash)
+	}
+	return nil
+}
+
+// TransactionsMessage holds transactions served for a request
+type TransactionsMessage struct {
+	Transactions []*transaction.Transaction
+}
+
+// Proto converts TransactionsMessage to protobuf format
+func (m *TransactionsMessage) Proto() proto.Message {
+	txs := make([]*pb.Transaction, len(m.Transactions))
+	for i, tx := range m.Transactions {
+		txs[i] = tx.Proto().(*pb.Transaction)
+	}
+	return &pb.Transactions{
+		Transactions: txs,
+	}
+}
+
+// FromProto populates TransactionsMessage from a protobuf message
+func (m *TransactionsMessage) FromProto(pbTxs *pb.Transactions) error {
+	m.Transactions = make([]*transaction.Transaction, len(pbTxs.Transactions))
+	for i, pbTx := range pbTxs.Transactions {
+		if pbTx == nil {
+			return errors.New("missing transaction")
+		}
+		m.Transactions[i] = &transaction.Transaction{}
+		if err := m.Transactions[i].FromProto(pbTx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
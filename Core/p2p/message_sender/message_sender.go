@@ -2,6 +2,8 @@ package message_sender
 
 import (
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
@@ -11,17 +13,66 @@ import (
 	"FichainCore/p2p"
 	"FichainCore/p2p/lookup_table"
 	"FichainCore/p2p/message"
+	"FichainCore/signer"
 )
 
+// MessageSender builds, signs and sends messages. Every header is signed by
+// the node's signer so the receiver can check it against the wallet proved
+// in the handshake.
 type MessageSender struct {
 	lookupTable *lookup_table.LookupTable
+	signer      *signer.Signer
+
+	lastRequestID atomic.Uint64
+	pending       map[pendingKey]chan *message.Message
+	pendingMu     sync.Mutex
 }
 
 // NewMessageSender initializes and returns a new MessageSender
-func NewMessageSender(lt *lookup_table.LookupTable) *MessageSender {
+func NewMessageSender(lt *lookup_table.LookupTable, signer *signer.Signer) *MessageSender {
 	return &MessageSender{
 		lookupTable: lt,
+		signer:      signer,
+		pending:     make(map[pendingKey]chan *message.Message),
+	}
+}
+
+// NewMessage builds a signed message with the given type and payload
+func (ms *MessageSender) NewMessage(msgType string, payload message.HaveProto) (*message.Message, error) {
+	msg := ms.newUnsigned(msgType, payload)
+	if err := ms.Sign(msg); err != nil {
+		return nil, err
 	}
+	return msg, nil
+}
+
+func (ms *MessageSender) newUnsigned(msgType string, payload message.HaveProto) *message.Message {
+	return &message.Message{
+		Header: &message.Header{
+			Version:     message.ProtocolVersion,
+			SenderID:    config.GetConfig().NodeID,
+			MessageType: msgType,
+			Timestamp:   time.Now().Unix(),
+		},
+		Payload: payload,
+	}
+}
+
+// Sign fills the header signature of a message
+func (ms *MessageSender) Sign(msg *message.Message) error {
+	if ms.signer == nil {
+		return errors.New("message sender has no signer")
+	}
+	hash, err := msg.SigningHash()
+	if err != nil {
+		return err
+	}
+	sign, err := ms.signer.SignHash(hash)
+	if err != nil {
+		return err
+	}
+	msg.Header.Signature = sign.Bytes()
+	return nil
 }
 
 // SendToPeer sends a message directly to a TcpPeer
@@ -48,37 +99,63 @@ func (ms *MessageSender) SendMessageToPeer(
 	msgType string,
 	payload message.HaveProto,
 ) error {
-	msg := &message.Message{
-		Header: &message.Header{
-			Version:     1,
-			SenderID:    config.GetConfig().NodeID,
-			MessageType: msgType,
-			Timestamp:   time.Now().Unix(),
-			Signature:   []byte{}, // Optionally fill signature later
-		},
-		Payload: payload,
+	msg, err := ms.NewMessage(msgType, payload)
+	if err != nil {
+		return err
 	}
 	logger.DebugP("[MessageSender] sent to peer", p.Address(), msgType)
 
 	return ms.SendToPeer(p, msg)
 }
 
+// ReplyToPeer builds and sends the response to a request, echoing its
+// request ID so the requester can match it
+func (ms *MessageSender) ReplyToPeer(
+	p p2p.Peer,
+	request *message.Message,
+	msgType string,
+	payload message.HaveProto,
+) error {
+	msg, err := ms.newResponse(request, msgType, payload)
+	if err != nil {
+		return err
+	}
+	logger.DebugP("[MessageSender] replied to peer", p.Address(), msgType)
+
+	return ms.SendToPeer(p, msg)
+}
+
 // SendMessageToAddress builds and sends a Message with provided type and payload
 func (ms *MessageSender) SendMessageToAddress(
 	addr common.Address,
 	msgType string,
 	payload message.HaveProto,
 ) error {
-	msg := &message.Message{
-		Header: &message.Header{
-			Version:     1,
-			SenderID:    config.GetConfig().NodeID,
-			MessageType: msgType,
-			Timestamp:   time.Now().Unix(),
-			Signature:   []byte{}, // Optionally fill signature later
-		},
-		Payload: payload,
+	msg, err := ms.NewMessage(msgType, payload)
+	if err != nil {
+		return err
 	}
 
 	return ms.SendToAddress(addr, msg)
 }
+
+// BroadcastMessage sends the same message to every given address,
+// returning the last error if some of the sends failed
+func (ms *MessageSender) BroadcastMessage(
+	addrs []common.Address,
+	msgType string,
+	payload message.HaveProto,
+) error {
+	msg, err := ms.NewMessage(msgType, payload)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, addr := range addrs {
+		if err := ms.SendToAddress(addr, msg); err != nil {
+			logger.Warn("[MessageSender] broadcast failed", addr, msgType, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,149 @@
+package message_sender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/common"
+	"FichainCore/config"
+	"FichainCore/crypto"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+	"FichainCore/signer"
+	"FichainCore/types"
+)
+
+func TestSignedMessage(t *testing.T) {
+	config.SetConfig(&config.Config{NodeID: "node-1"})
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	wallet := crypto.PubkeyToAddress(key.PublicKey)
+	sender := NewMessageSender(nil, signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key))))
+
+	// receive decodes the wire bytes like a peer does
+	receive := func(msg *message.Message) *message.Message {
+		data, err := msg.Marshal()
+		assert.NoError(t, err)
+		received := &message.Message{}
+		assert.NoError(t, received.Unmarshal(data))
+		return received
+	}
+
+	msg, err := sender.NewMessage(message.MessagePing, &message.Ping{NodeID: "node-1", Timestamp: 1})
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.NoError(t, receive(msg).Verify(wallet, time.Minute, now))
+
+	// signed by another wallet
+	other := common.BigToAddress(common.Big1)
+	assert.True(t, errors.Is(receive(msg).Verify(other, time.Minute, now), message.ErrInvalidSignature))
+
+	// a message too old or from the future
+	assert.True(t, errors.Is(receive(msg).Verify(wallet, time.Minute, now.Add(2*time.Minute)), message.ErrStaleMessage))
+	assert.True(t, errors.Is(receive(msg).Verify(wallet, time.Minute, now.Add(-2*time.Minute)), message.ErrStaleMessage))
+
+	// a tampered header or payload
+	tampered := receive(msg)
+	tampered.Header.MessageType = message.MessagePong
+	assert.True(t, errors.Is(tampered.Verify(wallet, time.Minute, now), message.ErrInvalidSignature))
+
+	tampered = &message.Message{Header: msg.Header, Payload: &message.Ping{NodeID: "node-2", Timestamp: 1}}
+	tampered = receive(tampered)
+	assert.True(t, errors.Is(tampered.Verify(wallet, time.Minute, now), message.ErrInvalidSignature))
+
+	// unsigned
+	unsigned := &message.Message{
+		Header:  &message.Header{Version: 1, MessageType: message.MessagePing, Timestamp: now.Unix()},
+		Payload: &message.Ping{NodeID: "node-1", Timestamp: 1},
+	}
+	assert.True(t, errors.Is(receive(unsigned).Verify(wallet, time.Minute, now), message.ErrInvalidSignature))
+}
+
+type requestPeer struct {
+	p2p.Peer
+	id   string
+	sent chan *message.Message
+	done chan struct{}
+}
+
+func (p *requestPeer) ID() string            { return p.id }
+func (p *requestPeer) Address() string       { return p.id }
+func (p *requestPeer) Done() <-chan struct{} { return p.done }
+func (p *requestPeer) Send(msg *message.Message) error {
+	p.sent <- msg
+	return nil
+}
+
+func TestRequest(t *testing.T) {
+	config.SetConfig(&config.Config{NodeID: "node-1"})
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	requester := NewMessageSender(nil, signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key))))
+	key, err = crypto.GenerateKey()
+	assert.NoError(t, err)
+	responder := NewMessageSender(nil, signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key))))
+	peer := &requestPeer{id: "peer-1", sent: make(chan *message.Message, 2), done: make(chan struct{})}
+
+	// concurrent requests of the same type each get their own response
+	type result struct {
+		data []byte
+		err  error
+	}
+	results := make([]chan result, 2)
+	for i := range results {
+		results[i] = make(chan result, 1)
+		go func(i int) {
+			res, err := requester.Request(context.Background(), peer, message.MessageGetNonce, &message.BytesMessage{Data: []byte{byte(i)}})
+			if err != nil {
+				results[i] <- result{err: err}
+				return
+			}
+			results[i] <- result{data: res.Payload.(*message.BytesMessage).Data}
+		}(i)
+	}
+	requests := []*message.Message{<-peer.sent, <-peer.sent}
+	assert.NotEqual(t, requests[0].Header.RequestID, requests[1].Header.RequestID)
+
+	other := &requestPeer{id: "peer-2"}
+	for i := len(requests) - 1; i >= 0; i-- {
+		request := requests[i]
+		response, err := responder.newResponse(request, message.MessageNonce, &message.BytesMessage{
+			Data: request.Payload.(*message.BytesMessage).Data,
+		})
+		assert.NoError(t, err)
+		assert.True(t, response.Header.Response)
+		assert.Equal(t, request.Header.RequestID, response.Header.RequestID)
+		// only the peer the request was sent to can answer it
+		assert.False(t, requester.HandleResponse(other, response))
+		assert.True(t, requester.HandleResponse(peer, response))
+		assert.False(t, requester.HandleResponse(peer, response))
+	}
+	for i, results := range results {
+		res := <-results
+		assert.NoError(t, res.err)
+		assert.Equal(t, []byte{byte(i)}, res.data)
+	}
+
+	// the request ID is signed
+	response, err := responder.newResponse(requests[0], message.MessageNonce, &message.BytesMessage{})
+	assert.NoError(t, err)
+	responseSigner, err := response.Signer()
+	assert.NoError(t, err)
+	response.Header.RequestID++
+	assert.ErrorIs(t, response.Verify(responseSigner, time.Minute, time.Now()), message.ErrInvalidSignature)
+
+	// a request without response times out, or ends with its peer
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = requester.Request(ctx, peer, message.MessageGetNonce, &message.BytesMessage{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-peer.sent
+	close(peer.done)
+	_, err = requester.Request(context.Background(), peer, message.MessageGetNonce, &message.BytesMessage{})
+	assert.ErrorIs(t, err, ErrPeerDisconnected)
+	assert.Empty(t, requester.pending)
+}
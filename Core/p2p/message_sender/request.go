@@ -0,0 +1,117 @@
+package message_sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+)
+
+// DefaultRequestTimeout bounds a request whose context has no deadline
+const DefaultRequestTimeout = 30 * time.Second
+
+// ErrPeerDisconnected is returned for a request whose peer disconnected
+// before responding
+var ErrPeerDisconnected = errors.New("peer disconnected")
+
+// pendingKey identifies a request by the peer it was sent to, so a peer
+// cannot answer the requests sent to another one
+type pendingKey struct {
+	peerID    string
+	requestID uint64
+}
+
+// Request sends a message with a new request ID to a peer and waits for the
+// response echoing it, until the context is done or DefaultRequestTimeout
+// elapsed if the context has no deadline. Concurrent requests of the same
+// type each get their own response.
+func (ms *MessageSender) Request(
+	ctx context.Context,
+	p p2p.Peer,
+	msgType string,
+	payload message.HaveProto,
+) (*message.Message, error) {
+	if p == nil {
+		return nil, errors.New("peer is nil")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+
+	msg, err := ms.newRequest(msgType, payload)
+	if err != nil {
+		return nil, err
+	}
+	key := pendingKey{peerID: p.ID(), requestID: msg.Header.RequestID}
+	response := make(chan *message.Message, 1)
+	ms.pendingMu.Lock()
+	ms.pending[key] = response
+	ms.pendingMu.Unlock()
+	defer func() {
+		ms.pendingMu.Lock()
+		delete(ms.pending, key)
+		ms.pendingMu.Unlock()
+	}()
+
+	if err := ms.SendToPeer(p, msg); err != nil {
+		return nil, err
+	}
+	select {
+	case res := <-response:
+		return res, nil
+	case <-p.Done():
+		return nil, fmt.Errorf("%w: %s", ErrPeerDisconnected, msgType)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("request %s to %s: %w", msgType, p.ID(), ctx.Err())
+	}
+}
+
+// HandleResponse hands a response to the request waiting for it, it
+// implements p2p.ResponseHandler. It returns false when no request of the
+// peer is waiting for it.
+func (ms *MessageSender) HandleResponse(p p2p.Peer, msg *message.Message) bool {
+	key := pendingKey{peerID: p.ID(), requestID: msg.Header.RequestID}
+	ms.pendingMu.Lock()
+	response, ok := ms.pending[key]
+	if ok {
+		delete(ms.pending, key)
+	}
+	ms.pendingMu.Unlock()
+	if !ok {
+		return false
+	}
+	response <- msg
+	return true
+}
+
+func (ms *MessageSender) newRequest(msgType string, payload message.HaveProto) (*message.Message, error) {
+	msg := ms.newUnsigned(msgType, payload)
+	msg.Header.RequestID = ms.lastRequestID.Add(1)
+	if err := ms.Sign(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// newResponse echoes the request ID of a request, a request sent without ID
+// gets a plain message as before
+func (ms *MessageSender) newResponse(
+	request *message.Message,
+	msgType string,
+	payload message.HaveProto,
+) (*message.Message, error) {
+	msg := ms.newUnsigned(msgType, payload)
+	if request != nil && request.Header != nil && request.Header.RequestID != 0 {
+		msg.Header.RequestID = request.Header.RequestID
+		msg.Header.Response = true
+	}
+	if err := ms.Sign(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
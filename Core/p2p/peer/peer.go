@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"sync"
+	"time"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
 
@@ -19,16 +20,22 @@ import (
 const maxMessageSize = 128 * 1024 * 1024
 
 type TcpPeer struct {
-	id       string
-	conn     net.Conn
-	addr     string
-	writer   *bufio.Writer
-	reader   *bufio.Reader
-	sendLock sync.Mutex
-	alive    bool
-	done     chan struct{} // Channel to signal when the peer is done
+	id        string
+	conn      net.Conn
+	addr      string
+	writer    *bufio.Writer
+	reader    *bufio.Reader
+	sendLock  sync.Mutex
+	alive     bool
+	done      chan struct{} // Channel to signal when the peer is done
+	closeOnce sync.Once
+	session   *Session // Encrypts frames once the handshake completed
+
+	writeTimeout time.Duration // Deadline of a frame write, 0 waits forever
 
 	walletAddress common.Address
+	status        *message.Status
+	version       uint32
 }
 
 // Create a new peer from an accepted or dialed TCP connection
@@ -65,6 +72,16 @@ func (p *TcpPeer) Send(msg *message.Message) error {
 	p.sendLock.Lock()
 	defer p.sendLock.Unlock()
 
+	if p.session != nil {
+		data = p.session.Seal(data)
+	}
+	if p.writeTimeout > 0 {
+		if err := p.conn.SetWriteDeadline(time.Now().Add(p.writeTimeout)); err != nil {
+			p.Close()
+			return err
+		}
+	}
+
 	// --- UPDATED: Start ---
 	// Write message length (8 bytes) followed by message data
 	length := uint64(len(data))
@@ -87,9 +104,13 @@ func (p *TcpPeer) Send(msg *message.Message) error {
 }
 
 func (p *TcpPeer) Close() error {
-	p.alive = false
-	close(p.done) // Signal the done channel when the peer is closed
-	return p.conn.Close()
+	var err error
+	p.closeOnce.Do(func() {
+		p.alive = false
+		close(p.done) // Signal the done channel when the peer is closed
+		err = p.conn.Close()
+	})
+	return err
 }
 
 func (p *TcpPeer) IsAlive() bool {
@@ -135,9 +156,21 @@ func (p *TcpPeer) ReadLoop(router p2p.Router) {
 		}
 		// --- UPDATED: End ---
 
+		if p.session != nil {
+			decrypted, err := p.session.Open(data)
+			if err != nil {
+				logger.Error("Failed to decrypt message:", err)
+				break
+			}
+			data = decrypted
+		}
+
 		msg := &message.Message{}
 		if err := msg.Unmarshal(data); err != nil {
 			logger.Error("Failed to unmarshal message:", err)
+			if scorer, ok := router.(p2p.Scorer); ok {
+				scorer.Penalize(p, p2p.PenaltyDecodeFailure, err.Error())
+			}
 			continue // Don't disconnect for a single malformed message.
 		}
 
@@ -175,6 +208,15 @@ func (p *TcpPeer) ReadMessage() (*message.Message, error) {
 	}
 	// --- UPDATED: End ---
 
+	if p.session != nil {
+		decrypted, err := p.session.Open(data)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		data = decrypted
+	}
+
 	msg := &message.Message{}
 	if err := msg.Unmarshal(data); err != nil {
 		return nil, err
@@ -190,3 +232,34 @@ func (p *TcpPeer) SetWalletAddress(addr common.Address) {
 func (p *TcpPeer) WalletAddress() common.Address {
 	return p.walletAddress
 }
+
+// SetStatus records the status of the peer and the protocol version
+// negotiated with it, before the peer is routed
+func (p *TcpPeer) SetStatus(status *message.Status, version uint32) {
+	p.status = status
+	p.version = version
+}
+
+func (p *TcpPeer) Status() *message.Status {
+	return p.status
+}
+
+func (p *TcpPeer) Version() uint32 {
+	return p.version
+}
+
+// EnableEncryption encrypts every frame sent and received from now on, it is
+// called by both sides right after the HandshakeConfirm frame
+func (p *TcpPeer) EnableEncryption(session *Session) {
+	p.sendLock.Lock()
+	defer p.sendLock.Unlock()
+	p.session = session
+}
+
+// SetWriteTimeout bounds the time a frame write may take, so a peer that
+// stops reading cannot block its senders
+func (p *TcpPeer) SetWriteTimeout(timeout time.Duration) {
+	p.sendLock.Lock()
+	defer p.sendLock.Unlock()
+	p.writeTimeout = timeout
+}
@@ -0,0 +1,120 @@
+package peer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"FichainCore/crypto"
+	"FichainCore/crypto/ecies"
+)
+
+var (
+	dialerKeyLabel   = []byte("fichain p2p dialer key")
+	listenerKeyLabel = []byte("fichain p2p listener key")
+)
+
+// ErrInvalidEphemeralKey is returned when the remote ephemeral key is not a
+// secp256k1 point
+var ErrInvalidEphemeralKey = errors.New("invalid ephemeral key")
+
+// Session encrypts the frames of a peer once its handshake completed. Both
+// sides derive one AES-256-GCM key per direction from the ECDH secret of the
+// ephemeral keys exchanged in HandshakeInit and HandshakeAck. The nonce of a
+// frame is its sequence number in its direction, so a replayed, dropped or
+// reordered frame fails to open.
+type Session struct {
+	sealer    cipher.AEAD
+	opener    cipher.AEAD
+	sendNonce uint64
+	recvNonce uint64
+}
+
+// GenerateEphemeralKey creates the key pair of one handshake
+func GenerateEphemeralKey() (*ecies.PrivateKey, error) {
+	return ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+}
+
+// EphemeralPublicKey encodes the public part of an ephemeral key as sent in
+// the handshake
+func EphemeralPublicKey(key *ecies.PrivateKey) []byte {
+	return crypto.FromECDSAPub(key.PublicKey.ExportECDSA())
+}
+
+// SessionBinding is the data a peer signs in the handshake: the challenge of
+// the other side, both ephemeral keys and the encoded status the signer
+// announced. The signature proves the wallet owns its ephemeral key and its
+// status, so nobody in between can swap them.
+func SessionBinding(challenge []byte, dialerKey []byte, listenerKey []byte, status []byte) []byte {
+	data := make([]byte, 0, len(challenge)+len(dialerKey)+len(listenerKey)+32)
+	data = append(data, challenge...)
+	data = append(data, dialerKey...)
+	data = append(data, listenerKey...)
+	return append(data, crypto.Keccak256(status)...)
+}
+
+// NewSession derives the session keys from the local ephemeral key and the
+// one received from the remote peer, dialer is true on the side that sent
+// HandshakeInit
+func NewSession(ephemeral *ecies.PrivateKey, remoteKey []byte, dialer bool) (*Session, error) {
+	pub := crypto.ToECDSAPub(remoteKey)
+	if pub == nil || pub.X == nil {
+		return nil, ErrInvalidEphemeralKey
+	}
+	shared, err := ephemeral.GenerateShared(ecies.ImportECDSAPublic(pub), 32, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEphemeralKey, err)
+	}
+
+	dialerKey, listenerKey := EphemeralPublicKey(ephemeral), remoteKey
+	if !dialer {
+		dialerKey, listenerKey = listenerKey, dialerKey
+	}
+	dialerAEAD, err := newAEAD(crypto.Keccak256(shared, dialerKeyLabel, dialerKey, listenerKey))
+	if err != nil {
+		return nil, err
+	}
+	listenerAEAD, err := newAEAD(crypto.Keccak256(shared, listenerKeyLabel, dialerKey, listenerKey))
+	if err != nil {
+		return nil, err
+	}
+	if dialer {
+		return &Session{sealer: dialerAEAD, opener: listenerAEAD}, nil
+	}
+	return &Session{sealer: listenerAEAD, opener: dialerAEAD}, nil
+}
+
+// Seal encrypts the next outgoing frame, calls must be serialized
+func (s *Session) Seal(frame []byte) []byte {
+	nonce := s.nonce(s.sendNonce)
+	s.sendNonce++
+	return s.sealer.Seal(nil, nonce, frame, nil)
+}
+
+// Open decrypts the next incoming frame, calls must be serialized
+func (s *Session) Open(frame []byte) ([]byte, error) {
+	nonce := s.nonce(s.recvNonce)
+	data, err := s.opener.Open(nil, nonce, frame, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt frame %d: %w", s.recvNonce, err)
+	}
+	s.recvNonce++
+	return data, nil
+}
+
+func (s *Session) nonce(counter uint64) []byte {
+	nonce := make([]byte, s.sealer.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], counter)
+	return nonce
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,52 @@
+package peer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	dialerKey, err := GenerateEphemeralKey()
+	assert.NoError(t, err)
+	listenerKey, err := GenerateEphemeralKey()
+	assert.NoError(t, err)
+
+	dialer, err := NewSession(dialerKey, EphemeralPublicKey(listenerKey), true)
+	assert.NoError(t, err)
+	listener, err := NewSession(listenerKey, EphemeralPublicKey(dialerKey), false)
+	assert.NoError(t, err)
+
+	first := dialer.Seal([]byte("first"))
+	second := dialer.Seal([]byte("first"))
+	assert.NotEqual(t, first, second)
+
+	// frames open in order only
+	_, err = listener.Open(second)
+	assert.Error(t, err)
+	data, err := listener.Open(first)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("first"), data)
+	_, err = listener.Open(first)
+	assert.Error(t, err)
+	data, err = listener.Open(second)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("first"), data)
+
+	// each direction has its own key
+	reply := listener.Seal([]byte("reply"))
+	_, err = listener.Open(reply)
+	assert.Error(t, err)
+	data, err = dialer.Open(reply)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("reply"), data)
+
+	// a tampered frame does not open
+	tampered := dialer.Seal([]byte("third"))
+	tampered[0] ^= 1
+	_, err = listener.Open(tampered)
+	assert.Error(t, err)
+
+	_, err = NewSession(dialerKey, []byte{4, 1, 2, 3}, true)
+	assert.ErrorIs(t, err, ErrInvalidEphemeralKey)
+}
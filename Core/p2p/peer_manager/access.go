@@ -0,0 +1,113 @@
+package peer_manager
+
+import (
+	"fmt"
+	"slices"
+
+	"FichainCore/common"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+)
+
+// Roles is a set of roles
+type Roles uint8
+
+// RolesOf returns the set of the given roles
+func RolesOf(roles ...Role) Roles {
+	var set Roles
+	for _, role := range roles {
+		set |= 1 << role
+	}
+	return set
+}
+
+// Has tells whether a role is in the set
+func (r Roles) Has(role Role) bool {
+	return r&(1<<role) != 0
+}
+
+var (
+	validators = RolesOf(RoleValidator)
+	nodes      = RolesOf(RoleValidator, RoleObserver)
+	readers    = RolesOf(RoleValidator, RoleObserver, RoleExplorer)
+	wallets    = RolesOf(RoleValidator, RoleObserver, RoleExplorer, RoleClient)
+)
+
+// DefaultPolicies returns the roles allowed to send each restricted message
+// type. Types without a policy, like pings, handshakes and the responses to
+// requests of this node, may be sent by any peer. Requests about an account
+// are open to wallets, their handlers only serve the accounts the peer may
+// access, see CanAccess. Observers replay the chain, so they are trusted with
+// the raw blocks and state like validators and explorers.
+func DefaultPolicies() map[string]Roles {
+	return map[string]Roles{
+		// consensus and gossip between nodes
+		message.MessageVote:                 validators,
+		message.MessageEvidence:             validators,
+		message.MessageNewTransactionHashes: validators,
+		message.MessageGetTransactions:      validators,
+		message.MessageTransactions:         validators,
+		message.MessageBlock:                nodes,
+		message.MessagePeerList:             nodes,
+
+		// chain data, observers need blocks and state to follow the chain
+		message.MessageGetHeadBlock:    readers,
+		message.MessageGetBlock:        readers,
+		message.MessageGetHeaders:      readers,
+		message.MessageGetBodies:       readers,
+		message.MessageGetNodeData:     readers,
+		message.MessageGetReceipts:     readers,
+		message.MessageGetSettlement:   readers,
+		message.MessageGetSupplyReport: readers,
+
+		// finality, nodes missing the votes fetch the commit certificates
+		message.MessageGetCommitCertificate: readers,
+
+		// account data and transactions
+		message.MessageSendTransaction:   wallets,
+		message.MessageCallSmartContract: wallets,
+		message.MessageGetBalance:        wallets,
+		message.MessageGetNonce:          wallets,
+		message.MessageGetReceipt:        wallets,
+	}
+}
+
+// SetPolicy sets the roles allowed to send a message type
+func (m *PeerManager) SetPolicy(msgType string, roles ...Role) {
+	m.Lock()
+	defer m.Unlock()
+	m.policies[msgType] = RolesOf(roles...)
+}
+
+// Authorize tells whether the role of a peer may send a message type, it
+// implements p2p.AccessController. The role is looked up in the current
+// authority, so a validator leaving the set loses its access at once.
+func (m *PeerManager) Authorize(peer p2p.Peer, msgType string) error {
+	m.Lock()
+	roles, ok := m.policies[msgType]
+	m.Unlock()
+	if !ok {
+		return nil
+	}
+	role := m.RoleOf(peer.WalletAddress())
+	if !roles.Has(role) {
+		return fmt.Errorf("%w: %s may not send %s", p2p.ErrAccessDenied, role, msgType)
+	}
+	return nil
+}
+
+// CanAccess tells whether a peer may see the data of one of the given
+// accounts, like a receipt of a transfer between them. Validators, observers
+// and explorers see every account, since the blocks and state they are
+// served hold them all, and clients their own wallet. Without account, it
+// tells whether the peer sees every account.
+func (m *PeerManager) CanAccess(peer p2p.Peer, accounts ...common.Address) bool {
+	wallet := peer.WalletAddress()
+	switch m.RoleOf(wallet) {
+	case RoleValidator, RoleObserver, RoleExplorer:
+		return true
+	case RoleClient:
+		return slices.Contains(accounts, wallet)
+	}
+	return false
+}
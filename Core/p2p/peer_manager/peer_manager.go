@@ -0,0 +1,315 @@
+package peer_manager
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	logger "github.com/HendrickPhan/golang-simple-logger"
+
+	"FichainCore/common"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+)
+
+var (
+	ErrBanned        = errors.New("peer is banned")
+	ErrTooManyPeers  = errors.New("too many peers")
+	ErrTooManyShakes = errors.New("too many pending handshakes")
+)
+
+// Role is the part a peer plays in the network, it decides which connection
+// cap the peer counts against and which messages it may send
+type Role int
+
+const (
+	RoleClient    Role = iota // wallets and other users of the network
+	RoleObserver              // nodes following the chain, trusted with all chain data
+	RoleValidator             // nodes sealing blocks
+	RoleExplorer              // explorers set in the config, they count as clients
+	RoleAnonymous             // peers that proved no wallet, they count as clients
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleValidator:
+		return "validator"
+	case RoleObserver:
+		return "observer"
+	case RoleExplorer:
+		return "explorer"
+	case RoleAnonymous:
+		return "anonymous"
+	default:
+		return "client"
+	}
+}
+
+// ParseRole returns the role with the given name
+func ParseRole(name string) (Role, error) {
+	for _, role := range []Role{RoleClient, RoleObserver, RoleValidator, RoleExplorer, RoleAnonymous} {
+		if role.String() == name {
+			return role, nil
+		}
+	}
+	return RoleAnonymous, fmt.Errorf("unknown role: %s", name)
+}
+
+// Authority lists the validators and the observers, implemented by
+// poa_consensus.Authority
+type Authority interface {
+	ListValidators() map[common.Address]*big.Int
+	ListObservers() map[common.Address][]common.Address
+}
+
+type peerState struct {
+	peer    p2p.Peer
+	role    Role
+	score   int
+	decayed time.Time
+	buckets map[string]*bucket
+}
+
+// PeerManager admits peers within the caps of their role, limits the rate of
+// the messages they send and bans the peers that misbehave. A ban covers
+// both the wallet and the host of the peer, since a client can make up a new
+// wallet for every connection.
+type PeerManager struct {
+	config    *p2p.Config
+	authority Authority
+	status    *message.Status // status of this node, validators and observers must be on its chain
+	now       func() time.Time
+	policies  map[string]Roles // message type -> roles allowed to send it
+
+	peers   map[string]*peerState // peer ID -> state
+	counts  map[Role]int
+	pending int
+	bans    map[string]time.Time // wallet hex or host -> end of the ban
+
+	sync.Mutex
+}
+
+func NewPeerManager(config *p2p.Config, authority Authority) *PeerManager {
+	return &PeerManager{
+		config:    config,
+		authority: authority,
+		now:       time.Now,
+		policies:  DefaultPolicies(),
+		peers:     make(map[string]*peerState),
+		counts:    make(map[Role]int),
+		bans:      make(map[string]time.Time),
+	}
+}
+
+// Config returns the network config the manager enforces
+func (m *PeerManager) Config() *p2p.Config {
+	return m.config
+}
+
+// SetStatus sets the status of this node. Once set, a peer whose wallet is a
+// validator or an observer is only admitted if it announced the same chain
+// ID and genesis hash, the empty status is left to clients.
+func (m *PeerManager) SetStatus(status *message.Status) {
+	m.Lock()
+	defer m.Unlock()
+	m.status = status
+}
+
+// RoleOf returns the role of a wallet in the current authority
+func (m *PeerManager) RoleOf(wallet common.Address) Role {
+	if wallet == (common.Address{}) {
+		return RoleAnonymous
+	}
+	if m.authority != nil {
+		if _, ok := m.authority.ListValidators()[wallet]; ok {
+			return RoleValidator
+		}
+		if _, ok := m.authority.ListObservers()[wallet]; ok {
+			return RoleObserver
+		}
+	}
+	for _, explorer := range m.config.Explorers {
+		if explorer == wallet {
+			return RoleExplorer
+		}
+	}
+	return RoleClient
+}
+
+// BeginHandshake is called when a connection is accepted, before its
+// handshake. It refuses banned hosts and bounds the handshakes in progress,
+// the returned func must be called once the handshake ended.
+func (m *PeerManager) BeginHandshake(remoteAddress string) (func(), error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.bannedLocked(host(remoteAddress)) {
+		return nil, ErrBanned
+	}
+	if m.config.MaxPeers > 0 && m.pending >= m.config.MaxPeers {
+		return nil, ErrTooManyShakes
+	}
+	m.pending++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.Lock()
+			m.pending--
+			m.Unlock()
+		})
+	}, nil
+}
+
+// Admit counts a peer that completed its handshake against the cap of its
+// role, the peer is released once it disconnects
+func (m *PeerManager) Admit(p p2p.Peer) error {
+	wallet := p.WalletAddress()
+	role := m.RoleOf(wallet)
+	if role == RoleExplorer || role == RoleAnonymous {
+		role = RoleClient
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if m.status != nil && (role == RoleValidator || role == RoleObserver) {
+		if err := m.status.SameChain(p.Status()); err != nil {
+			return fmt.Errorf("%s peer: %w", role, err)
+		}
+	}
+	if m.bannedLocked(wallet.Hex()) || m.bannedLocked(host(p.Address())) {
+		return ErrBanned
+	}
+	if _, ok := m.peers[p.ID()]; ok {
+		return nil
+	}
+	if limit := m.roleLimit(role); limit > 0 && m.counts[role] >= limit {
+		return fmt.Errorf("%w: %d %s peers connected", ErrTooManyPeers, m.counts[role], role)
+	}
+	if m.config.MaxPeers > 0 && len(m.peers) >= m.config.MaxPeers {
+		return fmt.Errorf("%w: %d peers connected", ErrTooManyPeers, len(m.peers))
+	}
+	m.peers[p.ID()] = &peerState{
+		peer:    p,
+		role:    role,
+		decayed: m.now(),
+		buckets: make(map[string]*bucket),
+	}
+	m.counts[role]++
+
+	go func() {
+		<-p.Done()
+		m.release(p)
+	}()
+	return nil
+}
+
+// Penalize adds misbehaviour points to the score of a peer, a peer reaching
+// the ban threshold is disconnected and banned
+func (m *PeerManager) Penalize(p p2p.Peer, points int, reason string) {
+	m.Lock()
+	state, ok := m.peers[p.ID()]
+	if !ok || state.peer != p {
+		m.Unlock()
+		return
+	}
+	now := m.now()
+	m.decayLocked(state, now)
+	state.score += points
+	logger.Debug("[PeerManager] penalized", p.ID(), reason, "score", state.score)
+	if m.config.BanThreshold <= 0 || state.score < m.config.BanThreshold {
+		m.Unlock()
+		return
+	}
+	until := now.Add(m.config.BanDuration)
+	m.bans[p.WalletAddress().Hex()] = until
+	m.bans[host(p.Address())] = until
+	m.Unlock()
+
+	logger.Warn("[PeerManager] banning peer", p.ID(), p.WalletAddress().Hex(), "until", until, reason)
+	p.Close()
+}
+
+// Score returns the current misbehaviour score of a peer
+func (m *PeerManager) Score(p p2p.Peer) int {
+	m.Lock()
+	defer m.Unlock()
+	state, ok := m.peers[p.ID()]
+	if !ok {
+		return 0
+	}
+	m.decayLocked(state, m.now())
+	return state.score
+}
+
+// IsBanned tells whether a wallet is banned
+func (m *PeerManager) IsBanned(wallet common.Address) bool {
+	m.Lock()
+	defer m.Unlock()
+	return m.bannedLocked(wallet.Hex())
+}
+
+// Count returns the number of connected peers of a role
+func (m *PeerManager) Count(role Role) int {
+	m.Lock()
+	defer m.Unlock()
+	return m.counts[role]
+}
+
+func (m *PeerManager) release(p p2p.Peer) {
+	m.Lock()
+	defer m.Unlock()
+	state, ok := m.peers[p.ID()]
+	if !ok || state.peer != p {
+		return
+	}
+	delete(m.peers, p.ID())
+	m.counts[state.role]--
+}
+
+func (m *PeerManager) roleLimit(role Role) int {
+	switch role {
+	case RoleValidator:
+		return m.config.MaxValidatorPeers
+	case RoleObserver:
+		return m.config.MaxObserverPeers
+	default:
+		return m.config.MaxClientPeers
+	}
+}
+
+func (m *PeerManager) bannedLocked(key string) bool {
+	until, ok := m.bans[key]
+	if !ok {
+		return false
+	}
+	if m.now().Before(until) {
+		return true
+	}
+	delete(m.bans, key)
+	return false
+}
+
+// decayLocked forgives ScoreDecay points for every second since the last
+// decay
+func (m *PeerManager) decayLocked(state *peerState, now time.Time) {
+	seconds := int(now.Sub(state.decayed) / time.Second)
+	if seconds <= 0 {
+		return
+	}
+	state.decayed = state.decayed.Add(time.Duration(seconds) * time.Second)
+	state.score -= seconds * m.config.ScoreDecay
+	if state.score < 0 {
+		state.score = 0
+	}
+}
+
+// host strips the port of a remote address
+func host(address string) string {
+	h, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return h
+}
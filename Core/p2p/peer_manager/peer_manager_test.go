@@ -0,0 +1,241 @@
+package peer_manager
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/common"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+)
+
+type testPeer struct {
+	p2p.Peer
+	id     string
+	addr   string
+	wallet common.Address
+	status *message.Status
+	done   chan struct{}
+	closed bool
+}
+
+func newTestPeer(id string, addr string, wallet int64) *testPeer {
+	return &testPeer{
+		id:     id,
+		addr:   addr,
+		wallet: common.BigToAddress(big.NewInt(wallet)),
+		done:   make(chan struct{}),
+	}
+}
+
+func (p *testPeer) ID() string                    { return p.id }
+func (p *testPeer) Address() string               { return p.addr }
+func (p *testPeer) WalletAddress() common.Address { return p.wallet }
+func (p *testPeer) Done() <-chan struct{}         { return p.done }
+func (p *testPeer) Status() *message.Status       { return p.status }
+func (p *testPeer) Close() error {
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	return nil
+}
+
+type testAuthority struct {
+	validators map[common.Address]*big.Int
+	observers  map[common.Address][]common.Address
+}
+
+func (a *testAuthority) ListValidators() map[common.Address]*big.Int        { return a.validators }
+func (a *testAuthority) ListObservers() map[common.Address][]common.Address { return a.observers }
+
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newTestManager(cfg *p2p.Config) (*PeerManager, *testClock) {
+	authority := &testAuthority{
+		validators: map[common.Address]*big.Int{common.BigToAddress(big.NewInt(1)): common.Big1},
+		observers:  map[common.Address][]common.Address{common.BigToAddress(big.NewInt(2)): nil},
+	}
+	clock := &testClock{now: time.Unix(1000, 0)}
+	m := NewPeerManager(cfg, authority)
+	m.now = clock.Now
+	return m, clock
+}
+
+func TestAdmit(t *testing.T) {
+	cfg := p2p.DefaultConfig()
+	cfg.MaxPeers = 3
+	cfg.MaxClientPeers = 1
+	m, _ := newTestManager(cfg)
+
+	assert.Equal(t, RoleValidator, m.RoleOf(common.BigToAddress(big.NewInt(1))))
+	assert.Equal(t, RoleObserver, m.RoleOf(common.BigToAddress(big.NewInt(2))))
+	assert.Equal(t, RoleClient, m.RoleOf(common.BigToAddress(big.NewInt(3))))
+
+	client := newTestPeer("c1", "10.0.0.3:1000", 3)
+	assert.NoError(t, m.Admit(client))
+	assert.ErrorIs(t, m.Admit(newTestPeer("c2", "10.0.0.4:1000", 4)), ErrTooManyPeers)
+
+	// the client cap does not hold validators and observers back
+	assert.NoError(t, m.Admit(newTestPeer("v1", "10.0.0.1:1000", 1)))
+	assert.NoError(t, m.Admit(newTestPeer("o1", "10.0.0.2:1000", 2)))
+	assert.ErrorIs(t, m.Admit(newTestPeer("o2", "10.0.0.2:2000", 2)), ErrTooManyPeers)
+
+	// a disconnected peer frees its slot
+	client.Close()
+	assert.Eventually(t, func() bool { return m.Count(RoleClient) == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, m.Admit(newTestPeer("c2", "10.0.0.4:1000", 4)))
+
+	// pending handshakes are bounded too
+	var releases []func()
+	for i := 0; i < cfg.MaxPeers; i++ {
+		release, err := m.BeginHandshake("10.0.0.9:1000")
+		assert.NoError(t, err)
+		releases = append(releases, release)
+	}
+	_, err := m.BeginHandshake("10.0.0.9:1000")
+	assert.ErrorIs(t, err, ErrTooManyShakes)
+	// a release frees a single slot however many times it is called
+	releases[0]()
+	releases[0]()
+	_, err = m.BeginHandshake("10.0.0.9:1000")
+	assert.NoError(t, err)
+	_, err = m.BeginHandshake("10.0.0.9:1000")
+	assert.ErrorIs(t, err, ErrTooManyShakes)
+}
+
+func TestAdmitChain(t *testing.T) {
+	m, _ := newTestManager(p2p.DefaultConfig())
+	genesis := common.BytesToHash([]byte{0x01})
+	m.SetStatus(message.NewStatus(1, genesis, message.RoleNameValidator))
+
+	// validators and observers must announce the chain of this node
+	for _, wallet := range []int64{1, 2} {
+		peer := newTestPeer("p", "10.0.0.1:1000", wallet)
+		assert.ErrorIs(t, m.Admit(peer), message.ErrNoStatus)
+		peer.status = message.ClientStatus()
+		assert.ErrorIs(t, m.Admit(peer), message.ErrChainMismatch)
+		peer.status = message.NewStatus(1, common.Hash{}, message.RoleNameValidator)
+		assert.ErrorIs(t, m.Admit(peer), message.ErrGenesisMismatch)
+		peer.status = message.NewStatus(1, genesis, message.RoleNameValidator)
+		peer.id = peer.id + string(rune('0'+wallet))
+		assert.NoError(t, m.Admit(peer))
+	}
+
+	// clients may connect without knowing the chain
+	client := newTestPeer("c1", "10.0.0.3:1000", 3)
+	client.status = message.ClientStatus()
+	assert.NoError(t, m.Admit(client))
+}
+
+func TestPenalize(t *testing.T) {
+	cfg := p2p.DefaultConfig()
+	m, clock := newTestManager(cfg)
+	peer := newTestPeer("c1", "10.0.0.3:1000", 3)
+	assert.NoError(t, m.Admit(peer))
+
+	m.Penalize(peer, 60, "bad")
+	assert.Equal(t, 60, m.Score(peer))
+	// the score decays with time
+	clock.now = clock.now.Add(20 * time.Second)
+	assert.Equal(t, 40, m.Score(peer))
+	m.Penalize(peer, 50, "bad")
+	assert.False(t, peer.closed)
+	m.Penalize(peer, p2p.PenaltyInvalidPayload, "invalid transaction")
+	assert.True(t, peer.closed)
+
+	// the wallet and the host are banned for the ban duration
+	assert.True(t, m.IsBanned(peer.wallet))
+	assert.ErrorIs(t, m.Admit(newTestPeer("c2", "10.0.0.3:2000", 5)), ErrBanned)
+	assert.ErrorIs(t, m.Admit(newTestPeer("c3", "10.0.0.8:2000", 3)), ErrBanned)
+	_, err := m.BeginHandshake("10.0.0.3:3000")
+	assert.ErrorIs(t, err, ErrBanned)
+
+	clock.now = clock.now.Add(cfg.BanDuration)
+	assert.False(t, m.IsBanned(peer.wallet))
+	assert.NoError(t, m.Admit(newTestPeer("c4", "10.0.0.3:4000", 3)))
+}
+
+func TestAllow(t *testing.T) {
+	cfg := p2p.DefaultConfig()
+	cfg.MessageRate = p2p.RateLimit{Rate: 10, Burst: 5}
+	cfg.MessageRates["send_transaction"] = p2p.RateLimit{Rate: 1, Burst: 1}
+	m, clock := newTestManager(cfg)
+	peer := newTestPeer("c1", "10.0.0.3:1000", 3)
+	assert.NoError(t, m.Admit(peer))
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, m.Allow(peer.ID(), "ping"))
+	}
+	assert.False(t, m.Allow(peer.ID(), "ping"))
+	// each message type has its own bucket
+	assert.True(t, m.Allow(peer.ID(), "send_transaction"))
+	assert.False(t, m.Allow(peer.ID(), "send_transaction"))
+	// and each peer
+	other := newTestPeer("c2", "10.0.0.4:1000", 4)
+	assert.NoError(t, m.Admit(other))
+	assert.True(t, m.Allow(other.ID(), "ping"))
+
+	clock.now = clock.now.Add(200 * time.Millisecond)
+	assert.True(t, m.Allow(peer.ID(), "ping"))
+	assert.True(t, m.Allow(peer.ID(), "ping"))
+	assert.False(t, m.Allow(peer.ID(), "ping"))
+	assert.False(t, m.Allow(peer.ID(), "send_transaction"))
+
+	cfg.EnableRateLimiter = false
+	assert.True(t, m.Allow(peer.ID(), "ping"))
+}
+
+func TestAuthorize(t *testing.T) {
+	cfg := p2p.DefaultConfig()
+	explorer := common.BigToAddress(big.NewInt(4))
+	cfg.Explorers = []common.Address{explorer}
+	m, _ := newTestManager(cfg)
+	customer := common.BigToAddress(big.NewInt(10))
+	other := common.BigToAddress(big.NewInt(11))
+	m.authority.(*testAuthority).observers[common.BigToAddress(big.NewInt(2))] = []common.Address{customer}
+
+	validator := newTestPeer("v1", "10.0.0.1:1000", 1)
+	observer := newTestPeer("o1", "10.0.0.2:1000", 2)
+	client := newTestPeer("c1", "10.0.0.3:1000", 3)
+	explorerPeer := newTestPeer("e1", "10.0.0.4:1000", 4)
+	anonymous := newTestPeer("a1", "10.0.0.5:1000", 0)
+	assert.Equal(t, RoleExplorer, m.RoleOf(explorer))
+	assert.Equal(t, RoleAnonymous, m.RoleOf(common.Address{}))
+
+	assert.NoError(t, m.Authorize(validator, message.MessageVote))
+	assert.ErrorIs(t, m.Authorize(observer, message.MessageVote), p2p.ErrAccessDenied)
+	assert.NoError(t, m.Authorize(observer, message.MessageBlock))
+	assert.ErrorIs(t, m.Authorize(client, message.MessageBlock), p2p.ErrAccessDenied)
+	assert.NoError(t, m.Authorize(explorerPeer, message.MessageGetReceipts))
+	assert.ErrorIs(t, m.Authorize(client, message.MessageGetReceipts), p2p.ErrAccessDenied)
+	assert.NoError(t, m.Authorize(client, message.MessageGetReceipt))
+	assert.ErrorIs(t, m.Authorize(anonymous, message.MessageGetReceipt), p2p.ErrAccessDenied)
+	// types without a policy are open
+	assert.NoError(t, m.Authorize(anonymous, message.MessagePing))
+	m.SetPolicy(message.MessagePing, RoleValidator)
+	assert.ErrorIs(t, m.Authorize(client, message.MessagePing), p2p.ErrAccessDenied)
+
+	// validators, observers and explorers see every account, observers are
+	// served the raw chain data whatever their access list
+	assert.NoError(t, m.Authorize(observer, message.MessageGetNodeData))
+	assert.True(t, m.CanAccess(validator))
+	assert.True(t, m.CanAccess(explorerPeer, other))
+	assert.True(t, m.CanAccess(observer))
+	assert.True(t, m.CanAccess(observer, other))
+	// clients their own wallet
+	assert.False(t, m.CanAccess(client))
+	assert.True(t, m.CanAccess(client, client.wallet, other))
+	assert.False(t, m.CanAccess(client, customer))
+	assert.False(t, m.CanAccess(anonymous, common.Address{}))
+
+	// explorers and anonymous peers count as clients
+	cfg.MaxClientPeers = 1
+	assert.NoError(t, m.Admit(explorerPeer))
+	assert.ErrorIs(t, m.Admit(client), ErrTooManyPeers)
+}
@@ -0,0 +1,59 @@
+package peer_manager
+
+import (
+	"time"
+
+	"FichainCore/p2p"
+)
+
+// bucket is a token bucket refilled at the rate of a message type
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) take(limit p2p.RateLimit, now time.Time) bool {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	b.tokens += now.Sub(b.last).Seconds() * limit.Rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Allow takes a token from the bucket of the message type of a peer, it
+// implements p2p.RateLimiter. Peers that were not admitted are not limited.
+func (m *PeerManager) Allow(peerID string, msgType string) bool {
+	if !m.config.EnableRateLimiter {
+		return true
+	}
+	limit, ok := m.config.MessageRates[msgType]
+	if !ok {
+		limit = m.config.MessageRate
+	}
+	if limit.Rate <= 0 {
+		return true
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	state, ok := m.peers[peerID]
+	if !ok {
+		return true
+	}
+	now := m.now()
+	b, ok := state.buckets[msgType]
+	if !ok {
+		b = &bucket{tokens: float64(max(limit.Burst, 1)), last: now}
+		state.buckets[msgType] = b
+	}
+	return b.take(limit, now)
+}
@@ -1,7 +1,10 @@
 package router
 
 import (
+	"errors"
+	"sort"
 	"sync"
+	"time"
 
 	logger "github.com/HendrickPhan/golang-simple-logger"
 
@@ -9,22 +12,118 @@ import (
 	"FichainCore/p2p/message"
 )
 
-// Router implements the Router interface using a map of handlers.
+// DefaultMaxMessageAge is how far a message timestamp may be from the local
+// clock before the message is dropped
+const DefaultMaxMessageAge = time.Minute
+
+// Router implements the Router interface using a map of handlers. Only
+// messages signed by the wallet the peer proved in the handshake, with a
+// fresh timestamp, reach the handlers. When a rate limiter and a scorer are
+// set, messages over the rate of their peer are dropped and the peer is
+// penalized for dropped messages and for handler errors marked as its fault
+// with p2p.ErrInvalidPayload or p2p.ErrAccessDenied. Responses carrying a
+// request ID go to the response handler instead of the handler of their
+// type. When an access controller is set, messages a peer may not send are
+// dropped before their handler.
 type Router struct {
-	handlers map[string]func(p2p.Peer, *message.Message) error
-	lock     sync.RWMutex
+	handlers  map[string]func(p2p.Peer, *message.Message) error
+	maxAge    time.Duration
+	limiter   p2p.RateLimiter
+	scorer    p2p.Scorer
+	responses p2p.ResponseHandler
+	access    p2p.AccessController
+	lock      sync.RWMutex
 }
 
 // NewRouter creates a new Router instance.
 func NewRouter() *Router {
 	return &Router{
 		handlers: make(map[string]func(p2p.Peer, *message.Message) error),
+		maxAge:   DefaultMaxMessageAge,
+	}
+}
+
+// SetMaxMessageAge sets how old a message may be
+func (r *Router) SetMaxMessageAge(maxAge time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.maxAge = maxAge
+}
+
+// SetRateLimiter sets the limiter of the messages of each peer
+func (r *Router) SetRateLimiter(limiter p2p.RateLimiter) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.limiter = limiter
+}
+
+// SetScorer sets the scorer told about misbehaving peers
+func (r *Router) SetScorer(scorer p2p.Scorer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.scorer = scorer
+}
+
+// SetResponseHandler sets the handler waiting for the responses to requests
+func (r *Router) SetResponseHandler(responses p2p.ResponseHandler) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.responses = responses
+}
+
+// SetAccessController sets the controller of the message types each peer
+// may send
+func (r *Router) SetAccessController(access p2p.AccessController) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.access = access
+}
+
+// Penalize reports a misbehaving peer to the scorer, peers call it for
+// frames they cannot decode
+func (r *Router) Penalize(peer p2p.Peer, points int, reason string) {
+	r.lock.RLock()
+	scorer := r.scorer
+	r.lock.RUnlock()
+	if scorer != nil {
+		scorer.Penalize(peer, points, reason)
 	}
 }
 
 // Route finds and executes the appropriate handler for the message.
 func (r *Router) Route(peer p2p.Peer, msg *message.Message) {
 	logger.DebugP("Receive message type", msg.Header.MessageType)
+	r.lock.RLock()
+	maxAge := r.maxAge
+	limiter := r.limiter
+	responses := r.responses
+	access := r.access
+	r.lock.RUnlock()
+	if limiter != nil && !limiter.Allow(peer.ID(), msg.Header.MessageType) {
+		logger.Warn("[Router] rate limited", msg.Header.MessageType, "from", peer.ID())
+		r.Penalize(peer, p2p.PenaltyRateLimited, "rate limited")
+		return
+	}
+	if err := msg.Verify(peer.WalletAddress(), maxAge, time.Now()); err != nil {
+		logger.Warn("[Router] dropping message", msg.Header.MessageType, "from", peer.ID(), err)
+		r.Penalize(peer, p2p.PenaltyInvalidMessage, err.Error())
+		return
+	}
+	if msg.Header.Response && msg.Header.RequestID != 0 {
+		if responses == nil || !responses.HandleResponse(peer, msg) {
+			// the request timed out or was never sent
+			logger.Debug("[Router] dropping unexpected response", msg.Header.MessageType, "from", peer.ID())
+		}
+		return
+	}
+	if access != nil {
+		if err := access.Authorize(peer, msg.Header.MessageType); err != nil {
+			logger.Warn("[Router] dropping message", msg.Header.MessageType, "from", peer.ID(), err)
+			r.Penalize(peer, p2p.PenaltyUnauthorized, err.Error())
+			return
+		}
+	}
+
 	r.lock.RLock()
 	handler, ok := r.handlers[msg.Header.MessageType]
 	r.lock.RUnlock()
@@ -33,6 +132,13 @@ func (r *Router) Route(peer p2p.Peer, msg *message.Message) {
 		err := handler(peer, msg)
 		if err != nil {
 			logger.Error("[Router] Error when handle message type: ", msg.Header.MessageType, err)
+			// other errors may come from the local node, such as a chain
+			// behind the peer, so they are not held against it
+			if errors.Is(err, p2p.ErrInvalidPayload) {
+				r.Penalize(peer, p2p.PenaltyInvalidPayload, err.Error())
+			} else if errors.Is(err, p2p.ErrAccessDenied) {
+				r.Penalize(peer, p2p.PenaltyUnauthorized, err.Error())
+			}
 		}
 	} else {
 		logger.Warn("[Router] No handler registered for message type: ", msg.Header.MessageType)
@@ -40,12 +146,29 @@ func (r *Router) Route(peer p2p.Peer, msg *message.Message) {
 }
 
 // RegisterHandler registers a handler function for a given message type.
+// The type must be registered with message.Register, peers cannot decode
+// messages of other types.
 func (r *Router) RegisterHandler(msgType string, handler func(p2p.Peer, *message.Message) error) {
+	if !message.IsRegistered(msgType) {
+		logger.Warn("[Router] handler registered for unknown message type", msgType)
+	}
 	r.lock.Lock()
 	defer r.lock.Unlock()
 	r.handlers[msgType] = handler
 }
 
+// Routes lists the message types with a handler in order, for debugging
+func (r *Router) Routes() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	routes := make([]string, 0, len(r.handlers))
+	for msgType := range r.handlers {
+		routes = append(routes, msgType)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
 func (r *Router) RegisterHanlders(handlers map[string]func(p2p.Peer, *message.Message) error) {
 	for i, v := range handlers {
 		r.RegisterHandler(i, v)
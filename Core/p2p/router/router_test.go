@@ -0,0 +1,105 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/common"
+	"FichainCore/config"
+	"FichainCore/crypto"
+	"FichainCore/p2p"
+	"FichainCore/p2p/message"
+	"FichainCore/p2p/message_sender"
+	"FichainCore/signer"
+	"FichainCore/types"
+)
+
+type testPeer struct {
+	p2p.Peer
+	wallet common.Address
+}
+
+func (p *testPeer) ID() string                    { return p.wallet.Hex() }
+func (p *testPeer) WalletAddress() common.Address { return p.wallet }
+
+type testScorer struct {
+	reasons []string
+}
+
+func (s *testScorer) Penalize(peer p2p.Peer, points int, reason string) {
+	s.reasons = append(s.reasons, reason)
+}
+
+func TestRouteEmptyPayload(t *testing.T) {
+	config.SetConfig(&config.Config{NodeID: "node-1"})
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	sender := message_sender.NewMessageSender(nil, signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key))))
+	peer := &testPeer{wallet: crypto.PubkeyToAddress(key.PublicKey)}
+
+	scorer := &testScorer{}
+	router := NewRouter()
+	router.SetScorer(scorer)
+	handled := 0
+	for _, msgType := range []string{
+		message.MessageGetHeadBlock,
+		message.MessageGetBalance,
+		message.MessageGetSupplyReport,
+	} {
+		router.RegisterHandler(msgType, func(p2p.Peer, *message.Message) error {
+			handled++
+			return nil
+		})
+	}
+
+	// requests without parameters are sent with no payload or an empty one,
+	// both decode to no payload and verify on the receiving side
+	for _, payload := range []message.HaveProto{nil, &message.BytesMessage{}} {
+		for _, msgType := range []string{
+			message.MessageGetHeadBlock,
+			message.MessageGetBalance,
+			message.MessageGetSupplyReport,
+		} {
+			msg, err := sender.NewMessage(msgType, payload)
+			assert.NoError(t, err)
+			data, err := msg.Marshal()
+			assert.NoError(t, err)
+			received := &message.Message{}
+			assert.NoError(t, received.Unmarshal(data))
+			assert.Nil(t, received.Payload)
+			router.Route(peer, received)
+		}
+	}
+	assert.Equal(t, 6, handled)
+	assert.Empty(t, scorer.reasons)
+}
+
+func TestRouteHandlerError(t *testing.T) {
+	config.SetConfig(&config.Config{NodeID: "node-1"})
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	sender := message_sender.NewMessageSender(nil, signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key))))
+	peer := &testPeer{wallet: crypto.PubkeyToAddress(key.PublicKey)}
+
+	scorer := &testScorer{}
+	router := NewRouter()
+	router.SetScorer(scorer)
+	for _, handlerErr := range []error{
+		errors.New("unknown ancestor"),
+		fmt.Errorf("%w: not a block", p2p.ErrInvalidPayload),
+		fmt.Errorf("%w: nonce", p2p.ErrAccessDenied),
+	} {
+		handlerErr := handlerErr
+		router.RegisterHandler(message.MessageGetHeadBlock, func(p2p.Peer, *message.Message) error {
+			return handlerErr
+		})
+		msg, err := sender.NewMessage(message.MessageGetHeadBlock, nil)
+		assert.NoError(t, err)
+		router.Route(peer, msg)
+	}
+	// only the errors blamed on the peer are penalized
+	assert.Equal(t, []string{"invalid payload: not a block", "access denied: nonce"}, scorer.reasons)
+}
@@ -0,0 +1,23 @@
+package p2p
+
+import "errors"
+
+// Misbehaviour points added to the score of a peer, a peer whose score
+// reaches Config.BanThreshold is disconnected and banned
+const (
+	PenaltyDecodeFailure  = 10 // a frame that is not a message
+	PenaltyInvalidMessage = 10 // a bad signature or a stale timestamp
+	PenaltyInvalidPayload = 20 // a payload the peer should not have sent
+	PenaltyRateLimited    = 2
+	PenaltyUnauthorized   = 5 // a message the role of the peer may not send
+)
+
+var (
+	// ErrInvalidPayload marks a handler error caused by the peer, such as an
+	// invalid transaction, rather than by the local node
+	ErrInvalidPayload = errors.New("invalid payload")
+
+	// ErrAccessDenied is returned when a peer asks for a message type or for
+	// data its role does not give access to
+	ErrAccessDenied = errors.New("access denied")
+)
@@ -18,6 +18,7 @@ import (
 	"FichainCore/p2p/lookup_table"
 	"FichainCore/p2p/message"
 	"FichainCore/p2p/peer"
+	"FichainCore/p2p/peer_manager"
 	"FichainCore/signer"
 )
 
@@ -28,6 +29,9 @@ type TCPServer struct {
 	peerLock    sync.Mutex          // Mutex to protect access to the peers map
 	router      p2p.Router          // Router to route messages
 	lookupTable *lookup_table.LookupTable
+	onConnect   func(p2p.Peer) // called once a peer completed the handshake
+	manager     *peer_manager.PeerManager
+	status      *message.Status // Chain and protocol announced in the handshake
 
 	signer *signer.Signer
 }
@@ -45,9 +49,16 @@ func NewTCPServer(
 		router:      router,
 		signer:      signer,
 		lookupTable: lookupTable,
+		status:      message.NewStatus(0, common.Hash{}, ""),
 	}
 }
 
+// SetStatus sets the status announced to the peers, peers of another chain
+// or without a common protocol version are refused
+func (s *TCPServer) SetStatus(status *message.Status) {
+	s.status = status
+}
+
 // Listen starts the server and listens for incoming peer connections on the specified address.
 func (s *TCPServer) Listen() error {
 	// Start listening on the provided address and port
@@ -75,7 +86,7 @@ func (s *TCPServer) Listen() error {
 // handleConnection processes an incoming TCP connection, performs a handshake, and adds the peer.
 func (s *TCPServer) handleConnection(conn net.Conn) {
 	// Perform the handshake with the new peer
-	peer, err := s.handshake(conn)
+	peer, err := s.admitHandshake(conn)
 	if err != nil {
 		log.Printf("Handshake failed: %v", err)
 		conn.Close()
@@ -92,6 +103,9 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 
 	// Log when the peer connects
 	log.Printf("Peer connected: %s", peer.ID())
+	if s.onConnect != nil {
+		s.onConnect(peer)
+	}
 
 	// Wait for the peer to disconnect
 	<-peer.Done()
@@ -104,12 +118,43 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 	log.Printf("Peer disconnected: %s", peer.ID())
 }
 
+// admitHandshake runs the handshake of a connection within the limits of the
+// peer manager, if any: banned hosts are refused, the handshakes in progress
+// are bounded and must complete within the handshake timeout, and the peer
+// must fit in the cap of its role
+func (s *TCPServer) admitHandshake(conn net.Conn) (p2p.Peer, error) {
+	if s.manager == nil {
+		return s.handshake(conn)
+	}
+	done, err := s.manager.BeginHandshake(conn.RemoteAddr().String())
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	cfg := s.manager.Config()
+	if err := conn.SetDeadline(time.Now().Add(cfg.HandshakeTimeout)); err != nil {
+		return nil, err
+	}
+	p, err := s.handshake(conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		p.Close()
+		return nil, err
+	}
+	if tcpPeer, ok := p.(*peer.TcpPeer); ok {
+		tcpPeer.SetWriteTimeout(cfg.MessageWriteTimeout)
+	}
+	return p, nil
+}
+
 // handshake performs an initial handshake with the peer (you can add custom logic here).
 func (s *TCPServer) handshake(conn net.Conn) (p2p.Peer, error) {
 	// Example handshake: Create a new tcpPeer and return it.
 	peerID := conn.RemoteAddr().
 		String()
-	p := peer.NewTCPPeer(conn, peerID)
+	p := peer.NewTCPPeer(conn, peerID).(*peer.TcpPeer)
 	// 1. wait for initial handshake
 
 	timeout := time.After(5 * time.Second)
@@ -131,8 +176,24 @@ func (s *TCPServer) handshake(conn net.Conn) (p2p.Peer, error) {
 				continue // malformed payload, ignore
 			}
 			slog.Info(fmt.Sprintf("Receive Handshake init from %v", peerID))
+			version, err := s.status.Negotiate(initMsg.Status)
+			if err != nil {
+				return nil, fmt.Errorf("incompatible peer %s: %w", peerID, err)
+			}
 			// 2. send ack
-			ackSign, err := s.signer.SignBytes(initMsg.Payload)
+			ephemeral, err := peer.GenerateEphemeralKey()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate session key: %w", err)
+			}
+			ephemeralKey := peer.EphemeralPublicKey(ephemeral)
+			// peers without a session key would talk in clear text
+			session, err := peer.NewSession(ephemeral, initMsg.EphemeralKey, false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to establish session: %w", err)
+			}
+			ackSign, err := s.signer.SignBytes(
+				peer.SessionBinding(initMsg.Payload, initMsg.EphemeralKey, ephemeralKey, s.status.Bytes()),
+			)
 			if err != nil {
 				return nil, fmt.Errorf("failed sign ack: %w", err)
 			}
@@ -151,10 +212,12 @@ func (s *TCPServer) handshake(conn net.Conn) (p2p.Peer, error) {
 				WalletAddress: walletAddress.Bytes(),
 				Payload:       bPayload,
 				Signature:     ackSign.Bytes(),
+				EphemeralKey:  ephemeralKey,
+				Status:        s.status,
 			}
 			fmtConfirmMsg := &message.Message{
 				Header: &message.Header{
-					Version:     config.GetConfig().Version,
+					Version:     message.ProtocolVersion,
 					SenderID:    config.GetConfig().NodeID,
 					MessageType: message.MessageHandshakeAck,
 					Timestamp:   time.Now().Unix(),
@@ -188,7 +251,15 @@ func (s *TCPServer) handshake(conn net.Conn) (p2p.Peer, error) {
 					}
 
 					slog.Info(fmt.Sprintf("Receive Handshake confirm from %v", peerID))
-					pub, err := crypto.SigToPub(crypto.Keccak256(bPayload), confirmMsg.Signature)
+					// the confirm signs our challenge, both session keys and
+					// the status of the dialer
+					binding := peer.SessionBinding(
+						bPayload,
+						initMsg.EphemeralKey,
+						ephemeralKey,
+						initMsg.Status.Bytes(),
+					)
+					pub, err := crypto.SigToPub(crypto.Keccak256(binding), confirmMsg.Signature)
 					if err != nil {
 						return nil, fmt.Errorf("failed to extrack pub from sign: %w", err)
 					}
@@ -197,8 +268,16 @@ func (s *TCPServer) handshake(conn net.Conn) (p2p.Peer, error) {
 						slog.Warn("Invalid sign in HandshakeConfirm message")
 						continue // malformed sign, ignore
 					}
+					// every frame after the confirmation is encrypted
+					p.EnableEncryption(session)
 					// add to lookupTable
 					p.SetWalletAddress(common.BytesToAddress(initMsg.WalletAddress))
+					p.SetStatus(initMsg.Status, version)
+					if s.manager != nil {
+						if err := s.manager.Admit(p); err != nil {
+							return nil, err
+						}
+					}
 					s.lookupTable.Add(addr, p)
 					return p, nil
 				}
@@ -207,6 +286,18 @@ func (s *TCPServer) handshake(conn net.Conn) (p2p.Peer, error) {
 	}
 }
 
+// SetPeerManager makes the server enforce the peer caps, timeouts and bans
+// of a peer manager
+func (s *TCPServer) SetPeerManager(manager *peer_manager.PeerManager) {
+	s.manager = manager
+}
+
+// OnPeerConnected sets a handler called for every inbound and registered
+// outbound peer once its handshake completed
+func (s *TCPServer) OnPeerConnected(handler func(p2p.Peer)) {
+	s.onConnect = handler
+}
+
 // Close shuts down the server (closes all open connections).
 func (s *TCPServer) Close() error {
 	// Iterate over all connected peers and close their connections
@@ -223,6 +314,17 @@ func (s *TCPServer) Close() error {
 
 // RegisterPeer adds an already connected peer to the server and starts ReadLoop.
 func (s *TCPServer) RegisterPeer(p p2p.Peer) {
+	if s.manager != nil {
+		if err := s.manager.Admit(p); err != nil {
+			log.Printf("Refused outbound peer %s: %v", p.ID(), err)
+			s.lookupTable.RemovePeer(p.WalletAddress(), p)
+			p.Close()
+			return
+		}
+		if tcpPeer, ok := p.(*peer.TcpPeer); ok {
+			tcpPeer.SetWriteTimeout(s.manager.Config().MessageWriteTimeout)
+		}
+	}
 	s.peerLock.Lock()
 	s.peers[p.ID()] = p
 	s.peerLock.Unlock()
@@ -232,6 +334,9 @@ func (s *TCPServer) RegisterPeer(p p2p.Peer) {
 
 	// Log connection
 	log.Printf("Outbound peer registered: %s", p.ID())
+	if s.onConnect != nil {
+		s.onConnect(p)
+	}
 
 	// Wait for disconnection
 	go func() {
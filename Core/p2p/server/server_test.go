@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/common"
+	"FichainCore/config"
+	"FichainCore/crypto"
+	"FichainCore/p2p"
+	"FichainCore/p2p/client"
+	"FichainCore/p2p/lookup_table"
+	"FichainCore/p2p/message"
+	"FichainCore/signer"
+	"FichainCore/types"
+)
+
+// recordConn keeps every byte read from the wire
+type recordConn struct {
+	net.Conn
+	read bytes.Buffer
+	sync.Mutex
+}
+
+func (c *recordConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.Lock()
+	c.read.Write(b[:n])
+	c.Unlock()
+	return n, err
+}
+
+func (c *recordConn) bytes() []byte {
+	c.Lock()
+	defer c.Unlock()
+	return append([]byte{}, c.read.Bytes()...)
+}
+
+func newTestSigner(t *testing.T) *signer.Signer {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	return signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key)))
+}
+
+func TestEncryptedHandshake(t *testing.T) {
+	config.SetConfig(&config.Config{NodeID: "node-1", Version: 1})
+	serverSigner := newTestSigner(t)
+	clientSigner := newTestSigner(t)
+	serverWallet, _ := serverSigner.WalletAddress()
+	clientWallet, _ := clientSigner.WalletAddress()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	lookupTable := lookup_table.NewLookupTable()
+	s := NewTCPServer(listener.Addr().String(), nil, lookupTable, serverSigner)
+	type accepted struct {
+		peer p2p.Peer
+		conn *recordConn
+		err  error
+	}
+	result := make(chan accepted, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			result <- accepted{err: err}
+			return
+		}
+		recorded := &recordConn{Conn: conn}
+		p, err := s.handshake(recorded)
+		result <- accepted{peer: p, conn: recorded, err: err}
+	}()
+
+	dialed, err := client.NewTCPClient(time.Second, clientSigner).Dial(listener.Addr().String())
+	assert.NoError(t, err)
+	defer dialed.Close()
+	inbound := <-result
+	assert.NoError(t, inbound.err)
+	defer inbound.peer.Close()
+
+	// both sides know the wallet the other proved
+	assert.Equal(t, serverWallet, dialed.WalletAddress())
+	assert.Equal(t, clientWallet, inbound.peer.WalletAddress())
+	assert.True(t, lookupTable.Has(clientWallet))
+
+	secret := "customer-payment-0042"
+	for i := 0; i < 3; i++ {
+		err = dialed.Send(&message.Message{
+			Header:  &message.Header{Version: 1, MessageType: message.MessagePing},
+			Payload: &message.Ping{NodeID: secret, Timestamp: int64(i)},
+		})
+		assert.NoError(t, err)
+		msg, err := inbound.peer.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, secret, msg.Payload.(*message.Ping).NodeID)
+		assert.Equal(t, int64(i), msg.Payload.(*message.Ping).Timestamp)
+	}
+	assert.False(t, bytes.Contains(inbound.conn.bytes(), []byte(secret)))
+}
+
+func TestHandshakeStatus(t *testing.T) {
+	config.SetConfig(&config.Config{NodeID: "node-1", Version: 1})
+	genesis := common.HexToHash("0x01")
+	serverStatus := message.NewStatus(2510, genesis, message.RoleNameValidator, message.CapabilityTxGossip)
+
+	handshake := func(clientStatus *message.Status) (p2p.Peer, p2p.Peer, error, error) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+		s := NewTCPServer(listener.Addr().String(), nil, lookup_table.NewLookupTable(), newTestSigner(t))
+		s.SetStatus(serverStatus)
+		type accepted struct {
+			peer p2p.Peer
+			err  error
+		}
+		result := make(chan accepted, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				result <- accepted{err: err}
+				return
+			}
+			p, err := s.handshake(conn)
+			if err != nil {
+				conn.Close()
+			}
+			result <- accepted{peer: p, err: err}
+		}()
+		dialer := client.NewTCPClient(time.Second, newTestSigner(t))
+		if clientStatus != nil {
+			dialer.SetStatus(clientStatus)
+		}
+		dialed, dialErr := dialer.Dial(listener.Addr().String())
+		inbound := <-result
+		return dialed, inbound.peer, dialErr, inbound.err
+	}
+
+	// a node of the same chain
+	dialed, inbound, dialErr, acceptErr := handshake(
+		message.NewStatus(2510, genesis, message.RoleNameObserver, message.CapabilityFastSync),
+	)
+	assert.NoError(t, dialErr)
+	assert.NoError(t, acceptErr)
+	assert.Equal(t, message.ProtocolVersion, dialed.Version())
+	assert.Equal(t, message.ProtocolVersion, inbound.Version())
+	assert.True(t, dialed.Status().Has(message.CapabilityTxGossip))
+	assert.Equal(t, message.RoleNameObserver, inbound.Status().Role)
+	dialed.Close()
+	inbound.Close()
+
+	// a client that does not know the chain
+	dialed, inbound, dialErr, acceptErr = handshake(nil)
+	assert.NoError(t, dialErr)
+	assert.NoError(t, acceptErr)
+	assert.Equal(t, message.RoleNameClient, inbound.Status().Role)
+	dialed.Close()
+	inbound.Close()
+
+	// a node of another chain
+	_, _, dialErr, acceptErr = handshake(
+		message.NewStatus(2510, common.HexToHash("0x02"), message.RoleNameValidator),
+	)
+	assert.Error(t, dialErr)
+	assert.ErrorIs(t, acceptErr, message.ErrGenesisMismatch)
+	_, _, dialErr, acceptErr = handshake(message.NewStatus(1, genesis, message.RoleNameValidator))
+	assert.Error(t, dialErr)
+	assert.ErrorIs(t, acceptErr, message.ErrChainMismatch)
+
+	// a release without a common protocol version
+	old := message.NewStatus(2510, genesis, message.RoleNameValidator)
+	old.MinVersion, old.MaxVersion = 1, 1
+	_, _, dialErr, acceptErr = handshake(old)
+	assert.Error(t, dialErr)
+	assert.ErrorIs(t, acceptErr, message.ErrIncompatibleVersion)
+}
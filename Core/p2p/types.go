@@ -19,6 +19,9 @@ type Peer interface {
 	Done() <-chan struct{}
 	SetWalletAddress(common.Address)
 	WalletAddress() common.Address
+	SetStatus(status *message.Status, version uint32)
+	Status() *message.Status // status the peer sent in the handshake
+	Version() uint32         // protocol version negotiated in the handshake
 }
 
 // Defines message routing behavior
@@ -54,3 +57,19 @@ type PeerDiscovery interface {
 type RateLimiter interface {
 	Allow(peerID string, msgType string) bool
 }
+
+// Scorer tracks the misbehaviour of peers and bans the worst ones
+type Scorer interface {
+	Penalize(peer Peer, points int, reason string)
+}
+
+// AccessController tells whether a peer may send a message type
+type AccessController interface {
+	Authorize(peer Peer, msgType string) error
+}
+
+// ResponseHandler takes the responses to pending requests before they reach
+// the handlers of their message type
+type ResponseHandler interface {
+	HandleResponse(peer Peer, msg *message.Message) bool
+}
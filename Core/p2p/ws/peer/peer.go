@@ -1,6 +1,7 @@
 package peer // Or your chosen package for peer implementations
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -13,6 +14,9 @@ import (
 	"FichainCore/p2p/message" // Your Protobuf message definitions
 )
 
+// errMalformed is returned by ReadMessage for a frame that is not a message
+var errMalformed = errors.New("malformed message")
+
 // WebSocketPeer represents a peer connected via WebSocket.
 type WebSocketPeer struct {
 	id         string
@@ -25,6 +29,8 @@ type WebSocketPeer struct {
 	remoteAddr string // Network address of the remote peer
 
 	walletAddress common.Address
+	status        *message.Status
+	version       uint32
 }
 
 // NewWebSocketPeer creates a new WebSocketPeer.
@@ -209,6 +215,11 @@ func (p *WebSocketPeer) ReadLoop(router p2p.Router) {
 			// The read deadline is managed by the PongHandler and initial SetReadDeadline.
 			// No need to set it in every loop iteration here if pings/pongs are active.
 			msg, err := p.ReadMessage() // ReadMessage itself will call p.Close() on critical error
+			if errors.Is(err, errMalformed) {
+				if scorer, ok := router.(p2p.Scorer); ok {
+					scorer.Penalize(p, p2p.PenaltyDecodeFailure, err.Error())
+				}
+			}
 			if err != nil {
 				// Error handling for ReadMessage
 				if websocket.IsCloseError(
@@ -321,7 +332,7 @@ func (p *WebSocketPeer) ReadMessage() (*message.Message, error) {
 			"data_len",
 			len(data),
 		)
-		return nil, fmt.Errorf("failed to unmarshal protobuf message from peer %s: %w", p.id, err)
+		return nil, fmt.Errorf("%w from peer %s: %v", errMalformed, p.id, err)
 	}
 
 	return msg, nil
@@ -340,3 +351,18 @@ func (p *WebSocketPeer) SetWalletAddress(addr common.Address) {
 func (p *WebSocketPeer) WalletAddress() common.Address {
 	return p.walletAddress
 }
+
+// SetStatus records the status of the peer and the protocol version
+// negotiated with it, before the peer is routed
+func (p *WebSocketPeer) SetStatus(status *message.Status, version uint32) {
+	p.status = status
+	p.version = version
+}
+
+func (p *WebSocketPeer) Status() *message.Status {
+	return p.status
+}
+
+func (p *WebSocketPeer) Version() uint32 {
+	return p.version
+}
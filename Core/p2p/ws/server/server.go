@@ -21,6 +21,7 @@ import (
 	"FichainCore/p2p" // Your P2P interfaces
 	"FichainCore/p2p/lookup_table"
 	"FichainCore/p2p/message" // Your Protobuf message definitions
+	"FichainCore/p2p/peer_manager"
 	"FichainCore/p2p/ws/peer" // Where your updated WebSocketPeer (using protobuf) resides
 	"FichainCore/signer"
 )
@@ -39,6 +40,8 @@ type WebSocketServer struct {
 	httpServer  *http.Server
 	mu          sync.Mutex // Protects httpServer and isListening state
 	isListening bool
+	manager     *peer_manager.PeerManager // Enforces peer caps, rate limits and bans, optional
+	status      *message.Status           // Chain and protocol announced in the handshake
 }
 
 // NewWebSocketServer creates a new instance of a WebSocketServer.
@@ -55,6 +58,7 @@ func NewWebSocketServer(
 		router:      router,
 		signer:      signer,
 		lookupTable: lookupTable,
+		status:      message.NewStatus(0, common.Hash{}, ""),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -74,6 +78,18 @@ func NewWebSocketServer(
 	}
 }
 
+// SetPeerManager makes the server enforce the peer caps, message size,
+// timeouts and bans of a peer manager
+func (s *WebSocketServer) SetPeerManager(manager *peer_manager.PeerManager) {
+	s.manager = manager
+}
+
+// SetStatus sets the status announced to the peers, peers of another chain
+// or without a common protocol version are refused
+func (s *WebSocketServer) SetStatus(status *message.Status) {
+	s.status = status
+}
+
 // Listen starts the HTTP server and listens for WebSocket upgrade requests on the given address.
 // This method conforms to the p2p.Server interface.
 func (s *WebSocketServer) Listen(address string) error {
@@ -118,25 +134,43 @@ func (s *WebSocketServer) Listen(address string) error {
 
 // handleWebSocketUpgrade attempts to upgrade an HTTP connection to a WebSocket connection.
 func (s *WebSocketServer) handleWebSocketUpgrade(w http.ResponseWriter, r *http.Request) {
+	// banned hosts and connections over the handshake limit are refused
+	// before the upgrade
+	done := func() {}
+	if s.manager != nil {
+		var err error
+		done, err = s.manager.BeginHandshake(r.RemoteAddr)
+		if err != nil {
+			slog.Warn("Refused WebSocket connection", "remote_addr", r.RemoteAddr, "error", err)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+	}
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		done()
 		slog.Warn("Failed to upgrade to WebSocket", "remote_addr", r.RemoteAddr, "error", err)
 		// http.Error(w, "Could not open websocket connection", http.StatusBadRequest) // Optionally send HTTP error
 		return
 	}
 	slog.Info("WebSocket connection upgrade successful", "remote_addr", r.RemoteAddr)
+	if s.manager != nil {
+		conn.SetReadLimit(s.manager.Config().MaxWsMessageSize)
+	}
 
 	// Pass r.RemoteAddr for peer identification and the Address() method
 	// The actual peer.ID() will be generated inside NewWebSocketPeer
-	go s.handleConnection(conn, r.RemoteAddr)
+	go s.handleConnection(conn, r.RemoteAddr, done)
 }
 
 // handleConnection processes an incoming WebSocket connection, performs a handshake, and registers the peer.
-func (s *WebSocketServer) handleConnection(wsConn *websocket.Conn, remoteAddr string) {
+// handshakeDone is called once the handshake ended.
+func (s *WebSocketServer) handleConnection(wsConn *websocket.Conn, remoteAddr string, handshakeDone func()) {
 	// Perform the server-side handshake with the new peer.
 	// remoteAddr is the network address (ip:port) from the HTTP request.
 	// NewWebSocketPeer will use this for its Address() method and to generate a unique ID.
 	newPeer, err := s.performServerHandshake(wsConn, remoteAddr)
+	handshakeDone()
 	if err != nil {
 		slog.Error("WebSocket handshake failed", "remote_addr", remoteAddr, "error", err)
 		// Ensure the WebSocket connection is closed if handshake fails.
@@ -166,10 +200,15 @@ func (s *WebSocketServer) performServerHandshake(
 		Conn() *websocket.Conn // Assert that it also has Conn() method for handshake deadlines
 	})
 
+	handshakeTimeout := 15 * time.Second
+	if s.manager != nil {
+		handshakeTimeout = s.manager.Config().HandshakeTimeout
+	}
+
 	// 1. Wait for initial HandshakeInit message
 	// Set a specific read deadline for this handshake message
 	// Use tempPeer.Conn() if WebSocketPeer exposes the underlying connection for such specific operations
-	if err := tempPeer.Conn().SetReadDeadline(time.Now().Add(15 * time.Second)); err != nil {
+	if err := tempPeer.Conn().SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
 		return nil, fmt.Errorf("failed to set read deadline for handshake init: %w", err)
 	}
 	msg, err := tempPeer.ReadMessage()                                   // ReadMessage now expects Protobuf
@@ -207,6 +246,16 @@ func (s *WebSocketServer) performServerHandshake(
 		common.BytesToAddress(initMsg.WalletAddress).Hex(),
 	)
 
+	// web wallets that do not send a status connect as clients of any chain
+	remoteStatus := initMsg.Status
+	if remoteStatus == nil {
+		remoteStatus = message.ClientStatus()
+	}
+	version, err := s.status.Negotiate(remoteStatus)
+	if err != nil {
+		return nil, fmt.Errorf("incompatible peer %s: %w", remoteAddrForID, err)
+	}
+
 	// 2. Send HandshakeAck
 	// Server signs the payload received in HandshakeInit (initMsg.Payload).
 	// initMsg.Payload is already []byte from Protobuf.
@@ -235,6 +284,7 @@ func (s *WebSocketServer) performServerHandshake(
 		WalletAddress: myServerWalletAddress.Bytes(),
 		Payload:       bPayload,        // This is the challenge client must sign
 		Signature:     ackSign.Bytes(), // Server's signature over client's initMsg.Payload
+		Status:        s.status,
 	}
 
 	ackFullMsg := &message.Message{
@@ -253,7 +303,7 @@ func (s *WebSocketServer) performServerHandshake(
 	slog.Info("Sent WebSocket HandshakeAck", "peer_id_attempt", tempPeer.ID())
 
 	// 3. Wait for HandshakeConfirm
-	if err := tempPeer.Conn().SetReadDeadline(time.Now().Add(15 * time.Second)); err != nil {
+	if err := tempPeer.Conn().SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
 		return nil, fmt.Errorf("failed to set read deadline for handshake confirm: %w", err)
 	}
 	msgConfirm, err := tempPeer.ReadMessage()                            // ReadMessage expects Protobuf
@@ -318,6 +368,12 @@ func (s *WebSocketServer) performServerHandshake(
 		clientAuthenticatedWalletAddr.Hex(),
 	)
 	tempPeer.SetWalletAddress(common.BytesToAddress(initMsg.WalletAddress))
+	tempPeer.SetStatus(remoteStatus, version)
+	if s.manager != nil {
+		if err := s.manager.Admit(tempPeer); err != nil {
+			return nil, fmt.Errorf("refused peer %s: %w", remoteAddrForID, err)
+		}
+	}
 	s.lookupTable.Add(clientAuthenticatedWalletAddr, tempPeer) // Add to lookup table
 
 	// At this point, the tempPeer is fully authenticated and ready.
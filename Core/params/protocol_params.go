@@ -10,8 +10,17 @@ var TempGasPrice, _ = new(big.Int).SetString("100000000000000", 10) // 0.1 finne
 
 const (
 	// my temp configs
-	TempGasLimit    uint64 = 500_000_000
-	TempEpochLength        = 20_000_000
+	TempGasLimit               uint64 = 500_000_000
+	TempEpochLength                   = 20_000_000
+	TempProposerTimeout               = 5       // seconds before the next round's backup proposer may seal
+	TempGovernanceProposalTTL         = 100_000 // blocks a governance proposal stays open for votes
+	TempUnbondingPeriod               = 100_000 // blocks before unbonded stake can be withdrawn
+	TempMaxUnbondingEntries           = 7       // unbonds a delegator may have pending per validator
+	TempMinSelfBond                   = 100     // stake every validator bonds to itself before the weights follow the stake
+	TempDoubleSignSlashPercent        = 10      // percent of the bonded stake burned for a double sign
+	TempTreasuryFeePercent            = 10      // percent of the block fees sent to the treasury
+	TempProposerFeePercent            = 0       // percent of the block fees kept by the proposer
+	TempMaxReorgDepth                 = 1024    // blocks a reorg may roll back consensus state derived from the chain
 	//
 
 	GasLimitBoundDivisor uint64 = 1024    // The bound divisor of the gas limit, used in update calculations.
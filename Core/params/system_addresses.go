@@ -0,0 +1,14 @@
+package params
+
+import "FichainCore/common"
+
+// System addresses receive native transactions whose data is interpreted by
+// the consensus engine rather than the EVM.
+var (
+	GovernanceAddress = common.HexToAddress("0x0000000000000000000000000000000000001000")
+	StakingAddress    = common.HexToAddress("0x0000000000000000000000000000000000001001") // holds the bonded and unbonding stake
+	SlashingAddress   = common.HexToAddress("0x0000000000000000000000000000000000001002") // receives double sign evidence
+	TreasuryAddress   = common.HexToAddress("0x0000000000000000000000000000000000001003") // default consortium treasury
+	SettlementAddress = common.HexToAddress("0x0000000000000000000000000000000000001004") // holds the per-epoch fee settlement records
+	ReserveAddress    = common.HexToAddress("0x0000000000000000000000000000000000001005") // mints and burns native VND against the bank reserves
+)
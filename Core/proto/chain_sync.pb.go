@@ -0,0 +1,479 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v5.28.0--dev
+// source: chain_sync.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// HeadersRequest asks for consecutive canonical headers
+type HeadersRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From  uint64 `protobuf:"varint,1,opt,name=From,proto3" json:"From,omitempty"`   // Height of the first header
+	Count uint64 `protobuf:"varint,2,opt,name=Count,proto3" json:"Count,omitempty"` // Number of headers, capped by the serving node
+}
+
+func (x *HeadersRequest) Reset() {
+	*x = HeadersRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_chain_sync_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HeadersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeadersRequest) ProtoMessage() {}
+
+func (x *HeadersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chain_sync_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeadersRequest.ProtoReflect.Descriptor instead.
+func (*HeadersRequest) Descriptor() ([]byte, []int) {
+	return file_chain_sync_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *HeadersRequest) GetFrom() uint64 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *HeadersRequest) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// BlockHeaders is a batch of consecutive canonical headers
+type BlockHeaders struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Headers []*BlockHeader `protobuf:"bytes,1,rep,name=Headers,proto3" json:"Headers,omitempty"`
+}
+
+func (x *BlockHeaders) Reset() {
+	*x = BlockHeaders{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_chain_sync_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockHeaders) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockHeaders) ProtoMessage() {}
+
+func (x *BlockHeaders) ProtoReflect() protoreflect.Message {
+	mi := &file_chain_sync_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockHeaders.ProtoReflect.Descriptor instead.
+func (*BlockHeaders) Descriptor() ([]byte, []int) {
+	return file_chain_sync_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BlockHeaders) GetHeaders() []*BlockHeader {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+// BodiesRequest asks for the bodies of blocks by hash
+type BodiesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hashes [][]byte `protobuf:"bytes,1,rep,name=Hashes,proto3" json:"Hashes,omitempty"`
+}
+
+func (x *BodiesRequest) Reset() {
+	*x = BodiesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_chain_sync_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BodiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BodiesRequest) ProtoMessage() {}
+
+func (x *BodiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chain_sync_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BodiesRequest.ProtoReflect.Descriptor instead.
+func (*BodiesRequest) Descriptor() ([]byte, []int) {
+	return file_chain_sync_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BodiesRequest) GetHashes() [][]byte {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+// BlockBodies holds the bodies of the requested blocks in request order, the
+// serving node stops at the first block it does not have
+type BlockBodies struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Bodies []*Body `protobuf:"bytes,1,rep,name=Bodies,proto3" json:"Bodies,omitempty"`
+}
+
+func (x *BlockBodies) Reset() {
+	*x = BlockBodies{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_chain_sync_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockBodies) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockBodies) ProtoMessage() {}
+
+func (x *BlockBodies) ProtoReflect() protoreflect.Message {
+	mi := &file_chain_sync_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockBodies.ProtoReflect.Descriptor instead.
+func (*BlockBodies) Descriptor() ([]byte, []int) {
+	return file_chain_sync_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BlockBodies) GetBodies() []*Body {
+	if x != nil {
+		return x.Bodies
+	}
+	return nil
+}
+
+// NodeDataRequest asks for state trie nodes and contract code by hash
+type NodeDataRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hashes [][]byte `protobuf:"bytes,1,rep,name=Hashes,proto3" json:"Hashes,omitempty"`
+}
+
+func (x *NodeDataRequest) Reset() {
+	*x = NodeDataRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_chain_sync_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NodeDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeDataRequest) ProtoMessage() {}
+
+func (x *NodeDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chain_sync_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeDataRequest.ProtoReflect.Descriptor instead.
+func (*NodeDataRequest) Descriptor() ([]byte, []int) {
+	return file_chain_sync_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *NodeDataRequest) GetHashes() [][]byte {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+// NodeData holds the requested entries the serving node has, unknown hashes
+// are skipped
+type NodeData struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data [][]byte `protobuf:"bytes,1,rep,name=Data,proto3" json:"Data,omitempty"`
+}
+
+func (x *NodeData) Reset() {
+	*x = NodeData{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_chain_sync_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NodeData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeData) ProtoMessage() {}
+
+func (x *NodeData) ProtoReflect() protoreflect.Message {
+	mi := &file_chain_sync_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeData.ProtoReflect.Descriptor instead.
+func (*NodeData) Descriptor() ([]byte, []int) {
+	return file_chain_sync_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NodeData) GetData() [][]byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_chain_sync_proto protoreflect.FileDescriptor
+
+var file_chain_sync_proto_rawDesc = []byte{
+	0x0a, 0x10, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x73, 0x79, 0x6e, 0x63, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x0a, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x73, 0x79, 0x6e, 0x63, 0x1a, 0x0b,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x3a, 0x0a, 0x0e, 0x48,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x46, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x46, 0x72, 0x6f,
+	0x6d, 0x12, 0x14, 0x0a, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x3c, 0x0a, 0x0c, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12, 0x2c, 0x0a, 0x07, 0x48, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x07, 0x48, 0x65,
+	0x61, 0x64, 0x65, 0x72, 0x73, 0x22, 0x27, 0x0a, 0x0d, 0x42, 0x6f, 0x64, 0x69, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x32,
+	0x0a, 0x0b, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x42, 0x6f, 0x64, 0x69, 0x65, 0x73, 0x12, 0x23, 0x0a,
+	0x06, 0x42, 0x6f, 0x64, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x2e, 0x42, 0x6f, 0x64, 0x79, 0x52, 0x06, 0x42, 0x6f, 0x64, 0x69,
+	0x65, 0x73, 0x22, 0x29, 0x0a, 0x0f, 0x4e, 0x6f, 0x64, 0x65, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x1e, 0x0a,
+	0x08, 0x4e, 0x6f, 0x64, 0x65, 0x44, 0x61, 0x74, 0x61, 0x12, 0x12, 0x0a, 0x04, 0x44, 0x61, 0x74,
+	0x61, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x04, 0x44, 0x61, 0x74, 0x61, 0x42, 0x08, 0x5a,
+	0x06, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_chain_sync_proto_rawDescOnce sync.Once
+	file_chain_sync_proto_rawDescData = file_chain_sync_proto_rawDesc
+)
+
+func file_chain_sync_proto_rawDescGZIP() []byte {
+	file_chain_sync_proto_rawDescOnce.Do(func() {
+		file_chain_sync_proto_rawDescData = protoimpl.X.CompressGZIP(file_chain_sync_proto_rawDescData)
+	})
+	return file_chain_sync_proto_rawDescData
+}
+
+var file_chain_sync_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_chain_sync_proto_goTypes = []interface{}{
+	(*HeadersRequest)(nil),  // 0: chain_sync.HeadersRequest
+	(*BlockHeaders)(nil),    // 1: chain_sync.BlockHeaders
+	(*BodiesRequest)(nil),   // 2: chain_sync.BodiesRequest
+	(*BlockBodies)(nil),     // 3: chain_sync.BlockBodies
+	(*NodeDataRequest)(nil), // 4: chain_sync.NodeDataRequest
+	(*NodeData)(nil),        // 5: chain_sync.NodeData
+	(*BlockHeader)(nil),     // 6: block.BlockHeader
+	(*Body)(nil),            // 7: block.Body
+}
+var file_chain_sync_proto_depIdxs = []int32{
+	6, // 0: chain_sync.BlockHeaders.Headers:type_name -> block.BlockHeader
+	7, // 1: chain_sync.BlockBodies.Bodies:type_name -> block.Body
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_chain_sync_proto_init() }
+func file_chain_sync_proto_init() {
+	if File_chain_sync_proto != nil {
+		return
+	}
+	file_block_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_chain_sync_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HeadersRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_chain_sync_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockHeaders); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_chain_sync_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BodiesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_chain_sync_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockBodies); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_chain_sync_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NodeDataRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_chain_sync_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NodeData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_chain_sync_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_chain_sync_proto_goTypes,
+		DependencyIndexes: file_chain_sync_proto_depIdxs,
+		MessageInfos:      file_chain_sync_proto_msgTypes,
+	}.Build()
+	File_chain_sync_proto = out.File
+	file_chain_sync_proto_rawDesc = nil
+	file_chain_sync_proto_goTypes = nil
+	file_chain_sync_proto_depIdxs = nil
+}
@@ -0,0 +1,161 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v5.28.0--dev
+// source: evidence.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// DoubleSignEvidence proves a proposer signed two different headers for the
+// same height and round
+type DoubleSignEvidence struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	First  *BlockHeader `protobuf:"bytes,1,opt,name=First,proto3" json:"First,omitempty"`   // First signed header
+	Second *BlockHeader `protobuf:"bytes,2,opt,name=Second,proto3" json:"Second,omitempty"` // Conflicting signed header
+}
+
+func (x *DoubleSignEvidence) Reset() {
+	*x = DoubleSignEvidence{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_evidence_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DoubleSignEvidence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DoubleSignEvidence) ProtoMessage() {}
+
+func (x *DoubleSignEvidence) ProtoReflect() protoreflect.Message {
+	mi := &file_evidence_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DoubleSignEvidence.ProtoReflect.Descriptor instead.
+func (*DoubleSignEvidence) Descriptor() ([]byte, []int) {
+	return file_evidence_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DoubleSignEvidence) GetFirst() *BlockHeader {
+	if x != nil {
+		return x.First
+	}
+	return nil
+}
+
+func (x *DoubleSignEvidence) GetSecond() *BlockHeader {
+	if x != nil {
+		return x.Second
+	}
+	return nil
+}
+
+var File_evidence_proto protoreflect.FileDescriptor
+
+var file_evidence_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x65, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x08, 0x65, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x1a, 0x0b, 0x62, 0x6c, 0x6f, 0x63,
+	0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x6a, 0x0a, 0x12, 0x44, 0x6f, 0x75, 0x62, 0x6c,
+	0x65, 0x53, 0x69, 0x67, 0x6e, 0x45, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x28, 0x0a,
+	0x05, 0x46, 0x69, 0x72, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x52, 0x05, 0x46, 0x69, 0x72, 0x73, 0x74, 0x12, 0x2a, 0x0a, 0x06, 0x53, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x2e,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x06, 0x53, 0x65, 0x63,
+	0x6f, 0x6e, 0x64, 0x42, 0x08, 0x5a, 0x06, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_evidence_proto_rawDescOnce sync.Once
+	file_evidence_proto_rawDescData = file_evidence_proto_rawDesc
+)
+
+func file_evidence_proto_rawDescGZIP() []byte {
+	file_evidence_proto_rawDescOnce.Do(func() {
+		file_evidence_proto_rawDescData = protoimpl.X.CompressGZIP(file_evidence_proto_rawDescData)
+	})
+	return file_evidence_proto_rawDescData
+}
+
+var file_evidence_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_evidence_proto_goTypes = []interface{}{
+	(*DoubleSignEvidence)(nil), // 0: evidence.DoubleSignEvidence
+	(*BlockHeader)(nil),        // 1: block.BlockHeader
+}
+var file_evidence_proto_depIdxs = []int32{
+	1, // 0: evidence.DoubleSignEvidence.First:type_name -> block.BlockHeader
+	1, // 1: evidence.DoubleSignEvidence.Second:type_name -> block.BlockHeader
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_evidence_proto_init() }
+func file_evidence_proto_init() {
+	if File_evidence_proto != nil {
+		return
+	}
+	file_block_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_evidence_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DoubleSignEvidence); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_evidence_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_evidence_proto_goTypes,
+		DependencyIndexes: file_evidence_proto_depIdxs,
+		MessageInfos:      file_evidence_proto_msgTypes,
+	}.Build()
+	File_evidence_proto = out.File
+	file_evidence_proto_rawDesc = nil
+	file_evidence_proto_goTypes = nil
+	file_evidence_proto_depIdxs = nil
+}
@@ -0,0 +1,259 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v5.28.0--dev
+// source: governance.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// GovernanceProposal changes the validator or observer set
+type GovernanceProposal struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Action     uint32   `protobuf:"varint,1,opt,name=Action,proto3" json:"Action,omitempty"`        // Kind of change: add, remove, reweight validator, add, remove observer
+	Target     []byte   `protobuf:"bytes,2,opt,name=Target,proto3" json:"Target,omitempty"`         // Address of the validator or observer
+	Weight     []byte   `protobuf:"bytes,3,opt,name=Weight,proto3" json:"Weight,omitempty"`         // Validator weight for add and reweight
+	AccessList [][]byte `protobuf:"bytes,4,rep,name=AccessList,proto3" json:"AccessList,omitempty"` // Accessible nodes of an observer
+}
+
+func (x *GovernanceProposal) Reset() {
+	*x = GovernanceProposal{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_governance_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GovernanceProposal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GovernanceProposal) ProtoMessage() {}
+
+func (x *GovernanceProposal) ProtoReflect() protoreflect.Message {
+	mi := &file_governance_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GovernanceProposal.ProtoReflect.Descriptor instead.
+func (*GovernanceProposal) Descriptor() ([]byte, []int) {
+	return file_governance_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GovernanceProposal) GetAction() uint32 {
+	if x != nil {
+		return x.Action
+	}
+	return 0
+}
+
+func (x *GovernanceProposal) GetTarget() []byte {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *GovernanceProposal) GetWeight() []byte {
+	if x != nil {
+		return x.Weight
+	}
+	return nil
+}
+
+func (x *GovernanceProposal) GetAccessList() [][]byte {
+	if x != nil {
+		return x.AccessList
+	}
+	return nil
+}
+
+// GovernanceCall is the data of a transaction sent to the governance address
+type GovernanceCall struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Proposal   *GovernanceProposal `protobuf:"bytes,1,opt,name=Proposal,proto3" json:"Proposal,omitempty"`     // Set to submit a new proposal
+	ProposalId []byte              `protobuf:"bytes,2,opt,name=ProposalId,proto3" json:"ProposalId,omitempty"` // Set to vote on an existing proposal
+	Approve    bool                `protobuf:"varint,3,opt,name=Approve,proto3" json:"Approve,omitempty"`      // Vote decision
+}
+
+func (x *GovernanceCall) Reset() {
+	*x = GovernanceCall{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_governance_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GovernanceCall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GovernanceCall) ProtoMessage() {}
+
+func (x *GovernanceCall) ProtoReflect() protoreflect.Message {
+	mi := &file_governance_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GovernanceCall.ProtoReflect.Descriptor instead.
+func (*GovernanceCall) Descriptor() ([]byte, []int) {
+	return file_governance_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GovernanceCall) GetProposal() *GovernanceProposal {
+	if x != nil {
+		return x.Proposal
+	}
+	return nil
+}
+
+func (x *GovernanceCall) GetProposalId() []byte {
+	if x != nil {
+		return x.ProposalId
+	}
+	return nil
+}
+
+func (x *GovernanceCall) GetApprove() bool {
+	if x != nil {
+		return x.Approve
+	}
+	return false
+}
+
+var File_governance_proto protoreflect.FileDescriptor
+
+var file_governance_proto_rawDesc = []byte{
+	0x0a, 0x10, 0x67, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x0a, 0x67, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x22, 0x7c,
+	0x0a, 0x12, 0x47, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x50, 0x72, 0x6f, 0x70,
+	0x6f, 0x73, 0x61, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06,
+	0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x54, 0x61,
+	0x72, 0x67, 0x65, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1e, 0x0a, 0x0a,
+	0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0c,
+	0x52, 0x0a, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x22, 0x86, 0x01, 0x0a,
+	0x0e, 0x47, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x61, 0x6c, 0x6c, 0x12,
+	0x3a, 0x0a, 0x08, 0x50, 0x72, 0x6f, 0x70, 0x6f, 0x73, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x67, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x2e, 0x47,
+	0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x50, 0x72, 0x6f, 0x70, 0x6f, 0x73, 0x61,
+	0x6c, 0x52, 0x08, 0x50, 0x72, 0x6f, 0x70, 0x6f, 0x73, 0x61, 0x6c, 0x12, 0x1e, 0x0a, 0x0a, 0x50,
+	0x72, 0x6f, 0x70, 0x6f, 0x73, 0x61, 0x6c, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x0a, 0x50, 0x72, 0x6f, 0x70, 0x6f, 0x73, 0x61, 0x6c, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x41,
+	0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x41, 0x70,
+	0x70, 0x72, 0x6f, 0x76, 0x65, 0x42, 0x08, 0x5a, 0x06, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_governance_proto_rawDescOnce sync.Once
+	file_governance_proto_rawDescData = file_governance_proto_rawDesc
+)
+
+func file_governance_proto_rawDescGZIP() []byte {
+	file_governance_proto_rawDescOnce.Do(func() {
+		file_governance_proto_rawDescData = protoimpl.X.CompressGZIP(file_governance_proto_rawDescData)
+	})
+	return file_governance_proto_rawDescData
+}
+
+var file_governance_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_governance_proto_goTypes = []interface{}{
+	(*GovernanceProposal)(nil), // 0: governance.GovernanceProposal
+	(*GovernanceCall)(nil),     // 1: governance.GovernanceCall
+}
+var file_governance_proto_depIdxs = []int32{
+	0, // 0: governance.GovernanceCall.Proposal:type_name -> governance.GovernanceProposal
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_governance_proto_init() }
+func file_governance_proto_init() {
+	if File_governance_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_governance_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GovernanceProposal); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_governance_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GovernanceCall); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_governance_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_governance_proto_goTypes,
+		DependencyIndexes: file_governance_proto_depIdxs,
+		MessageInfos:      file_governance_proto_msgTypes,
+	}.Build()
+	File_governance_proto = out.File
+	file_governance_proto_rawDesc = nil
+	file_governance_proto_goTypes = nil
+	file_governance_proto_depIdxs = nil
+}
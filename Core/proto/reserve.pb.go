@@ -0,0 +1,454 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v5.28.0--dev
+// source: reserve.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ReserveCall is the data of a transaction sent to the reserve address
+type ReserveCall struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Action    uint32 `protobuf:"varint,1,opt,name=Action,proto3" json:"Action,omitempty"`       // Kind of operation: mint, burn, deposit, withdraw
+	Recipient []byte `protobuf:"bytes,2,opt,name=Recipient,proto3" json:"Recipient,omitempty"`  // Account credited by a mint
+	Amount    []byte `protobuf:"bytes,3,opt,name=Amount,proto3" json:"Amount,omitempty"`        // Amount minted or moved in or out of the reserve, burns use the transaction amount, attestations the attested balance
+	Timestamp uint64 `protobuf:"varint,4,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"` // Time the attested balance was observed by the bank
+}
+
+func (x *ReserveCall) Reset() {
+	*x = ReserveCall{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reserve_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReserveCall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReserveCall) ProtoMessage() {}
+
+func (x *ReserveCall) ProtoReflect() protoreflect.Message {
+	mi := &file_reserve_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReserveCall.ProtoReflect.Descriptor instead.
+func (*ReserveCall) Descriptor() ([]byte, []int) {
+	return file_reserve_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ReserveCall) GetAction() uint32 {
+	if x != nil {
+		return x.Action
+	}
+	return 0
+}
+
+func (x *ReserveCall) GetRecipient() []byte {
+	if x != nil {
+		return x.Recipient
+	}
+	return nil
+}
+
+func (x *ReserveCall) GetAmount() []byte {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *ReserveCall) GetTimestamp() uint64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+// ReserveAttestation is the latest reserve balance a bank attested on chain
+type ReserveAttestation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Balance   []byte `protobuf:"bytes,1,opt,name=Balance,proto3" json:"Balance,omitempty"`      // Attested fiat balance
+	Timestamp uint64 `protobuf:"varint,2,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"` // Time the balance was observed by the bank
+	Height    uint64 `protobuf:"varint,3,opt,name=Height,proto3" json:"Height,omitempty"`       // Block the attestation was included in
+	TxHash    []byte `protobuf:"bytes,4,opt,name=TxHash,proto3" json:"TxHash,omitempty"`        // Hash of the signed attestation transaction
+}
+
+func (x *ReserveAttestation) Reset() {
+	*x = ReserveAttestation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reserve_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReserveAttestation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReserveAttestation) ProtoMessage() {}
+
+func (x *ReserveAttestation) ProtoReflect() protoreflect.Message {
+	mi := &file_reserve_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReserveAttestation.ProtoReflect.Descriptor instead.
+func (*ReserveAttestation) Descriptor() ([]byte, []int) {
+	return file_reserve_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ReserveAttestation) GetBalance() []byte {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+func (x *ReserveAttestation) GetTimestamp() uint64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ReserveAttestation) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *ReserveAttestation) GetTxHash() []byte {
+	if x != nil {
+		return x.TxHash
+	}
+	return nil
+}
+
+// BankReserve is the reserve position of a bank
+type BankReserve struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Bank        []byte              `protobuf:"bytes,1,opt,name=Bank,proto3" json:"Bank,omitempty"`
+	Balance     []byte              `protobuf:"bytes,2,opt,name=Balance,proto3" json:"Balance,omitempty"`         // Reserve balance recorded on chain
+	Issued      []byte              `protobuf:"bytes,3,opt,name=Issued,proto3" json:"Issued,omitempty"`           // Native VND outstanding against the reserve
+	Attestation *ReserveAttestation `protobuf:"bytes,4,opt,name=Attestation,proto3" json:"Attestation,omitempty"` // Latest attestation, empty if the bank never attested
+}
+
+func (x *BankReserve) Reset() {
+	*x = BankReserve{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reserve_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BankReserve) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BankReserve) ProtoMessage() {}
+
+func (x *BankReserve) ProtoReflect() protoreflect.Message {
+	mi := &file_reserve_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BankReserve.ProtoReflect.Descriptor instead.
+func (*BankReserve) Descriptor() ([]byte, []int) {
+	return file_reserve_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BankReserve) GetBank() []byte {
+	if x != nil {
+		return x.Bank
+	}
+	return nil
+}
+
+func (x *BankReserve) GetBalance() []byte {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+func (x *BankReserve) GetIssued() []byte {
+	if x != nil {
+		return x.Issued
+	}
+	return nil
+}
+
+func (x *BankReserve) GetAttestation() *ReserveAttestation {
+	if x != nil {
+		return x.Attestation
+	}
+	return nil
+}
+
+// SupplyReport compares the native supply with the fiat reserves at a block
+type SupplyReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Height  uint64         `protobuf:"varint,1,opt,name=Height,proto3" json:"Height,omitempty"`
+	Supply  []byte         `protobuf:"bytes,2,opt,name=Supply,proto3" json:"Supply,omitempty"`   // Sum of all account balances
+	Reserve []byte         `protobuf:"bytes,3,opt,name=Reserve,proto3" json:"Reserve,omitempty"` // Sum of all bank reserves
+	Banks   []*BankReserve `protobuf:"bytes,4,rep,name=Banks,proto3" json:"Banks,omitempty"`
+}
+
+func (x *SupplyReport) Reset() {
+	*x = SupplyReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reserve_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SupplyReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SupplyReport) ProtoMessage() {}
+
+func (x *SupplyReport) ProtoReflect() protoreflect.Message {
+	mi := &file_reserve_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SupplyReport.ProtoReflect.Descriptor instead.
+func (*SupplyReport) Descriptor() ([]byte, []int) {
+	return file_reserve_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SupplyReport) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *SupplyReport) GetSupply() []byte {
+	if x != nil {
+		return x.Supply
+	}
+	return nil
+}
+
+func (x *SupplyReport) GetReserve() []byte {
+	if x != nil {
+		return x.Reserve
+	}
+	return nil
+}
+
+func (x *SupplyReport) GetBanks() []*BankReserve {
+	if x != nil {
+		return x.Banks
+	}
+	return nil
+}
+
+var File_reserve_proto protoreflect.FileDescriptor
+
+var file_reserve_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x07, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x22, 0x79, 0x0a, 0x0b, 0x52, 0x65, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x43, 0x61, 0x6c, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x41, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x1c, 0x0a, 0x09, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x09, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x41,
+	0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x22, 0x7c, 0x0a, 0x12, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x41, 0x74,
+	0x74, 0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x42, 0x61, 0x6c,
+	0x61, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x42, 0x61, 0x6c, 0x61,
+	0x6e, 0x63, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x12, 0x16, 0x0a, 0x06, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x06, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x78, 0x48,
+	0x61, 0x73, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x54, 0x78, 0x48, 0x61, 0x73,
+	0x68, 0x22, 0x92, 0x01, 0x0a, 0x0b, 0x42, 0x61, 0x6e, 0x6b, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x42, 0x61, 0x6e, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x04, 0x42, 0x61, 0x6e, 0x6b, 0x12, 0x18, 0x0a, 0x07, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x49, 0x73, 0x73, 0x75, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x06, 0x49, 0x73, 0x73, 0x75, 0x65, 0x64, 0x12, 0x3d, 0x0a, 0x0b, 0x41, 0x74, 0x74, 0x65, 0x73,
+	0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x72,
+	0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x41, 0x74,
+	0x74, 0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x41, 0x74, 0x74, 0x65, 0x73,
+	0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x84, 0x01, 0x0a, 0x0c, 0x53, 0x75, 0x70, 0x70, 0x6c,
+	0x79, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x48, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12,
+	0x16, 0x0a, 0x06, 0x53, 0x75, 0x70, 0x70, 0x6c, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x06, 0x53, 0x75, 0x70, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x52, 0x65, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x12, 0x2a, 0x0a, 0x05, 0x42, 0x61, 0x6e, 0x6b, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x2e, 0x42, 0x61, 0x6e, 0x6b, 0x52,
+	0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x52, 0x05, 0x42, 0x61, 0x6e, 0x6b, 0x73, 0x42, 0x08, 0x5a,
+	0x06, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_reserve_proto_rawDescOnce sync.Once
+	file_reserve_proto_rawDescData = file_reserve_proto_rawDesc
+)
+
+func file_reserve_proto_rawDescGZIP() []byte {
+	file_reserve_proto_rawDescOnce.Do(func() {
+		file_reserve_proto_rawDescData = protoimpl.X.CompressGZIP(file_reserve_proto_rawDescData)
+	})
+	return file_reserve_proto_rawDescData
+}
+
+var file_reserve_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_reserve_proto_goTypes = []interface{}{
+	(*ReserveCall)(nil),        // 0: reserve.ReserveCall
+	(*ReserveAttestation)(nil), // 1: reserve.ReserveAttestation
+	(*BankReserve)(nil),        // 2: reserve.BankReserve
+	(*SupplyReport)(nil),       // 3: reserve.SupplyReport
+}
+var file_reserve_proto_depIdxs = []int32{
+	1, // 0: reserve.BankReserve.Attestation:type_name -> reserve.ReserveAttestation
+	2, // 1: reserve.SupplyReport.Banks:type_name -> reserve.BankReserve
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_reserve_proto_init() }
+func file_reserve_proto_init() {
+	if File_reserve_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_reserve_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReserveCall); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reserve_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReserveAttestation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reserve_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BankReserve); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reserve_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SupplyReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_reserve_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_reserve_proto_goTypes,
+		DependencyIndexes: file_reserve_proto_depIdxs,
+		MessageInfos:      file_reserve_proto_msgTypes,
+	}.Build()
+	File_reserve_proto = out.File
+	file_reserve_proto_rawDesc = nil
+	file_reserve_proto_goTypes = nil
+	file_reserve_proto_depIdxs = nil
+}
@@ -0,0 +1,247 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v5.28.0--dev
+// source: reward.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ValidatorReward is the amount a validator earned during an epoch
+type ValidatorReward struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Validator []byte `protobuf:"bytes,1,opt,name=Validator,proto3" json:"Validator,omitempty"` // Address of the validator
+	Amount    []byte `protobuf:"bytes,2,opt,name=Amount,proto3" json:"Amount,omitempty"`       // Fees credited to the validator
+}
+
+func (x *ValidatorReward) Reset() {
+	*x = ValidatorReward{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reward_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidatorReward) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidatorReward) ProtoMessage() {}
+
+func (x *ValidatorReward) ProtoReflect() protoreflect.Message {
+	mi := &file_reward_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidatorReward.ProtoReflect.Descriptor instead.
+func (*ValidatorReward) Descriptor() ([]byte, []int) {
+	return file_reward_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ValidatorReward) GetValidator() []byte {
+	if x != nil {
+		return x.Validator
+	}
+	return nil
+}
+
+func (x *ValidatorReward) GetAmount() []byte {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+// EpochSettlement is the fee distribution record of an epoch
+type EpochSettlement struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Epoch    uint64             `protobuf:"varint,1,opt,name=Epoch,proto3" json:"Epoch,omitempty"`      // Settled epoch
+	Fees     []byte             `protobuf:"bytes,2,opt,name=Fees,proto3" json:"Fees,omitempty"`         // Total transaction fees collected
+	Treasury []byte             `protobuf:"bytes,3,opt,name=Treasury,proto3" json:"Treasury,omitempty"` // Fees sent to the consortium treasury
+	Rewards  []*ValidatorReward `protobuf:"bytes,4,rep,name=Rewards,proto3" json:"Rewards,omitempty"`   // Fees credited per validator
+}
+
+func (x *EpochSettlement) Reset() {
+	*x = EpochSettlement{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reward_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EpochSettlement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EpochSettlement) ProtoMessage() {}
+
+func (x *EpochSettlement) ProtoReflect() protoreflect.Message {
+	mi := &file_reward_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EpochSettlement.ProtoReflect.Descriptor instead.
+func (*EpochSettlement) Descriptor() ([]byte, []int) {
+	return file_reward_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EpochSettlement) GetEpoch() uint64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+func (x *EpochSettlement) GetFees() []byte {
+	if x != nil {
+		return x.Fees
+	}
+	return nil
+}
+
+func (x *EpochSettlement) GetTreasury() []byte {
+	if x != nil {
+		return x.Treasury
+	}
+	return nil
+}
+
+func (x *EpochSettlement) GetRewards() []*ValidatorReward {
+	if x != nil {
+		return x.Rewards
+	}
+	return nil
+}
+
+var File_reward_proto protoreflect.FileDescriptor
+
+var file_reward_proto_rawDesc = []byte{
+	0x0a, 0x0c, 0x72, 0x65, 0x77, 0x61, 0x72, 0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06,
+	0x72, 0x65, 0x77, 0x61, 0x72, 0x64, 0x22, 0x47, 0x0a, 0x0f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61,
+	0x74, 0x6f, 0x72, 0x52, 0x65, 0x77, 0x61, 0x72, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x56, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x56, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x41, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x22,
+	0x8a, 0x01, 0x0a, 0x0f, 0x45, 0x70, 0x6f, 0x63, 0x68, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x45, 0x70, 0x6f, 0x63, 0x68, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x05, 0x45, 0x70, 0x6f, 0x63, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x46, 0x65, 0x65,
+	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x46, 0x65, 0x65, 0x73, 0x12, 0x1a, 0x0a,
+	0x08, 0x54, 0x72, 0x65, 0x61, 0x73, 0x75, 0x72, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x08, 0x54, 0x72, 0x65, 0x61, 0x73, 0x75, 0x72, 0x79, 0x12, 0x31, 0x0a, 0x07, 0x52, 0x65, 0x77,
+	0x61, 0x72, 0x64, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x72, 0x65, 0x77,
+	0x61, 0x72, 0x64, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x77,
+	0x61, 0x72, 0x64, 0x52, 0x07, 0x52, 0x65, 0x77, 0x61, 0x72, 0x64, 0x73, 0x42, 0x08, 0x5a, 0x06,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_reward_proto_rawDescOnce sync.Once
+	file_reward_proto_rawDescData = file_reward_proto_rawDesc
+)
+
+func file_reward_proto_rawDescGZIP() []byte {
+	file_reward_proto_rawDescOnce.Do(func() {
+		file_reward_proto_rawDescData = protoimpl.X.CompressGZIP(file_reward_proto_rawDescData)
+	})
+	return file_reward_proto_rawDescData
+}
+
+var file_reward_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_reward_proto_goTypes = []interface{}{
+	(*ValidatorReward)(nil), // 0: reward.ValidatorReward
+	(*EpochSettlement)(nil), // 1: reward.EpochSettlement
+}
+var file_reward_proto_depIdxs = []int32{
+	0, // 0: reward.EpochSettlement.Rewards:type_name -> reward.ValidatorReward
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_reward_proto_init() }
+func file_reward_proto_init() {
+	if File_reward_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_reward_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ValidatorReward); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reward_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EpochSettlement); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_reward_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_reward_proto_goTypes,
+		DependencyIndexes: file_reward_proto_depIdxs,
+		MessageInfos:      file_reward_proto_msgTypes,
+	}.Build()
+	File_reward_proto = out.File
+	file_reward_proto_rawDesc = nil
+	file_reward_proto_goTypes = nil
+	file_reward_proto_depIdxs = nil
+}
@@ -0,0 +1,162 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v5.28.0--dev
+// source: stake.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StakeCall is the data of a transaction sent to the staking address
+type StakeCall struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Action    uint32 `protobuf:"varint,1,opt,name=Action,proto3" json:"Action,omitempty"`      // Kind of operation: delegate, unbond, withdraw
+	Validator []byte `protobuf:"bytes,2,opt,name=Validator,proto3" json:"Validator,omitempty"` // Address of the validator the stake is bonded to
+	Amount    []byte `protobuf:"bytes,3,opt,name=Amount,proto3" json:"Amount,omitempty"`       // Amount to unbond, delegations use the transaction amount
+}
+
+func (x *StakeCall) Reset() {
+	*x = StakeCall{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_stake_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StakeCall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StakeCall) ProtoMessage() {}
+
+func (x *StakeCall) ProtoReflect() protoreflect.Message {
+	mi := &file_stake_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StakeCall.ProtoReflect.Descriptor instead.
+func (*StakeCall) Descriptor() ([]byte, []int) {
+	return file_stake_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StakeCall) GetAction() uint32 {
+	if x != nil {
+		return x.Action
+	}
+	return 0
+}
+
+func (x *StakeCall) GetValidator() []byte {
+	if x != nil {
+		return x.Validator
+	}
+	return nil
+}
+
+func (x *StakeCall) GetAmount() []byte {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+var File_stake_proto protoreflect.FileDescriptor
+
+var file_stake_proto_rawDesc = []byte{
+	0x0a, 0x0b, 0x73, 0x74, 0x61, 0x6b, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x73,
+	0x74, 0x61, 0x6b, 0x65, 0x22, 0x59, 0x0a, 0x09, 0x53, 0x74, 0x61, 0x6b, 0x65, 0x43, 0x61, 0x6c,
+	0x6c, 0x12, 0x16, 0x0a, 0x06, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x06, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x56, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x56, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x41, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x42,
+	0x08, 0x5a, 0x06, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_stake_proto_rawDescOnce sync.Once
+	file_stake_proto_rawDescData = file_stake_proto_rawDesc
+)
+
+func file_stake_proto_rawDescGZIP() []byte {
+	file_stake_proto_rawDescOnce.Do(func() {
+		file_stake_proto_rawDescData = protoimpl.X.CompressGZIP(file_stake_proto_rawDescData)
+	})
+	return file_stake_proto_rawDescData
+}
+
+var file_stake_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_stake_proto_goTypes = []interface{}{
+	(*StakeCall)(nil), // 0: stake.StakeCall
+}
+var file_stake_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_stake_proto_init() }
+func file_stake_proto_init() {
+	if File_stake_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_stake_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StakeCall); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_stake_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_stake_proto_goTypes,
+		DependencyIndexes: file_stake_proto_depIdxs,
+		MessageInfos:      file_stake_proto_msgTypes,
+	}.Build()
+	File_stake_proto = out.File
+	file_stake_proto_rawDesc = nil
+	file_stake_proto_goTypes = nil
+	file_stake_proto_depIdxs = nil
+}
@@ -0,0 +1,215 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v5.28.0--dev
+// source: tx_gossip.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// TransactionHashes announces the transactions a validator holds, or asks a
+// validator for them
+type TransactionHashes struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hashes [][]byte `protobuf:"bytes,1,rep,name=Hashes,proto3" json:"Hashes,omitempty"`
+}
+
+func (x *TransactionHashes) Reset() {
+	*x = TransactionHashes{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tx_gossip_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TransactionHashes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionHashes) ProtoMessage() {}
+
+func (x *TransactionHashes) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_gossip_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionHashes.ProtoReflect.Descriptor instead.
+func (*TransactionHashes) Descriptor() ([]byte, []int) {
+	return file_tx_gossip_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TransactionHashes) GetHashes() [][]byte {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+// Transactions holds the requested transactions the serving node has,
+// unknown hashes are skipped
+type Transactions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Transactions []*Transaction `protobuf:"bytes,1,rep,name=Transactions,proto3" json:"Transactions,omitempty"`
+}
+
+func (x *Transactions) Reset() {
+	*x = Transactions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tx_gossip_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Transactions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transactions) ProtoMessage() {}
+
+func (x *Transactions) ProtoReflect() protoreflect.Message {
+	mi := &file_tx_gossip_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transactions.ProtoReflect.Descriptor instead.
+func (*Transactions) Descriptor() ([]byte, []int) {
+	return file_tx_gossip_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Transactions) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+var File_tx_gossip_proto protoreflect.FileDescriptor
+
+var file_tx_gossip_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x74, 0x78, 0x5f, 0x67, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x09, 0x74, 0x78, 0x5f, 0x67, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x1a, 0x11, 0x74, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
+	0x2b, 0x0a, 0x11, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x61,
+	0x73, 0x68, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x4c, 0x0a, 0x0c,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x3c, 0x0a, 0x0c,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0c, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x42, 0x08, 0x5a, 0x06, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_tx_gossip_proto_rawDescOnce sync.Once
+	file_tx_gossip_proto_rawDescData = file_tx_gossip_proto_rawDesc
+)
+
+func file_tx_gossip_proto_rawDescGZIP() []byte {
+	file_tx_gossip_proto_rawDescOnce.Do(func() {
+		file_tx_gossip_proto_rawDescData = protoimpl.X.CompressGZIP(file_tx_gossip_proto_rawDescData)
+	})
+	return file_tx_gossip_proto_rawDescData
+}
+
+var file_tx_gossip_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_tx_gossip_proto_goTypes = []interface{}{
+	(*TransactionHashes)(nil), // 0: tx_gossip.TransactionHashes
+	(*Transactions)(nil),      // 1: tx_gossip.Transactions
+	(*Transaction)(nil),       // 2: transaction.Transaction
+}
+var file_tx_gossip_proto_depIdxs = []int32{
+	2, // 0: tx_gossip.Transactions.Transactions:type_name -> transaction.Transaction
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_tx_gossip_proto_init() }
+func file_tx_gossip_proto_init() {
+	if File_tx_gossip_proto != nil {
+		return
+	}
+	file_transaction_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_tx_gossip_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TransactionHashes); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tx_gossip_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Transactions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_tx_gossip_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_tx_gossip_proto_goTypes,
+		DependencyIndexes: file_tx_gossip_proto_depIdxs,
+		MessageInfos:      file_tx_gossip_proto_msgTypes,
+	}.Build()
+	File_tx_gossip_proto = out.File
+	file_tx_gossip_proto_rawDesc = nil
+	file_tx_gossip_proto_goTypes = nil
+	file_tx_gossip_proto_depIdxs = nil
+}
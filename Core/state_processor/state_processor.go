@@ -36,7 +36,7 @@ func NewStateProcessor(
 	return &StateProcessor{
 		config: config,
 		bc:     bc,
-		// engine: engine,
+		engine: engine,
 	}
 }
 
@@ -80,9 +80,22 @@ func (p *StateProcessor) Process(
 		allLogs = append(allLogs, receipt.Logs...)
 	}
 
-	// TODO
-	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	// p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
+	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards).
+	// A copy of the header is used so the imported header is left untouched.
+	if p.engine != nil {
+		chain, _ := p.bc.(consensus.ChainReader)
+		finalHeader := *header
+		if _, err := p.engine.Finalize(
+			chain,
+			&finalHeader,
+			statedb,
+			block.Transactions,
+			block.Uncles,
+			receipts,
+		); err != nil {
+			return nil, nil, 0, err
+		}
+	}
 
 	return receipts, allLogs, *usedGas, nil
 }
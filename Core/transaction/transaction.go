@@ -77,6 +77,19 @@ func (t *Transaction) calculateHash() (e_common.Hash, error) {
 	return crypto.Keccak256Hash(bHashData), nil
 }
 
+// ValidateHash checks that the hash received with a transaction is the hash
+// of its content
+func (t *Transaction) ValidateHash() error {
+	hash, err := t.calculateHash()
+	if err != nil {
+		return err
+	}
+	if t.hash != (e_common.Hash{}) && t.hash != hash {
+		return fmt.Errorf("transaction hash mismatch: have %s, want %s", t.hash.Hex(), hash.Hex())
+	}
+	return nil
+}
+
 func (t *Transaction) HashSign(chainId *big.Int) (e_common.Hash, error) {
 	signData := &pb.TransactionSignData{
 		ToAddress: t.To().Bytes(),
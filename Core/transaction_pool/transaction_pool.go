@@ -2,6 +2,8 @@ package transaction_pool
 
 import (
 	"errors"
+	"fmt"
+	"math/big"
 	"sort"
 	"sync"
 
@@ -12,88 +14,446 @@ import (
 	"FichainCore/transaction"
 )
 
+var (
+	ErrAlreadyKnown       = errors.New("transaction already exists in mempool")
+	ErrInvalidSender      = errors.New("invalid transaction sender")
+	ErrNonceTooLow        = errors.New("nonce too low")
+	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
+	ErrAccountFull        = errors.New("too many queued transactions for account")
+	ErrPoolFull           = errors.New("transaction pool is full")
+)
+
+// Config caps the transactions the pool holds
+type Config struct {
+	AccountSlots int    // executable transactions held per account
+	AccountQueue int    // future transactions held per account
+	GlobalSlots  int    // executable transactions held for all accounts
+	GlobalQueue  int    // future transactions held for all accounts
+	PriceBump    uint64 // percent a replacement must raise the gas price of the transaction with the same nonce
+}
+
+func DefaultConfig() Config {
+	return Config{
+		AccountSlots: 16,
+		AccountQueue: 64,
+		GlobalSlots:  4096,
+		GlobalQueue:  1024,
+		PriceBump:    10,
+	}
+}
+
+// StateReader reads the nonce of an account the pool knows nothing about from
+// the state of the chain head
+type StateReader interface {
+	GetNonce(address common.Address) uint64
+}
+
+// account holds the pooled transactions of a sender. Pending transactions are
+// executable: their nonces follow the account nonce without gap. Queued
+// transactions wait for the nonces before them.
+type account struct {
+	nonce   uint64
+	pending []*transaction.Transaction // sorted by nonce, starting at nonce
+	queued  []*transaction.Transaction // sorted by nonce, above the pending ones
+}
+
+// nextNonce is the nonce of the next executable transaction
+func (a *account) nextNonce() uint64 {
+	return a.nonce + uint64(len(a.pending))
+}
+
+type pooled struct {
+	tx   *transaction.Transaction
+	from common.Address
+}
+
+// TransactionPool keeps the transactions waiting to be included in a block by
+// sender and nonce. A transaction whose nonce follows the account nonce is
+// pending, one arriving ahead of a missing nonce is queued and promoted once
+// the gap is filled or the account nonce advances. Transactions stay in the
+// pool until a block including them, or a later nonce of their sender, is
+// imported, so the ones a block builder skips are proposed again.
 type TransactionPool struct {
-	mu           sync.Mutex
-	transactions []*transaction.Transaction
+	config Config
+	state  StateReader
+
+	all      map[common.Hash]*pooled
+	accounts map[common.Address]*account
+	pending  int
+	queued   int
+
+	mu sync.Mutex
 }
 
-func NewTransactionPool() *TransactionPool {
+func NewTransactionPool(config Config, state StateReader) *TransactionPool {
 	return &TransactionPool{
-		transactions: []*transaction.Transaction{},
+		config:   config,
+		state:    state,
+		all:      make(map[common.Hash]*pooled),
+		accounts: make(map[common.Address]*account),
 	}
 }
 
+// AddTransaction pools a transaction, replacing the one with the same nonce of
+// its sender if it pays a high enough gas price
 func (m *TransactionPool) AddTransaction(tx *transaction.Transaction) error {
+	hash := tx.Hash()
+	from, err := tx.From(params.TempChainId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSender, err)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if _, ok := m.all[hash]; ok {
+		return ErrAlreadyKnown
+	}
+	acc := m.account(from)
+	if tx.Nonce() < acc.nonce {
+		m.dropIfEmpty(from, acc)
+		return fmt.Errorf("%w: next nonce %d, transaction nonce %d", ErrNonceTooLow, acc.nonce, tx.Nonce())
+	}
 
-	// (Tùy chọn) kiểm tra trùng lặp
-	for _, existing := range m.transactions {
-		if existing.Hash() == tx.Hash() {
-			return errors.New("transaction already exists in mempool")
+	if replaced, err := m.replace(acc, tx); replaced || err != nil {
+		if err == nil {
+			m.all[hash] = &pooled{tx: tx, from: from}
 		}
+		return err
 	}
 
-	m.transactions = append(m.transactions, tx)
+	if tx.Nonce() == acc.nextNonce() && m.canPromote(acc) {
+		acc.pending = append(acc.pending, tx)
+		m.pending++
+	} else {
+		if err := m.makeRoom(from, acc); err != nil {
+			m.dropIfEmpty(from, acc)
+			return err
+		}
+		acc.queued = insert(acc.queued, tx)
+		m.queued++
+	}
+	m.all[hash] = &pooled{tx: tx, from: from}
+	m.promote(acc)
 	return nil
 }
 
-// GetPendingTransactions returns a map of transactions grouped by fromAddress,
-// and sorted by nonce in ascending order.
+// GetPendingTransactions returns the executable transactions grouped by
+// sender and sorted by nonce
 func (m *TransactionPool) GetPendingTransactions() map[common.Address][]*transaction.Transaction {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	grouped := make(map[common.Address][]*transaction.Transaction)
-
-	// Group transactions by from address
-	for _, tx := range m.transactions {
-		from, err := tx.From(params.TempChainId)
-		if err != nil {
-			logger.Error("error when get from from tx", err)
+	pending := make(map[common.Address][]*transaction.Transaction, len(m.accounts))
+	for from, acc := range m.accounts {
+		if len(acc.pending) > 0 {
+			pending[from] = append([]*transaction.Transaction(nil), acc.pending...)
 		}
-		grouped[from] = append(grouped[from], tx)
 	}
-
-	// Sort each group by nonce
-	for from, txs := range grouped {
-		sorted := txs
-		sort.Slice(sorted, func(i, j int) bool {
-			return sorted[i].Nonce() < sorted[j].Nonce()
-		})
-		grouped[from] = sorted
-	}
-
-	return grouped
+	return pending
 }
 
+// RemoveTransactions is called with the transactions of an imported block. It
+// advances the nonce of their senders past them, drops every pooled
+// transaction made stale and promotes the queued ones that became executable.
 func (m *TransactionPool) RemoveTransactions(txs []*transaction.Transaction) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	hashSet := make(map[common.Hash]bool)
+	touched := make(map[common.Address]*account)
 	for _, tx := range txs {
-		hashSet[tx.Hash()] = true
+		from, ok := m.sender(tx)
+		if !ok {
+			continue
+		}
+		acc, ok := m.accounts[from]
+		if !ok {
+			continue
+		}
+		if tx.Nonce()+1 > acc.nonce {
+			m.advance(acc, tx.Nonce()+1)
+		}
+		touched[from] = acc
+	}
+	for from, acc := range touched {
+		m.dropIfEmpty(from, acc)
+	}
+	// pending slots freed by the block let any account promote
+	for _, acc := range m.accounts {
+		m.promote(acc)
 	}
+	return nil
+}
+
+// Discard drops a transaction that can never be included, the later nonces of
+// its sender are queued again until the nonce is filled
+func (m *TransactionPool) Discard(tx *transaction.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	filtered := m.transactions[:0]
-	for _, tx := range m.transactions {
-		if !hashSet[tx.Hash()] {
-			filtered = append(filtered, tx)
+	p, ok := m.all[tx.Hash()]
+	if !ok {
+		return
+	}
+	acc := m.accounts[p.from]
+	delete(m.all, tx.Hash())
+	for i, queued := range acc.queued {
+		if queued == p.tx {
+			acc.queued = append(acc.queued[:i], acc.queued[i+1:]...)
+			m.queued--
+			m.dropIfEmpty(p.from, acc)
+			return
 		}
 	}
-	m.transactions = filtered
+	for i, pending := range acc.pending {
+		if pending == p.tx {
+			demoted := acc.pending[i+1:]
+			acc.pending = acc.pending[:i]
+			m.pending -= len(demoted) + 1
+			m.queued += len(demoted)
+			acc.queued = append(append([]*transaction.Transaction(nil), demoted...), acc.queued...)
+			break
+		}
+	}
+	m.dropIfEmpty(p.from, acc)
+}
+
+// SetNonce moves an account up to the nonce of the state, for a block builder
+// finding its next transaction already used. The transactions below the nonce
+// are dropped and the rest promoted from it.
+func (m *TransactionPool) SetNonce(address common.Address, nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acc, ok := m.accounts[address]
+	if !ok || nonce <= acc.nonce {
+		return
+	}
+	m.advance(acc, nonce)
+	m.promote(acc)
+	m.dropIfEmpty(address, acc)
+}
+
+// Get returns a pooled transaction by hash, nil if it is not in the pool
+func (m *TransactionPool) Get(hash common.Hash) *transaction.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.all[hash]; ok {
+		return p.tx
+	}
 	return nil
 }
 
+// Has tells whether a transaction is in the pool
+func (m *TransactionPool) Has(hash common.Hash) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.all[hash]
+	return ok
+}
+
+// Nonce returns the nonce following the pending transactions of an account
+func (m *TransactionPool) Nonce(address common.Address) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if acc, ok := m.accounts[address]; ok {
+		return acc.nextNonce()
+	}
+	if m.state == nil {
+		return 0
+	}
+	return m.state.GetNonce(address)
+}
+
 func (m *TransactionPool) Size() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return len(m.transactions)
+	return len(m.all)
+}
+
+// Stats returns the number of pending and queued transactions
+func (m *TransactionPool) Stats() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pending, m.queued
 }
 
 func (m *TransactionPool) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.transactions = []*transaction.Transaction{}
+	m.all = make(map[common.Hash]*pooled)
+	m.accounts = make(map[common.Address]*account)
+	m.pending = 0
+	m.queued = 0
+}
+
+// account returns the transactions of a sender, reading its nonce from the
+// state the first time it is seen
+func (m *TransactionPool) account(from common.Address) *account {
+	acc, ok := m.accounts[from]
+	if !ok {
+		acc = &account{}
+		if m.state != nil {
+			acc.nonce = m.state.GetNonce(from)
+		}
+		m.accounts[from] = acc
+	}
+	return acc
+}
+
+func (m *TransactionPool) sender(tx *transaction.Transaction) (common.Address, bool) {
+	if p, ok := m.all[tx.Hash()]; ok {
+		return p.from, true
+	}
+	from, err := tx.From(params.TempChainId)
+	if err != nil {
+		logger.Error("error when get from from tx", err)
+		return common.Address{}, false
+	}
+	return from, true
+}
+
+// replace swaps the pooled transaction with the nonce of tx for tx, it tells
+// whether there was one
+func (m *TransactionPool) replace(acc *account, tx *transaction.Transaction) (bool, error) {
+	list := acc.pending
+	i := search(list, tx.Nonce())
+	if i == len(list) || list[i].Nonce() != tx.Nonce() {
+		list = acc.queued
+		i = search(list, tx.Nonce())
+		if i == len(list) || list[i].Nonce() != tx.Nonce() {
+			return false, nil
+		}
+	}
+	old := list[i]
+	if !m.outbids(tx, old) {
+		return true, fmt.Errorf(
+			"%w: gas price %v, %d%% above %v required",
+			ErrReplaceUnderpriced, tx.GasPrice(), m.config.PriceBump, old.GasPrice(),
+		)
+	}
+	delete(m.all, old.Hash())
+	list[i] = tx
+	logger.Debug("[TransactionPool] replaced", old.Hash().Hex(), "with", tx.Hash().Hex())
+	return true, nil
+}
+
+// outbids tells whether tx raises the gas price of old by the price bump
+func (m *TransactionPool) outbids(tx, old *transaction.Transaction) bool {
+	price, oldPrice := gasPrice(tx), gasPrice(old)
+	if price.Cmp(oldPrice) <= 0 {
+		return false
+	}
+	threshold := new(big.Int).Mul(oldPrice, big.NewInt(int64(100+m.config.PriceBump)))
+	threshold.Div(threshold, big.NewInt(100))
+	return price.Cmp(threshold) >= 0
+}
+
+// makeRoom checks the queue caps before a transaction of from is queued. A
+// full pool drops the furthest transaction of the account queuing the most,
+// unless from is that account.
+func (m *TransactionPool) makeRoom(from common.Address, acc *account) error {
+	if m.config.AccountQueue > 0 && len(acc.queued) >= m.config.AccountQueue {
+		return fmt.Errorf("%w: %d queued", ErrAccountFull, len(acc.queued))
+	}
+	if m.config.GlobalQueue <= 0 || m.queued < m.config.GlobalQueue {
+		return nil
+	}
+	var (
+		victim     common.Address
+		victimAcc  *account
+		victimSize = len(acc.queued)
+	)
+	for address, other := range m.accounts {
+		if address != from && len(other.queued) > victimSize {
+			victim, victimAcc, victimSize = address, other, len(other.queued)
+		}
+	}
+	if victimAcc == nil {
+		return fmt.Errorf("%w: %d queued", ErrPoolFull, m.queued)
+	}
+	last := len(victimAcc.queued) - 1
+	evicted := victimAcc.queued[last]
+	victimAcc.queued = victimAcc.queued[:last]
+	delete(m.all, evicted.Hash())
+	m.queued--
+	logger.Debug("[TransactionPool] evicted", evicted.Hash().Hex(), "of", victim.Hex())
+	m.dropIfEmpty(victim, victimAcc)
+	return nil
+}
+
+func (m *TransactionPool) canPromote(acc *account) bool {
+	if m.config.AccountSlots > 0 && len(acc.pending) >= m.config.AccountSlots {
+		return false
+	}
+	return m.config.GlobalSlots <= 0 || m.pending < m.config.GlobalSlots
+}
+
+// promote moves the queued transactions that follow the pending ones without
+// gap to the pending ones, within the slot caps
+func (m *TransactionPool) promote(acc *account) {
+	for len(acc.queued) > 0 && acc.queued[0].Nonce() == acc.nextNonce() && m.canPromote(acc) {
+		acc.pending = append(acc.pending, acc.queued[0])
+		acc.queued = acc.queued[1:]
+		m.pending++
+		m.queued--
+	}
+}
+
+// advance sets the nonce of an account and drops its transactions below it
+func (m *TransactionPool) advance(acc *account, nonce uint64) {
+	acc.nonce = nonce
+	stale := 0
+	for stale < len(acc.pending) && acc.pending[stale].Nonce() < nonce {
+		delete(m.all, acc.pending[stale].Hash())
+		stale++
+	}
+	acc.pending = acc.pending[stale:]
+	m.pending -= stale
+
+	stale = 0
+	for stale < len(acc.queued) && acc.queued[stale].Nonce() < nonce {
+		delete(m.all, acc.queued[stale].Hash())
+		stale++
+	}
+	acc.queued = acc.queued[stale:]
+	m.queued -= stale
+
+	// pending transactions must start at the account nonce
+	if len(acc.pending) > 0 && acc.pending[0].Nonce() != nonce {
+		m.pending -= len(acc.pending)
+		m.queued += len(acc.pending)
+		acc.queued = append(acc.pending, acc.queued...)
+		acc.pending = nil
+	}
+}
+
+// dropIfEmpty forgets an account without transactions, its nonce is read
+// from the state again when it sends a new one
+func (m *TransactionPool) dropIfEmpty(from common.Address, acc *account) {
+	if len(acc.pending) == 0 && len(acc.queued) == 0 {
+		delete(m.accounts, from)
+	}
+}
+
+// search returns the index of the first transaction with a nonce not below
+// nonce
+func search(txs []*transaction.Transaction, nonce uint64) int {
+	return sort.Search(len(txs), func(i int) bool {
+		return txs[i].Nonce() >= nonce
+	})
+}
+
+func insert(txs []*transaction.Transaction, tx *transaction.Transaction) []*transaction.Transaction {
+	i := search(txs, tx.Nonce())
+	txs = append(txs, nil)
+	copy(txs[i+1:], txs[i:])
+	txs[i] = tx
+	return txs
+}
+
+func gasPrice(tx *transaction.Transaction) *big.Int {
+	if tx.GasPrice() == nil {
+		return new(big.Int)
+	}
+	return tx.GasPrice()
 }
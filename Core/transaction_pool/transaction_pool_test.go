@@ -0,0 +1,179 @@
+package transaction_pool
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FichainCore/common"
+	"FichainCore/crypto"
+	"FichainCore/params"
+	"FichainCore/signer"
+	"FichainCore/transaction"
+	"FichainCore/types"
+)
+
+type testState map[common.Address]uint64
+
+func (s testState) GetNonce(address common.Address) uint64 { return s[address] }
+
+func newTestSigner(t *testing.T) (*signer.Signer, common.Address) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	s := signer.NewSigner(types.PrivateKeyFromBytes(crypto.FromECDSA(key)))
+	return s, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func newTestTx(t *testing.T, s *signer.Signer, nonce uint64, gasPrice int64) *transaction.Transaction {
+	tx := transaction.NewTransaction(
+		common.HexToAddress("0x1234"),
+		nonce,
+		big.NewInt(1),
+		nil,
+		21000,
+		big.NewInt(gasPrice),
+		"",
+	)
+	hash, err := tx.HashSign(params.TempChainId)
+	assert.NoError(t, err)
+	sign, err := s.SignHash(hash)
+	assert.NoError(t, err)
+	tx.SetSign(sign)
+	return tx
+}
+
+func nonces(txs []*transaction.Transaction) []uint64 {
+	result := make([]uint64, len(txs))
+	for i, tx := range txs {
+		result[i] = tx.Nonce()
+	}
+	return result
+}
+
+func TestPromote(t *testing.T) {
+	s, from := newTestSigner(t)
+	pool := NewTransactionPool(DefaultConfig(), testState{from: 3})
+
+	assert.ErrorIs(t, pool.AddTransaction(newTestTx(t, s, 2, 1)), ErrNonceTooLow)
+	// a nonce gap queues the transaction instead of dropping it
+	tx5 := newTestTx(t, s, 5, 1)
+	assert.NoError(t, pool.AddTransaction(tx5))
+	assert.ErrorIs(t, pool.AddTransaction(tx5), ErrAlreadyKnown)
+	assert.Empty(t, pool.GetPendingTransactions())
+	pending, queued := pool.Stats()
+	assert.Equal(t, 0, pending)
+	assert.Equal(t, 1, queued)
+
+	// filling the gap promotes the queued transaction
+	tx3 := newTestTx(t, s, 3, 1)
+	assert.NoError(t, pool.AddTransaction(tx3))
+	assert.Equal(t, []uint64{3}, nonces(pool.GetPendingTransactions()[from]))
+	assert.NoError(t, pool.AddTransaction(newTestTx(t, s, 4, 1)))
+	assert.Equal(t, []uint64{3, 4, 5}, nonces(pool.GetPendingTransactions()[from]))
+	assert.Equal(t, uint64(6), pool.Nonce(from))
+
+	// an imported block drops the included and stale transactions only
+	assert.NoError(t, pool.RemoveTransactions([]*transaction.Transaction{newTestTx(t, s, 4, 2)}))
+	assert.Nil(t, pool.Get(tx3.Hash()))
+	assert.Equal(t, []uint64{5}, nonces(pool.GetPendingTransactions()[from]))
+	assert.ErrorIs(t, pool.AddTransaction(newTestTx(t, s, 4, 1)), ErrNonceTooLow)
+
+	// a discarded transaction sends the later nonces back to the queue
+	assert.NoError(t, pool.AddTransaction(newTestTx(t, s, 6, 1)))
+	pool.Discard(tx5)
+	assert.Empty(t, pool.GetPendingTransactions())
+	pending, queued = pool.Stats()
+	assert.Equal(t, 0, pending)
+	assert.Equal(t, 1, queued)
+	assert.NoError(t, pool.AddTransaction(newTestTx(t, s, 5, 1)))
+	assert.Equal(t, []uint64{5, 6}, nonces(pool.GetPendingTransactions()[from]))
+
+	pool.RemoveTransactions([]*transaction.Transaction{newTestTx(t, s, 6, 1)})
+	assert.Equal(t, 0, pool.Size())
+}
+
+func TestSetNonce(t *testing.T) {
+	s, from := newTestSigner(t)
+	pool := NewTransactionPool(DefaultConfig(), testState{from: 3})
+	for nonce := uint64(3); nonce < 6; nonce++ {
+		assert.NoError(t, pool.AddTransaction(newTestTx(t, s, nonce, 1)))
+	}
+
+	// the state moved past nonces the pool still holds, the rest stays
+	// executable from the state nonce
+	pool.SetNonce(from, 4)
+	assert.Equal(t, []uint64{4, 5}, nonces(pool.GetPendingTransactions()[from]))
+	assert.Equal(t, uint64(6), pool.Nonce(from))
+
+	// a lower nonce is ignored
+	pool.SetNonce(from, 2)
+	assert.Equal(t, []uint64{4, 5}, nonces(pool.GetPendingTransactions()[from]))
+
+	pool.SetNonce(from, 6)
+	assert.Equal(t, 0, pool.Size())
+	pending, queued := pool.Stats()
+	assert.Equal(t, 0, pending)
+	assert.Equal(t, 0, queued)
+}
+
+func TestReplace(t *testing.T) {
+	s, from := newTestSigner(t)
+	pool := NewTransactionPool(DefaultConfig(), testState{})
+
+	old := newTestTx(t, s, 0, 100)
+	assert.NoError(t, pool.AddTransaction(old))
+	assert.ErrorIs(t, pool.AddTransaction(newTestTx(t, s, 0, 109)), ErrReplaceUnderpriced)
+	replacement := newTestTx(t, s, 0, 110)
+	assert.NoError(t, pool.AddTransaction(replacement))
+	assert.False(t, pool.Has(old.Hash()))
+	assert.Equal(t, []*transaction.Transaction{replacement}, pool.GetPendingTransactions()[from])
+
+	// queued transactions are replaced too
+	assert.NoError(t, pool.AddTransaction(newTestTx(t, s, 2, 100)))
+	queuedReplacement := newTestTx(t, s, 2, 200)
+	assert.NoError(t, pool.AddTransaction(queuedReplacement))
+	assert.Equal(t, 2, pool.Size())
+	assert.NoError(t, pool.AddTransaction(newTestTx(t, s, 1, 100)))
+	assert.Equal(t, queuedReplacement, pool.GetPendingTransactions()[from][2])
+}
+
+func TestCaps(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountSlots = 2
+	config.AccountQueue = 3
+	config.GlobalSlots = 3
+	config.GlobalQueue = 3
+	sA, fromA := newTestSigner(t)
+	sB, fromB := newTestSigner(t)
+	pool := NewTransactionPool(config, testState{})
+
+	// executable transactions above the account slots wait in the queue
+	for nonce := uint64(0); nonce < 5; nonce++ {
+		assert.NoError(t, pool.AddTransaction(newTestTx(t, sA, nonce, 1)))
+	}
+	assert.ErrorIs(t, pool.AddTransaction(newTestTx(t, sA, 5, 1)), ErrAccountFull)
+	assert.Equal(t, []uint64{0, 1}, nonces(pool.GetPendingTransactions()[fromA]))
+
+	// a full queue evicts the furthest transaction of the account queuing
+	// the most
+	furthest := newTestTx(t, sA, 4, 1)
+	assert.True(t, pool.Has(furthest.Hash()))
+	assert.NoError(t, pool.AddTransaction(newTestTx(t, sB, 0, 1)))
+	assert.NoError(t, pool.AddTransaction(newTestTx(t, sB, 1, 1)))
+	assert.False(t, pool.Has(furthest.Hash()))
+	assert.NoError(t, pool.AddTransaction(newTestTx(t, sB, 3, 1)))
+	pending, queued := pool.Stats()
+	assert.Equal(t, 3, pending)
+	assert.Equal(t, 3, queued)
+	// unless the sender is that account
+	assert.ErrorIs(t, pool.AddTransaction(newTestTx(t, sB, 4, 1)), ErrPoolFull)
+
+	// executable transactions above the global slots wait in the queue too,
+	// until a block frees their slots
+	assert.Equal(t, []uint64{0}, nonces(pool.GetPendingTransactions()[fromB]))
+	pool.RemoveTransactions(pool.GetPendingTransactions()[fromA])
+	assert.Equal(t, []uint64{2}, nonces(pool.GetPendingTransactions()[fromA]))
+	assert.Equal(t, []uint64{0, 1}, nonces(pool.GetPendingTransactions()[fromB]))
+	assert.Equal(t, uint64(2), pool.Nonce(fromB))
+}